@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// ABICache serves contract ABIs to validations and report decoding.
+// It reads through to the chain, and falls back on the ABIs bundled
+// with the launch (the `contracts` of the config, verified against
+// the launch data hashes) when the endpoint can't serve them, which
+// happens under load or without a history node.
+type ABICache struct {
+	api     *eos.API
+	breaker *circuitBreaker
+
+	// bundle maps accounts to the ABI the boot sequence sets on them.
+	bundle map[eos.AccountName]*eos.ABI
+
+	cache map[eos.AccountName]*eos.ABI
+	lock  sync.Mutex
+}
+
+// abis returns the ABI cache, bundling the launch contracts the first
+// time around.
+func (b *BIOS) abis() *ABICache {
+	b.abiCacheOnce.Do(func() {
+		b.abiCache = &ABICache{
+			api:     b.validationAPI(),
+			breaker: b.breaker("get_abi"),
+			bundle:  map[eos.AccountName]*eos.ABI{},
+			cache:   map[eos.AccountName]*eos.ABI{},
+		}
+
+		for _, step := range b.LaunchData.BootSequence {
+			setCode, ok := step.Data.(*OpSetCode)
+			if !ok {
+				continue
+			}
+			abi, err := readABIFile(b.Config.Contracts[setCode.ContractNameRef].ABIPath)
+			if err != nil {
+				info.Printf("Not bundling ABI of %q: %s\n", setCode.ContractNameRef, err)
+				continue
+			}
+			b.abiCache.bundle[setCode.Account] = abi
+		}
+	})
+	return b.abiCache
+}
+
+func readABIFile(filename string) (*eos.ABI, error) {
+	cnt, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var abi *eos.ABI
+	if err := json.Unmarshal(cnt, &abi); err != nil {
+		return nil, fmt.Errorf("decoding %q: %s", filename, err)
+	}
+	return abi, nil
+}
+
+// Get returns the ABI of `account`, preferring the chain's. A chain
+// ABI differing from the bundled one is reported, but trusted.
+func (c *ABICache) Get(account eos.AccountName) (*eos.ABI, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if abi := c.cache[account]; abi != nil {
+		return abi, nil
+	}
+
+	onChain, err := c.fetch(account)
+	if err == nil {
+		if bundled := c.bundle[account]; bundled != nil && !sameABI(bundled, onChain) {
+			info.Printf("WARNING: the ABI of %s on chain differs from the bundled one\n", account)
+		}
+		c.cache[account] = onChain
+		return onChain, nil
+	}
+
+	if bundled := c.bundle[account]; bundled != nil {
+		verbose.Printf("Using bundled ABI for %s, chain didn't serve it: %s\n", account, err)
+		return bundled, nil
+	}
+
+	return nil, fmt.Errorf("ABI of %s: %s", account, err)
+}
+
+// CrossCheck compares the chain's ABI of `account` with the bundle.
+func (c *ABICache) CrossCheck(account eos.AccountName) error {
+	bundled := c.bundle[account]
+	if bundled == nil {
+		return fmt.Errorf("no bundled ABI for %s", account)
+	}
+
+	onChain, err := c.fetch(account)
+	if err != nil {
+		return err
+	}
+
+	if !sameABI(bundled, onChain) {
+		return fmt.Errorf("ABI of %s on chain differs from the launch contract's", account)
+	}
+	return nil
+}
+
+func (c *ABICache) fetch(account eos.AccountName) (out *eos.ABI, err error) {
+	err = c.breaker.Call(func() error {
+		code, err := c.api.GetCode(account)
+		if err != nil {
+			return err
+		}
+		out = &code.ABI
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Actions) == 0 && len(out.Structs) == 0 {
+		return nil, fmt.Errorf("no ABI set on %s", account)
+	}
+	return out, nil
+}
+
+// sameABI compares the parts of the ABIs that matter for decoding.
+func sameABI(a, b *eos.ABI) bool {
+	strip := func(abi *eos.ABI) []byte {
+		cnt, _ := json.Marshal(eos.ABI{Types: abi.Types, Structs: abi.Structs, Actions: abi.Actions, Tables: abi.Tables})
+		return cnt
+	}
+	return bytes.Equal(strip(a), strip(b))
+}
+
+// DecodeAction decodes the binary data of `act` with its contract's
+// ABI.
+func (c *ABICache) DecodeAction(act *eos.Action) (interface{}, error) {
+	abi, err := c.Get(act.Account)
+	if err != nil {
+		return nil, err
+	}
+
+	var structName string
+	for _, action := range abi.Actions {
+		if action.Name == act.Name {
+			structName = action.Type
+		}
+	}
+	if structName == "" {
+		return nil, fmt.Errorf("action %s not found in the ABI of %s", act.Name, act.Account)
+	}
+
+	data := []byte(act.HexData)
+	if len(data) == 0 && act.Data != nil {
+		data, err = eos.MarshalBinary(act.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	d := &abiDecoder{abi: abi, data: data}
+	out, err := d.decode(structName)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s::%s: %s", act.Account, act.Name, err)
+	}
+	if len(d.data) != 0 {
+		return nil, fmt.Errorf("decoding %s::%s: %d trailing bytes", act.Account, act.Name, len(d.data))
+	}
+	return out, nil
+}
+
+type abiDecoder struct {
+	abi  *eos.ABI
+	data []byte
+}
+
+func (d *abiDecoder) read(n int) ([]byte, error) {
+	if len(d.data) < n {
+		return nil, fmt.Errorf("unexpected end of data")
+	}
+	out := d.data[:n]
+	d.data = d.data[n:]
+	return out, nil
+}
+
+func (d *abiDecoder) readVaruint32() (uint32, error) {
+	var out uint32
+	for shift := uint(0); shift < 35; shift += 7 {
+		b, err := d.read(1)
+		if err != nil {
+			return 0, err
+		}
+		out |= uint32(b[0]&0x7f) << shift
+		if b[0]&0x80 == 0 {
+			return out, nil
+		}
+	}
+	return 0, fmt.Errorf("varuint32 too long")
+}
+
+func (d *abiDecoder) decode(typ string) (interface{}, error) {
+	for _, alias := range d.abi.Types {
+		if alias.NewTypeName == typ {
+			return d.decode(alias.Type)
+		}
+	}
+
+	if strings.HasSuffix(typ, "[]") {
+		count, err := d.readVaruint32()
+		if err != nil {
+			return nil, err
+		}
+		out := []interface{}{}
+		for i := uint32(0); i < count; i++ {
+			el, err := d.decode(strings.TrimSuffix(typ, "[]"))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, el)
+		}
+		return out, nil
+	}
+
+	if strings.HasSuffix(typ, "?") {
+		present, err := d.read(1)
+		if err != nil {
+			return nil, err
+		}
+		if present[0] == 0 {
+			return nil, nil
+		}
+		return d.decode(strings.TrimSuffix(typ, "?"))
+	}
+
+	for _, s := range d.abi.Structs {
+		if s.Name == typ {
+			return d.decodeStruct(s)
+		}
+	}
+
+	return d.decodeBuiltin(typ)
+}
+
+func (d *abiDecoder) decodeStruct(s eos.StructDef) (interface{}, error) {
+	out := map[string]interface{}{}
+	if s.Base != "" {
+		base, err := d.decode(s.Base)
+		if err != nil {
+			return nil, err
+		}
+		if fields, ok := base.(map[string]interface{}); ok {
+			for k, v := range fields {
+				out[k] = v
+			}
+		}
+	}
+
+	for _, field := range s.Fields {
+		val, err := d.decode(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %s", field.Name, err)
+		}
+		out[field.Name] = val
+	}
+	return out, nil
+}
+
+func (d *abiDecoder) decodeBuiltin(typ string) (interface{}, error) {
+	fixed := map[string]int{
+		"bool": 1, "int8": 1, "uint8": 1, "int16": 2, "uint16": 2,
+		"int32": 4, "uint32": 4, "int64": 8, "uint64": 8, "float64": 8,
+		"int128": 16, "uint128": 16, "checksum160": 20, "checksum256": 32,
+		"checksum512": 64, "time_point_sec": 4, "time": 4, "block_timestamp_type": 4,
+		"time_point": 8, "name": 8, "account_name": 8, "permission_name": 8,
+		"action_name": 8, "table_name": 8, "scope_name": 8, "symbol": 8,
+		"symbol_code": 8, "asset": 16, "public_key": 34, "signature": 66,
+	}
+
+	if size, ok := fixed[typ]; ok {
+		raw, err := d.read(size)
+		if err != nil {
+			return nil, err
+		}
+		switch typ {
+		case "bool":
+			return raw[0] != 0, nil
+		case "int8":
+			return int8(raw[0]), nil
+		case "uint8":
+			return raw[0], nil
+		case "int16":
+			return int16(binary.LittleEndian.Uint16(raw)), nil
+		case "uint16":
+			return binary.LittleEndian.Uint16(raw), nil
+		case "int32":
+			return int32(binary.LittleEndian.Uint32(raw)), nil
+		case "uint32", "time_point_sec", "time", "block_timestamp_type":
+			return binary.LittleEndian.Uint32(raw), nil
+		case "int64", "time_point":
+			return int64(binary.LittleEndian.Uint64(raw)), nil
+		case "uint64":
+			return binary.LittleEndian.Uint64(raw), nil
+		case "float64":
+			return math.Float64frombits(binary.LittleEndian.Uint64(raw)), nil
+		case "name", "account_name", "permission_name", "action_name", "table_name", "scope_name":
+			return eos.NameToString(binary.LittleEndian.Uint64(raw)), nil
+		case "symbol", "symbol_code":
+			return decodeSymbol(raw).Symbol, nil
+		case "asset":
+			return eos.Asset{Amount: int64(binary.LittleEndian.Uint64(raw)), Symbol: decodeSymbol(raw[8:])}.String(), nil
+		case "public_key":
+			return convertKey("PUB_K1_" + encodeK1(raw[1:]))
+		default:
+			return hex.EncodeToString(raw), nil
+		}
+	}
+
+	switch typ {
+	case "varuint32":
+		return d.readVaruint32()
+	case "string", "bytes":
+		size, err := d.readVaruint32()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := d.read(int(size))
+		if err != nil {
+			return nil, err
+		}
+		if typ == "string" {
+			return string(raw), nil
+		}
+		return hex.EncodeToString(raw), nil
+	}
+
+	return nil, fmt.Errorf("unsupported type %q", typ)
+}
+
+func decodeSymbol(raw []byte) eos.Symbol {
+	return eos.Symbol{Precision: raw[0], Symbol: strings.TrimRight(string(raw[1:8]), "\x00")}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestABIDecoderStruct(t *testing.T) {
+	abi := &eos.ABI{
+		Types: []eos.ABIType{{NewTypeName: "weight_type", Type: "uint16"}},
+		Structs: []eos.StructDef{
+			{Name: "base", Fields: []eos.FieldDef{{Name: "flag", Type: "bool"}}},
+			{Name: "memo", Base: "base", Fields: []eos.FieldDef{
+				{Name: "text", Type: "string"},
+				{Name: "weights", Type: "weight_type[]"},
+				{Name: "extra", Type: "uint32?"},
+			}},
+		},
+	}
+
+	d := &abiDecoder{abi: abi, data: []byte{
+		0x01,
+		0x02, 'h', 'i',
+		0x02, 0x01, 0x00, 0x02, 0x00,
+		0x00,
+	}}
+	out, err := d.decode("memo")
+	require.NoError(t, err)
+	assert.Empty(t, d.data)
+	assert.Equal(t, map[string]interface{}{
+		"flag":    true,
+		"text":    "hi",
+		"weights": []interface{}{uint16(1), uint16(2)},
+		"extra":   nil,
+	}, out)
+}
+
+func TestABIDecoderTruncated(t *testing.T) {
+	d := &abiDecoder{abi: &eos.ABI{}, data: []byte{0x05, 'a'}}
+	_, err := d.decode("string")
+	assert.Error(t, err)
+}
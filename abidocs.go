@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+)
+
+// maxActionDocLength caps the excerpt of a ricardian contract shown
+// by `describe`.
+const maxActionDocLength = 300
+
+// abiDocs holds the human-readable parts of an ABI: the ricardian
+// contract of each action, and the contract's ricardian clauses.
+// Translations are ABIs of the same shape, listed in the contract's
+// `translations` in the config.
+type abiDocs struct {
+	Actions []struct {
+		Name              eos.ActionName `json:"name"`
+		RicardianContract string         `json:"ricardian_contract"`
+	} `json:"actions"`
+	RicardianClauses []struct {
+		ID   string `json:"id"`
+		Body string `json:"body"`
+	} `json:"ricardian_clauses"`
+}
+
+func readABIDocs(filename string) (*abiDocs, error) {
+	cnt, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs *abiDocs
+	if err := json.Unmarshal(cnt, &docs); err != nil {
+		return nil, fmt.Errorf("decoding %q: %s", filename, err)
+	}
+	return docs, nil
+}
+
+func (d *abiDocs) action(name eos.ActionName) string {
+	for _, act := range d.Actions {
+		if act.Name == name {
+			return act.RicardianContract
+		}
+	}
+	return ""
+}
+
+// contractDocs are the docs of the contract set on an account, keyed
+// by language, the ABI's own being "default".
+type contractDocs map[string]*abiDocs
+
+func (c contractDocs) languages() (out []string) {
+	for lang := range c {
+		if lang != "default" {
+			out = append(out, lang)
+		}
+	}
+	sort.Strings(out)
+	return append([]string{"default"}, out...)
+}
+
+// loadContractDocs reads the docs of the contracts the boot sequence
+// sets, from the config's `contracts`.
+func (b *BIOS) loadContractDocs() map[eos.AccountName]contractDocs {
+	out := map[eos.AccountName]contractDocs{}
+	for _, step := range b.LaunchData.BootSequence {
+		setCode, ok := step.Data.(*OpSetCode)
+		if !ok {
+			continue
+		}
+
+		contract := b.Config.Contracts[setCode.ContractNameRef]
+		docs, err := readABIDocs(contract.ABIPath)
+		if err != nil {
+			info.Printf("No docs for %q: %s\n", setCode.ContractNameRef, err)
+			continue
+		}
+		out[setCode.Account] = contractDocs{"default": docs}
+
+		for lang, path := range contract.Translations {
+			translated, err := readABIDocs(path)
+			if err != nil {
+				info.Printf("No %q docs for %q: %s\n", lang, setCode.ContractNameRef, err)
+				continue
+			}
+			out[setCode.Account][lang] = translated
+		}
+	}
+	return out
+}
+
+// stepDistinctActions lists the distinct actions a step pushes, in order.
+// Steps whose actions can't be built before the launch are skipped.
+func (b *BIOS) stepDistinctActions(idx int, step *OperationType) (out []*eos.Action) {
+	if b.EphemeralPrivateKey == nil {
+		b.EphemeralPrivateKey, _ = ecc.NewRandomPrivateKey()
+	}
+
+	b.currentStep = idx
+	acts, err := step.Data.Actions(b)
+	if err != nil {
+		verbose.Printf("Not describing the actions of step %d: %s\n", idx, err)
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for _, act := range acts {
+		key := fmt.Sprintf("%s::%s", act.Account, act.Name)
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, act)
+		}
+	}
+	return
+}
+
+// describeStepActions prints the ricardian contract of each action of
+// a step, in every language available.
+func (b *BIOS) describeStepActions(idx int, step *OperationType, docs map[eos.AccountName]contractDocs) {
+	for _, act := range b.stepDistinctActions(idx, step) {
+		fmt.Printf("   - %s::%s\n", act.Account, act.Name)
+
+		contract := docs[act.Account]
+		if contract == nil {
+			continue
+		}
+		for _, lang := range contract.languages() {
+			if text := actionDocExcerpt(contract[lang].action(act.Name)); text != "" {
+				fmt.Printf("     [%s] %s\n", lang, text)
+			}
+		}
+	}
+}
+
+// describeClauses prints the ricardian clauses of the contracts.
+func describeClauses(docs map[eos.AccountName]contractDocs) {
+	var accounts []string
+	for account := range docs {
+		accounts = append(accounts, string(account))
+	}
+	sort.Strings(accounts)
+
+	for _, account := range accounts {
+		contract := docs[AN(account)]
+		for _, lang := range contract.languages() {
+			for _, clause := range contract[lang].RicardianClauses {
+				fmt.Printf("- %s, %s [%s]: %s\n", account, clause.ID, lang, actionDocExcerpt(clause.Body))
+			}
+		}
+	}
+}
+
+// actionDocExcerpt is the first paragraph of a ricardian text, on one
+// line.
+func actionDocExcerpt(text string) string {
+	text = strings.TrimSpace(text)
+	if idx := strings.Index(text, "\n\n"); idx != -1 {
+		text = text[:idx]
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	// Cut on runes, translations are rarely ASCII.
+	if runes := []rune(text); len(runes) > maxActionDocLength {
+		text = string(runes[:maxActionDocLength]) + "..."
+	}
+	return text
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/system"
+	"github.com/eoscanada/eos-go/token"
+)
+
+// The token accounting follows every token of the boot: issued,
+// transferred (plain or packed transfers), staked (`delegatebw`
+// transfers to `eosio.stake`), and what `eosio` retains in the end.
+// The balance sheet of each symbol, with its flows per boot step, is
+// verified against the chain's currency stats and `eosio`'s balance,
+// and lands in the run report.
+
+type TokenBalanceSheet struct {
+	Symbol      string        `json:"symbol"`
+	Phases      []*TokenPhase `json:"phases"`
+	Issued      eos.Asset     `json:"issued"`
+	Transferred eos.Asset     `json:"transferred"`
+	Staked      eos.Asset     `json:"staked"`
+	Retained    eos.Asset     `json:"retained"`
+	OnChain     *TokenOnChain `json:"on_chain,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	holdings    map[string]int64
+}
+
+// TokenPhase are the flows of one boot step.
+type TokenPhase struct {
+	Step        int       `json:"step"`
+	Op          string    `json:"op"`
+	Issued      eos.Asset `json:"issued"`
+	Transferred eos.Asset `json:"transferred"`
+	Staked      eos.Asset `json:"staked"`
+	Recipients  int       `json:"recipients"`
+}
+
+type TokenOnChain struct {
+	Supply       eos.Asset `json:"supply"`
+	EosioBalance eos.Asset `json:"eosio_balance"`
+}
+
+type tokenAccounting struct {
+	sheets map[string]*TokenBalanceSheet
+}
+
+func (t *tokenAccounting) sheet(symbol eos.Symbol) *TokenBalanceSheet {
+	sheet := t.sheets[symbol.Symbol]
+	if sheet == nil {
+		zero := eos.Asset{Symbol: symbol}
+		sheet = &TokenBalanceSheet{
+			Symbol:      symbol.Symbol,
+			Issued:      zero,
+			Transferred: zero,
+			Staked:      zero,
+			Retained:    zero,
+			holdings:    map[string]int64{},
+		}
+		t.sheets[symbol.Symbol] = sheet
+	}
+	return sheet
+}
+
+func (t *tokenAccounting) phase(sheet *TokenBalanceSheet, step int, op string) *TokenPhase {
+	if len(sheet.Phases) != 0 && sheet.Phases[len(sheet.Phases)-1].Step == step {
+		return sheet.Phases[len(sheet.Phases)-1]
+	}
+	zero := eos.Asset{Symbol: sheet.Issued.Symbol}
+	phase := &TokenPhase{Step: step, Op: op, Issued: zero, Transferred: zero, Staked: zero}
+	sheet.Phases = append(sheet.Phases, phase)
+	return phase
+}
+
+func (t *tokenAccounting) move(sheet *TokenBalanceSheet, from, to eos.AccountName, amount int64) {
+	if from != "" {
+		sheet.holdings[string(from)] -= amount
+	}
+	sheet.holdings[string(to)] += amount
+}
+
+// total sums up the sheet's phases.
+func (sheet *TokenBalanceSheet) total() {
+	for _, phase := range sheet.Phases {
+		sheet.Issued.Amount += phase.Issued.Amount
+		sheet.Transferred.Amount += phase.Transferred.Amount
+		sheet.Staked.Amount += phase.Staked.Amount
+	}
+	sheet.Retained.Amount = sheet.holdings["eosio"]
+}
+
+// addStep tallies the token flows of a step's actions.
+func (t *tokenAccounting) addStep(idx int, step *OperationType, acts []*eos.Action) error {
+	packed, _ := step.Data.(*OpTransferPacked)
+
+	for _, act := range acts {
+		switch {
+		case act.Account == AN("eosio.token") && act.Name == ActN("issue"):
+			var issue token.Issue
+			if err := decodeActionData(act, &issue); err != nil {
+				return err
+			}
+			sheet := t.sheet(issue.Quantity.Symbol)
+			phase := t.phase(sheet, idx, step.Op)
+			phase.Issued.Amount += issue.Quantity.Amount
+			phase.Recipients++
+			t.move(sheet, "", issue.To, issue.Quantity.Amount)
+
+		case act.Account == AN("eosio.token") && act.Name == ActN("transfer"):
+			var transfer token.Transfer
+			if err := decodeActionData(act, &transfer); err != nil {
+				return err
+			}
+			sheet := t.sheet(transfer.Quantity.Symbol)
+			phase := t.phase(sheet, idx, step.Op)
+			phase.Transferred.Amount += transfer.Quantity.Amount
+			phase.Recipients++
+			t.move(sheet, transfer.From, transfer.To, transfer.Quantity.Amount)
+
+		case act.Account == AN("eosio") && act.Name == ActN("delegatebw"):
+			var delegate system.DelegateBW
+			if err := decodeActionData(act, &delegate); err != nil {
+				return err
+			}
+			if !delegate.Transfer {
+				continue
+			}
+			sheet := t.sheet(delegate.StakeCPU.Symbol)
+			phase := t.phase(sheet, idx, step.Op)
+			amount := delegate.StakeCPU.Amount + delegate.StakeNet.Amount
+			phase.Staked.Amount += amount
+			phase.Recipients++
+			t.move(sheet, delegate.From, AN("eosio.stake"), amount)
+
+		case packed != nil && act.Account == packed.Contract && act.Name == packed.actionName():
+			var data PackedTransfersAction
+			if err := decodeActionData(act, &data); err != nil {
+				return err
+			}
+			transfers, err := expandPackedTransfers(&data)
+			if err != nil {
+				return err
+			}
+			sheet := t.sheet(data.Symbol)
+			phase := t.phase(sheet, idx, step.Op)
+			for _, transfer := range transfers {
+				phase.Transferred.Amount += transfer.Amount
+				phase.Recipients++
+				t.move(sheet, data.From, transfer.To, transfer.Amount)
+			}
+		}
+	}
+	return nil
+}
+
+// decodeActionData decodes an action's data, typed or packed.
+func decodeActionData(act *eos.Action, v interface{}) error {
+	data := []byte(act.HexData)
+	if len(data) == 0 {
+		var err error
+		data, err = eos.MarshalBinary(act.Data)
+		if err != nil {
+			return fmt.Errorf("packing %s::%s: %s", act.Account, act.Name, err)
+		}
+	}
+	if err := eos.UnmarshalBinary(data, v); err != nil {
+		return fmt.Errorf("decoding %s::%s: %s", act.Account, act.Name, err)
+	}
+	return nil
+}
+
+// RunTokenAccounting builds the balance sheet of the boot sequence,
+// checks it against the chain, and adds it to the run report. A
+// mismatch is reported, but doesn't fail the run, the step
+// validations check balances in detail.
+func (b *BIOS) RunTokenAccounting() error {
+	accounting := &tokenAccounting{sheets: map[string]*TokenBalanceSheet{}}
+	for idx, step := range b.LaunchData.BootSequence {
+		b.currentStep = idx
+		acts, err := b.stepActions(idx, step)
+		if err != nil {
+			return fmt.Errorf("getting actions for step %q: %s", step.Op, err)
+		}
+		if err := accounting.addStep(idx, step, acts); err != nil {
+			return fmt.Errorf("step %q: %s", step.Op, err)
+		}
+	}
+
+	var symbols []string
+	for symbol := range accounting.sheets {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	info.Println("- Token accounting of the boot:")
+	for _, symbol := range symbols {
+		sheet := accounting.sheets[symbol]
+		sheet.total()
+
+		info.Printf("  %s: issued %s, transferred %s, staked %s, retained by eosio %s\n", symbol, sheet.Issued, sheet.Transferred, sheet.Staked, sheet.Retained)
+		if err := b.verifyBalanceSheet(sheet); err != nil {
+			info.Println("  WARNING: token accounting doesn't match the chain:", err)
+			sheet.Error = err.Error()
+		}
+		b.Report.TokenAccounting = append(b.Report.TokenAccounting, sheet)
+	}
+	return nil
+}
+
+func (b *BIOS) verifyBalanceSheet(sheet *TokenBalanceSheet) error {
+	supply, err := b.getTokenSupply(sheet.Issued.Symbol)
+	if err != nil {
+		return fmt.Errorf("get supply of %s: %s", sheet.Symbol, err)
+	}
+	balance, err := b.getTokenBalance(AN("eosio"))
+	if err != nil {
+		return fmt.Errorf("get balance of eosio: %s", err)
+	}
+	sheet.OnChain = &TokenOnChain{Supply: supply, EosioBalance: balance}
+
+	if supply.Amount != sheet.Issued.Amount {
+		return fmt.Errorf("supply on chain is %s, the boot issued %s", supply, sheet.Issued)
+	}
+	if balance.Symbol.Symbol == sheet.Symbol && balance.Amount != sheet.Retained.Amount {
+		return fmt.Errorf("eosio holds %s on chain, it should retain %s", balance, sheet.Retained)
+	}
+	return nil
+}
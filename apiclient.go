@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// guardedTransport bounds each call to our node's API with a
+// timeout, and opens a circuit after consecutive failures, so a
+// wedged nodeos HTTP plugin fails the boot step instead of hanging
+// it. Only transport errors and timeouts count as failures: nodeos
+// answers rejected transactions with a 500, which is a response.
+type guardedTransport struct {
+	next     http.RoundTripper
+	timeout  time.Duration
+	timeouts map[string]time.Duration
+	breaker  *circuitBreaker
+}
+
+// newAPIClient returns the HTTP client of our node's API, configured
+// with `api`. With several `producer.api_address`, it also returns
+// the endpoints, to push through each of them (see `pushlanes.go`).
+func newAPIClient(config *Config) (*http.Client, []*apiEndpoint, error) {
+	conf := config.API
+
+	timeout := 30 * time.Second
+	if conf.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(conf.Timeout)
+		if err != nil {
+			return nil, nil, fmt.Errorf("api.timeout: %s", err)
+		}
+	}
+
+	timeouts := map[string]time.Duration{}
+	for endpoint, value := range conf.Timeouts {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("api.timeouts.%s: %s", endpoint, err)
+		}
+		timeouts[endpoint] = duration
+	}
+
+	threshold := conf.BreakerThreshold
+	if threshold == 0 {
+		threshold = 5
+	}
+	cooldown := 15 * time.Second
+	if conf.BreakerCooldown != "" {
+		var err error
+		cooldown, err = time.ParseDuration(conf.BreakerCooldown)
+		if err != nil {
+			return nil, nil, fmt.Errorf("api.breaker_cooldown: %s", err)
+		}
+	}
+
+	addresses := config.Producer.apiAddressURLs
+	if len(addresses) < 2 {
+		return &http.Client{
+			Transport: &guardedTransport{
+				next:     http.DefaultTransport,
+				timeout:  timeout,
+				timeouts: timeouts,
+				breaker:  newCircuitBreaker("node api", threshold, cooldown),
+			},
+		}, nil, nil
+	}
+
+	transport := &failoverTransport{}
+	for _, address := range addresses {
+		transport.endpoints = append(transport.endpoints, &apiEndpoint{
+			url: address,
+			transport: &guardedTransport{
+				next:     http.DefaultTransport,
+				timeout:  timeout,
+				timeouts: timeouts,
+				breaker:  newCircuitBreaker("node api "+address.Host, threshold, cooldown),
+			},
+		})
+	}
+	return &http.Client{Transport: transport}, transport.endpoints, nil
+}
+
+func (t *guardedTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	// Endpoints are named after the last element of their path, like
+	// "push_transaction" for `/v1/chain/push_transaction`.
+	timeout := t.timeout
+	if endpointTimeout, found := t.timeouts[path.Base(req.URL.Path)]; found {
+		timeout = endpointTimeout
+	}
+
+	callErr := t.breaker.Call(func() error {
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		resp, err = t.next.RoundTrip(req.WithContext(ctx))
+		if err != nil {
+			cancel()
+			if ctx.Err() == context.DeadlineExceeded {
+				err = fmt.Errorf("%s: no response after %s", req.URL.Path, timeout)
+			}
+			return err
+		}
+
+		// The deadline also bounds reading the body.
+		resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+		return nil
+	})
+	if callErr == errCircuitOpen {
+		return nil, fmt.Errorf("%s: node API %s", req.URL.Path, callErr)
+	}
+
+	return resp, err
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// APIAddresses is `producer.api_address`, a single endpoint or a list.
+type APIAddresses []string
+
+func (a *APIAddresses) UnmarshalJSON(data []byte) error {
+	var address string
+	if err := json.Unmarshal(data, &address); err == nil {
+		*a = nil
+		if address != "" {
+			*a = APIAddresses{address}
+		}
+		return nil
+	}
+
+	var addresses []string
+	if err := json.Unmarshal(data, &addresses); err != nil {
+		return fmt.Errorf("expected an address or a list of addresses")
+	}
+	*a = addresses
+	return nil
+}
+
+// failoverTransport sends the calls of our node's API to the first
+// of the `producer.api_address` endpoints, and when one fails there
+// (transport error, timeout, or its circuit open), to the next ones,
+// so an unresponsive node is skipped until its circuit closes.
+//
+// Pushes aren't failed over: the next node may not have the
+// transactions they depend on yet, and one timing out may still have
+// been accepted. They stay on their endpoint, and are spread over
+// the endpoints by the push lanes, which keep dependent transactions
+// on the same one (see `pushlanes.go`).
+type failoverTransport struct {
+	endpoints []*apiEndpoint
+}
+
+type apiEndpoint struct {
+	url       *url.URL
+	transport *guardedTransport
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	candidates := t.endpoints
+	if strings.HasPrefix(path.Base(req.URL.Path), "push_transaction") {
+		candidates = candidates[:1]
+	}
+
+	var failures []string
+	for i, endpoint := range candidates {
+		endpointReq, err := t.rewrite(req, endpoint, i != 0)
+		if err != nil {
+			failures = append(failures, err.Error())
+			break
+		}
+
+		resp, err := endpoint.transport.RoundTrip(endpointReq)
+		if err == nil {
+			return resp, nil
+		}
+		if len(candidates) == 1 {
+			return nil, err
+		}
+		verbose.Printf("API endpoint %s failed, trying the next one: %s\n", endpoint.url.Host, err)
+		failures = append(failures, fmt.Sprintf("%s: %s", endpoint.url.Host, err))
+	}
+
+	return nil, fmt.Errorf("all API endpoints failed: %s", strings.Join(failures, "; "))
+}
+
+// rewrite addresses a request for the first endpoint to `endpoint`.
+// Resending it needs a fresh copy of its body.
+func (t *failoverTransport) rewrite(req *http.Request, endpoint *apiEndpoint, resend bool) (*http.Request, error) {
+	endpointReq := req.WithContext(req.Context())
+
+	endpointURL := *req.URL
+	endpointURL.Scheme = endpoint.url.Scheme
+	endpointURL.Host = endpoint.url.Host
+	basePath := strings.TrimRight(t.endpoints[0].url.Path, "/")
+	endpointURL.Path = strings.TrimRight(endpoint.url.Path, "/") + strings.TrimPrefix(req.URL.Path, basePath)
+	endpointURL.RawPath = ""
+	endpointReq.URL = &endpointURL
+	endpointReq.Host = ""
+
+	if resend && req.Body != nil {
+		if req.GetBody == nil {
+			return nil, fmt.Errorf("%s: can't resend the request", req.URL.Path)
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("%s: resending the request: %s", req.URL.Path, err)
+		}
+		endpointReq.Body = body
+	}
+	return endpointReq, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIAddressesUnmarshal(t *testing.T) {
+	var single, list APIAddresses
+	require.NoError(t, json.Unmarshal([]byte(`"http://localhost:8888"`), &single))
+	require.NoError(t, json.Unmarshal([]byte(`["http://localhost:8888", "http://localhost:8889"]`), &list))
+
+	assert.Equal(t, APIAddresses{"http://localhost:8888"}, single)
+	assert.Equal(t, APIAddresses{"http://localhost:8888", "http://localhost:8889"}, list)
+	assert.Error(t, json.Unmarshal([]byte(`8888`), &single))
+}
+
+func TestFailoverTransport(t *testing.T) {
+	var hits []string
+	newNode := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits = append(hits, name+" "+r.URL.Path)
+		}))
+	}
+	a, b := newNode("a"), newNode("b")
+	defer a.Close()
+	defer b.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	config := &Config{}
+	config.Producer.APIAddresses = APIAddresses{down.URL, a.URL, b.URL}
+	for _, address := range config.Producer.APIAddresses {
+		addressURL, err := url.Parse(address)
+		require.NoError(t, err)
+		config.Producer.apiAddressURLs = append(config.Producer.apiAddressURLs, addressURL)
+	}
+	client, endpoints, err := newAPIClient(config)
+	require.NoError(t, err)
+	assert.Len(t, endpoints, 3)
+
+	resp, err := client.Post(down.URL+"/v1/chain/get_info", "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// Pushes stay on their endpoint.
+	_, err = client.Post(down.URL+"/v1/chain/push_transaction", "application/json", strings.NewReader("{}"))
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"a /v1/chain/get_info"}, hits)
+}
+
+func TestNextLane(t *testing.T) {
+	open := newCircuitBreaker("node api b", 1, time.Hour)
+	_ = open.Call(func() error { return errors.New("down") })
+	lanes := []*pushLane{{host: "a"}, {host: "b", breaker: open}, {host: "c"}}
+
+	lane, idx := nextLane(lanes, 0)
+	assert.Equal(t, "c", lane.host)
+	assert.Equal(t, 2, idx)
+
+	lane, idx = nextLane(lanes, 2)
+	assert.Equal(t, "a", lane.host)
+	assert.Equal(t, 0, idx)
+}
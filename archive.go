@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ArchiveEntry indexes one archived run report, with the metrics
+// compared by `eos-bios history`.
+type ArchiveEntry struct {
+	File       string    `json:"file"`
+	Network    string    `json:"network,omitempty"`
+	Account    string    `json:"account"`
+	Role       string    `json:"role"`
+	LaunchHash string    `json:"launch_hash"`
+	StartedAt  time.Time `json:"started_at"`
+	Duration   float64   `json:"duration_seconds"`
+	Error      string    `json:"error,omitempty"`
+
+	Transactions int `json:"transactions"`
+	// Divergences are the failed validations, what the boot did
+	// differently from the launch data.
+	Divergences int `json:"divergences"`
+	Retries     int `json:"retries"`
+}
+
+func readArchiveIndex(archiveDir string) (out []*ArchiveEntry, err error) {
+	cnt, err := ioutil.ReadFile(filepath.Join(archiveDir, "index.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(cnt, &out); err != nil {
+		return nil, fmt.Errorf("archive index: %s", err)
+	}
+	return out, nil
+}
+
+// archiveRunReport copies the report in the archive directory, and
+// adds it to the index.
+func archiveRunReport(archiveDir, network string, report *RunReport) error {
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return err
+	}
+
+	index, err := readArchiveIndex(archiveDir)
+	if err != nil {
+		return err
+	}
+
+	entry := &ArchiveEntry{
+		File:         fmt.Sprintf("%s-%s.json", report.StartedAt.Format("20060102-150405"), report.Account),
+		Network:      network,
+		Account:      report.Account,
+		Role:         report.Role,
+		LaunchHash:   report.LaunchHash,
+		StartedAt:    report.StartedAt,
+		Duration:     report.FinishedAt.Sub(report.StartedAt).Seconds(),
+		Error:        report.Error,
+		Transactions: len(report.Transactions),
+		Retries:      report.Retries,
+	}
+	for _, finding := range report.Validations {
+		if finding.Error != "" {
+			entry.Divergences++
+		}
+	}
+
+	cnt, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(archiveDir, entry.File), cnt, 0644); err != nil {
+		return err
+	}
+
+	index = append(index, entry)
+	sort.Slice(index, func(i, j int) bool { return index[i].StartedAt.Before(index[j].StartedAt) })
+
+	cnt, err = json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(archiveDir, "index.json"), cnt, 0644); err != nil {
+		return err
+	}
+
+	info.Println("Run report archived as", filepath.Join(archiveDir, entry.File))
+	return nil
+}
+
+// runHistory implements `eos-bios history`, comparing the archived
+// rehearsals.
+func runHistory(config *Config, args []string) error {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	archiveDir := fs.String("archive-dir", config.ArchiveDir, "Archive directory, defaults to the config's archive_dir")
+	last := fs.Int("last", 0, "Only show the last N rehearsals")
+	asJSON := fs.Bool("json", false, "Print the entries as JSON, for dashboards")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *archiveDir == "" {
+		return fmt.Errorf("specify --archive-dir, or archive_dir in your config")
+	}
+
+	index, err := readArchiveIndex(*archiveDir)
+	if err != nil {
+		return err
+	}
+	if *last != 0 && len(index) > *last {
+		index = index[len(index)-*last:]
+	}
+
+	if *asJSON {
+		cnt, _ := json.MarshalIndent(index, "", "  ")
+		fmt.Println(string(cnt))
+		return nil
+	}
+
+	if len(index) == 0 {
+		fmt.Println("No rehearsal archived yet.")
+		return nil
+	}
+
+	fmt.Printf("%-20s %-12s %-12s %10s %6s %11s %7s  %s\n", "STARTED", "NETWORK", "ROLE", "DURATION", "TXS", "DIVERGENCES", "RETRIES", "RESULT")
+	var succeeded int
+	var succeededDuration float64
+	for _, entry := range index {
+		result := "OKAY"
+		if entry.Error != "" {
+			result = "FAILED: " + entry.Error
+		} else if entry.Divergences != 0 {
+			result = "DIVERGED"
+		} else {
+			succeeded++
+			succeededDuration += entry.Duration
+		}
+		duration := (time.Duration(entry.Duration) * time.Second).String()
+		fmt.Printf("%-20s %-12s %-12s %10s %6d %11d %7d  %s\n", entry.StartedAt.Format("2006-01-02 15:04:05"), entry.Network, entry.Role, duration, entry.Transactions, entry.Divergences, entry.Retries, result)
+	}
+
+	fmt.Println("")
+	fmt.Printf("%d of %d rehearsal(s) succeeded", succeeded, len(index))
+	if succeeded != 0 {
+		fmt.Printf(", in %s on average", time.Duration(succeededDuration/float64(succeeded))*time.Second)
+	}
+	fmt.Println("")
+
+	streak := 0
+	for i := len(index) - 1; i >= 0 && index[i].Error == "" && index[i].Divergences == 0; i-- {
+		streak++
+	}
+	fmt.Printf("The last %d rehearsal(s) succeeded in a row.\n", streak)
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+)
+
+// AuthorityExport is the authority graph of the system and genesis
+// accounts, as found on chain after the boot.
+type AuthorityExport struct {
+	ChainID      string                `json:"chain_id"`
+	HeadBlockNum uint32                `json:"head_block_num"`
+	ExportedAt   time.Time             `json:"exported_at"`
+	Accounts     []*AccountAuthorities `json:"accounts"`
+}
+
+type AccountAuthorities struct {
+	AccountName eos.AccountName  `json:"account_name"`
+	Privileged  bool             `json:"privileged"`
+	Permissions []eos.Permission `json:"permissions"`
+	// DeclaredLinks are the links requested by the launch file's
+	// `system.wire_permissions` ops. The chain API can't list links,
+	// so those can't be read back.
+	DeclaredLinks []LinkDef `json:"declared_links,omitempty"`
+}
+
+// SignedAuthorityExport is signed by our block signing key, over the
+// sha256 of `Export`.
+type SignedAuthorityExport struct {
+	Export    json.RawMessage `json:"export"`
+	SignedBy  eos.AccountName `json:"signed_by"`
+	PublicKey ecc.PublicKey   `json:"public_key"`
+	Signature ecc.Signature   `json:"signature"`
+}
+
+// authorityAccounts lists the accounts the boot sequence, producers
+// and genesis accounts define.
+func (b *BIOS) authorityAccounts() (names []eos.AccountName, links map[eos.AccountName][]LinkDef) {
+	seen := map[eos.AccountName]bool{AN("eosio"): true, AN("eosio.prods"): true}
+	links = map[eos.AccountName][]LinkDef{}
+
+	for _, step := range b.LaunchData.BootSequence {
+		switch op := step.Data.(type) {
+		case *OpNewAccount:
+			seen[op.NewAccount] = true
+		case *OpCreateFund:
+			seen[op.Account] = true
+		case *OpSetupWrap:
+			seen[op.account()] = true
+		case *OpWirePermissions:
+			seen[op.Account] = true
+			links[op.Account] = append(links[op.Account], op.Links...)
+		}
+	}
+	for _, prod := range b.LaunchData.Producers {
+		seen[prod.AccountName] = true
+	}
+	for _, acct := range b.LaunchData.GenesisAccounts {
+		seen[acct.AccountName] = true
+	}
+
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return
+}
+
+func (b *BIOS) ExportAuthorities() (*AuthorityExport, error) {
+	chainInfo, err := b.validationAPI().GetInfo()
+	if err != nil {
+		return nil, fmt.Errorf("get info: %s", err)
+	}
+
+	export := &AuthorityExport{
+		ChainID:      hex.EncodeToString(chainInfo.ChainID),
+		HeadBlockNum: chainInfo.HeadBlockNum,
+		ExportedAt:   time.Now().UTC(),
+	}
+
+	names, links := b.authorityAccounts()
+	for _, name := range names {
+		acct, err := b.validationAPI().GetAccount(name)
+		if err != nil {
+			return nil, fmt.Errorf("get account %s: %s", name, err)
+		}
+		export.Accounts = append(export.Accounts, &AccountAuthorities{
+			AccountName:   name,
+			Privileged:    acct.Privileged,
+			Permissions:   acct.Permissions,
+			DeclaredLinks: links[name],
+		})
+	}
+
+	return export, nil
+}
+
+// runExportAuthorities implements `eos-bios export-authorities`.
+func (b *BIOS) runExportAuthorities(args []string) error {
+	fs := flag.NewFlagSet("export-authorities", flag.ExitOnError)
+	output := fs.String("output", "authorities.json", "Where to write the signed export")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	export, err := b.ExportAuthorities()
+	if err != nil {
+		return err
+	}
+
+	cnt, err := json.Marshal(export)
+	if err != nil {
+		return err
+	}
+
+	privKey := b.Config.Producer.blockSigningPrivateKey
+	if privKey == nil {
+		return fmt.Errorf("a block signing private key is required to sign the export")
+	}
+
+	hash := sha256.Sum256(cnt)
+	sig, err := privKey.Sign(hash[:])
+	if err != nil {
+		return fmt.Errorf("signing export: %s", err)
+	}
+
+	signed, _ := json.MarshalIndent(&SignedAuthorityExport{
+		Export:    cnt,
+		SignedBy:  AN(b.Config.Producer.MyAccount),
+		PublicKey: privKey.PublicKey(),
+		Signature: sig,
+	}, "", "  ")
+
+	if err := ioutil.WriteFile(*output, signed, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported the authorities of %d accounts at block %d to %s\n", len(export.Accounts), export.HeadBlockNum, *output)
+	return nil
+}
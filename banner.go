@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// printRoleBanner tells the operator what their role is, what comes
+// next, what to publish where, and which deadlines of the launch
+// data's `timeline` apply.
+func (b *BIOS) printRoleBanner() {
+	timeline := b.LaunchData.Timeline
+	var steps []string
+
+	switch {
+	case b.AmIBootNode():
+		milestone.Println("I AM THE BOOT NODE! Let's get the ball rolling.")
+		steps = []string{
+			fmt.Sprintf("Run the %d steps of the boot sequence against %s, nobody else can reach that node.", len(b.LaunchData.BootSequence), b.Config.Producer.APIAddress),
+			fmt.Sprintf("Publish the kickstart data %s, to %s.%s", b.kickstartEncryption(), b.kickstartChannelsSummary(), deadline(timeline.KickstartBy)),
+			"Keep your node running: the ABPs connect to your secret p2p address, and validate the chain.",
+			"Register as a producer, once the boot sequence is over.",
+		}
+
+	case b.AmIAppointedBlockProducer():
+		milestone.Println("I am NOT the BOOT NODE, but I AM ONE of the Appointed Block Producers.")
+		steps = []string{
+			fmt.Sprintf("Wait for the boot node's kickstart data, %s.%s", b.kickstartSource(), deadline(timeline.KickstartBy)),
+			"Connect to the boot node (the `connect_as_abp` hook), and wait for the end-of-boot marker.",
+			fmt.Sprintf("Validate the chain against the launch data. On failure: %s.%s", b.validationFailureAction(), deadline(timeline.ValidationBy)),
+			"Register as a producer. The other participants join once the ABPs announce the chain is valid.",
+		}
+
+	default:
+		milestone.Println("Okay... I'm not part of the Appointed Block Producers, we'll wait and be ready to join.")
+		steps = []string{
+			fmt.Sprintf("Wait for the ABPs to validate the chain, and to publish the kickstart data, %s.%s", b.kickstartSource(), deadline(timeline.PublicJoinAt)),
+			"Connect to the ABPs (the `connect_as_participant` hook), and wait for the end-of-boot marker.",
+			"Register as a producer. No validation is done, the ABPs have done it.",
+		}
+	}
+
+	info.Println("")
+	milestone.Println("What happens next:")
+	for idx, step := range steps {
+		milestone.Printf("  %d. %s\n", idx+1, step)
+	}
+}
+
+func (b *BIOS) kickstartEncryption() string {
+	if b.Config.PGP.Program == "" {
+		return "in CLEARTEXT (no `pgp.program` configured)"
+	}
+	return "encrypted to the ABPs"
+}
+
+// kickstartChannelsSummary lists where the boot node publishes the
+// kickstart data.
+func (b *BIOS) kickstartChannelsSummary() string {
+	channels := []string{"your terminal"}
+	if b.Config.Hooks["publish_kickstart_data"] != nil {
+		channels = append(channels, "the `publish_kickstart_data` hook")
+	}
+	if b.Config.Transport.Type != "" {
+		channels = append(channels, "the "+b.Config.Transport.Type+" transport")
+	}
+	publish := b.Config.KickstartPublish
+	for _, channel := range b.kickstartChannels() {
+		switch channel.name {
+		case "webhook":
+			channels = append(channels, publish.Webhook.URL)
+		case "keybase":
+			channels = append(channels, "Keybase")
+		case "ipfs":
+			channels = append(channels, "IPFS")
+		case "file":
+			channels = append(channels, publish.File.Path)
+		}
+	}
+	return strings.Join(channels, ", ")
+}
+
+// kickstartSource tells where we wait for the kickstart data, see
+// `waitOnKickstartData`.
+func (b *BIOS) kickstartSource() string {
+	switch {
+	case b.Config.KickstartListener.ListenAddress != "":
+		return fmt.Sprintf("POSTed to http://%s/kickstart", b.Config.KickstartListener.ListenAddress)
+	case b.Config.KickstartPollURL != "":
+		return "polled from " + b.Config.KickstartPollURL
+	case b.Config.KickstartInputFile != "":
+		return "scanned into " + b.Config.KickstartInputFile
+	case b.Config.Transport.Type != "":
+		return "through the " + b.Config.Transport.Type + " transport"
+	}
+	return "to be pasted in here, from the boot node's social presence"
+}
+
+func (b *BIOS) validationFailureAction() string {
+	if b.Config.OnValidationFailure == "sabotage" {
+		return "refuse to register, publish a signed failure report, and DISABLE our producer accounts"
+	}
+	return "refuse to register, and publish a signed failure report"
+}
+
+// deadline describes a timeline deadline, if set.
+func deadline(at time.Time) string {
+	if at.IsZero() {
+		return ""
+	}
+
+	left := time.Until(at)
+	if left < 0 {
+		return fmt.Sprintf(" Deadline: %s, PASSED %s ago.", at.UTC().Format(time.RFC3339), (-left).Truncate(time.Second))
+	}
+	return fmt.Sprintf(" Deadline: %s, in %s.", at.UTC().Format(time.RFC3339), left.Truncate(time.Second))
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/eoscanada/eos-go"
@@ -25,7 +26,70 @@ type BIOS struct {
 	ShuffledProducers []*ProducerDef
 	MyProducerDefs    []*ProducerDef
 
+	// ReadyAccounts restricts the shuffle pool, when the launch data
+	// requires readiness attestations.
+	ReadyAccounts map[eos.AccountName]bool
+
 	EphemeralPrivateKey *ecc.PrivateKey
+
+	// ValidationAPI, when set, is used for validations instead of
+	// API. See `observer.go`.
+	ValidationAPI *eos.API
+
+	// Ledger journals what the boot node pushed, nil when no
+	// `run_dir` is configured.
+	Ledger *Ledger
+
+	// Report is filled as the run progresses, see `report.go`.
+	Report RunReport
+
+	// Preview lists the precomputed transaction IDs, see `precompute.go`.
+	Preview TxPreview
+
+	// Status is served by the status API, when configured.
+	Status Status
+
+	// Watchdog, nil when not configured, is told about progress.
+	Watchdog *Watchdog
+
+	// Transport, when configured, carries kickstart data and
+	// notifications to the other participants.
+	Transport Transport
+
+	// HookQueue delivers the hooks configured with `queue: true`.
+	HookQueue *HookQueue
+
+	// PushLanes are the endpoints of `producer.api_address` to push
+	// through, when there are several. See `pushlanes.go`.
+	PushLanes []*pushLane
+	// pushedThrough is the last block holding pushed chunks.
+	pushedThrough uint32
+
+	// breakers protect optional integrations, see `breaker.go`.
+	breakers     map[string]*circuitBreaker
+	breakersLock sync.Mutex
+
+	// hookRunners deliver hooks in the background, see `hookrunner.go`.
+	hookRunners     map[string]*hookRunner
+	hookRunnersLock sync.Mutex
+
+	// hookResultsLock guards the report's hook results, recorded by
+	// background deliveries too. See `hookresults.go`.
+	hookResultsLock sync.Mutex
+
+	// abiCache serves ABIs to validations, see `abicache.go`.
+	abiCache     *ABICache
+	abiCacheOnce sync.Once
+
+	// hardwareAPI signs with the Ledger device, see `hardwarewallet.go`.
+	hardwareAPI *eos.API
+
+	// prebuild holds the actions built in the background, see `prebuild.go`.
+	prebuild *prebuildSpool
+
+	// currentStep is the index of the boot sequence step being
+	// processed, used to tag actions with their provenance.
+	currentStep int
 }
 
 func NewBIOS(launchData *LaunchData, config *Config, snapshotData Snapshot, api *eos.API) *BIOS {
@@ -39,69 +103,117 @@ func NewBIOS(launchData *LaunchData, config *Config, snapshotData Snapshot, api
 }
 
 func (b *BIOS) Run() error {
-	fmt.Println("Start BIOS process", time.Now())
+	milestone.Println("Start BIOS process", time.Now())
 
 	if err := b.DispatchInit(); err != nil {
 		return fmt.Errorf("failed init hook: %s", err)
 	}
 
+	if err := b.RunPreflightChecks(); err != nil {
+		return fmt.Errorf("pre-flight: %s", err)
+	}
+
+	if b.Config.Status.ListenAddress != "" {
+		b.startStatusServer()
+	}
+
+	if b.Config.Watchdog.StallAfter != "" {
+		if err := b.startWatchdog(); err != nil {
+			return err
+		}
+		defer b.Watchdog.Stop()
+	}
+
 	b.PrintAppointedBlockProducers()
 
 	if b.AmIBootNode() {
+		b.setStage("boot node stage 1")
 		if err := b.RunBootNodeStage1(); err != nil {
 			return fmt.Errorf("boot node stage1: %s", err)
 		}
 	} else if b.AmIAppointedBlockProducer() {
+		b.setStage("abp stage 1")
 		if err := b.RunABPStage1(); err != nil {
 			return fmt.Errorf("abp stage1: %s", err)
 		}
 	} else {
+		b.setStage("waiting for stage 1")
 		if err := b.WaitStage1End(); err != nil {
 			return fmt.Errorf("waiting stage1: %s", err)
 		}
 	}
 
-	fmt.Println("Registering my producer account")
+	b.setStage("registering")
+	milestone.Println("Registering my producer account")
 
-	_, err := b.API.SignPushActions(system.NewRegProducer(AN(b.Config.Producer.MyAccount), b.Config.Producer.BlockSigningPublicKey, b.Config.MyParameters))
+	producerAPI, err := b.producerAPI()
+	if err != nil {
+		return err
+	}
+
+	_, err = producerAPI.SignPushActions(system.NewRegProducer(AN(b.Config.Producer.MyAccount), b.Config.Producer.BlockSigningPublicKey, b.Config.MyParameters))
 	if err != nil {
 		return fmt.Errorf("regproducer: %s", err)
 	}
 
-	fmt.Println("BIOS Sequence Terminated")
+	if b.Config.Monitor.Duration != "" || b.Config.Monitor.UntilVotingActivated || len(b.LaunchData.ScheduledActions) != 0 {
+		b.setStage("monitoring")
+		if err := b.RunMonitor(); err != nil {
+			return fmt.Errorf("monitor: %s", err)
+		}
+	}
+
+	milestone.Println("BIOS Sequence Terminated")
+
+	b.PrintIntegrationsStatus()
 
 	return b.DispatchDone()
 }
 
 func (b *BIOS) PrintAppointedBlockProducers() {
-	fmt.Println("###############################################################################################")
-	fmt.Println("###################################  SHUFFLING RESULTS  #######################################")
-	fmt.Println("")
+	info.Println("###############################################################################################")
+	info.Println("###################################  SHUFFLING RESULTS  #######################################")
+	info.Println("")
 
-	fmt.Printf("BIOS NODE: %s\n", b.ShuffledProducers[0].String())
+	milestone.Printf("BIOS NODE: %s\n", b.ShuffledProducers[0].String())
 	for i := 1; i < 22 && len(b.ShuffledProducers) > i; i++ {
-		fmt.Printf("ABP %02d:    %s\n", i, b.ShuffledProducers[i].String())
+		milestone.Printf("ABP %02d:    %s\n", i, b.ShuffledProducers[i].String())
 	}
-	fmt.Println("")
-	fmt.Println("###############################################################################################")
-	fmt.Println("########################################  BOOTING  ############################################")
-	fmt.Println("")
-	if b.AmIBootNode() {
-		fmt.Println("I AM THE BOOT NODE! Let's get the ball rolling.")
+	info.Println("")
+	info.Println("###############################################################################################")
+	info.Println("########################################  BOOTING  ############################################")
+	info.Println("")
+	b.printRoleBanner()
+	info.Println("")
 
-	} else if b.AmIAppointedBlockProducer() {
-		fmt.Println("I am NOT the BOOT NODE, but I AM ONE of the Appointed Block Producers. Stay tuned and watch the Boot node's media properties.")
-	} else {
-		fmt.Println("Okay... I'm not part of the Appointed Block Producers, we'll wait and be ready to join")
-	}
-	fmt.Println("")
-
-	fmt.Println("###############################################################################################")
-	fmt.Println("")
+	info.Println("###############################################################################################")
+	info.Println("")
 }
 
 func (b *BIOS) RunBootNodeStage1() error {
-	ephemeralPrivateKey, err := b.GenerateEphemeralPrivKey()
+	if b.LaunchData.DeterministicTransactions && b.Config.CoSign.Enabled {
+		return fmt.Errorf("the launch data requires deterministic_transactions, disable co_sign")
+	}
+
+	resume, err := b.loadResumeState()
+	if err != nil {
+		return fmt.Errorf("reading ledger: %s", err)
+	}
+	if resume != nil && !b.Config.Resume {
+		return fmt.Errorf("the run directory holds an interrupted boot, use --resume to continue it, or start over with an empty run_dir")
+	}
+
+	var ephemeralPrivateKey *ecc.PrivateKey
+	if resume != nil {
+		milestone.Println("Resuming the boot recorded in the run directory")
+		if err := b.checkResumeIntegrity(resume); err != nil {
+			return fmt.Errorf("resume: %s", err)
+		}
+		ephemeralPrivateKey, err = ecc.NewPrivateKey(resume.start.EphemeralPrivateKey)
+		b.Report.EntropyCommitments = resume.start.EntropyCommitments
+	} else {
+		ephemeralPrivateKey, err = b.GenerateEphemeralPrivKey()
+	}
 	if err != nil {
 		return err
 	}
@@ -113,7 +225,7 @@ func (b *BIOS) RunBootNodeStage1() error {
 	pubKey := ephemeralPrivateKey.PublicKey().String()
 	privKey := ephemeralPrivateKey.String()
 
-	fmt.Println("Generated ephemeral private keys:", pubKey, privKey)
+	verbose.Println("Generated ephemeral private keys:", pubKey, privKey)
 
 	// Store keys in wallet, to sign `SetCode` and friends..
 	if err := b.API.Signer.ImportPrivateKey(privKey); err != nil {
@@ -122,91 +234,296 @@ func (b *BIOS) RunBootNodeStage1() error {
 
 	keys, _ := b.API.Signer.(*eos.KeyBag).AvailableKeys()
 	for _, key := range keys {
-		fmt.Println("Available key in the KeyBag:", key)
+		verbose.Println("Available key in the KeyBag:", key)
 	}
 
-	genesisData := b.GenerateGenesisJSON(pubKey)
+	var genesisData string
+	if resume != nil {
+		// The node is already running with that genesis.
+		genesisData = resume.start.GenesisJSON
+	} else {
+		genesisData = b.GenerateGenesisJSON(pubKey)
 
-	if err = b.DispatchStartBIOSBoot(genesisData, pubKey, privKey); err != nil {
-		return fmt.Errorf("dispatch config_ready hook: %s", err)
-	}
+		if err = b.DispatchStartBIOSBoot(genesisData, pubKey, privKey); err != nil {
+			return fmt.Errorf("dispatch config_ready hook: %s", err)
+		}
+
+		verbose.Println(b.API.Signer.AvailableKeys())
 
-	fmt.Println(b.API.Signer.AvailableKeys())
+		if err := b.ledgerAppend(&LedgerEntry{
+			Type:                "start",
+			LaunchHash:          b.LaunchData.fileHash,
+			GenesisJSON:         genesisData,
+			EphemeralPrivateKey: privKey,
+			EntropyCommitments:  b.Report.EntropyCommitments,
+		}); err != nil {
+			return fmt.Errorf("ledger: %s", err)
+		}
+	}
 
 	// Run boot sequence
+	var allActions []*eos.Action
+
+	b.waitPrebuild()
 
-	// TODO: add an action at the end, with `nonce` and a message to indicate the end of the Boot process ?
-	// This way, nodes that sync can assume all boot actions are done once that nonce action goes through.
-	for _, step := range b.LaunchData.BootSequence {
-		fmt.Printf("%s  [%s]\n", step.Label, step.Op)
+	// The end-of-boot marker rides in the last step's last chunk, see `endmarker.go`.
+	lastStep := len(b.LaunchData.BootSequence) - 1
+	endMarkerPushed := false
 
-		acts, err := step.Data.Actions(b)
+	b.Report.Pipeline = &PipelineStats{}
+	var confirmations *confirmationWorker
+	if b.Config.Confirmation.Enabled {
+		confirmations, err = b.startConfirmationWorker()
+		if err != nil {
+			return err
+		}
+		defer confirmations.close()
+	}
+
+	stateManifest, err := b.PublishStateManifest()
+	if err != nil {
+		return fmt.Errorf("state manifest: %s", err)
+	}
+
+	for idx, step := range b.LaunchData.BootSequence {
+		milestone.Printf("%s  [%s]\n", step.Label, step.Op)
+		b.Watchdog.Progress(fmt.Sprintf("step %d [%s] started", idx, step.Op))
+		b.setStage(fmt.Sprintf("boot node stage 1, step %d [%s]", idx, step.Op))
+
+		b.currentStep = idx
+
+		if waiter, ok := step.Data.(WaitOperation); ok {
+			if err := waiter.Wait(b); err != nil {
+				return fmt.Errorf("step %q: %s", step.Op, err)
+			}
+		}
+
+		acts, err := b.stepActions(idx, step)
 		if err != nil {
 			return fmt.Errorf("getting actions for step %q: %s", step.Op, err)
 		}
 
 		if len(acts) != 0 {
-			for idx, chunk := range chunkifyActions(acts, 400) { // transfers max out resources higher than ~400
-				_, err = b.API.SignPushActions(chunk...)
+			limits, err := b.stepChunkLimits(idx, step.Op, acts, resume)
+			if err != nil {
+				return fmt.Errorf("step %q: %s", step.Op, err)
+			}
+
+			chunks := b.tagChunks(chunkifyActions(acts, limits))
+			if idx == lastStep {
+				chunks = b.appendEndMarker(chunks)
+				endMarkerPushed = true
+			}
+			if b.LaunchData.DeterministicTransactions {
+				chunks = b.sequenceChunks(idx, chunks)
+			}
+
+			var lastBlockNum uint32
+			var precomputed []*precomputedTx
+			var signing *signingPool
+			if (b.Config.PrecomputeIDs.Enabled || b.Config.SigningPool.Workers > 0 || b.Config.ReviewDir != "" || b.LaunchData.DeterministicTransactions) && !b.requiresCoSign(step.Op) {
+				precomputed, err = b.precomputeChunks(idx, step.Op, chunks, resume)
 				if err != nil {
-					return fmt.Errorf("SignPushActions for step %q, chunk %d: %s", step.Op, idx, err)
+					return fmt.Errorf("step %q: %s", step.Op, err)
+				}
+				if b.Config.ReviewDir != "" {
+					// Signed in full for review, the signing pool has nothing left to do.
+					if err := b.exportForReview(idx, step.Op, precomputed); err != nil {
+						return fmt.Errorf("review, step %q: %s", step.Op, err)
+					}
+				} else if b.Config.SigningPool.Workers > 0 {
+					signing = b.startSigningPool(precomputed)
+				}
+			}
+
+			push := &stepPush{step: idx, op: step.Op, limits: limits, precomputed: precomputed, signing: signing, confirmations: confirmations}
+			var pending []int
+			for chunkIdx, chunk := range chunks {
+				pushed, err := resume.pushedChunk(idx, chunkIdx, chunk)
+				if err != nil {
+					return err
+				}
+				if pushed == nil {
+					pending = append(pending, chunkIdx)
+					continue
+				}
+				verbose.Printf("Chunk %d already pushed in transaction %s, skipping\n", chunkIdx, pushed.TransactionID)
+				lastBlockNum = pushed.BlockNum
+				b.Report.Transactions = append(b.Report.Transactions, &ReportTransaction{
+					Step:          idx,
+					Op:            step.Op,
+					Chunk:         chunkIdx,
+					TransactionID: pushed.TransactionID,
+					Actions:       b.reportActions(chunk),
+				})
+			}
+
+			if b.parallelPush(step.Op) {
+				blockNum, err := b.pushParallel(push, chunks, pending, idx == lastStep)
+				if err != nil {
+					return err
+				}
+				if blockNum != 0 {
+					lastBlockNum = blockNum
+				}
+			} else {
+				for _, chunkIdx := range pending {
+					resp, err := b.pushChunk(push, chunkIdx, chunks[chunkIdx], b.API)
+					if err != nil {
+						return err
+					}
+					lastBlockNum = resp.BlockNum
+					if err := b.recordChunk(push, chunkIdx, chunks[chunkIdx], resp); err != nil {
+						return err
+					}
 				}
 			}
+			allActions = append(allActions, acts...)
+
+			if b.requiresIrreversibility(step.Op) && lastBlockNum != 0 {
+				if confirmations != nil {
+					if err := confirmations.drain(); err != nil {
+						return err
+					}
+				}
+				if err := b.waitStepIrreversible(idx, step.Op, lastBlockNum); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if confirmations != nil {
+		if err := confirmations.drain(); err != nil {
+			return err
+		}
+		info.Printf("All %d chunks confirmed on chain\n", b.Report.Pipeline.Confirmed)
+	}
+
+	if !endMarkerPushed {
+		if _, err := b.API.SignPushActions(newNonce(endMarker(b.LaunchData.fileHash))); err != nil {
+			return fmt.Errorf("pushing the end-of-boot marker: %s", err)
+		}
+	}
+	milestone.Println("End-of-boot marker pushed")
+
+	if err := b.RunTokenAccounting(); err != nil {
+		info.Println("WARNING: token accounting:", err)
+	}
+
+	if err := b.RunStateManifestVerification(stateManifest, ""); err != nil {
+		info.Println("WARNING: the chain doesn't match the state manifest:", err)
+	}
+
+	if b.Ledger != nil {
+		stateAccounts := b.StateAccounts(allActions)
+		stateHash, err := computeStateHash(b.API, stateAccounts)
+		if err != nil {
+			return fmt.Errorf("computing state hash: %s", err)
+		}
+		milestone.Println("State hash after boot sequence:", stateHash)
+		b.Report.StateHash = stateHash
+
+		if err := b.ledgerAppend(&LedgerEntry{
+			Type:          "end",
+			StateAccounts: stateAccounts,
+			StateHash:     stateHash,
+		}); err != nil {
+			return fmt.Errorf("ledger: %s", err)
 		}
 	}
 
-	fmt.Println("Preparing kickstart data")
+	info.Println("Preparing kickstart data")
+
+	kickstartTTL := b.Config.KickstartTTL
+	if kickstartTTL == "" {
+		kickstartTTL = "30m"
+	}
 
 	kickstartData := &KickstartData{
-		BIOSP2PAddress: b.Config.Producer.SecretP2PAddress,
-		PublicKeyUsed:  pubKey,
-		PrivateKeyUsed: privKey,
-		GenesisJSON:    genesisData,
+		Version:           kickstartVersion,
+		BIOSP2PAddress:    b.Config.Producer.SecretP2PAddress,
+		PublicKeyUsed:     pubKey,
+		PrivateKeyUsed:    privKey,
+		GenesisJSON:       genesisData,
+		IssuedAt:          time.Now().UTC().Truncate(time.Second),
+		TTL:               kickstartTTL,
+		StateManifestRoot: stateManifest.Root,
+	}
+	if err := kickstartData.Validate(); err != nil {
+		return fmt.Errorf("kickstart data: %s", err)
 	}
 	kd, _ := json.Marshal(kickstartData)
+	if b.Config.KickstartCompact {
+		kd, err = encodeCompactKickstart(kickstartData)
+		if err != nil {
+			return fmt.Errorf("compact kickstart data: %s", err)
+		}
+		info.Printf("Compact kickstart data: %d bytes\n", len(kd))
+	}
 	ksdata := base64.RawStdEncoding.EncodeToString(kd)
 
-	// TODO: encrypt it for those who need it
+	if b.Config.PGP.Program != "" {
+		ksdata, err = b.encryptKickstart(kd)
+		if err != nil {
+			return fmt.Errorf("encrypting kickstart data: %s", err)
+		}
+	} else {
+		milestone.Println("WARNING: no `pgp.program` configured, the kickstart data is NOT encrypted, and holds the ephemeral private key")
+	}
+
+	milestone.Println("PUBLISH THIS KICKSTART DATA:")
+	milestone.Println("")
+	milestone.Println(ksdata)
+	milestone.Println("")
 
-	fmt.Println("PUBLISH THIS KICKSTART DATA:")
-	fmt.Println("")
-	fmt.Println(ksdata)
-	fmt.Println("")
+	if err := b.printKickstartQR(ksdata); err != nil {
+		milestone.Println("WARNING:", err)
+	}
 
 	if err = b.DispatchPublishKickstartData(ksdata); err != nil {
 		return fmt.Errorf("dispatch publish_kickstart_data: %s", err)
 	}
 
+	if err = b.publish("kickstart", ksdata); err != nil {
+		return fmt.Errorf("publishing kickstart data: %s", err)
+	}
+
+	if err = b.fanOutKickstart(ksdata); err != nil {
+		return err
+	}
+
+	if err = b.publishBootManifest(genesisData); err != nil {
+		return fmt.Errorf("boot manifest: %s", err)
+	}
+
 	// Call `regproducer` for myself now
 
 	return nil
 }
 
 func (b *BIOS) RunABPStage1() error {
-	fmt.Println("Waiting on kickstart data from the BIOS Node.")
-	fmt.Println("Paste it in here. Finish with a blank line (ENTER)")
+	milestone.Println("Waiting on kickstart data from the BIOS Node.")
+	milestone.Println("Paste it in here (base64, or the armored PGP message). Finish with a blank line (ENTER)")
 
 	kickstart, err := b.waitOnKickstartData()
 	if err != nil {
 		return err
 	}
 
-	// TODO: Decrypt the Kickstart data
-	//   Do extensive validation on the input (tight regexp for address, for private key?)
-
 	if err = b.DispatchConnectAsABP(kickstart, b.MyProducerDefs); err != nil {
 		return err
 	}
 
-	fmt.Println("###############################################################################################")
-	fmt.Println("As an Appointer Block Producer, we're now launching battery of verifications...")
+	info.Println("###############################################################################################")
+	info.Println("As an Appointer Block Producer, we're now launching battery of verifications...")
 
-	fmt.Printf("- Verifying the `eosio` system account was properly disabled: ")
+	info.Printf("- Verifying the `eosio` system account was properly disabled: ")
 	for {
 		time.Sleep(1 * time.Second)
 		acct, err := b.API.GetAccount(AN("eosio"))
 		if err != nil {
-			fmt.Printf("e")
+			verbose.Printf("e")
 			continue
 		}
 
@@ -214,20 +531,37 @@ func (b *BIOS) RunABPStage1() error {
 			// FIXME: perhaps check that there are no keys and
 			// accounts.. that the account is *really* disabled.  we
 			// can check elsewhere though.
-			fmt.Printf(".")
+			verbose.Printf(".")
 			continue
 		}
 
-		fmt.Println(" OKAY")
+		info.Println(" OKAY")
 		break
 	}
 
-	fmt.Println("Chain sync'd!")
+	if _, err := b.waitEndMarker(); err != nil {
+		return err
+	}
+
+	milestone.Println("Chain sync'd!")
+
+	auditErr := b.RunChainAudit()
+	validationErr := b.RunStepValidations()
+	stateErr := b.RunStateManifestVerification(nil, kickstart.StateManifestRoot)
+	if validationErr == nil {
+		validationErr = auditErr
+	}
+	if validationErr == nil && stateErr != nil {
+		validationErr = fmt.Errorf("state manifest: %s", stateErr)
+	}
+	if validationErr != nil {
+		return b.handleValidationFailure(validationErr)
+	}
+
+	if err := b.RunTokenAccounting(); err != nil {
+		info.Println("WARNING: token accounting:", err)
+	}
 
-	// TODO: loop operations, check all actions against blocks that you can fetch from here.
-	// Do all the checks:
-	//  - all Producers are properly setup
-	//  - anything fails, SABOTAGE
 	// Publish a PGP Signed message with your local IP.. push to properties
 	// Dispatch webhook PublishKickstartPublic (with a Kickstart Data object)
 
@@ -235,10 +569,8 @@ func (b *BIOS) RunABPStage1() error {
 }
 
 func (b *BIOS) WaitStage1End() error {
-	fmt.Println("Waiting for Appointed Block Producers to finish their jobs. Check their social presence!")
+	milestone.Println("Waiting for Appointed Block Producers to finish their jobs. Check their social presence!")
 
-	// TODO: check if kickstartData invalid, then either ignore it or destroy the network
-	// TODO: rather, loop for kickstar tdatas, until something valid is dropped in..
 	kickstart, err := b.waitOnKickstartData()
 	if err != nil {
 		return err
@@ -248,30 +580,104 @@ func (b *BIOS) WaitStage1End() error {
 		return err
 	}
 
-	fmt.Println("Not doing any validation, the ABPs have done it")
+	if _, err := b.waitEndMarker(); err != nil {
+		return err
+	}
+
+	info.Println("Not doing any validation, the ABPs have done it")
 
 	return nil
 }
 
 func (b *BIOS) waitOnKickstartData() (kickstart KickstartData, err error) {
-	// Wait on stdin for kickstart data (will we have some other polling / subscription mechanisms?)
+	b.Watchdog.Pause("waiting on kickstart data")
+	defer b.Watchdog.Progress("kickstart data received")
+
+	if b.Config.KickstartListener.ListenAddress != "" {
+		return b.waitOnKickstartHTTP()
+	}
+
+	if b.Config.KickstartPollURL != "" {
+		return b.pollKickstartURL()
+	}
+
+	if b.Config.KickstartInputFile != "" {
+		return b.readKickstartFile()
+	}
+
+	if b.Transport != nil {
+		info.Printf("Waiting for kickstart data through the %s transport\n", b.Config.Transport.Type)
+		for {
+			payloads, err := b.Transport.Receive("kickstart")
+			if err != nil {
+				return kickstart, fmt.Errorf("receiving kickstart data: %s", err)
+			}
+			for _, payload := range payloads {
+				kickstart, err = b.parseKickstartData(payload)
+				if err == nil {
+					return kickstart, nil
+				}
+				info.Println("Ignoring invalid kickstart data received:", err)
+			}
+		}
+	}
+
+	// Wait on stdin for kickstart data
 	//    Accept any base64, unpadded, multi-line until we receive a blank line, concat and decode.
 	// FIXME: this is a quick hack to just pass the p2p address
-	lines, err := ScanLinesUntilBlank()
-	if err != nil {
-		return
+	for {
+		lines, err := ScanLinesUntilBlank()
+		if err != nil {
+			return kickstart, err
+		}
+
+		kickstart, err = b.parseKickstartData(lines)
+		if err == nil {
+			b.recordOperatorAction("", "input", "kickstart data pasted on stdin", "")
+			return kickstart, nil
+		}
+		milestone.Println("Invalid kickstart data, paste another one:", err)
 	}
+}
 
-	rawKickstartData, err := base64.RawStdEncoding.DecodeString(strings.Replace(strings.TrimSpace(lines), "\n", "", -1))
-	if err != nil {
-		return kickstart, fmt.Errorf("kickstart base64 decode: %s", err)
+func (b *BIOS) parseKickstartData(lines string) (kickstart KickstartData, err error) {
+	var rawKickstartData []byte
+	if strings.HasPrefix(strings.TrimSpace(lines), pgpMessageHeader) {
+		rawKickstartData, err = b.decryptKickstart(strings.TrimSpace(lines))
+		if err != nil {
+			return kickstart, fmt.Errorf("kickstart decrypt: %s", err)
+		}
+	} else {
+		rawKickstartData, err = base64.RawStdEncoding.DecodeString(strings.Replace(strings.TrimSpace(lines), "\n", "", -1))
+		if err != nil {
+			return kickstart, fmt.Errorf("kickstart base64 decode: %s", err)
+		}
 	}
 
-	err = json.Unmarshal(rawKickstartData, &kickstart)
+	kickstart, err = decodeKickstartPayload(rawKickstartData)
 	if err != nil {
 		return kickstart, fmt.Errorf("unmarshal kickstart data: %s", err)
 	}
 
+	if err := kickstart.Validate(); err != nil {
+		return kickstart, fmt.Errorf("invalid kickstart data: %s", err)
+	}
+
+	var maxAge time.Duration
+	if b.Config.KickstartMaxAge != "" {
+		maxAge, err = time.ParseDuration(b.Config.KickstartMaxAge)
+		if err != nil {
+			return kickstart, fmt.Errorf("kickstart_max_age: %s", err)
+		}
+	}
+	if err := kickstart.CheckFreshness(time.Now(), maxAge); err != nil {
+		return kickstart, fmt.Errorf("refusing kickstart data: %s", err)
+	}
+
+	if err := b.verifyGenesis(kickstart); err != nil {
+		return kickstart, fmt.Errorf("refusing kickstart data: %s", err)
+	}
+
 	privKey, err := ecc.NewPrivateKey(kickstart.PrivateKeyUsed)
 	if err != nil {
 		return kickstart, fmt.Errorf("unable to load private key %q: %s", kickstart.PrivateKeyUsed, err)
@@ -279,20 +685,34 @@ func (b *BIOS) waitOnKickstartData() (kickstart KickstartData, err error) {
 
 	b.EphemeralPrivateKey = privKey
 
-	// TODO: check if the privKey corresponds to the public key sent, if not, we should
-	// drop that kickstart data.. and listen to another one..
-
 	return
 }
 
 func (b *BIOS) GenerateEphemeralPrivKey() (*ecc.PrivateKey, error) {
-	return ecc.NewRandomPrivateKey()
+	if !b.Config.EphemeralKey.MixBeacon && b.Config.EphemeralKey.EntropyFile == "" {
+		return ecc.NewRandomPrivateKey()
+	}
+
+	sources, err := b.ephemeralEntropySources()
+	if err != nil {
+		return nil, err
+	}
+
+	b.Report.EntropyCommitments = nil
+	for _, source := range sources {
+		b.Report.EntropyCommitments = append(b.Report.EntropyCommitments, &EntropyCommitment{Source: source.Label, Commitment: source.Commitment()})
+	}
+
+	return newMixedPrivateKey(sources)
 }
 
 func (b *BIOS) GenerateGenesisJSON(pubKey string) string {
+	// `genesis_timestamp` is checked at startup
+	genesisTime, _ := b.genesisTime()
+
 	// known not to fail
 	cnt, _ := json.Marshal(&GenesisJSON{
-		InitialTimestamp: b.ShuffleBlock.Time.UTC().Format("2006-01-02T15:04:05"),
+		InitialTimestamp: genesisTime.Format("2006-01-02T15:04:05"),
 		InitialKey:       pubKey,
 		InitialChainID:   hex.EncodeToString(b.API.ChainID),
 	})
@@ -300,16 +720,24 @@ func (b *BIOS) GenerateGenesisJSON(pubKey string) string {
 }
 
 func (b *BIOS) ShuffleProducers(btcMerkleRoot []byte, blockTime time.Time) error {
-	// we'll shuffle later :)
 	if b.Config.Debug.NoShuffle {
-		fmt.Println("DEBUG: Skipping shuffle, using order in launch.yaml")
-		b.ShuffledProducers = b.LaunchData.Producers
+		info.Println("DEBUG: Skipping shuffle, using order in launch.yaml")
+		b.ShuffledProducers = b.shufflePool()
 		b.ShuffleBlock.Time = time.Now().UTC()
 		b.ShuffleBlock.MerkleRoot = []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
 	} else {
-		fmt.Println("Shuffling producers listed in the launch file [NOT IMPLEMENTED]")
-		// TODO: write the algorithm...
-		b.ShuffledProducers = b.LaunchData.Producers
+		algo := b.LaunchData.ShuffleAlgorithm
+		if algo == "" {
+			algo = defaultShuffleAlgorithm
+		}
+		shuffler, found := shufflersRegistry[algo]
+		if !found {
+			return fmt.Errorf("shuffle algorithm %q invalid, use one of: %q", algo, shuffleAlgorithms())
+		}
+
+		seed := shuffleSeed(btcMerkleRoot, blockTime)
+		info.Printf("Shuffling producers listed in the launch file, using %q with seed %x\n", algo, seed)
+		b.ShuffledProducers = shuffler.Shuffle(b.shufflePool(), seed)
 		b.ShuffleBlock.Time = blockTime
 		b.ShuffleBlock.MerkleRoot = btcMerkleRoot
 	}
@@ -398,21 +826,6 @@ func (b *BIOS) setMyProducerDefs() error {
 	return nil
 }
 
-func chunkifyActions(actions []*eos.Action, chunkSize int) (out [][]*eos.Action) {
-	currentChunk := []*eos.Action{}
-	for _, act := range actions {
-		if len(currentChunk) > chunkSize {
-			out = append(out, currentChunk)
-			currentChunk = []*eos.Action{}
-		}
-		currentChunk = append(currentChunk, act)
-	}
-	if len(currentChunk) > 0 {
-		out = append(out, currentChunk)
-	}
-	return
-}
-
 func accountVariation(name eos.AccountName, variation int) eos.AccountName {
 	if len(name) > 10 {
 		name = AN(string(name)[:10])
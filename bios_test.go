@@ -17,6 +17,8 @@ producers:
 `, `
 producer:
   my_account: mama
+debug:
+  no_shuffle: true
 `)
 	assert.True(t, bios.AmIBootNode())
 	assert.False(t, bios.IsAppointedBlockProducer("mama"))
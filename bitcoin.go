@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The shuffle is seeded by the Bitcoin block at the launch file's
+// `launch_btc_block_height`, unknown to anyone before it is mined.
+// Its header is fetched from several independent sources, which must
+// agree on the merkle root and time. Merkle roots are used as the
+// hex strings displayed by explorers and `bitcoind` decode to.
+
+type BitcoinBlock struct {
+	Height     int
+	Hash       string
+	MerkleRoot []byte
+	Time       time.Time
+}
+
+// BitcoinSource is a Bitcoin block explorer API, or a node.
+type BitcoinSource interface {
+	TipHeight() (int, error)
+	BlockAt(height int) (*BitcoinBlock, error)
+}
+
+var bitcoinSourcesRegistry = map[string]func(config *Config) BitcoinSource{
+	"blockchain.info": func(config *Config) BitcoinSource { return &blockchainInfoSource{} },
+	"blockcypher":     func(config *Config) BitcoinSource { return &blockcypherSource{} },
+	"blockstream":     func(config *Config) BitcoinSource { return &blockstreamSource{} },
+	"bitcoind":        newBitcoindSource,
+}
+
+var defaultBitcoinSources = []string{"blockchain.info", "blockcypher", "blockstream"}
+
+var entropyClient = &http.Client{Timeout: 20 * time.Second}
+
+func bitcoinSourceNames() (out []string) {
+	for name := range bitcoinSourcesRegistry {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return
+}
+
+// fetchBitcoinBlock returns the block at `launch_btc_block_height`
+// once it has enough confirmations, as agreed by the configured
+// sources. Unless `wait` is set, it fails right away when the block
+// isn't there yet.
+func (b *BIOS) fetchBitcoinBlock(wait bool) (*BitcoinBlock, error) {
+	conf := b.Config.Bitcoin
+	height := b.LaunchData.LaunchBitcoinBlockHeight
+
+	names := conf.Sources
+	if len(names) == 0 {
+		names = defaultBitcoinSources
+	}
+	sources := map[string]BitcoinSource{}
+	for _, name := range names {
+		factory, found := bitcoinSourcesRegistry[name]
+		if !found {
+			return nil, fmt.Errorf("unknown bitcoin source %q, use one of: %q", name, bitcoinSourceNames())
+		}
+		sources[name] = factory(b.Config)
+	}
+
+	minAgreeing := conf.MinAgreeing
+	if minAgreeing == 0 {
+		minAgreeing = (len(sources) + 2) / 2
+	}
+	if minAgreeing > len(sources) {
+		return nil, fmt.Errorf("bitcoin.min_agreeing is %d, but only %d source(s) configured", minAgreeing, len(sources))
+	}
+
+	confirmations := conf.Confirmations
+	if confirmations == 0 {
+		confirmations = 6
+	}
+
+	pollInterval := time.Minute
+	if conf.PollInterval != "" {
+		var err error
+		pollInterval, err = time.ParseDuration(conf.PollInterval)
+		if err != nil {
+			return nil, fmt.Errorf("bitcoin.poll_interval: %s", err)
+		}
+	}
+
+	milestone.Printf("Waiting for Bitcoin block %d, with %d confirmation(s)\n", height, confirmations)
+	for {
+		block, err := agreeingBitcoinBlock(sources, height, confirmations, minAgreeing)
+		if err == nil {
+			milestone.Printf("Bitcoin block %d: hash %s, merkle root %x, time %s\n", block.Height, block.Hash, block.MerkleRoot, block.Time.UTC())
+			return block, nil
+		}
+		if !wait {
+			return nil, err
+		}
+
+		info.Printf("Bitcoin block %d not agreed upon yet: %s\n", height, err)
+		b.Watchdog.Progress(fmt.Sprintf("waiting for bitcoin block %d", height))
+		time.Sleep(pollInterval)
+	}
+}
+
+// agreeingBitcoinBlock asks all sources for the block at `height`,
+// and returns it when at least `minAgreeing` of them return the same
+// hash, merkle root and time. Any source disagreeing fails it: it
+// needs a human to look.
+func agreeingBitcoinBlock(sources map[string]BitcoinSource, height, confirmations, minAgreeing int) (*BitcoinBlock, error) {
+	var names []string
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var agreed *BitcoinBlock
+	var agreeing []string
+	var failures []string
+	for _, name := range names {
+		source := sources[name]
+
+		tip, err := source.TipHeight()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: tip height: %s", name, err))
+			continue
+		}
+		if tip < height+confirmations-1 {
+			failures = append(failures, fmt.Sprintf("%s: tip at %d", name, tip))
+			continue
+		}
+
+		block, err := source.BlockAt(height)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", name, err))
+			continue
+		}
+		verbose.Printf("Bitcoin source %s: block %d hash %s, merkle root %x, time %s\n", name, height, block.Hash, block.MerkleRoot, block.Time.UTC())
+
+		if agreed == nil {
+			agreed = block
+		} else if block.Hash != agreed.Hash || !bytes.Equal(block.MerkleRoot, agreed.MerkleRoot) || !block.Time.Equal(agreed.Time) {
+			return nil, fmt.Errorf("bitcoin sources disagree on block %d: %s says hash %s, merkle root %x, but %s says hash %s, merkle root %x", height, strings.Join(agreeing, ", "), agreed.Hash, agreed.MerkleRoot, name, block.Hash, block.MerkleRoot)
+		}
+		agreeing = append(agreeing, name)
+	}
+
+	if len(agreeing) < minAgreeing {
+		return nil, fmt.Errorf("%d source(s) agree, %d needed (%s)", len(agreeing), minAgreeing, strings.Join(failures, "; "))
+	}
+	return agreed, nil
+}
+
+func bitcoinGetJSON(url string, out interface{}) error {
+	resp, err := entropyClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	cnt, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	if text, ok := out.(*string); ok {
+		*text = strings.TrimSpace(string(cnt))
+		return nil
+	}
+	return json.Unmarshal(cnt, out)
+}
+
+func newBitcoinBlock(height int, hash, merkleRoot string, blockTime time.Time) (*BitcoinBlock, error) {
+	root, err := hex.DecodeString(merkleRoot)
+	if err != nil || len(root) != 32 {
+		return nil, fmt.Errorf("invalid merkle root %q", merkleRoot)
+	}
+	return &BitcoinBlock{Height: height, Hash: hash, MerkleRoot: root, Time: blockTime.UTC()}, nil
+}
+
+type blockchainInfoSource struct{}
+
+func (s *blockchainInfoSource) TipHeight() (int, error) {
+	var text string
+	if err := bitcoinGetJSON("https://blockchain.info/q/getblockcount", &text); err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(text)
+}
+
+func (s *blockchainInfoSource) BlockAt(height int) (*BitcoinBlock, error) {
+	var resp struct {
+		Blocks []struct {
+			Hash       string `json:"hash"`
+			MerkleRoot string `json:"mrkl_root"`
+			Time       int64  `json:"time"`
+			MainChain  bool   `json:"main_chain"`
+		} `json:"blocks"`
+	}
+	if err := bitcoinGetJSON(fmt.Sprintf("https://blockchain.info/block-height/%d?format=json", height), &resp); err != nil {
+		return nil, err
+	}
+	for _, block := range resp.Blocks {
+		if block.MainChain {
+			return newBitcoinBlock(height, block.Hash, block.MerkleRoot, time.Unix(block.Time, 0))
+		}
+	}
+	return nil, fmt.Errorf("no main chain block at height %d", height)
+}
+
+type blockcypherSource struct{}
+
+func (s *blockcypherSource) TipHeight() (int, error) {
+	var resp struct {
+		Height int `json:"height"`
+	}
+	err := bitcoinGetJSON("https://api.blockcypher.com/v1/btc/main", &resp)
+	return resp.Height, err
+}
+
+func (s *blockcypherSource) BlockAt(height int) (*BitcoinBlock, error) {
+	var resp struct {
+		Hash       string    `json:"hash"`
+		MerkleRoot string    `json:"mrkl_root"`
+		Time       time.Time `json:"time"`
+	}
+	if err := bitcoinGetJSON(fmt.Sprintf("https://api.blockcypher.com/v1/btc/main/blocks/%d?txstart=0&limit=1", height), &resp); err != nil {
+		return nil, err
+	}
+	return newBitcoinBlock(height, resp.Hash, resp.MerkleRoot, resp.Time)
+}
+
+type blockstreamSource struct{}
+
+func (s *blockstreamSource) TipHeight() (int, error) {
+	var text string
+	if err := bitcoinGetJSON("https://blockstream.info/api/blocks/tip/height", &text); err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(text)
+}
+
+func (s *blockstreamSource) BlockAt(height int) (*BitcoinBlock, error) {
+	var hash string
+	if err := bitcoinGetJSON(fmt.Sprintf("https://blockstream.info/api/block-height/%d", height), &hash); err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		MerkleRoot string `json:"merkle_root"`
+		Timestamp  int64  `json:"timestamp"`
+	}
+	if err := bitcoinGetJSON("https://blockstream.info/api/block/"+hash, &resp); err != nil {
+		return nil, err
+	}
+	return newBitcoinBlock(height, hash, resp.MerkleRoot, time.Unix(resp.Timestamp, 0))
+}
+
+// bitcoindSource talks JSON-RPC to a local `bitcoind`.
+type bitcoindSource struct {
+	url      string
+	user     string
+	password string
+}
+
+func newBitcoindSource(config *Config) BitcoinSource {
+	conf := config.Bitcoin.Bitcoind
+	url := conf.URL
+	if url == "" {
+		url = "http://127.0.0.1:8332"
+	}
+	return &bitcoindSource{url: url, user: conf.User, password: conf.Password}
+}
+
+func (s *bitcoindSource) call(method string, out interface{}, params ...interface{}) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "1.0",
+		"id":      "eos-bios",
+		"method":  method,
+		"params":  params,
+	})
+
+	req, err := http.NewRequest("POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.user != "" {
+		req.SetBasicAuth(s.user, s.password)
+	}
+
+	resp, err := entropyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("%s: %s (%s)", method, err, resp.Status)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("%s: %s", method, rpcResp.Error.Message)
+	}
+	return json.Unmarshal(rpcResp.Result, out)
+}
+
+func (s *bitcoindSource) TipHeight() (height int, err error) {
+	err = s.call("getblockcount", &height)
+	return
+}
+
+func (s *bitcoindSource) BlockAt(height int) (*BitcoinBlock, error) {
+	var hash string
+	if err := s.call("getblockhash", &hash, height); err != nil {
+		return nil, err
+	}
+
+	var header struct {
+		MerkleRoot string `json:"merkleroot"`
+		Time       int64  `json:"time"`
+	}
+	if err := s.call("getblockheader", &header, hash); err != nil {
+		return nil, err
+	}
+	return newBitcoinBlock(height, hash, header.MerkleRoot, time.Unix(header.Time, 0))
+}
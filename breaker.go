@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var errCircuitOpen = errors.New("circuit open, skipping call")
+
+const (
+	circuitClosed   = "CLOSED"
+	circuitOpen     = "OPEN"
+	circuitHalfOpen = "HALF-OPEN"
+)
+
+// circuitBreaker stops calling a failing dependency after `threshold`
+// consecutive failures, and lets a single trial call through once
+// `cooldown` has elapsed.
+type circuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	lock     sync.Mutex
+	state    string
+	failures int
+	// total counts all failures, for the run report.
+	total    int
+	openedAt time.Time
+	lastErr  error
+	// trial is set while the half-open trial call runs, other
+	// callers being skipped meanwhile.
+	trial bool
+}
+
+func newCircuitBreaker(name string, threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		name:      name,
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     circuitClosed,
+	}
+}
+
+func (c *circuitBreaker) Call(f func() error) error {
+	var trial bool
+	c.lock.Lock()
+	if c.state == circuitHalfOpen && c.trial {
+		c.lock.Unlock()
+		return errCircuitOpen
+	}
+	if c.state == circuitOpen {
+		if time.Since(c.openedAt) < c.cooldown {
+			c.lock.Unlock()
+			return errCircuitOpen
+		}
+		c.transition(circuitHalfOpen)
+		c.trial, trial = true, true
+	}
+	c.lock.Unlock()
+
+	err := f()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if trial {
+		c.trial = false
+	}
+
+	if err == nil {
+		c.failures = 0
+		if c.state != circuitClosed {
+			c.transition(circuitClosed)
+		}
+		return nil
+	}
+
+	c.failures++
+	c.total++
+	c.lastErr = err
+	if c.state == circuitHalfOpen || c.failures >= c.threshold {
+		c.openedAt = time.Now()
+		if c.state != circuitOpen {
+			c.transition(circuitOpen)
+		}
+	}
+
+	return err
+}
+
+// transition must be called with the lock held.
+func (c *circuitBreaker) transition(state string) {
+	info.Printf("Circuit %q: %s -> %s\n", c.name, c.state, state)
+	c.state = state
+}
+
+func (c *circuitBreaker) Status() (state string, lastErr error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.state, c.lastErr
+}
+
+// Skipping tells whether calls are skipped, the circuit being open
+// and cooling down, or its trial call still running.
+func (c *circuitBreaker) Skipping() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.state == circuitHalfOpen {
+		return c.trial
+	}
+	return c.state == circuitOpen && time.Since(c.openedAt) < c.cooldown
+}
+
+// breaker returns the circuit breaker protecting the optional
+// integration `name`, creating it on first use.
+func (b *BIOS) breaker(name string) *circuitBreaker {
+	b.breakersLock.Lock()
+	defer b.breakersLock.Unlock()
+
+	if b.breakers == nil {
+		b.breakers = map[string]*circuitBreaker{}
+	}
+	if b.breakers[name] == nil {
+		b.breakers[name] = newCircuitBreaker(name, 3, time.Minute)
+	}
+	return b.breakers[name]
+}
+
+// integrationFailures is the number of failed calls to optional
+// integrations during the run.
+func (b *BIOS) integrationFailures() (total int) {
+	b.breakersLock.Lock()
+	defer b.breakersLock.Unlock()
+
+	for _, breaker := range b.breakers {
+		breaker.lock.Lock()
+		total += breaker.total
+		breaker.lock.Unlock()
+	}
+	return
+}
+
+// PrintIntegrationsStatus shows the health of optional integrations.
+func (b *BIOS) PrintIntegrationsStatus() {
+	b.breakersLock.Lock()
+	defer b.breakersLock.Unlock()
+
+	if len(b.breakers) == 0 {
+		return
+	}
+
+	var names []string
+	for name := range b.breakers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	info.Println("Optional integrations status:")
+	for _, name := range names {
+		state, lastErr := b.breakers[name].Status()
+		if lastErr != nil {
+			info.Printf("- %s: %s (last error: %s)\n", name, state, lastErr)
+		} else {
+			info.Printf("- %s: %s\n", name, state)
+		}
+	}
+}
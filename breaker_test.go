@@ -0,0 +1,41 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerSingleTrial(t *testing.T) {
+	c := newCircuitBreaker("test", 1, time.Millisecond)
+	assert.Error(t, c.Call(func() error { return errCircuitOpen }))
+	time.Sleep(2 * time.Millisecond)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, c.Call(func() error {
+			close(started)
+			<-release
+			return nil
+		}))
+	}()
+	<-started
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, errCircuitOpen, c.Call(func() error { return nil }))
+	}
+	assert.True(t, c.Skipping())
+
+	close(release)
+	wg.Wait()
+
+	state, _ := c.Status()
+	assert.Equal(t, circuitClosed, state)
+	assert.NoError(t, c.Call(func() error { return nil }))
+}
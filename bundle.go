@@ -0,0 +1,280 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bronze1man/go-yaml2json"
+	"github.com/eoscanada/eos-go"
+	"golang.org/x/crypto/openpgp"
+)
+
+// StarterBundle is a starter config prepared by the launch
+// coordinator for one producer, PGP-encrypted to them with `eos-bios
+// bundle-configs`, and imported with `--import-bundle`.
+type StarterBundle struct {
+	LaunchHash string          `json:"launch_hash"`
+	Account    eos.AccountName `json:"account"`
+	CreatedAt  time.Time       `json:"created_at"`
+
+	// RoleHint tells the team what to prepare for.
+	RoleHint string `json:"role_hint"`
+	// Endpoints to use during the launch (peers, observers, ...).
+	Endpoints map[string]interface{} `json:"endpoints,omitempty"`
+	// Deadlines of the launch (readiness, launch, ...).
+	Deadlines map[string]time.Time `json:"deadlines,omitempty"`
+	// Peering suggests how to set up the node's peers, see `mesh.go`.
+	Peering *BundlePeering `json:"peering,omitempty"`
+	// Config is merged into the producer's local config.
+	Config map[string]interface{} `json:"config"`
+}
+
+type BundlePeering struct {
+	Region       string `json:"region,omitempty"`
+	LatencyGroup string `json:"latency_group,omitempty"`
+	// PeerCount is a sensible number of `p2p-peer-address` entries.
+	PeerCount int `json:"peer_count"`
+	// Nearby producers to peer with first, closest first.
+	Nearby []string `json:"nearby"`
+}
+
+// bundleTemplate is the coordinator's input to `bundle-configs`.
+type bundleTemplate struct {
+	Endpoints map[string]interface{} `json:"endpoints"`
+	Deadlines map[string]time.Time   `json:"deadlines"`
+	Config    map[string]interface{} `json:"config"`
+	// Producers override the above, keyed by account name.
+	Producers map[string]struct {
+		RoleHint  string                 `json:"role_hint"`
+		Endpoints map[string]interface{} `json:"endpoints"`
+		Config    map[string]interface{} `json:"config"`
+	} `json:"producers"`
+}
+
+const defaultRoleHint = "participant: the shuffle may appoint you boot node or ABP, be ready for both"
+
+// runBundleConfigs implements `eos-bios bundle-configs`, run by the
+// launch coordinator.
+func runBundleConfigs(launch *LaunchData, config *Config, args []string) error {
+	fs := flag.NewFlagSet("bundle-configs", flag.ExitOnError)
+	templatePath := fs.String("template", "", "YAML file with the endpoints, deadlines, config defaults and per-producer overrides to bundle")
+	signingKey := fs.String("signing-key", "", "Armored PGP private key of the coordinator, signing the bundles")
+	output := fs.String("output", "", "Directory receiving the bundles, defaults to `bundles` in run_dir")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *templatePath == "" || *signingKey == "" {
+		fs.Usage()
+		return fmt.Errorf("--template and --signing-key are required")
+	}
+
+	outputDir := *output
+	if outputDir == "" {
+		if config.RunDir == "" {
+			return fmt.Errorf("specify --output, or run_dir in your config")
+		}
+		outputDir = filepath.Join(config.RunDir, "bundles")
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+
+	var tpl bundleTemplate
+	cnt, err := ioutil.ReadFile(*templatePath)
+	if err != nil {
+		return err
+	}
+	if err := yamlUnmarshal(cnt, &tpl); err != nil {
+		return fmt.Errorf("template: %s", err)
+	}
+
+	signer, err := readArmoredKeyFile(*signingKey)
+	if err != nil {
+		return fmt.Errorf("signing key: %s", err)
+	}
+
+	recipients, err := producersPGPKeys(launch)
+	if err != nil {
+		return err
+	}
+
+	failures := 0
+	for _, prod := range launch.Producers {
+		account := string(prod.AccountName)
+		if recipients[account] == nil {
+			failures++
+			fmt.Printf("- %s: SKIPPED, no pgp_public_key in the launch file\n", account)
+			continue
+		}
+
+		bundle := &StarterBundle{
+			LaunchHash: launch.fileHash,
+			Account:    prod.AccountName,
+			CreatedAt:  time.Now().UTC(),
+			RoleHint:   defaultRoleHint,
+			Endpoints:  tpl.Endpoints,
+			Deadlines:  tpl.Deadlines,
+			Config: deepMerge(tpl.Config, map[string]interface{}{
+				"producer": map[string]interface{}{"my_account": account},
+			}),
+		}
+
+		if prod.Region != "" || prod.LatencyGroup != "" {
+			count := peerCount(prod, launch.Producers)
+			bundle.Peering = &BundlePeering{
+				Region:       prod.Region,
+				LatencyGroup: prod.LatencyGroup,
+				PeerCount:    count,
+			}
+			for _, peer := range preferredPeers(prod, launch.Producers, count) {
+				bundle.Peering.Nearby = append(bundle.Peering.Nearby, string(peer.AccountName))
+			}
+		}
+
+		if override, found := tpl.Producers[account]; found {
+			if override.RoleHint != "" {
+				bundle.RoleHint = override.RoleHint
+			}
+			if override.Endpoints != nil {
+				bundle.Endpoints = deepMerge(bundle.Endpoints, override.Endpoints)
+			}
+			if override.Config != nil {
+				bundle.Config = deepMerge(bundle.Config, override.Config)
+			}
+		}
+
+		payload, _ := json.MarshalIndent(bundle, "", "  ")
+		encrypted, err := pgpEncrypt(payload, recipients[account], signer[0])
+		if err != nil {
+			return fmt.Errorf("encrypting bundle of %s: %s", account, err)
+		}
+
+		filename := filepath.Join(outputDir, account+".bundle.asc")
+		if err := ioutil.WriteFile(filename, []byte(encrypted), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("- %s: %s\n", account, filename)
+	}
+
+	if failures != 0 {
+		return fmt.Errorf("%d producer(s) without a bundle", failures)
+	}
+	return nil
+}
+
+// importBundle implements `--import-bundle`: it decrypts a starter
+// bundle with `--decryption-key`, checks it was signed by one of the
+// launch file's producers for this very launch file, and merges it
+// into `--local-config`. Settings already in the local config win.
+func importBundle(bundlePath, launchPath, configPath string) error {
+	if *decryptionKeyPath == "" {
+		return fmt.Errorf("--decryption-key is required to decrypt the bundle")
+	}
+	if configPath == "" {
+		return fmt.Errorf("--local-config is required, to know where to import")
+	}
+
+	launchCnt, err := readMaybeEncrypted(launchPath)
+	if err != nil {
+		return fmt.Errorf("launch data: %s", err)
+	}
+	var launch *LaunchData
+	if err := yamlUnmarshal(launchCnt, &launch); err != nil {
+		return fmt.Errorf("launch data: %s", err)
+	}
+
+	trusted, err := producersPGPKeys(launch)
+	if err != nil {
+		return err
+	}
+	var trustedList openpgp.EntityList
+	for _, keys := range trusted {
+		trustedList = append(trustedList, keys...)
+	}
+
+	keyring, err := readArmoredKeyFile(*decryptionKeyPath)
+	if err != nil {
+		return fmt.Errorf("decryption key: %s", err)
+	}
+
+	message, err := ioutil.ReadFile(bundlePath)
+	if err != nil {
+		return err
+	}
+	plain, err := pgpDecrypt(string(message), keyring, trustedList)
+	if err != nil {
+		return fmt.Errorf("bundle: %s", err)
+	}
+
+	var bundle *StarterBundle
+	if err := json.Unmarshal(plain, &bundle); err != nil {
+		return fmt.Errorf("bundle: %s", err)
+	}
+
+	launchHash := sha256.Sum256(launchCnt)
+	if hex.EncodeToString(launchHash[:]) != bundle.LaunchHash {
+		return fmt.Errorf("bundle is for launch file %s, but %q hashes to %x", bundle.LaunchHash, launchPath, launchHash)
+	}
+
+	fmt.Printf("Starter bundle for %s, created %s\n", bundle.Account, bundle.CreatedAt)
+	fmt.Println("Role:", bundle.RoleHint)
+	if len(bundle.Deadlines) != 0 {
+		fmt.Println("Deadlines:")
+		var names []string
+		for name := range bundle.Deadlines {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("- %s: %s\n", name, bundle.Deadlines[name].UTC())
+		}
+	}
+	if peering := bundle.Peering; peering != nil {
+		fmt.Printf("Peering: region %q, latency group %q, aim for %d peers, starting with: %s\n", peering.Region, peering.LatencyGroup, peering.PeerCount, strings.Join(peering.Nearby, ", "))
+	}
+	if len(bundle.Endpoints) != 0 {
+		endpoints, _ := json.MarshalIndent(bundle.Endpoints, "", "  ")
+		fmt.Println("Endpoints:", string(endpoints))
+	}
+
+	merged := bundle.Config
+	target := configPath
+	if cnt, err := ioutil.ReadFile(configPath); err == nil {
+		jsonCnt, err := yaml2json.Convert(cnt)
+		if err != nil {
+			return fmt.Errorf("local config: %s", err)
+		}
+		var local map[string]interface{}
+		if err := json.Unmarshal(jsonCnt, &local); err != nil {
+			return fmt.Errorf("local config: %s", err)
+		}
+		merged = deepMerge(bundle.Config, local)
+		// We don't rewrite the operator's file, comments and all.
+		target = configPath + ".bundle.yaml"
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// JSON is valid YAML, and loads as a local config as is.
+	out, _ := json.MarshalIndent(merged, "", "  ")
+	if err := ioutil.WriteFile(target, out, 0600); err != nil {
+		return err
+	}
+
+	if target != configPath {
+		fmt.Printf("Merged config written to %s, review it and move it to %s\n", target, configPath)
+	} else {
+		fmt.Printf("Starter config written to %s, complete the keys and paths it lacks\n", target)
+	}
+	return nil
+}
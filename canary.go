@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// pushCanary pushes a single `nonce` action before a heavy step, and
+// measures how long it takes to be included and become irreversible.
+func (b *BIOS) pushCanary(step int) (latency time.Duration, err error) {
+	timeout := 2 * time.Minute
+	if b.Config.Canary.IrreversibleTimeout != "" {
+		timeout, err = time.ParseDuration(b.Config.Canary.IrreversibleTimeout)
+		if err != nil {
+			return 0, fmt.Errorf("canary.irreversible_timeout: %s", err)
+		}
+	}
+
+	start := time.Now()
+	resp, err := b.API.SignPushActions(newNonce(fmt.Sprintf("eos-bios:canary:%d:%d", step, start.UnixNano())))
+	if err != nil {
+		return 0, fmt.Errorf("pushing canary: %s", err)
+	}
+	latency = time.Since(start)
+
+	irreversibleAfter, err := b.waitIrreversible(resp.BlockNum, timeout)
+	if err != nil {
+		return 0, fmt.Errorf("canary in block %d: %s", resp.BlockNum, err)
+	}
+
+	info.Printf("Canary pushed in %s, included in block %d, irreversible after %s\n", latency, resp.BlockNum, irreversibleAfter)
+
+	return latency, nil
+}
+
+// waitIrreversible waits until `blockNum` is irreversible, and tells
+// how long it took.
+func (b *BIOS) waitIrreversible(blockNum uint32, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	for {
+		chainInfo, err := b.API.GetInfo()
+		if err == nil && chainInfo.LastIrreversibleBlockNum >= blockNum {
+			return time.Since(start), nil
+		}
+		if time.Since(start) > timeout {
+			return 0, fmt.Errorf("not irreversible after %s", timeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+func (b *BIOS) requiresIrreversibility(op string) bool {
+	if !b.Config.WaitIrreversible.Enabled {
+		return false
+	}
+	if len(b.Config.WaitIrreversible.Ops) == 0 {
+		return true
+	}
+	for _, name := range b.Config.WaitIrreversible.Ops {
+		if name == op {
+			return true
+		}
+	}
+	return false
+}
+
+// waitStepIrreversible waits until `blockNum`, the block of a step's
+// last transaction, is irreversible, per `wait_irreversible`.
+func (b *BIOS) waitStepIrreversible(step int, op string, blockNum uint32) error {
+	timeout := 2 * time.Minute
+	if b.Config.WaitIrreversible.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(b.Config.WaitIrreversible.Timeout)
+		if err != nil {
+			return fmt.Errorf("wait_irreversible.timeout: %s", err)
+		}
+	}
+
+	info.Printf("Waiting for block %d, the last of step %d [%s], to be irreversible\n", blockNum, step, op)
+	took, err := b.waitIrreversible(blockNum, timeout)
+	if err != nil {
+		return fmt.Errorf("step %d [%s], block %d: %s", step, op, blockNum, err)
+	}
+	info.Printf("Block %d irreversible after %s\n", blockNum, took)
+	return nil
+}
+
+// tunedChunkLimits shrinks chunks when a single action already
+// takes long to push, to keep each transaction well within the node's
+// limits.
+func tunedChunkLimits(canaryLatency time.Duration, limits ChunkLimits) ChunkLimits {
+	divisor := 1
+	switch {
+	case canaryLatency > 5*time.Second:
+		divisor = 4
+	case canaryLatency > 2*time.Second:
+		divisor = 2
+	}
+	return ChunkLimits{MaxActions: limits.MaxActions / divisor, MaxBytes: limits.MaxBytes / divisor}
+}
+
+// stepChunkLimits picks the chunk limits for a step, pushing a canary
+// first for heavy steps (more than one chunk). When resuming, the
+// limits recorded in the ledger win, so chunks line up.
+func (b *BIOS) stepChunkLimits(step int, op string, acts []*eos.Action, resume *resumeState) (ChunkLimits, error) {
+	if limits, found := resume.chunkLimits(step); found {
+		return limits, nil
+	}
+
+	limits := b.chunkLimits(op)
+	if !b.Config.Canary.Enabled || len(chunkifyActions(acts, limits)) <= 1 || b.LaunchData.DeterministicTransactions {
+		return limits, nil
+	}
+
+	latency, err := b.pushCanary(step)
+	if err != nil {
+		return limits, fmt.Errorf("node misbehaving, aborting before the heavy step: %s", err)
+	}
+
+	tuned := tunedChunkLimits(latency, limits)
+	if tuned != limits {
+		info.Printf("Using chunks of %d actions and %d bytes at most for this step, given the canary's latency\n", tuned.MaxActions, tuned.MaxBytes)
+	}
+	return tuned, nil
+}
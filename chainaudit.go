@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// The chain audit is the ABPs' full check of the boot: all blocks
+// since genesis are fetched, the actions of every boot step are
+// rebuilt from the launch data and the snapshot, and compared, in
+// order, with the actions the boot node actually pushed. Nonces the
+// boot node adds (canaries, sequence nonces) are skipped.
+//
+// The actions of the ops in `auditShapeOnlyOps` depend on who builds
+// them (our own account, the current time), so only their contract
+// and action name are compared.
+var auditShapeOnlyOps = map[string]bool{
+	"msig.propose": true,
+	"msig.approve": true,
+	"msig.exec":    true,
+}
+
+type chainAction struct {
+	blockNum uint32
+	act      *eos.Action
+}
+
+// RunChainAudit compares the chain with the boot sequence, records a
+// finding per step in the report, and fails if any step doesn't
+// match.
+func (b *BIOS) RunChainAudit() error {
+	info.Println("- Fetching all blocks since genesis, to audit the boot node's actions")
+
+	var onChain []*chainAction
+	if err := b.forEachBlockAction(1, func(blockNum uint32, act *eos.Action) error {
+		onChain = append(onChain, &chainAction{blockNum: blockNum, act: act})
+		return nil
+	}); err != nil {
+		return fmt.Errorf("chain audit: %s", err)
+	}
+	info.Printf("  %d actions found on chain\n", len(onChain))
+
+	cursor := 0
+	lost := false
+	failures := 0
+	for idx, step := range b.LaunchData.BootSequence {
+		b.currentStep = idx
+
+		info.Printf("- Auditing step %d, %s [%s]: ", idx, step.Label, step.Op)
+		finding := &ValidationFinding{Step: idx, Op: step.Op, Check: "audit"}
+
+		next, matched, err := b.auditStep(step, onChain, cursor, lost)
+		if err != nil {
+			info.Println("FAILED:", err)
+			finding.Error = err.Error()
+			failures++
+			lost = true
+		} else {
+			info.Printf("OKAY, %d actions\n", matched)
+			cursor = next
+			lost = false
+		}
+		b.Report.Validations = append(b.Report.Validations, finding)
+	}
+
+	trailing := 0
+	for _, action := range onChain[cursor:] {
+		if !isNonce(action.act.Account, action.act.Name) {
+			trailing++
+		}
+	}
+	if trailing != 0 && !lost {
+		info.Printf("  %d actions pushed after the boot sequence, not audited\n", trailing)
+	}
+
+	if failures != 0 {
+		return fmt.Errorf("%d boot step(s) failed the chain audit", failures)
+	}
+	return nil
+}
+
+// auditStep matches the rebuilt actions of `step` with `onChain`,
+// starting at `cursor`. When a previous step failed (`lost`), the
+// step's first action is searched for further down the chain first.
+// It returns the position past the step's actions, and the number of
+// actions matched.
+func (b *BIOS) auditStep(step *OperationType, onChain []*chainAction, cursor int, lost bool) (next int, matched int, err error) {
+	acts, err := step.Data.Actions(b)
+	if err != nil {
+		return cursor, 0, fmt.Errorf("rebuilding actions: %s", err)
+	}
+	expected, err := spoolActions(acts)
+	if err != nil {
+		return cursor, 0, fmt.Errorf("rebuilding actions: %s", err)
+	}
+	if len(expected) == 0 {
+		return cursor, 0, nil
+	}
+
+	shapeOnly := auditShapeOnlyOps[step.Op]
+
+	if lost {
+		for cursor < len(onChain) && compareAuditedAction(expected[0], onChain[cursor].act, shapeOnly) != nil {
+			cursor++
+		}
+	}
+
+	for idx, exp := range expected {
+		if !isNonce(exp.Account, exp.Name) {
+			for cursor < len(onChain) && isNonce(onChain[cursor].act.Account, onChain[cursor].act.Name) {
+				cursor++
+			}
+		}
+		if cursor >= len(onChain) {
+			return cursor, idx, fmt.Errorf("action %d of %d, %s::%s, not found: the chain ends before", idx+1, len(expected), exp.Account, exp.Name)
+		}
+
+		actual := onChain[cursor]
+		if err := compareAuditedAction(exp, actual.act, shapeOnly); err != nil {
+			return cursor, idx, fmt.Errorf("action %d of %d, in block %d: %s", idx+1, len(expected), actual.blockNum, err)
+		}
+		cursor++
+	}
+
+	return cursor, len(expected), nil
+}
+
+func compareAuditedAction(expected *spooledAction, actual *eos.Action, shapeOnly bool) error {
+	if actual.Account != expected.Account || actual.Name != expected.Name {
+		return fmt.Errorf("expected %s::%s, chain has %s::%s", expected.Account, expected.Name, actual.Account, actual.Name)
+	}
+	if shapeOnly {
+		return nil
+	}
+
+	if fmt.Sprint(actual.Authorization) != fmt.Sprint(expected.Authorization) {
+		return fmt.Errorf("%s::%s authorized by %v, expected %v", actual.Account, actual.Name, actual.Authorization, expected.Authorization)
+	}
+	if !bytes.Equal(actual.HexData, expected.HexData) {
+		return fmt.Errorf("%s::%s data differs (%d bytes on chain, %d expected)", actual.Account, actual.Name, len(actual.HexData), len(expected.HexData))
+	}
+	return nil
+}
+
+func isNonce(account eos.AccountName, name eos.ActionName) bool {
+	return account == AN("eosio") && name == ActN("nonce")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"github.com/eoscanada/eos-go"
+)
+
+// A step's actions are packed in transactions up to a number of
+// actions and an estimate of their serialized size, per `chunking`
+// in the config, so small actions (transfers) fill transactions, and
+// large ones (`newaccount` with big authorities, `setcode`) don't push
+// a transaction past the chain's `max_transaction_net_usage`.
+
+const (
+	// defaultChunkActions is the number of actions per transaction,
+	// as transfers max out resources higher than ~400.
+	defaultChunkActions = 400
+	// defaultChunkBytes stays well below the default 512 KiB
+	// `max_transaction_net_usage`.
+	defaultChunkBytes = 128 * 1024
+)
+
+// ChunkLimits bound the transactions of a step. Zero fields take the
+// defaults.
+type ChunkLimits struct {
+	MaxActions int `json:"max_actions"`
+	MaxBytes   int `json:"max_bytes"`
+}
+
+// chunkLimits are the limits for the steps of `op`, its override in
+// `chunking.ops` winning.
+func (b *BIOS) chunkLimits(op string) ChunkLimits {
+	limits := ChunkLimits{MaxActions: defaultChunkActions, MaxBytes: defaultChunkBytes}
+
+	for _, override := range []ChunkLimits{b.Config.Chunking.ChunkLimits, b.Config.Chunking.Ops[op]} {
+		if override.MaxActions != 0 {
+			limits.MaxActions = override.MaxActions
+		}
+		if override.MaxBytes != 0 {
+			limits.MaxBytes = override.MaxBytes
+		}
+	}
+	return limits
+}
+
+// actionSize estimates the serialized size of `act` in a transaction:
+// account, name, authorizations and data, with their length prefixes.
+func actionSize(act *eos.Action) int {
+	dataLen := len(act.HexData)
+	if dataLen == 0 && act.Data != nil {
+		data, err := eos.MarshalBinary(act.Data)
+		if err == nil {
+			dataLen = len(data)
+		}
+	}
+	return 8 + 8 + varUint32Size(len(act.Authorization)) + 16*len(act.Authorization) + varUint32Size(dataLen) + dataLen
+}
+
+func varUint32Size(v int) int {
+	size := 1
+	for v >= 0x80 {
+		v >>= 7
+		size++
+	}
+	return size
+}
+
+// chunkifyActions packs `actions`, in order, in chunks of at most
+// `limits.MaxActions` actions and `limits.MaxBytes` bytes. An action
+// larger than the byte budget gets a chunk of its own. Zero limits
+// are unbounded.
+func chunkifyActions(actions []*eos.Action, limits ChunkLimits) (out [][]*eos.Action) {
+	currentChunk := []*eos.Action{}
+	currentBytes := 0
+	for _, act := range actions {
+		size := actionSize(act)
+		full := limits.MaxActions != 0 && len(currentChunk) >= limits.MaxActions
+		tooBig := limits.MaxBytes != 0 && currentBytes+size > limits.MaxBytes
+		if len(currentChunk) > 0 && (full || tooBig) {
+			out = append(out, currentChunk)
+			currentChunk = []*eos.Action{}
+			currentBytes = 0
+		}
+		currentChunk = append(currentChunk, act)
+		currentBytes += size
+	}
+	if len(currentChunk) > 0 {
+		out = append(out, currentChunk)
+	}
+	return
+}
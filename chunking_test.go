@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkifyActions(t *testing.T) {
+	act := func(dataLen int) *eos.Action {
+		return &eos.Action{
+			Account:       AN("eosio"),
+			Name:          ActN("nonce"),
+			Authorization: []eos.PermissionLevel{{Actor: AN("eosio"), Permission: PN("active")}},
+			ActionData:    eos.ActionData{HexData: make([]byte, dataLen)},
+		}
+	}
+
+	small := []*eos.Action{act(10), act(10), act(10), act(10), act(10)}
+	assert.Equal(t, 44, actionSize(small[0]))
+	assert.Len(t, chunkifyActions(small, ChunkLimits{MaxActions: 2}), 3)
+	assert.Len(t, chunkifyActions(small, ChunkLimits{MaxBytes: 110}), 3)
+	assert.Len(t, chunkifyActions(small, ChunkLimits{}), 1)
+
+	// An action above the budget still gets pushed, alone.
+	mixed := []*eos.Action{act(10), act(500), act(10)}
+	chunks := chunkifyActions(mixed, ChunkLimits{MaxBytes: 200})
+	if assert.Len(t, chunks, 3) {
+		assert.Len(t, chunks[1], 1)
+	}
+}
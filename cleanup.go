@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eoscanada/eos-go/ecc"
+)
+
+// After a failed run, a cleanup plan is written to the run directory
+// as `cleanup.json`, listing what stands between us and a clean
+// rehearsal: nodeos' data directory, the run's keys imported in
+// keosd, the published kickstart data, and the run directory itself,
+// which a next run would otherwise resume. The plan is printed with
+// the commands to do it by hand.
+//
+// With `cleanup.execute`, or with `eos-bios cleanup --execute` (also
+// for a run that was killed), the plan is handed to the `cleanup`
+// hook, which does what lives outside of eos-bios, and the run
+// directory is then moved to the archive directory (or aside).
+
+type CleanupPlan struct {
+	CreatedAt time.Time      `json:"created_at"`
+	Account   string         `json:"account"`
+	Role      string         `json:"role"`
+	Error     string         `json:"error,omitempty"`
+	Steps     []*CleanupStep `json:"steps"`
+}
+
+type CleanupStep struct {
+	// Kind is "wipe_data_dir", "remove_keys", "invalidate_kickstart"
+	// or "archive_run".
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	// Target is the directory, wallet or published reference.
+	Target     string   `json:"target,omitempty"`
+	PublicKeys []string `json:"public_keys,omitempty"`
+	// Command does the step by hand.
+	Command string `json:"command,omitempty"`
+	Done    bool   `json:"done"`
+}
+
+// cleanupPlan plans the cleanup of a run which ended with `runErr`.
+func (b *BIOS) cleanupPlan(runErr error) (*CleanupPlan, error) {
+	conf := b.Config.Cleanup
+	plan := &CleanupPlan{
+		CreatedAt: time.Now().UTC(),
+		Account:   b.Config.Producer.MyAccount,
+		Role:      b.role(),
+	}
+	if runErr != nil {
+		plan.Error = runErr.Error()
+	}
+
+	dataDir := conf.DataDir
+	if dataDir == "" {
+		dataDir = b.Config.Preflight.DataDir
+	}
+	wipe := &CleanupStep{
+		Kind:        "wipe_data_dir",
+		Description: "Stop nodeos and wipe its data directory, holding the blocks and state of the failed chain",
+		Target:      dataDir,
+	}
+	if dataDir != "" {
+		wipe.Command = fmt.Sprintf("rm -rf %s %s", filepath.Join(dataDir, "blocks"), filepath.Join(dataDir, "state"))
+	} else {
+		wipe.Description += " (set `cleanup.data_dir` to have it in the plan)"
+	}
+	plan.Steps = append(plan.Steps, wipe)
+
+	publicKeys, err := b.runPublicKeys()
+	if err != nil {
+		return nil, err
+	}
+	if len(publicKeys) != 0 {
+		walletName := conf.WalletName
+		if walletName == "" {
+			walletName = "default"
+		}
+		cleos := "cleos"
+		target := walletName
+		if conf.WalletURL != "" {
+			cleos += " --wallet-url " + conf.WalletURL
+			target += "@" + conf.WalletURL
+		}
+
+		var commands []string
+		for _, publicKey := range publicKeys {
+			commands = append(commands, fmt.Sprintf("%s wallet remove_key %s -n %s", cleos, publicKey, walletName))
+		}
+		plan.Steps = append(plan.Steps, &CleanupStep{
+			Kind:        "remove_keys",
+			Description: "Remove the run's ephemeral key from keosd",
+			Target:      target,
+			PublicKeys:  publicKeys,
+			Command:     strings.Join(commands, " && "),
+		})
+	}
+
+	if b.AmIBootNode() {
+		publications := b.Report.KickstartPublications
+		if len(publications) == 0 {
+			// Killed before the report: whatever is configured may have been published.
+			for _, channel := range b.kickstartChannels() {
+				publication := &KickstartPublication{Channel: channel.name}
+				if channel.name == "file" {
+					publication.Reference = b.Config.KickstartPublish.File.Path
+				}
+				publications = append(publications, publication)
+			}
+		}
+		for _, publication := range publications {
+			if publication.Error != "" {
+				continue
+			}
+			plan.Steps = append(plan.Steps, kickstartCleanupStep(publication))
+		}
+	}
+
+	if b.Config.RunDir != "" {
+		archivePath := b.runArchivePath(plan.CreatedAt)
+		plan.Steps = append(plan.Steps, &CleanupStep{
+			Kind:        "archive_run",
+			Description: "Archive the run directory, so the next run starts afresh instead of resuming",
+			Target:      archivePath,
+			Command:     fmt.Sprintf("mv %s %s", b.Config.RunDir, archivePath),
+		})
+	}
+
+	return plan, nil
+}
+
+func kickstartCleanupStep(publication *KickstartPublication) *CleanupStep {
+	step := &CleanupStep{
+		Kind:        "invalidate_kickstart",
+		Description: fmt.Sprintf("Retract the kickstart data published on %s, so nobody joins the failed chain", publication.Channel),
+		Target:      publication.Reference,
+	}
+	switch publication.Channel {
+	case "file":
+		step.Description = "Remove the published kickstart data file"
+		if publication.Reference != "" {
+			step.Command = "rm -f " + publication.Reference
+		}
+	case "ipfs":
+		step.Description = "Unpin the kickstart data from IPFS"
+		if publication.Reference != "" {
+			step.Command = "ipfs pin rm " + strings.TrimPrefix(publication.Reference, "/ipfs/")
+		}
+	}
+	return step
+}
+
+// runPublicKeys are the keys generated for the run, which operators
+// import in keosd: the boot node's ephemeral key, taken from the
+// ledger for a run that was killed.
+func (b *BIOS) runPublicKeys() ([]string, error) {
+	if b.EphemeralPrivateKey != nil {
+		return []string{b.EphemeralPrivateKey.PublicKey().String()}, nil
+	}
+
+	state, err := b.loadResumeState()
+	if err != nil {
+		return nil, fmt.Errorf("reading ledger: %s", err)
+	}
+	if state == nil || state.start.EphemeralPrivateKey == "" {
+		return nil, nil
+	}
+	privKey, err := ecc.NewPrivateKey(state.start.EphemeralPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("ledger ephemeral key: %s", err)
+	}
+	return []string{privKey.PublicKey().String()}, nil
+}
+
+// runArchivePath is where the run directory is archived: in the
+// archive directory when configured, or else beside itself.
+func (b *BIOS) runArchivePath(at time.Time) string {
+	stamp := at.Format("20060102-150405")
+	if b.Config.ArchiveDir != "" {
+		return filepath.Join(b.Config.ArchiveDir, fmt.Sprintf("%s-%s-run", stamp, b.Config.Producer.MyAccount))
+	}
+	return strings.TrimRight(b.Config.RunDir, "/") + ".failed-" + stamp
+}
+
+// printCleanupPlan prints what is left to do for the operator.
+func printCleanupPlan(plan *CleanupPlan) {
+	milestone.Println("To start the next rehearsal from a clean state:")
+	for idx, step := range plan.Steps {
+		if step.Done {
+			milestone.Printf("%d. %s [done]\n", idx+1, step.Description)
+			continue
+		}
+		milestone.Printf("%d. %s\n", idx+1, step.Description)
+		if step.Command != "" {
+			milestone.Printf("   $ %s\n", step.Command)
+		}
+	}
+}
+
+func writeCleanupPlan(plan *CleanupPlan, dir string) error {
+	if dir == "" {
+		return nil
+	}
+
+	cnt, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	filename := filepath.Join(dir, "cleanup.json")
+	if err := ioutil.WriteFile(filename, cnt, 0644); err != nil {
+		return err
+	}
+	info.Println("Cleanup plan written to", filename)
+	return nil
+}
+
+// executeCleanup runs the plan: the `cleanup` hook for the steps
+// outside of eos-bios, then the archiving of the run directory.
+func (b *BIOS) executeCleanup(plan *CleanupPlan) error {
+	if b.Config.Hooks["cleanup"] == nil {
+		return fmt.Errorf("executing the cleanup plan needs the `cleanup` hook")
+	}
+
+	if err := b.DispatchCleanup(plan); err != nil {
+		return fmt.Errorf("dispatch cleanup: %s", err)
+	}
+
+	var archive *CleanupStep
+	for _, step := range plan.Steps {
+		if step.Kind == "archive_run" {
+			archive = step
+			continue
+		}
+		step.Done = true
+	}
+	if archive == nil {
+		return nil
+	}
+
+	// The ledger and the hook queue live in the run directory.
+	if b.HookQueue != nil {
+		if !b.HookQueue.Flush(30 * time.Second) {
+			milestone.Println("WARNING: archiving the run with hook deliveries still queued")
+		}
+		b.HookQueue = nil
+	}
+	if b.Ledger != nil {
+		b.Ledger.Close()
+		b.Ledger = nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(archive.Target), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(b.Config.RunDir, archive.Target); err != nil {
+		return fmt.Errorf("archiving run directory: %s", err)
+	}
+	archive.Done = true
+	info.Println("Run directory archived as", archive.Target)
+
+	return writeCleanupPlan(plan, archive.Target)
+}
+
+// cleanup writes the plan, and runs it when asked to. What's left
+// undone is printed.
+func (b *BIOS) cleanup(plan *CleanupPlan, execute bool) error {
+	if err := writeCleanupPlan(plan, b.Config.RunDir); err != nil {
+		return err
+	}
+
+	if !execute {
+		printCleanupPlan(plan)
+		return nil
+	}
+
+	if err := b.executeCleanup(plan); err != nil {
+		printCleanupPlan(plan)
+		return err
+	}
+	milestone.Println("Cleanup done, ready for the next rehearsal")
+	return nil
+}
+
+// RunFailureCleanup plans the cleanup after a failed run, and runs
+// it with `cleanup.execute`.
+func (b *BIOS) RunFailureCleanup(runErr error) error {
+	plan, err := b.cleanupPlan(runErr)
+	if err != nil {
+		return err
+	}
+	return b.cleanup(plan, b.Config.Cleanup.Execute)
+}
+
+// RunCleanup implements `eos-bios cleanup`, planning (and with
+// `--execute`, running) the cleanup of the last run, including one
+// that was killed before writing its report.
+func (b *BIOS) RunCleanup(args []string) error {
+	fs := flag.NewFlagSet("cleanup", flag.ExitOnError)
+	execute := fs.Bool("execute", false, "Run the plan with the `cleanup` hook, and archive the run directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var runErr error
+	if b.Config.RunDir != "" {
+		cnt, err := ioutil.ReadFile(filepath.Join(b.Config.RunDir, "report.json"))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil {
+			var report RunReport
+			if err := json.Unmarshal(cnt, &report); err != nil {
+				return fmt.Errorf("run report: %s", err)
+			}
+			b.Report.KickstartPublications = report.KickstartPublications
+			if report.Error != "" {
+				runErr = fmt.Errorf("%s", report.Error)
+			}
+		}
+	}
+
+	plan, err := b.cleanupPlan(runErr)
+	if err != nil {
+		return err
+	}
+	return b.cleanup(plan, *execute)
+}
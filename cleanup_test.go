@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKickstartCleanupStep(t *testing.T) {
+	ipfs := kickstartCleanupStep(&KickstartPublication{Channel: "ipfs", Reference: "/ipfs/QmHash"})
+	assert.Equal(t, "ipfs pin rm QmHash", ipfs.Command)
+	assert.Equal(t, "/ipfs/QmHash", ipfs.Target)
+
+	file := kickstartCleanupStep(&KickstartPublication{Channel: "file", Reference: "/srv/kickstart.txt"})
+	assert.Equal(t, "rm -f /srv/kickstart.txt", file.Command)
+
+	keybase := kickstartCleanupStep(&KickstartPublication{Channel: "keybase"})
+	assert.Equal(t, "invalidate_kickstart", keybase.Kind)
+	assert.Equal(t, "", keybase.Command)
+}
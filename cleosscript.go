@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+)
+
+// `eos-bios cleos-script` writes the boot sequence as a shell script
+// of `cleos` commands, one per action, for auditors who'd rather not
+// read Go. Contracts are set with `cleos set code` and `cleos set
+// abi`, everything else is a `cleos push action` with the action's
+// data decoded with its contract's ABI. The ephemeral key isn't known
+// in advance, it's read from $EPHEMERAL_KEY. The boot node groups the
+// same actions in fewer transactions, and adds sequence nonces in
+// deterministic mode, which the script leaves out.
+
+// RunCleosScript implements `eos-bios cleos-script`.
+func (b *BIOS) RunCleosScript(args []string) error {
+	fs := flag.NewFlagSet("cleos-script", flag.ExitOnError)
+	out := fs.String("out", "boot.sh", "File to write the script to.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// A throw-away key, replaced by $EPHEMERAL_KEY in the script.
+	ephemeralPrivateKey, err := ecc.NewRandomPrivateKey()
+	if err != nil {
+		return err
+	}
+	b.EphemeralPrivateKey = ephemeralPrivateKey
+	ephemeralKey := ephemeralPrivateKey.PublicKey().String()
+
+	var script []string
+	script = append(script,
+		"#!/bin/sh",
+		"# Boot sequence of launch "+b.LaunchData.fileHash+", generated by eos-bios "+version+".",
+		"# Equivalent to the boot node's transactions, one action per command.",
+		"set -e",
+		"",
+		`: "${EPHEMERAL_KEY:?set it to the initial_key of the genesis}"`,
+		`CLEOS="${CLEOS:-cleos}"`,
+	)
+
+	for idx, step := range b.LaunchData.BootSequence {
+		b.currentStep = idx
+
+		acts, err := step.Data.Actions(b)
+		if err != nil {
+			return fmt.Errorf("getting actions for step %q: %s", step.Op, err)
+		}
+
+		script = append(script, "", fmt.Sprintf("# %d. %s [%s]", idx+1, step.Label, step.Op))
+
+		if setCode, ok := step.Data.(*OpSetCode); ok {
+			contract := b.Config.Contracts[setCode.ContractNameRef]
+			perm := shellQuote(string(setCode.Account) + "@active")
+			script = append(script,
+				fmt.Sprintf(`"$CLEOS" set code %s %s -p %s`, setCode.Account, shellQuote(contract.CodePath), perm),
+				fmt.Sprintf(`"$CLEOS" set abi %s %s -p %s`, setCode.Account, shellQuote(contract.ABIPath), perm),
+			)
+			continue
+		}
+
+		for _, act := range acts {
+			line, err := b.cleosCommand(act, ephemeralKey)
+			if err != nil {
+				return fmt.Errorf("step %q: %s", step.Op, err)
+			}
+			script = append(script, line)
+		}
+	}
+
+	// The boot node pushes it with the last step, see `endmarker.go`.
+	marker, err := b.cleosCommand(newNonce(endMarker(b.LaunchData.fileHash)), ephemeralKey)
+	if err != nil {
+		return err
+	}
+	script = append(script, "", "# End-of-boot marker", marker)
+
+	if err := ioutil.WriteFile(*out, []byte(strings.Join(script, "\n")+"\n"), 0755); err != nil {
+		return err
+	}
+
+	milestone.Println("cleos script of the boot sequence written to", *out)
+	return nil
+}
+
+// cleosCommand is the `cleos` command pushing `act`. Actions the
+// ABIs can't decode are pushed as a transaction with hex data.
+func (b *BIOS) cleosCommand(act *eos.Action, ephemeralKey string) (string, error) {
+	var perms []string
+	for _, auth := range act.Authorization {
+		perms = append(perms, "-p "+shellQuote(fmt.Sprintf("%s@%s", auth.Actor, auth.Permission)))
+	}
+
+	data, err := b.abis().DecodeAction(act)
+	if err != nil {
+		trace.Printf("Not decoding %s::%s for the cleos script: %s\n", act.Account, act.Name, err)
+		return b.cleosHexCommand(act)
+	}
+
+	cnt, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`"$CLEOS" push action %s %s %s %s`, act.Account, act.Name, shellQuoteKey(string(cnt), ephemeralKey), strings.Join(perms, " ")), nil
+}
+
+func (b *BIOS) cleosHexCommand(act *eos.Action) (string, error) {
+	data := []byte(act.HexData)
+	if len(data) == 0 {
+		var err error
+		data, err = eos.MarshalBinary(act.Data)
+		if err != nil {
+			return "", fmt.Errorf("packing %s::%s: %s", act.Account, act.Name, err)
+		}
+	}
+
+	tx, err := json.Marshal(map[string]interface{}{
+		"actions": []map[string]interface{}{{
+			"account":       act.Account,
+			"name":          act.Name,
+			"authorization": act.Authorization,
+			"data":          hex.EncodeToString(data),
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`"$CLEOS" push transaction %s`, shellQuote(string(tx))), nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// shellQuoteKey quotes `s`, with the ephemeral key expanded from
+// $EPHEMERAL_KEY.
+func shellQuoteKey(s, ephemeralKey string) string {
+	parts := strings.Split(s, ephemeralKey)
+	for i, part := range parts {
+		parts[i] = shellQuote(part)
+	}
+	return strings.Join(parts, `"$EPHEMERAL_KEY"`)
+}
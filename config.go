@@ -1,11 +1,13 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/url"
 	"strings"
+	"time"
 
+	"github.com/bronze1man/go-yaml2json"
 	"github.com/eoscanada/eos-go/ecc"
 	"github.com/eoscanada/eos-go/system"
 )
@@ -13,19 +15,98 @@ import (
 type Config struct {
 	Contracts map[string]ContractLocation `json:"contracts"`
 
+	// ConstitutionPath is the agreed constitution document, whose
+	// hash must match `constitution_hash` in the launch data.
+	ConstitutionPath string `json:"constitution_path"`
+
+	// Networks describe several simultaneous launches in one config,
+	// each overriding the settings above (ports, keys, ...). One is
+	// selected with `--network`, see `network.go`.
+	Networks map[string]interface{} `json:"networks"`
+	// Network is the selected network, if any.
+	Network string `json:"-"`
+
+	// Strict refuses unknown fields in this config and the launch
+	// data. Defaults to true, unless a `debug` setting is used. See
+	// `strict.go`.
+	Strict *bool `json:"strict"`
+
+	// LaunchData is the launch file of this network, used unless
+	// `--launch-data` is given explicitly.
+	LaunchData string `json:"launch_data"`
+
+	// RunDir holds the state of a run, like the boot ledger (see
+	// `ledger.go`). Leave empty to keep no state on disk.
+	RunDir string `json:"run_dir"`
+
+	// EncryptLedger encrypts the ledger's entries at rest, with the
+	// passphrase of the encrypted config files. See `ledger.go`.
+	EncryptLedger bool `json:"encrypt_ledger"`
+
+	// ArchiveDir keeps the run report of every rehearsal, with an
+	// index, to compare them with `eos-bios history`. See `archive.go`.
+	ArchiveDir string `json:"archive_dir"`
+
+	// Operator identifies who confirms gates and supplies manual
+	// inputs, in the ledger and the run report. See `operator.go`.
+	Operator struct {
+		// Name, overridden by $EOS_BIOS_OPERATOR, defaults to the login user.
+		Name string `json:"name"`
+		// Ask has gates ask for the operator's name, for teams taking turns.
+		Ask bool `json:"ask"`
+	} `json:"operator"`
+
+	// Cleanup plans how to get back to a clean state after a failed
+	// run, for the next rehearsal. See `cleanup.go`.
+	Cleanup struct {
+		// DataDir is nodeos' data directory to wipe, defaults to `preflight.data_dir`.
+		DataDir string `json:"data_dir"`
+		// WalletURL of the keosd the run's keys were imported in, like "http://127.0.0.1:6666".
+		WalletURL string `json:"wallet_url"`
+		// WalletName defaults to "default".
+		WalletName string `json:"wallet_name"`
+		// Execute runs the plan through the `cleanup` hook right
+		// after a failed run, instead of only writing it.
+		Execute bool `json:"execute"`
+	} `json:"cleanup"`
+
+	// Resume continues an interrupted boot recorded in RunDir, set by
+	// the `--resume` flag. See `resume.go`.
+	Resume bool `json:"-"`
+
+	// FlagList locates the flag list pinned by the launch data, and
+	// its detached signature. See `flaglist.go`.
+	FlagList struct {
+		Path           string `json:"path"`
+		SignaturePath  string `json:"signature_path"`
+		SigningKeyPath string `json:"signing_key_path"`
+	} `json:"flag_list"`
+
 	// OpeningBalancesSnapshotPath represents the `snapshot.csv` file,
 	// which holds the opening balances for all ERC-20 token holders.
 	OpeningBalances struct {
 		// SnapshotPath is the path to the `csv` file, extracted using the `genesis` tool.
 		SnapshotPath string `json:"snapshot_path"`
+
+		// DeltaPath is a signed delta revising the snapshot above,
+		// when it was revised close to launch. It is applied in place
+		// if the snapshot doesn't match the launch data yet. See
+		// `snapshotdelta.go`.
+		DeltaPath           string `json:"delta_path"`
+		DeltaSignaturePath  string `json:"delta_signature_path"`
+		DeltaSigningKeyPath string `json:"delta_signing_key_path"`
 	} `json:"opening_balances"`
 
 	// Producer describes your producing node.
 	Producer struct {
 		// MyAccount is the name of the `account_name` this producer will be using on chain
 		MyAccount string `json:"my_account"`
-		// APIAddress is the target API endpoint for the locally booting node, a clean-slate node. It can be routable only from the local machine.
-		APIAddress    string `json:"api_address"`
+		// APIAddresses is the target API endpoint for the locally booting node, a clean-slate node. It can be routable only from the local machine.
+		// It can also be a list, the booting node first, then nodes peered with it, to spread parallel pushes over. See `pushlanes.go`.
+		APIAddresses   APIAddresses `json:"api_address"`
+		apiAddressURLs []*url.URL
+		// APIAddress is the first of APIAddresses.
+		APIAddress    string `json:"-"`
 		apiAddressURL *url.URL
 		// SecretP2PAddress is the endpoint which will be published at the end of the process. Needs to be externally routable.  It must be kept secret for DDoS protection.
 		SecretP2PAddress string `json:"secret_p2p_address"`
@@ -35,21 +116,384 @@ type Config struct {
 
 		// Private key used to setup your config.ini as an ABP (or as someone joining the network)
 		BlockSigningPrivateKeyPath string `json:"block_signing_private_key_path"`
+		// BlockSigningPrivateKeySecret fetches the key from a secret
+		// provider instead, like "vault:secret/data/bp#signing_key".
+		// See `secrets.go`.
+		BlockSigningPrivateKeySecret string `json:"block_signing_private_key_secret"`
 
 		// Available once loaded successfuly from the previous field's path.
 		blockSigningPrivateKey *ecc.PrivateKey
+
+		// Ledger signs the producer's own actions (`regproducer`, ...)
+		// with a Ledger device running the EOS app, keeping the account
+		// keys on hardware. See `hardwarewallet.go`.
+		Ledger struct {
+			Enabled bool `json:"enabled"`
+			// DerivationPath defaults to "44'/194'/0'/0/0".
+			DerivationPath string `json:"derivation_path"`
+		} `json:"ledger"`
 	} `json:"producer"`
 
 	MyParameters system.EOSIOParameters `json:"my_parameters"`
 
+	// CoSign holds chunks until a second party co-signs them, for
+	// organizations with two-person controls. See `cosign.go`.
+	CoSign struct {
+		Enabled bool `json:"enabled"`
+		// Ops restricts co-signing to these boot sequence ops, all of them when empty.
+		Ops []string `json:"ops"`
+		// Timeout is how long to wait for each co-signature, at most "1h".
+		Timeout string `json:"timeout"`
+		// ListenAddress serves the control API, to fetch chunks and supply co-signatures over HTTP.
+		ListenAddress string `json:"listen_address"`
+	} `json:"cosign"`
+
+	// API guards the calls to `producer.api_address`, see `apiclient.go`.
+	API struct {
+		// Timeout of each call, defaults to "30s".
+		Timeout string `json:"timeout"`
+		// Timeouts per endpoint, like {"push_transaction": "2m"}.
+		Timeouts map[string]string `json:"timeouts"`
+		// BreakerThreshold consecutive failures (defaults to 5) open
+		// the circuit for BreakerCooldown (defaults to "15s").
+		BreakerThreshold int    `json:"breaker_threshold"`
+		BreakerCooldown  string `json:"breaker_cooldown"`
+	} `json:"api"`
+
+	// Monitor follows block production after registration, see `monitor.go`.
+	Monitor struct {
+		// Duration is how long to monitor, like "30m". Leave empty to skip.
+		Duration string `json:"duration"`
+		// UntilVotingActivated keeps monitoring past Duration, until the chain activates.
+		UntilVotingActivated bool `json:"until_voting_activated"`
+	} `json:"monitor"`
+
+	// Status serves the run's status (voting progress, integrations
+	// health, ...) over HTTP, see `status.go`.
+	Status struct {
+		// ListenAddress, like "127.0.0.1:9999". Leave empty to disable.
+		ListenAddress string `json:"listen_address"`
+	} `json:"status"`
+
+	KickstartListener struct {
+		// ListenAddress, like "127.0.0.1:9899", to receive the kickstart
+		// data on `POST /kickstart` instead of stdin. Leave empty to disable.
+		ListenAddress string `json:"listen_address"`
+		// TokenPath holds the shared token, expected as `Authorization: Bearer <token>`.
+		TokenPath string `json:"token_path"`
+	} `json:"kickstart_listener"`
+
+	// KickstartPollURL is fetched every KickstartPollInterval
+	// (defaults to "15s") until it serves valid kickstart data, for
+	// unattended launches where the boot node publishes to a known
+	// location.
+	KickstartPollURL      string `json:"kickstart_poll_url"`
+	KickstartPollInterval string `json:"kickstart_poll_interval"`
+
+	// GenesisTimestamp, like "2018-06-01T12:00:00", replaces the
+	// shuffle block's time as genesis timestamp, as announced for an
+	// offline-signed boot. ABPs verify the genesis against it, see
+	// `genesis.go`.
+	GenesisTimestamp string `json:"genesis_timestamp"`
+
+	// KickstartCompact publishes the kickstart data in a compact
+	// encoding, for QR codes and voice calls. KickstartInputFile is
+	// read for kickstart data instead of stdin, like the text of a
+	// scanned QR code. See `kickstartcompact.go`.
+	KickstartCompact   bool   `json:"kickstart_compact"`
+	KickstartInputFile string `json:"kickstart_input_file"`
+
+	// KickstartTTL is how long the kickstart data we publish stays
+	// valid, defaults to "30m". KickstartMaxAge refuses received
+	// kickstart data older than that, even within its own TTL. Leave
+	// empty to rely on the TTL alone. See `kickstart.go`.
+	KickstartTTL    string `json:"kickstart_ttl"`
+	KickstartMaxAge string `json:"kickstart_max_age"`
+
+	// KickstartPublish fans the kickstart data out to several
+	// channels at once, on top of the `publish_kickstart_data` hook
+	// and the transport. See `kickstartpublish.go`.
+	KickstartPublish struct {
+		Webhook struct {
+			URL string `json:"url"`
+			// TokenSecret references a secret (see `secrets.go`) sent as a bearer token.
+			TokenSecret string `json:"token_secret"`
+		} `json:"webhook"`
+		// Keybase posts to the channel of `transport.keybase`.
+		Keybase bool `json:"keybase"`
+		IPFS    struct {
+			// APIAddress of an IPFS node, like "http://127.0.0.1:5001".
+			APIAddress string `json:"api_address"`
+		} `json:"ipfs"`
+		File struct {
+			Path string `json:"path"`
+		} `json:"file"`
+	} `json:"kickstart_publish"`
+
+	// KickstartQR renders the published kickstart data as a QR code,
+	// see `qrcode.go`.
+	KickstartQR struct {
+		Terminal bool `json:"terminal"`
+		// Inverse draws the light modules, for terminals with a dark background.
+		Inverse bool   `json:"inverse"`
+		PNGPath string `json:"png_path"`
+	} `json:"kickstart_qr"`
+
+	// Canary pushes a tiny `nonce` action before heavy steps (more
+	// than one chunk), to tune chunk sizes and abort early on a
+	// misbehaving node. See `canary.go`.
+	Canary struct {
+		Enabled bool `json:"enabled"`
+		// IrreversibleTimeout is how long the canary may take to become irreversible, defaults to "2m".
+		IrreversibleTimeout string `json:"irreversible_timeout"`
+	} `json:"canary"`
+
+	// Chunking bounds the transactions the boot node pushes, in
+	// actions and estimated bytes, with overrides per boot sequence
+	// op, like "snapshot.inject". Boot nodes meant to push
+	// identical transactions (`deterministic_transactions`) need the
+	// same settings. See `chunking.go`.
+	Chunking struct {
+		ChunkLimits
+		Ops map[string]ChunkLimits `json:"ops"`
+	} `json:"chunking"`
+
+	// WaitIrreversible makes the boot node wait, after each step, until
+	// the step's last transaction is irreversible before pushing the
+	// next one, instead of firing everything into a possibly forking
+	// head.
+	WaitIrreversible struct {
+		Enabled bool `json:"enabled"`
+		// Ops restricts waiting to these boot sequence ops, like
+		// "system.setcode" and "system.destroy_accounts", all of them when empty.
+		Ops []string `json:"ops"`
+		// Timeout of each wait, defaults to "2m".
+		Timeout string `json:"timeout"`
+	} `json:"wait_irreversible"`
+
+	// Confirmation has a worker trail the pusher, checking each
+	// chunk landed in a block, and optionally stayed there once
+	// irreversible, before recording it in the ledger. See
+	// `confirm.go`.
+	Confirmation struct {
+		Enabled      bool `json:"enabled"`
+		Irreversible bool `json:"irreversible"`
+		// Timeout of each irreversibility wait, defaults to "2m".
+		Timeout string `json:"timeout"`
+	} `json:"confirmation"`
+
+	// PrecomputeIDs builds each step's transactions before pushing
+	// them, and publishes their IDs. See `precompute.go`. Co-signed
+	// steps are not precomputed.
+	PrecomputeIDs struct {
+		Enabled bool `json:"enabled"`
+		// Expiration of the transactions past the step's first head block, defaults to "55m".
+		Expiration string `json:"expiration"`
+	} `json:"precompute_ids"`
+
+	// Bitcoin configures how the block seeding the shuffle is
+	// fetched, see `bitcoin.go`.
+	Bitcoin struct {
+		// Sources to ask, defaults to "blockchain.info", "blockcypher" and "blockstream".
+		Sources []string `json:"sources"`
+		// MinAgreeing is how many sources must return the same block, defaults to a majority.
+		MinAgreeing int `json:"min_agreeing"`
+		// Confirmations, counting the block itself, before using it. Defaults to 6.
+		Confirmations int `json:"confirmations"`
+		// PollInterval while waiting for the block, defaults to "1m".
+		PollInterval string `json:"poll_interval"`
+		// Bitcoind is the RPC endpoint of the "bitcoind" source.
+		Bitcoind struct {
+			URL      string `json:"url"`
+			User     string `json:"user"`
+			Password string `json:"password"`
+		} `json:"bitcoind"`
+	} `json:"bitcoin"`
+
+	// Ethereum configures the "ethereum" shuffle entropy source.
+	Ethereum struct {
+		// RPCURLs are JSON-RPC endpoints which must all agree, defaults to Cloudflare's.
+		RPCURLs []string `json:"rpc_urls"`
+		// Confirmations, counting the block itself. Defaults to 12.
+		Confirmations int `json:"confirmations"`
+	} `json:"ethereum"`
+
+	// Prebuild builds the heavy steps' actions in the background,
+	// while waiting for the seed block, and spools them in `run_dir`.
+	// See `prebuild.go`.
+	Prebuild struct {
+		Enabled bool `json:"enabled"`
+	} `json:"prebuild"`
+
+	// ReviewDir receives every signed transaction of each step,
+	// before it's pushed, for other producers to audit. See
+	// `review.go`.
+	ReviewDir string `json:"review_dir"`
+
+	// SigningPool signs each step's transactions concurrently, ahead
+	// of pushing them. They are precomputed even without
+	// `precompute_ids`, see `signingpool.go`.
+	SigningPool struct {
+		// Workers signing in parallel, 0 signs each transaction as it's pushed.
+		Workers int `json:"workers"`
+	} `json:"signing_pool"`
+
+	// ParallelPush pushes the chunks of the snapshot steps
+	// concurrently, a chunk waiting only for the chunks creating the
+	// accounts it refers to. See `pushpipeline.go`.
+	ParallelPush struct {
+		// Workers pushing in parallel, 0 or 1 pushes one chunk at a
+		// time. Defaults to one per endpoint with several
+		// `producer.api_address`, see `pushlanes.go`.
+		Workers int `json:"workers"`
+		// Ops restricts parallel pushes to these boot sequence ops,
+		// defaults to "snapshot.inject", "snapshot.inject_bulk" and
+		// "snapshot.transfer_packed". Co-signed steps are always serial.
+		Ops []string `json:"ops"`
+	} `json:"parallel_push"`
+
+	// Watchdog alerts when the boot makes no progress, see `watchdog.go`.
+	Watchdog struct {
+		// StallAfter is the time without progress before alerting, like "5m". Leave empty to disable.
+		StallAfter string `json:"stall_after"`
+	} `json:"watchdog"`
+
+	// SelfTest configures `eos-bios self-test`, see `selftest.go`.
+	SelfTest struct {
+		// Peers are p2p endpoints (host:port) we expect to connect to.
+		Peers []string `json:"peers"`
+		// StageDeadline is the time budget for stage 1, like "2h".
+		StageDeadline string `json:"stage_deadline"`
+	} `json:"self_test"`
+
+	// Observer points validations to a remote, public API endpoint
+	// instead of the local node, for `eos-bios observe`.
+	Observer struct {
+		APIAddress        string  `json:"api_address"`
+		RequestsPerSecond float64 `json:"requests_per_second"`
+		PageSize          uint32  `json:"page_size"`
+	} `json:"observer"`
+
+	// Rollout configures `eos-bios verify-rollout`, see `rollout.go`.
+	Rollout struct {
+		// HeadTolerance is how many blocks a producer's endpoint can lag
+		// behind the reference node, defaults to two rounds.
+		HeadTolerance uint32 `json:"head_tolerance"`
+		// SampleTables are compared across endpoints, defaults to the core token's `stat`.
+		SampleTables []rolloutSample `json:"sample_tables"`
+		// FailOnFlagged exits with an error when any producer is flagged.
+		FailOnFlagged bool `json:"fail_on_flagged"`
+	} `json:"rollout"`
+
+	// OnValidationFailure is what an ABP does when its validations
+	// find the chain deviates from the launch data, on top of refusing
+	// to register: `report` (the default) publishes a signed failure
+	// report, `sabotage` also disables our producer accounts. See
+	// `sabotagenetwork.go`.
+	OnValidationFailure string `json:"on_validation_failure"`
+
+	// Release optionally verifies a detached PGP signature of this
+	// binary, on top of the launch data's pin.
+	Release struct {
+		SignaturePath  string `json:"signature_path"`
+		SigningKeyPath string `json:"signing_key_path"`
+
+		// ManifestURL serves the release manifest announcing the build
+		// to run, signed with the key above at ManifestURL + ".sig".
+		// See `updatecheck.go`. Leave empty to skip the check.
+		ManifestURL string `json:"manifest_url"`
+		// UpdatePolicy when a newer build is announced: `warn` (the
+		// default), `prompt` to ask before continuing, or `abort`.
+		UpdatePolicy string `json:"update_policy"`
+	} `json:"release"`
+
+	// Preflight configures the environment checks run before stage 1, see `preflight.go`.
+	Preflight struct {
+		// DataDir is nodeos' data directory, where the blocks log grows.
+		DataDir string `json:"data_dir"`
+		// MinOpenFiles is enforced when set, below the default of 8192 only a warning is printed.
+		MinOpenFiles uint64 `json:"min_open_files"`
+		Skip         bool   `json:"skip"`
+	} `json:"preflight"`
+
+	Readiness struct {
+		// AttestationsPath is the agreed-upon file of readiness attestations (see `readiness.go`).
+		AttestationsPath string `json:"attestations_path"`
+	} `json:"readiness"`
+
+	// EphemeralKey controls the entropy used to generate the
+	// ephemeral key, when we are the BIOS Boot node. See `entropy.go`.
+	EphemeralKey struct {
+		// MixBeacon mixes the shuffle beacon (block merkle root) into the key's entropy.
+		MixBeacon bool `json:"mix_beacon"`
+		// EntropyFile points to a file holding additional, user-supplied entropy.
+		EntropyFile string `json:"entropy_file"`
+	} `json:"ephemeral_key"`
+
 	// PGP manages the PGP keys, used for the communications channel.
+	// It encrypts the kickstart data, see `kickstartpgp.go`.
 	PGP struct {
-		// Program represents the type of program to use (gpg, keybase ?)
+		// Program represents the type of program to use: `gpg`, or `native` for in-process cryptography. Leave empty to publish the kickstart data in cleartext.
 		Program string `json:"program"`
-		// Path to binary executable.. unless we use in-process cryptography..
+		// Path to the `gpg` binary executable, defaults to `gpg` in the PATH.
 		Path string `json:"path"`
+		// PrivateKeyPath is our armored PGP key for `native`, matching our launch file's pgp_public_key.
+		PrivateKeyPath string `json:"private_key_path"`
 	} `json:"pgp"`
 
+	// Transport exchanges kickstart data and attestations with the
+	// other participants, instead of copy-pasting. See `transport.go`.
+	Transport struct {
+		// Type is one of the transports in `transportsRegistry`. Leave empty to copy-paste.
+		Type   string `json:"type"`
+		Matrix struct {
+			Homeserver      string `json:"homeserver"`
+			RoomID          string `json:"room_id"`
+			AccessTokenPath string `json:"access_token_path"`
+		} `json:"matrix"`
+		Email struct {
+			From        string `json:"from"`
+			SMTPAddress string `json:"smtp_address"`
+			// IMAPAddress is polled over TLS, like "imap.example.com:993".
+			IMAPAddress  string `json:"imap_address"`
+			Username     string `json:"username"`
+			PasswordPath string `json:"password_path"`
+			PollInterval string `json:"poll_interval"`
+			// PGPPrivateKeyPath is our armored PGP key, matching our launch file's pgp_public_key.
+			PGPPrivateKeyPath string `json:"pgp_private_key_path"`
+		} `json:"email"`
+		Keybase struct {
+			Team string `json:"team"`
+			// Channel of the team, defaults to "general".
+			Channel string `json:"channel"`
+			// Path to the `keybase` executable, logged in as our launch file's keybase_user.
+			Path         string `json:"path"`
+			PollInterval string `json:"poll_interval"`
+		} `json:"keybase"`
+		// Subprocess runs an external transport, speaking the JSON
+		// protocol described in `subprocess.go`.
+		Subprocess struct {
+			// Exec is the command line, like "eos-bios-signal --account me".
+			Exec string `json:"exec"`
+			// Options are passed as is to the executable, with each request.
+			Options      map[string]string `json:"options"`
+			PollInterval string            `json:"poll_interval"`
+		} `json:"subprocess"`
+	} `json:"transport"`
+
+	// Secrets configures the secret providers, see `secrets.go`.
+	Secrets struct {
+		Vault struct {
+			// Address defaults to $VAULT_ADDR.
+			Address string `json:"address"`
+			// TokenPath holds a Vault token, defaults to $VAULT_TOKEN.
+			TokenPath string `json:"token_path"`
+		} `json:"vault"`
+		AWS struct {
+			Region string `json:"region"`
+		} `json:"aws"`
+	} `json:"secrets"`
+
 	// Hooks are called at different stages in the process, for
 	// remote systems to be notified and act.  They are simply `http`
 	// endpoints to which a POST will be sent with pre-defined structs
@@ -58,7 +502,9 @@ type Config struct {
 
 	// This must all be empty for production.
 	Debug struct {
-		// EnrichProducer will distribute coins to all producers in LaunchData.
+		// EnrichProducer will distribute coins to all producers in
+		// LaunchData. Prefer the `debug.enrich_producers` op, with
+		// amounts per producer and staking, see `enrich.go`.
 		EnrichProducers bool `json:"enrich_producers"`
 		// KeepSystemAccount prevents the destrution of the `eosio` account and removal of its keys.
 		KeepSystemAccount bool `json:"keep_system_account"`
@@ -66,30 +512,124 @@ type Config struct {
 		// LaunchData. Helps tests different roles (BIOS Boot Node,
 		// ABP, watcher...)
 		NoShuffle bool `json:"no_shuffle"`
-		// Truncate snapshot
+		// TruncateSnapshot injects only the first N snapshot rows.
 		TruncateSnapshot int `json:"truncate_snapshot"`
+		// AllowUnpinnedBuild runs even when this binary doesn't match the launch data's `eos_bios` pin.
+		AllowUnpinnedBuild bool `json:"allow_unpinned_build"`
+		// SnapshotSampling keeps only a subset of the snapshot, and
+		// optionally scales balances. See `snapshot.go`.
+		SnapshotSampling SnapshotSampling `json:"snapshot_sampling"`
+		// AllowSabotage lets the `debug.sabotage_*` ops run, to rehearse
+		// catching a malicious boot node. See `sabotage.go`.
+		AllowSabotage bool `json:"allow_sabotage"`
 	}
 }
 
 type ContractLocation struct {
 	CodePath string `json:"code_path"`
 	ABIPath  string `json:"abi_path"`
+	// Translations are ABIs with the ricardian contracts translated,
+	// keyed by language like "zh", shown by `describe`. See `abidocs.go`.
+	Translations map[string]string `json:"translations"`
 }
 
 type HookConfig struct {
 	URL  string `json:"url"`
 	Exec string `json:"exec"`
-	Wait bool   `json:"wait"`
+	// Wait pauses for ENTER after the hook, and records the reply
+	// to `url` deliveries in the run report (see `hookresults.go`).
+	Wait bool `json:"wait"`
+	// TokenSecret references a secret (see `secrets.go`) sent as a
+	// bearer token with `url` deliveries.
+	TokenSecret string `json:"token_secret"`
+	token       string
+	// Optional hooks never block the boot path: their failures are
+	// reported as warnings, and they are skipped for a while after
+	// repeated failures. See `breaker.go`.
+	Optional bool `json:"optional"`
+	// Queue persists `url` deliveries in the run directory and
+	// retries them until they succeed, instead of failing the
+	// process. See `hookqueue.go`.
+	Queue bool `json:"queue"`
+	// BatchSize delivers up to that many queued calls in one
+	// request, as a JSON array of calls, with the number of calls in
+	// the `X-EOS-BIOS-Batch` header.
+	BatchSize int `json:"batch_size"`
+
+	// The following apply to `url` deliveries. The sha256 of the
+	// uncompressed body is always sent in the `X-EOS-BIOS-SHA256`
+	// header, or trailer when streaming.
+	Gzip bool `json:"gzip"`
+	// Stream encodes (and gzips) the body as it is sent, with
+	// chunked transfer encoding, instead of building it in memory.
+	Stream bool `json:"stream"`
+	// MaxBodySize fails deliveries with larger bodies (in bytes), instead of having a proxy drop them.
+	MaxBodySize int64 `json:"max_body_size"`
+	// Timeout of each request, like "30s". No timeout when empty.
+	Timeout string `json:"timeout"`
+
+	// Setting Serialize or MaxConcurrency delivers the hook in the
+	// background, without stalling the boot. Serialize keeps calls
+	// strictly ordered, MaxConcurrency allows parallel deliveries.
+	// See `hookrunner.go`.
+	Serialize      bool `json:"serialize"`
+	MaxConcurrency int  `json:"max_concurrency"`
+	// BufferSize is the number of pending calls (defaults to 64),
+	// after which new calls block, or are dropped when OnFull is "drop".
+	BufferSize int    `json:"buffer_size"`
+	OnFull     string `json:"on_full"`
+}
+
+func LoadLocalConfig(localConfigPath, network string) (*Config, error) {
+	c, err := readLocalConfig(localConfigPath, network)
+	if err != nil {
+		return c, err
+	}
+
+	privKey, err := readBlockSigningPrivateKey(c)
+	if err != nil {
+		return c, err
+	}
+	// Observers don't need to produce anything.
+	if privKey == "" {
+		return c, nil
+	}
+
+	wif, err := ecc.NewPrivateKey(privKey)
+	if err != nil {
+		return c, err
+	}
+
+	c.Producer.blockSigningPrivateKey = wif
+
+	return c, nil
 }
 
-func LoadLocalConfig(localConfigPath string) (*Config, error) {
-	cnt, err := ioutil.ReadFile(localConfigPath)
+// readLocalConfig loads and checks the local config, without
+// touching the block signing private key.
+func readLocalConfig(localConfigPath, network string) (*Config, error) {
+	cnt, err := readMaybeEncrypted(localConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonCnt, err := yaml2json.Convert(cnt)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonCnt, err = selectNetwork(jsonCnt, network)
 	if err != nil {
 		return nil, err
 	}
 
 	var c *Config
-	if err = yamlUnmarshal(cnt, &c); err != nil {
+	if err = json.Unmarshal(jsonCnt, &c); err != nil {
+		return nil, err
+	}
+	c.Network = network
+
+	if err := checkUnknownFields("local config", jsonCnt, c, c.strictDecoding()); err != nil {
 		return nil, err
 	}
 
@@ -97,42 +637,94 @@ func LoadLocalConfig(localConfigPath string) (*Config, error) {
 	// TODO: test all Webhook URLs if defined
 	// TODO: test all Hooks's Exec templates, and compile them right away..
 	h := c.Hooks
-	fmt.Println("Hooks runtime config (see `hooks.go`):")
+	verbose.Println("Hooks runtime config (see `hooks.go`):")
 	for _, hook := range configuredHooks {
 		hconf := h[hook.Key]
 		if hconf == nil {
-			fmt.Printf("Hook %q NOT configured\n", hook.Key)
+			verbose.Printf("Hook %q NOT configured\n", hook.Key)
 			continue
 		}
 
 		if hconf.Exec != "" {
-			fmt.Printf("Hook %q configured to EXEC\n", hook.Key)
+			verbose.Printf("Hook %q configured to EXEC\n", hook.Key)
 		}
 		if hconf.URL != "" {
-			fmt.Printf("Hook %q configured to POST via HTTP\n", hook.Key)
+			verbose.Printf("Hook %q configured to POST via HTTP\n", hook.Key)
+		}
+		if hook.Key == "ledger_entry" {
+			// Streaming to an auditor must never stall the boot.
+			if hconf.URL != "" {
+				if c.RunDir == "" {
+					return nil, fmt.Errorf("hook %q requires a run_dir, to queue its deliveries", hook.Key)
+				}
+				hconf.Queue = true
+			}
+			if hconf.Exec != "" && !asyncHook(hconf) {
+				hconf.Serialize = true
+			}
+		}
+		if hconf.BatchSize > 1 && !hconf.Queue {
+			return nil, fmt.Errorf("hook %q: batch_size applies to queued deliveries, set queue: true", hook.Key)
+		}
+		if hconf.OnFull != "" && hconf.OnFull != "block" && hconf.OnFull != "drop" {
+			return nil, fmt.Errorf("hook %q: on_full must be either \"block\" or \"drop\"", hook.Key)
+		}
+		if hconf.TokenSecret != "" {
+			hconf.token, err = resolveSecret(c, hconf.TokenSecret)
+			if err != nil {
+				return nil, fmt.Errorf("hook %q: %s", hook.Key, err)
+			}
 		}
 	}
 
-	c.Producer.apiAddressURL, err = url.Parse(c.Producer.APIAddress)
-	if err != nil {
-		return c, err
+	if c.OnValidationFailure != "" && c.OnValidationFailure != "report" && c.OnValidationFailure != "sabotage" {
+		return nil, fmt.Errorf("on_validation_failure must be either \"report\" or \"sabotage\"")
 	}
 
-	privKey, err := ioutil.ReadFile(c.Producer.BlockSigningPrivateKeyPath)
-	if err != nil {
-		return c, err
+	if c.GenesisTimestamp != "" {
+		if _, err := time.Parse("2006-01-02T15:04:05", c.GenesisTimestamp); err != nil {
+			return nil, fmt.Errorf("genesis_timestamp: %s", err)
+		}
 	}
 
-	wif, err := ecc.NewPrivateKey(strings.TrimSpace(string(privKey)))
+	for _, address := range c.Producer.APIAddresses {
+		addressURL, err := url.Parse(address)
+		if err != nil {
+			return c, fmt.Errorf("producer.api_address: %s", err)
+		}
+		c.Producer.apiAddressURLs = append(c.Producer.apiAddressURLs, addressURL)
+	}
+	if len(c.Producer.APIAddresses) != 0 {
+		c.Producer.APIAddress = c.Producer.APIAddresses[0]
+	}
+	c.Producer.apiAddressURL, err = url.Parse(c.Producer.APIAddress)
 	if err != nil {
 		return c, err
 	}
 
-	c.Producer.blockSigningPrivateKey = wif
-
 	return c, nil
 }
 
+// readBlockSigningPrivateKey fetches the block signing private key
+// from `block_signing_private_key_secret`, or reads it from
+// `block_signing_private_key_path` (possibly encrypted, see
+// `readMaybeEncrypted`). It is empty for observers without a key.
+func readBlockSigningPrivateKey(c *Config) (string, error) {
+	if c.Producer.BlockSigningPrivateKeySecret != "" {
+		return resolveSecret(c, c.Producer.BlockSigningPrivateKeySecret)
+	}
+
+	if c.Producer.BlockSigningPrivateKeyPath == "" && c.Observer.APIAddress != "" {
+		return "", nil
+	}
+
+	cnt, err := readMaybeEncrypted(c.Producer.BlockSigningPrivateKeyPath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(cnt)), nil
+}
+
 /*
 
 Default values in code for eosio_parameters:
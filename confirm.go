@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// With `confirmation` enabled, a worker trails the pusher: each
+// pushed chunk is looked up in its block, optionally again once that
+// block is irreversible, and only then recorded in the ledger (and
+// streamed to the `ledger_entry` hook). A chunk dropped by a fork on
+// the boot node is caught, and stops the boot, instead of silently
+// losing the balances it injected. On resume, unconfirmed chunks are
+// pushed again.
+
+// PipelineStats instrument the push pipeline, in the run report.
+type PipelineStats struct {
+	Chunks          int   `json:"chunks"`
+	AvgPushMs       int64 `json:"avg_push_ms"`
+	MaxPushMs       int64 `json:"max_push_ms"`
+	Confirmed       int   `json:"confirmed,omitempty"`
+	AvgConfirmLagMs int64 `json:"avg_confirm_lag_ms,omitempty"`
+	MaxConfirmLagMs int64 `json:"max_confirm_lag_ms,omitempty"`
+
+	lock       sync.Mutex
+	pushTotal  time.Duration
+	confirmLag time.Duration
+}
+
+func (s *PipelineStats) pushed(took time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.Chunks++
+	s.pushTotal += took
+	s.AvgPushMs = int64(s.pushTotal/time.Duration(s.Chunks)) / int64(time.Millisecond)
+	if ms := int64(took / time.Millisecond); ms > s.MaxPushMs {
+		s.MaxPushMs = ms
+	}
+}
+
+func (s *PipelineStats) confirmed(lag time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.Confirmed++
+	s.confirmLag += lag
+	s.AvgConfirmLagMs = int64(s.confirmLag/time.Duration(s.Confirmed)) / int64(time.Millisecond)
+	if ms := int64(lag / time.Millisecond); ms > s.MaxConfirmLagMs {
+		s.MaxConfirmLagMs = ms
+	}
+}
+
+type pushedChunk struct {
+	entry    *LedgerEntry
+	actions  []*ReportAction
+	pushedAt time.Time
+}
+
+type confirmationWorker struct {
+	queue   chan *pushedChunk
+	pending sync.WaitGroup
+
+	lock sync.Mutex
+	err  error
+}
+
+func (b *BIOS) startConfirmationWorker() (*confirmationWorker, error) {
+	timeout := 2 * time.Minute
+	if b.Config.Confirmation.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(b.Config.Confirmation.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("confirmation.timeout: %s", err)
+		}
+	}
+
+	w := &confirmationWorker{queue: make(chan *pushedChunk, 1024)}
+	go func() {
+		for chunk := range w.queue {
+			if w.failed() == nil {
+				if err := b.confirmChunk(chunk, timeout); err != nil {
+					w.lock.Lock()
+					w.err = err
+					w.lock.Unlock()
+				}
+			}
+			w.pending.Done()
+		}
+	}()
+	return w, nil
+}
+
+// confirmChunk checks a chunk landed, and records it.
+func (b *BIOS) confirmChunk(chunk *pushedChunk, timeout time.Duration) error {
+	entry := chunk.entry
+	if err := b.verifyInclusion(entry.BlockNum, entry.TransactionID); err != nil {
+		return fmt.Errorf("step %d [%s], chunk %d: %s", entry.Step, entry.Op, entry.Chunk, err)
+	}
+
+	if b.Config.Confirmation.Irreversible {
+		if _, err := b.waitIrreversible(entry.BlockNum, timeout); err != nil {
+			return fmt.Errorf("step %d [%s], chunk %d, block %d: %s", entry.Step, entry.Op, entry.Chunk, entry.BlockNum, err)
+		}
+		// The block may have been replaced by a fork meanwhile.
+		if err := b.verifyInclusion(entry.BlockNum, entry.TransactionID); err != nil {
+			return fmt.Errorf("step %d [%s], chunk %d, dropped by a fork: %s", entry.Step, entry.Op, entry.Chunk, err)
+		}
+	}
+
+	b.Report.Pipeline.confirmed(time.Since(chunk.pushedAt))
+	verbose.Printf("Chunk %d of step %d confirmed in block %d\n", entry.Chunk, entry.Step, entry.BlockNum)
+
+	if err := b.ledgerAppend(entry); err != nil {
+		return fmt.Errorf("ledger: %s", err)
+	}
+	if err := b.DispatchLedgerEntry(entry, chunk.actions); err != nil {
+		return fmt.Errorf("dispatch ledger_entry: %s", err)
+	}
+	return nil
+}
+
+func (w *confirmationWorker) failed() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.err
+}
+
+// enqueue hands a pushed chunk to the worker, or returns the error
+// the worker stopped on.
+func (w *confirmationWorker) enqueue(chunk *pushedChunk) error {
+	if err := w.failed(); err != nil {
+		return fmt.Errorf("confirmation: %s", err)
+	}
+	w.pending.Add(1)
+	w.queue <- chunk
+	return nil
+}
+
+// drain waits until all pushed chunks are confirmed.
+func (w *confirmationWorker) drain() error {
+	w.pending.Wait()
+	if err := w.failed(); err != nil {
+		return fmt.Errorf("confirmation: %s", err)
+	}
+	return nil
+}
+
+func (w *confirmationWorker) close() {
+	close(w.queue)
+}
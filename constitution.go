@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// constitutionNonce is the value anchored on chain by the
+// `system.anchor_constitution` op.
+func constitutionNonce(hash string) string {
+	return "constitution:" + hash
+}
+
+// OpAnchorConstitution records the hash of the agreed constitution
+// (or launch agreement) on chain, in a nonce action.
+type OpAnchorConstitution struct{}
+
+func (op *OpAnchorConstitution) Actions(b *BIOS) (out []*eos.Action, err error) {
+	hash := b.LaunchData.ConstitutionHash
+	if hash == "" {
+		return nil, fmt.Errorf("no constitution_hash in launch data")
+	}
+
+	info.Printf("- Anchoring constitution hash %s\n", hash)
+	return append(out, newNonce(constitutionNonce(hash))), nil
+}
+
+func (op *OpAnchorConstitution) Validate(b *BIOS) error {
+	blockNum, err := b.findNonce(constitutionNonce(b.LaunchData.ConstitutionHash))
+	if err != nil {
+		return err
+	}
+	if blockNum == 0 {
+		return fmt.Errorf("constitution hash %s not anchored on chain", b.LaunchData.ConstitutionHash)
+	}
+	return nil
+}
+
+// constitutionChainID derives the chain ID from the constitution, so
+// the social agreement is part of the chain's identity. Returns
+// all-zeroes when no constitution is anchored.
+func constitutionChainID(launch *LaunchData) ([]byte, error) {
+	if launch.ConstitutionHash == "" {
+		return make([]byte, 32, 32), nil
+	}
+
+	chainID, err := hex.DecodeString(launch.ConstitutionHash)
+	if err != nil || len(chainID) != 32 {
+		return nil, fmt.Errorf("constitution_hash should be a hex-encoded sha256")
+	}
+	return chainID, nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// maxTransactionLifetime is the chain's default `max_trx_lifetime`.
+const maxTransactionLifetime = time.Hour
+
+// CoSignRequest is exported for each chunk requiring co-signature.
+// The co-signer adds their signature(s) to `Transaction`, and
+// supplies the result as `<name>.cosigned.json`, next to the
+// exported file, or through the control API.
+type CoSignRequest struct {
+	Step        int                    `json:"step"`
+	Op          string                 `json:"op"`
+	Chunk       int                    `json:"chunk"`
+	Transaction *eos.PackedTransaction `json:"transaction"`
+}
+
+var coSignServerOnce sync.Once
+
+func (b *BIOS) requiresCoSign(op string) bool {
+	if !b.Config.CoSign.Enabled {
+		return false
+	}
+	if len(b.Config.CoSign.Ops) == 0 {
+		return true
+	}
+	for _, name := range b.Config.CoSign.Ops {
+		if name == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *BIOS) coSignDir() string {
+	return filepath.Join(b.Config.RunDir, "cosign")
+}
+
+// coSignPush signs `chunk` with our keys, exports it, and pushes it
+// once the co-signed transaction is supplied.
+func (b *BIOS) coSignPush(step int, op string, chunkIdx int, chunk []*eos.Action) (*eos.PushTransactionFullResp, error) {
+	if b.Config.RunDir == "" {
+		return nil, fmt.Errorf("co-signing requires a run_dir")
+	}
+
+	timeout := maxTransactionLifetime
+	if b.Config.CoSign.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(b.Config.CoSign.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("cosign.timeout: %s", err)
+		}
+		if timeout > maxTransactionLifetime {
+			return nil, fmt.Errorf("cosign.timeout can't exceed the transaction lifetime of %s", maxTransactionLifetime)
+		}
+	}
+
+	if err := os.MkdirAll(b.coSignDir(), 0755); err != nil {
+		return nil, err
+	}
+
+	if b.Config.CoSign.ListenAddress != "" {
+		coSignServerOnce.Do(b.startCoSignServer)
+	}
+
+	_, packed, err := b.signTransaction(chunk, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("step-%03d-chunk-%03d", step, chunkIdx)
+	cnt, _ := json.MarshalIndent(&CoSignRequest{
+		Step:        step,
+		Op:          op,
+		Chunk:       chunkIdx,
+		Transaction: packed,
+	}, "", "  ")
+
+	exportPath := filepath.Join(b.coSignDir(), name+".json")
+	if err := ioutil.WriteFile(exportPath, cnt, 0644); err != nil {
+		return nil, err
+	}
+
+	cosignedPath := filepath.Join(b.coSignDir(), name+".cosigned.json")
+	milestone.Printf("Chunk %d of step %q requires co-signature, exported to %s\n", chunkIdx, op, exportPath)
+	milestone.Printf("Waiting up to %s for %s\n", timeout, cosignedPath)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		cosigned, err := readCoSigned(cosignedPath, packed)
+		if err == nil {
+			info.Printf("Co-signature received for %s, pushing\n", name)
+			operator, _ := ioutil.ReadFile(filepath.Join(b.coSignDir(), name+".operator"))
+			if len(operator) == 0 {
+				operator = []byte("unnamed (file in the cosign directory)")
+			}
+			b.recordOperatorAction(string(operator), "input", "co-signature of "+name, "")
+			return b.API.PushTransaction(cosigned)
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s: %s", cosignedPath, err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no co-signature for %s within %s", name, timeout)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// readCoSigned loads a co-signed transaction, and makes sure it
+// carries the exact content we signed, with more signatures.
+func readCoSigned(filename string, ours *eos.PackedTransaction) (*eos.PackedTransaction, error) {
+	cnt, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cosigned *eos.PackedTransaction
+	if err := json.Unmarshal(cnt, &cosigned); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(cosigned.PackedTransaction, ours.PackedTransaction) {
+		return nil, fmt.Errorf("co-signed transaction content differs from the exported one")
+	}
+	if len(cosigned.Signatures) <= len(ours.Signatures) {
+		return nil, fmt.Errorf("no additional signature found")
+	}
+
+	return cosigned, nil
+}
+
+// startCoSignServer serves the control API: GET `/cosign/<name>`
+// returns an exported chunk, PUT or POST receives its co-signed
+// version, the co-signer naming themselves with an `X-Operator`
+// header.
+func (b *BIOS) startCoSignServer() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cosign/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/cosign/")
+		if name == "" || strings.ContainsAny(name, "/.") {
+			http.Error(w, "invalid chunk name", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			http.ServeFile(w, r, filepath.Join(b.coSignDir(), name+".json"))
+		case "PUT", "POST":
+			cnt, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 10*1024*1024))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			// Who co-signed, recorded when the co-signature is used.
+			operator := r.Header.Get("X-Operator")
+			if operator == "" {
+				operator = "unnamed"
+			}
+			operator += " (control API from " + r.RemoteAddr + ")"
+			if err := ioutil.WriteFile(filepath.Join(b.coSignDir(), name+".operator"), []byte(operator), 0644); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if err := ioutil.WriteFile(filepath.Join(b.coSignDir(), name+".cosigned.json"), cnt, 0644); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	info.Println("Co-signing control API listening on", b.Config.CoSign.ListenAddress)
+	go func() {
+		if err := http.ListenAndServe(b.Config.CoSign.ListenAddress, mux); err != nil {
+			milestone.Println("WARNING: co-signing control API stopped:", err)
+		}
+	}()
+}
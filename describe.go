@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// Describe prints what this launch will do, from the loaded launch
+// file, config and snapshot. Its output is meant to be reviewed and
+// signed off by all teams before the launch.
+func (b *BIOS) Describe() {
+	launch := b.LaunchData
+
+	fmt.Println("# Launch description")
+	fmt.Println("")
+	fmt.Println("Launch file hash:", launch.fileHash)
+	fmt.Println("Bitcoin block height:", launch.LaunchBitcoinBlockHeight)
+	if launch.ConstitutionHash != "" {
+		fmt.Println("Constitution hash (chain ID):", launch.ConstitutionHash)
+	}
+	if launch.EOSBios.Version != "" || launch.EOSBios.Commit != "" {
+		fmt.Printf("Pinned eos-bios build: version %q, commit %q\n", launch.EOSBios.Version, launch.EOSBios.Commit)
+	}
+	fmt.Println("")
+
+	fmt.Println("## Phases")
+	fmt.Println("")
+	fmt.Println("1. The BIOS Boot node generates an ephemeral key and genesis, runs the boot sequence below, disables `eosio` and publishes the kickstart data.")
+	fmt.Println("2. The Appointed Block Producers connect to the BIOS Boot node, validate the chain, and publish their own p2p addresses.")
+	fmt.Println("3. All other participants connect to the ABPs, and everyone registers as a producer.")
+	fmt.Println("")
+
+	docs := b.loadContractDocs()
+
+	fmt.Println("## Boot sequence")
+	fmt.Println("")
+	for idx, step := range launch.BootSequence {
+		fmt.Printf("%d. %s [%s]\n", idx+1, step.Label, step.Op)
+		params, _ := json.Marshal(step.Data)
+		if string(params) != "{}" && string(params) != "null" {
+			fmt.Printf("   parameters: %s\n", params)
+		}
+		b.describeStepActions(idx, step, docs)
+	}
+	fmt.Println("")
+
+	if len(docs) != 0 {
+		fmt.Println("## Ricardian clauses")
+		fmt.Println("")
+		describeClauses(docs)
+		fmt.Println("")
+	}
+
+	fmt.Println("## Contracts")
+	fmt.Println("")
+	var contracts []string
+	for name := range launch.ContractHashes {
+		contracts = append(contracts, name)
+	}
+	sort.Strings(contracts)
+	for _, name := range contracts {
+		fmt.Printf("- %s: %s\n", name, launch.ContractHashes[name])
+	}
+	fmt.Println("")
+
+	fmt.Println("## Token economics")
+	fmt.Println("")
+	b.describeTokens()
+	fmt.Println("")
+
+	fmt.Println("## Producers")
+	fmt.Println("")
+	algo := launch.ShuffleAlgorithm
+	if algo == "" {
+		algo = defaultShuffleAlgorithm
+	}
+	fmt.Printf("Roles are assigned by the %q shuffle, seeded by %s. With the current seed:\n", algo, strings.Join(launch.shuffleEntropy(), " + "))
+	for idx, prod := range b.ShuffledProducers {
+		role := "participant"
+		if idx == 0 {
+			role = "BIOS Boot node"
+		} else if idx < 22 {
+			role = fmt.Sprintf("ABP %02d", idx)
+		}
+		if prod.clonedFrom != "" {
+			role += fmt.Sprintf(", clone of %s", prod.clonedFrom)
+		}
+		fmt.Printf("- %s (%s): %s\n", prod.AccountName, prod.OrganizationName, role)
+	}
+	fmt.Println("")
+
+	fmt.Println("## Validations")
+	fmt.Println("")
+	fmt.Println("- The opening balances snapshot, contracts and constitution match the hashes above.")
+	if launch.Readiness.Required {
+		fmt.Printf("- Only producers attesting readiness between %s and %s are shuffled.\n", launch.Readiness.WindowStart, launch.Readiness.WindowEnd)
+	}
+	if launch.flagList != nil {
+		fmt.Printf("- %d snapshot addresses are flagged by the signed flag list, ABPs verify each affected account.\n", len(launch.flagList.Flags))
+	}
+	if launch.DeterministicTransactions {
+		fmt.Println("- Boot transactions are deterministic: boot nodes given the same inputs produce byte-identical transactions.")
+	}
+	fmt.Println("- ABPs check the `eosio` account is disabled, then validate each step:")
+	for idx, step := range launch.BootSequence {
+		var rules []string
+		if _, ok := step.Data.(ValidatableOperation); ok {
+			rules = append(rules, "built-in checks")
+		}
+		if step.ValidateExec != "" {
+			rules = append(rules, fmt.Sprintf("external command %q", step.ValidateExec))
+		}
+		if len(rules) == 0 {
+			continue
+		}
+		fmt.Printf("  %d. %s: %s\n", idx+1, step.Label, strings.Join(rules, ", "))
+	}
+}
+
+func (b *BIOS) describeTokens() {
+	for _, step := range b.LaunchData.BootSequence {
+		switch op := step.Data.(type) {
+		case *OpCreateToken:
+			fmt.Printf("- Maximum supply of %s, created by %s\n", op.Amount, op.Account)
+		case *OpIssueToken:
+			fmt.Printf("- %s issued to %s\n", op.Amount, op.Account)
+		case *OpCreateFund:
+			fmt.Printf("- %s moved to the %s fund\n", op.Amount, op.Account)
+		}
+	}
+
+	var total eos.Asset
+	for idx, line := range b.Snapshot {
+		if idx == 0 {
+			total = line.Balance
+		} else {
+			total = total.Add(line.Balance)
+		}
+	}
+	fmt.Printf("- %d snapshot accounts, holding a total of %s\n", len(b.Snapshot), total)
+
+	for _, acct := range b.LaunchData.GenesisAccounts {
+		if acct.CarveOutFrom != "" {
+			fmt.Printf("- Genesis account %s receives %s, carved out of %s\n", acct.AccountName, acct.Balance, acct.CarveOutFrom)
+		} else {
+			fmt.Printf("- Genesis account %s receives %s\n", acct.AccountName, acct.Balance)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// DryRunStep summarizes what a boot step would push.
+type DryRunStep struct {
+	Step    int    `json:"step"`
+	Op      string `json:"op"`
+	Label   string `json:"label"`
+	Actions int    `json:"actions"`
+	Chunks  int    `json:"chunks"`
+	// ActionCounts are keyed by "contract::action".
+	ActionCounts map[string]int `json:"action_counts"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// RunDryRun builds every action of the boot sequence, snapshot
+// injection included, with a throw-away ephemeral key, and prints
+// what would be pushed, without pushing anything. The JSON summary is
+// written to `run_dir/dry-run.json`, or printed when there's no
+// run_dir.
+func (b *BIOS) RunDryRun() error {
+	ephemeralPrivateKey, err := b.GenerateEphemeralPrivKey()
+	if err != nil {
+		return err
+	}
+	b.EphemeralPrivateKey = ephemeralPrivateKey
+
+	milestone.Println("DRY RUN: building the boot sequence, nothing is pushed")
+	milestone.Println("")
+
+	var summary []*DryRunStep
+	failures := 0
+	totalActions := 0
+	totalChunks := 0
+	lastStep := len(b.LaunchData.BootSequence) - 1
+
+	for idx, step := range b.LaunchData.BootSequence {
+		b.currentStep = idx
+
+		result := &DryRunStep{Step: idx, Op: step.Op, Label: step.Label, ActionCounts: map[string]int{}}
+		summary = append(summary, result)
+
+		acts, err := step.Data.Actions(b)
+		if err != nil {
+			milestone.Printf("%d. %s [%s]: FAILED building actions: %s\n", idx, step.Label, step.Op, err)
+			result.Error = err.Error()
+			failures++
+			continue
+		}
+
+		if len(acts) != 0 {
+			chunks := b.tagChunks(chunkifyActions(acts, b.chunkLimits(step.Op)))
+			if idx == lastStep {
+				chunks = b.appendEndMarker(chunks)
+			}
+			if b.LaunchData.DeterministicTransactions {
+				chunks = b.sequenceChunks(idx, chunks)
+			}
+			result.Chunks = len(chunks)
+			for _, chunk := range chunks {
+				for _, act := range chunk {
+					result.ActionCounts[fmt.Sprintf("%s::%s", act.Account, act.Name)]++
+					result.Actions++
+				}
+			}
+		}
+		totalActions += result.Actions
+		totalChunks += result.Chunks
+
+		milestone.Printf("%d. %s [%s]: %d actions in %d transactions\n", idx, step.Label, step.Op, result.Actions, result.Chunks)
+		var names []string
+		for name := range result.ActionCounts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			info.Printf("   - %s x %d\n", name, result.ActionCounts[name])
+		}
+	}
+
+	milestone.Println("")
+	milestone.Printf("DRY RUN: %d actions in %d transactions, over %d steps\n", totalActions, totalChunks, len(b.LaunchData.BootSequence))
+
+	cnt, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	if b.Config.RunDir != "" {
+		filename := filepath.Join(b.Config.RunDir, "dry-run.json")
+		if err := ioutil.WriteFile(filename, cnt, 0644); err != nil {
+			return err
+		}
+		info.Println("Dry run summary written to", filename)
+	} else {
+		fmt.Println(string(cnt))
+	}
+
+	if failures != 0 {
+		return fmt.Errorf("%d step(s) failed to build", failures)
+	}
+	return nil
+}
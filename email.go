@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/smtp"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+// emailTransport sends payloads PGP-encrypted to each producer's
+// launch file `email`, and ingests those found in an IMAP inbox, when
+// signed by a producer of the launch file.
+type emailTransport struct {
+	from         string
+	smtpAddress  string
+	smtpAuth     smtp.Auth
+	imapAddress  string
+	imapUsername string
+	imapPassword string
+	pollInterval time.Duration
+
+	myAccount  string
+	keyring    openpgp.EntityList
+	recipients map[string]openpgp.EntityList // keyed by email
+	trusted    openpgp.EntityList
+}
+
+func newEmailTransport(config *Config, launch *LaunchData) (Transport, error) {
+	conf := config.Transport.Email
+	if conf.From == "" || conf.SMTPAddress == "" || conf.IMAPAddress == "" || conf.PGPPrivateKeyPath == "" {
+		return nil, fmt.Errorf("the email transport requires from, smtp_address, imap_address and pgp_private_key_path")
+	}
+
+	t := &emailTransport{
+		from:         conf.From,
+		smtpAddress:  conf.SMTPAddress,
+		imapAddress:  conf.IMAPAddress,
+		imapUsername: conf.Username,
+		pollInterval: 30 * time.Second,
+		myAccount:    config.Producer.MyAccount,
+		recipients:   map[string]openpgp.EntityList{},
+	}
+
+	if conf.PollInterval != "" {
+		var err error
+		t.pollInterval, err = time.ParseDuration(conf.PollInterval)
+		if err != nil {
+			return nil, fmt.Errorf("transport.email.poll_interval: %s", err)
+		}
+	}
+
+	if conf.PasswordPath != "" {
+		password, err := ioutil.ReadFile(conf.PasswordPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading email password: %s", err)
+		}
+		t.imapPassword = strings.TrimSpace(string(password))
+		host, _, _ := net.SplitHostPort(conf.SMTPAddress)
+		t.smtpAuth = smtp.PlainAuth("", conf.Username, t.imapPassword, host)
+	}
+
+	var err error
+	t.keyring, err = readArmoredKeyFile(conf.PGPPrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(t.keyring) == 0 || t.keyring[0].PrivateKey == nil {
+		return nil, fmt.Errorf("no PGP private key found in %q", conf.PGPPrivateKeyPath)
+	}
+
+	keys, err := producersPGPKeys(launch)
+	if err != nil {
+		return nil, err
+	}
+	for _, prod := range launch.Producers {
+		producerKeys := keys[string(prod.AccountName)]
+		t.trusted = append(t.trusted, producerKeys...)
+		if prod.Email == "" || string(prod.AccountName) == t.myAccount {
+			continue
+		}
+		if len(producerKeys) == 0 {
+			return nil, fmt.Errorf("%s has an email but no pgp_public_key to encrypt to", prod.AccountName)
+		}
+		t.recipients[prod.Email] = producerKeys
+	}
+
+	return t, nil
+}
+
+func (t *emailTransport) subject(kind string) string {
+	return "[eos-bios] " + kind
+}
+
+func (t *emailTransport) Publish(kind, payload string) error {
+	for email, keys := range t.recipients {
+		message, err := pgpEncrypt([]byte(payload), keys, t.keyring[0])
+		if err != nil {
+			return fmt.Errorf("encrypting for %s: %s", email, err)
+		}
+
+		body := strings.Join([]string{
+			"From: " + t.from,
+			"To: " + email,
+			"Subject: " + t.subject(kind),
+			"Date: " + time.Now().Format(time.RFC1123Z),
+			"Content-Type: text/plain; charset=us-ascii",
+			"",
+			message,
+		}, "\r\n")
+
+		verbose.Printf("Sending %s to %s\n", kind, email)
+		if err := smtp.SendMail(t.smtpAddress, t.smtpAuth, t.from, []string{email}, []byte(body)); err != nil {
+			return fmt.Errorf("sending to %s: %s", email, err)
+		}
+	}
+
+	return nil
+}
+
+func (t *emailTransport) Receive(kind string) ([]string, error) {
+	for {
+		out, err := t.fetchUnseen(kind)
+		if err != nil {
+			return nil, err
+		}
+		if len(out) != 0 {
+			return out, nil
+		}
+		time.Sleep(t.pollInterval)
+	}
+}
+
+// fetchUnseen returns the valid payloads of unread messages, marking
+// them as read.
+func (t *emailTransport) fetchUnseen(kind string) (out []string, err error) {
+	conn, err := dialIMAP(t.imapAddress)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Cmd("LOGIN %s %s", imapQuote(t.imapUsername), imapQuote(t.imapPassword)); err != nil {
+		return nil, fmt.Errorf("imap login: %s", err)
+	}
+	if _, err := conn.Cmd("SELECT INBOX"); err != nil {
+		return nil, fmt.Errorf("imap select: %s", err)
+	}
+
+	resp, err := conn.Cmd("UID SEARCH UNSEEN SUBJECT %s", imapQuote(t.subject(kind)))
+	if err != nil {
+		return nil, fmt.Errorf("imap search: %s", err)
+	}
+
+	var uids []string
+	for _, line := range resp.Lines {
+		if strings.HasPrefix(line, "* SEARCH") {
+			uids = append(uids, strings.Fields(strings.TrimPrefix(line, "* SEARCH"))...)
+		}
+	}
+
+	for _, uid := range uids {
+		resp, err := conn.Cmd("UID FETCH %s BODY[TEXT]", uid)
+		if err != nil {
+			return nil, fmt.Errorf("imap fetch: %s", err)
+		}
+		for _, literal := range resp.Literals {
+			payload, err := pgpDecrypt(string(literal), t.keyring, t.trusted)
+			if err != nil {
+				info.Printf("Ignoring email %s: %s\n", uid, err)
+				continue
+			}
+			out = append(out, string(payload))
+		}
+	}
+
+	_, _ = conn.Cmd("LOGOUT")
+
+	return out, nil
+}
+
+// imapConn is the bare minimum of an IMAP4rev1 client we need: tagged
+// commands, and their untagged responses and literals.
+type imapConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+type imapResponse struct {
+	Lines    []string
+	Literals [][]byte
+}
+
+var imapLiteralRE = regexp.MustCompile(`\{(\d+)\}$`)
+
+func dialIMAP(address string) (*imapConn, error) {
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", address, nil)
+	if err != nil {
+		return nil, fmt.Errorf("imap: %s", err)
+	}
+
+	c := &imapConn{conn: conn, r: bufio.NewReader(conn)}
+	if _, err := c.r.ReadString('\n'); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("imap greeting: %s", err)
+	}
+
+	return c, nil
+}
+
+func (c *imapConn) Cmd(format string, args ...interface{}) (*imapResponse, error) {
+	c.tag++
+	tag := fmt.Sprintf("A%03d", c.tag)
+
+	_ = c.conn.SetDeadline(time.Now().Add(2 * time.Minute))
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, err
+	}
+
+	resp := &imapResponse{}
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if match := imapLiteralRE.FindStringSubmatch(line); match != nil {
+			size, _ := strconv.Atoi(match[1])
+			literal := make([]byte, size)
+			if _, err := io.ReadFull(c.r, literal); err != nil {
+				return nil, err
+			}
+			resp.Literals = append(resp.Literals, literal)
+		}
+
+		if strings.HasPrefix(line, tag+" ") {
+			status := strings.TrimPrefix(line, tag+" ")
+			if !strings.HasPrefix(status, "OK") {
+				return resp, fmt.Errorf("%s", status)
+			}
+			return resp, nil
+		}
+
+		resp.Lines = append(resp.Lines, line)
+	}
+}
+
+func (c *imapConn) Close() error {
+	return c.conn.Close()
+}
+
+func imapQuote(in string) string {
+	return `"` + strings.Replace(strings.Replace(in, `\`, `\\`, -1), `"`, `\"`, -1) + `"`
+}
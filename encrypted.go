@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// cachedPassphrase is asked once, and reused for all files.
+var cachedPassphrase []byte
+
+// readMaybeEncrypted reads `filename`, decrypting it in memory when
+// it is a PGP message (armored, or with a `.gpg`/`.pgp` extension),
+// so machines prepared ahead of launch day don't hold sensitive
+// configuration in plaintext.
+func readMaybeEncrypted(filename string) ([]byte, error) {
+	cnt, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	armored := bytes.HasPrefix(bytes.TrimSpace(cnt), []byte("-----BEGIN PGP MESSAGE-----"))
+	ext := strings.ToLower(filepath.Ext(filename))
+	if !armored && ext != ".gpg" && ext != ".pgp" {
+		return cnt, nil
+	}
+
+	in := bytes.NewReader(cnt)
+	body := in
+	if armored {
+		block, err := armor.Decode(in)
+		if err != nil {
+			return nil, fmt.Errorf("decoding armor of %q: %s", filename, err)
+		}
+		raw, err := ioutil.ReadAll(block.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(raw)
+	}
+
+	var keyring openpgp.EntityList
+	if *decryptionKeyPath != "" {
+		keyFile, err := os.Open(*decryptionKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		defer keyFile.Close()
+
+		keyring, err = openpgp.ReadArmoredKeyRing(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading decryption key: %s", err)
+		}
+	}
+
+	attempts := 0
+	prompt := func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		attempts++
+		if attempts > 3 {
+			return nil, fmt.Errorf("wrong passphrase")
+		}
+		if attempts > 1 {
+			cachedPassphrase = nil
+		}
+
+		passphrase, err := getPassphrase(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		if symmetric {
+			return passphrase, nil
+		}
+
+		for _, key := range keys {
+			if key.PrivateKey != nil && key.PrivateKey.Encrypted {
+				_ = key.PrivateKey.Decrypt(passphrase)
+			}
+		}
+		return nil, nil
+	}
+
+	md, err := openpgp.ReadMessage(body, keyring, prompt, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %q: %s", filename, err)
+	}
+
+	plain, err := ioutil.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %q: %s", filename, err)
+	}
+
+	verbose.Printf("Decrypted %q in memory\n", filename)
+
+	return plain, nil
+}
+
+func getPassphrase(filename string) ([]byte, error) {
+	if cachedPassphrase != nil {
+		return cachedPassphrase, nil
+	}
+
+	switch {
+	case *passphraseFile != "":
+		cnt, err := ioutil.ReadFile(*passphraseFile)
+		if err != nil {
+			return nil, err
+		}
+		cachedPassphrase = bytes.TrimRight(cnt, "\r\n")
+	case os.Getenv("EOS_BIOS_PASSPHRASE") != "":
+		cachedPassphrase = []byte(os.Getenv("EOS_BIOS_PASSPHRASE"))
+	default:
+		fmt.Printf("Passphrase to unlock %q: ", filename)
+		passphrase, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println("")
+		if err != nil {
+			return nil, err
+		}
+		recordOperatorAction("", "input", fmt.Sprintf("passphrase to unlock %q", filename), "")
+		cachedPassphrase = passphrase
+	}
+
+	return cachedPassphrase, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// The boot node ends the boot sequence with an `eosio::nonce` of
+// "eos-bios:end:<launch hash>". It rides in the last transaction of
+// the last step, as that step usually disables `eosio` (authorities
+// are checked before the transaction applies). ABPs and participants
+// wait for it on chain before validating or registering, so nodes
+// still syncing don't act on a half-booted chain.
+
+func endMarker(launchHash string) string {
+	return "eos-bios:end:" + launchHash
+}
+
+// appendEndMarker adds the end-of-boot marker to the last chunk.
+func (b *BIOS) appendEndMarker(chunks [][]*eos.Action) [][]*eos.Action {
+	last := len(chunks) - 1
+	chunks[last] = append(chunks[last], newNonce(endMarker(b.LaunchData.fileHash)))
+	return chunks
+}
+
+// waitEndMarker polls the chain until the end-of-boot marker of our
+// launch data is found, and returns its block number.
+func (b *BIOS) waitEndMarker() (uint32, error) {
+	marker := endMarker(b.LaunchData.fileHash)
+	next := uint32(1)
+
+	info.Printf("- Waiting for the end-of-boot marker on chain: ")
+	for {
+		chainInfo, err := b.validationAPI().GetInfo()
+		if err != nil {
+			verbose.Printf("e")
+			time.Sleep(1 * time.Second)
+			continue
+		}
+
+		if chainInfo.HeadBlockNum >= next {
+			found, err := b.findNonceIn(next, chainInfo.HeadBlockNum, marker)
+			if err != nil {
+				return 0, fmt.Errorf("looking for the end-of-boot marker: %s", err)
+			}
+			if found != 0 {
+				info.Printf(" found in block %d\n", found)
+				return found, nil
+			}
+			next = chainInfo.HeadBlockNum + 1
+		}
+
+		verbose.Printf(".")
+		time.Sleep(1 * time.Second)
+	}
+}
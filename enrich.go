@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/system"
+	"github.com/eoscanada/eos-go/token"
+)
+
+// OpEnrichProducers funds the producers of a test network, so it
+// models realistic producer balances. It replaces the all-or-nothing
+// `debug.enrich_producers` config flag, and its outcome is validated
+// by the ABPs like any other step.
+type OpEnrichProducers struct {
+	// Amount each producer receives, unless listed in Amounts.
+	Amount  eos.Asset
+	Amounts map[eos.AccountName]eos.Asset
+	// StakeCPU and StakeNet, when set, are staked by `eosio` to each
+	// producer, and transferred to it.
+	StakeCPU eos.Asset `json:"stake_cpu"`
+	StakeNet eos.Asset `json:"stake_net"`
+}
+
+func (op *OpEnrichProducers) amount(account eos.AccountName) eos.Asset {
+	if amount, found := op.Amounts[account]; found {
+		return amount
+	}
+	return op.Amount
+}
+
+func (op *OpEnrichProducers) staking() bool {
+	return op.StakeCPU.Amount != 0 || op.StakeNet.Amount != 0
+}
+
+func (op *OpEnrichProducers) Actions(b *BIOS) (out []*eos.Action, err error) {
+	milestone.Println("DEBUG: enriching producers, this op is meant for test networks")
+
+	for account := range op.Amounts {
+		if !b.isShuffledProducer(account) {
+			return nil, fmt.Errorf("debug.enrich_producers: %s isn't a producer", account)
+		}
+	}
+
+	for _, prod := range b.ShuffledProducers {
+		if amount := op.amount(prod.AccountName); amount.Amount != 0 {
+			verbose.Printf("  DEBUG: Enriching producer %q with %s\n", prod.AccountName, amount)
+			out = append(out, token.NewTransfer(AN("eosio"), prod.AccountName, amount, b.memo("Hey, make good use of it!")))
+		}
+		if op.staking() {
+			out = append(out, system.NewDelegateBW(AN("eosio"), prod.AccountName, op.StakeCPU, op.StakeNet, true))
+		}
+	}
+	return
+}
+
+// Validate checks each producer holds at least its amount, and its
+// stake when staking.
+func (op *OpEnrichProducers) Validate(b *BIOS) error {
+	for _, prod := range b.ShuffledProducers {
+		expected := op.amount(prod.AccountName)
+		balance, err := b.getTokenBalance(prod.AccountName)
+		if err != nil {
+			return fmt.Errorf("get balance of %s: %s", prod.AccountName, err)
+		}
+		if balance.Amount < expected.Amount {
+			return fmt.Errorf("%s holds %s, expected at least %s", prod.AccountName, balance, expected)
+		}
+
+		if !op.staking() {
+			continue
+		}
+
+		cpu, net, err := b.getSelfStake(prod.AccountName)
+		if err != nil {
+			return fmt.Errorf("get stake of %s: %s", prod.AccountName, err)
+		}
+		if cpu.Amount < op.StakeCPU.Amount || net.Amount < op.StakeNet.Amount {
+			return fmt.Errorf("%s stakes %s CPU and %s NET to itself, expected at least %s and %s", prod.AccountName, cpu, net, op.StakeCPU, op.StakeNet)
+		}
+	}
+	return nil
+}
+
+// getSelfStake reads what `account` stakes to itself, from the
+// system contract's `delband` table.
+func (b *BIOS) getSelfStake(account eos.AccountName) (cpu, net eos.Asset, err error) {
+	rows, err := b.getAllTableRows(eos.GetTableRowsRequest{
+		JSON:  true,
+		Code:  "eosio",
+		Scope: string(account),
+		Table: "delband",
+	}, "to")
+	if err != nil {
+		return
+	}
+
+	for _, raw := range rows {
+		var row struct {
+			To        eos.AccountName `json:"to"`
+			NetWeight eos.Asset       `json:"net_weight"`
+			CPUWeight eos.Asset       `json:"cpu_weight"`
+		}
+		if err = json.Unmarshal(raw, &row); err != nil {
+			return cpu, net, fmt.Errorf("decoding delband row: %s", err)
+		}
+		if row.To == account {
+			return row.CPUWeight, row.NetWeight, nil
+		}
+	}
+	return eos.Asset{Symbol: eos.EOSSymbol}, eos.Asset{Symbol: eos.EOSSymbol}, nil
+}
+
+func (b *BIOS) isShuffledProducer(account eos.AccountName) bool {
+	for _, prod := range b.ShuffledProducers {
+		if prod.AccountName == account {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/eoscanada/eos-go/ecc"
+)
+
+// entropySource is one input to the ephemeral key generation.
+type entropySource struct {
+	Label string
+	Data  []byte
+}
+
+// Commitment is the hash of the source's data, which can be published
+// for audit purposes without revealing anything about the key.
+func (s entropySource) Commitment() string {
+	h := sha256.Sum256(s.Data)
+	return hex.EncodeToString(h[:])
+}
+
+// EntropyCommitment is a source's commitment, recorded in the
+// ledger's start entry and in the run report.
+type EntropyCommitment struct {
+	Source     string `json:"source"`
+	Commitment string `json:"commitment"`
+}
+
+// ephemeralEntropySources always includes 32 bytes from the local
+// CSPRNG, so a weak beacon or entropy file can never make the key
+// weaker than `ecc.NewRandomPrivateKey`.
+func (b *BIOS) ephemeralEntropySources() (out []entropySource, err error) {
+	local := make([]byte, 32)
+	if _, err := rand.Read(local); err != nil {
+		return nil, fmt.Errorf("reading local entropy: %s", err)
+	}
+	out = append(out, entropySource{"local", local})
+
+	if b.Config.EphemeralKey.MixBeacon {
+		if len(b.ShuffleBlock.MerkleRoot) == 0 {
+			return nil, fmt.Errorf("ephemeral_key.mix_beacon set, but no beacon value available")
+		}
+		out = append(out, entropySource{"beacon", b.ShuffleBlock.MerkleRoot})
+	}
+
+	if path := b.Config.EphemeralKey.EntropyFile; path != "" {
+		cnt, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading entropy file: %s", err)
+		}
+		if len(cnt) < 32 {
+			return nil, fmt.Errorf("entropy file %q too short, needs at least 32 bytes", path)
+		}
+		out = append(out, entropySource{"file", cnt})
+	}
+
+	return
+}
+
+// newMixedPrivateKey hashes all the sources together, in order, and
+// derives the private key from the result. Commitments for each
+// source are printed so the process can be documented, and kept in
+// the run report.
+func newMixedPrivateKey(sources []entropySource) (*ecc.PrivateKey, error) {
+	h := sha256.New()
+	info.Println("Mixing entropy for the ephemeral key:")
+	for _, source := range sources {
+		commitment := source.Commitment()
+		info.Printf("- %s commitment: %s\n", source.Label, commitment)
+
+		// Mix the raw data, never the commitments: these are public.
+		h.Write([]byte(source.Label))
+		h.Write([]byte(":"))
+		h.Write(source.Data)
+		h.Write([]byte(";"))
+	}
+
+	return ecc.NewDeterministicPrivateKey(bytes.NewReader(h.Sum(nil)))
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/system"
+)
+
+// FlagList holds the snapshot addresses the launch agreement flags,
+// like known-compromised keys. It is a YAML file, pinned by the
+// launch data's `flag_list_hash` and signed (detached PGP) by whoever
+// the agreement entrusts with it. Flagged addresses are handled by
+// `snapshot.inject`, according to their treatment: with `hold`, the
+// account is created with the holder's key, but its balance is
+// transferred to the `holding_account` instead. With `authority`,
+// the account is created with the flag's `authority` instead of the
+// holder's key.
+type FlagList struct {
+	HoldingAccount eos.AccountName   `json:"holding_account"`
+	Flags          []*FlaggedAddress `json:"flags"`
+}
+
+type FlaggedAddress struct {
+	Address   string `json:"address"`
+	Reason    string `json:"reason"`
+	Treatment string `json:"treatment"`
+	Authority struct {
+		Owner  eos.Authority `json:"owner"`
+		Active eos.Authority `json:"active"`
+	} `json:"authority"`
+}
+
+// loadFlagList verifies the configured flag list against the launch
+// data, and its signature.
+func loadFlagList(launch *LaunchData, config *Config) (*FlagList, error) {
+	conf := config.FlagList
+	if conf.Path == "" {
+		return nil, fmt.Errorf("launch data pins a flag list, but flag_list.path isn't configured")
+	}
+
+	hash, err := hashFile(conf.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	info.Printf("Hash of %q: %s\n", conf.Path, hash)
+
+	if hash != launch.FlagListHash {
+		return nil, fmt.Errorf("flag list hash doesn't match launch data")
+	}
+
+	if conf.SignaturePath == "" || conf.SigningKeyPath == "" {
+		return nil, fmt.Errorf("flag_list: path needs its signature_path and signing_key_path")
+	}
+	if err := checkDetachedSignature(conf.Path, conf.SignaturePath, conf.SigningKeyPath); err != nil {
+		return nil, fmt.Errorf("flag list signature: %s", err)
+	}
+
+	cnt, err := ioutil.ReadFile(conf.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var flagList *FlagList
+	if err := yamlUnmarshal(cnt, &flagList); err != nil {
+		return nil, fmt.Errorf("flag list: %s", err)
+	}
+
+	seen := map[string]bool{}
+	for _, flag := range flagList.Flags {
+		addr := strings.ToLower(flag.Address)
+		if seen[addr] {
+			return nil, fmt.Errorf("flag list: %s is flagged twice", flag.Address)
+		}
+		seen[addr] = true
+
+		switch flag.Treatment {
+		case "hold":
+			if flagList.HoldingAccount == "" {
+				return nil, fmt.Errorf("flag list: %s is held, but there's no holding_account", flag.Address)
+			}
+		case "authority":
+			if flag.Authority.Owner.Threshold == 0 || flag.Authority.Active.Threshold == 0 {
+				return nil, fmt.Errorf("flag list: %s needs an owner and active authority", flag.Address)
+			}
+		default:
+			return nil, fmt.Errorf("flag list: treatment %q of %s invalid, use `hold` or `authority`", flag.Treatment, flag.Address)
+		}
+	}
+
+	if err := checkSnapshotInjectedIndividually(launch, "the flag list"); err != nil {
+		return nil, err
+	}
+
+	info.Printf("%d snapshot addresses flagged\n", len(flagList.Flags))
+
+	return flagList, nil
+}
+
+// flaggedAddress returns the flag of a snapshot row, or nil.
+func (b *BIOS) flaggedAddress(hodler SnapshotLine) *FlaggedAddress {
+	if b.LaunchData.flagList == nil {
+		return nil
+	}
+	for _, flag := range b.LaunchData.flagList.Flags {
+		if strings.EqualFold(flag.Address, hodler.EthereumAddress) {
+			return flag
+		}
+	}
+	return nil
+}
+
+func newFlaggedAccount(account eos.AccountName, flag *FlaggedAddress) *eos.Action {
+	newAccount := system.NewNewAccount(AN("eosio"), account, nil)
+	newAccount.Data = eos.NewActionData(system.NewAccount{
+		Creator: AN("eosio"),
+		Name:    account,
+		Owner:   flag.Authority.Owner,
+		Active:  flag.Authority.Active,
+	})
+	return newAccount
+}
+
+// validateFlaggedAccounts checks, and lists, every account the flag
+// list affected, and how.
+func (b *BIOS) validateFlaggedAccounts() error {
+	if b.LaunchData.flagList == nil {
+		return nil
+	}
+
+	affected := 0
+	for idx, hodler := range b.injectedSnapshot() {
+		flag := b.flaggedAddress(hodler)
+		if flag == nil {
+			continue
+		}
+		affected++
+
+		account := snapshotAccountName(idx)
+		acct, err := b.validationAPI().GetAccount(account)
+		if err != nil {
+			return fmt.Errorf("flagged %s: get account %s: %s", hodler.EthereumAddress, account, err)
+		}
+
+		switch flag.Treatment {
+		case "hold":
+			balance, err := b.getTokenBalance(account)
+			if err != nil {
+				return fmt.Errorf("flagged %s: get balance of %s: %s", hodler.EthereumAddress, account, err)
+			}
+			if balance.Amount != 0 {
+				return fmt.Errorf("flagged %s: %s holds %s, its balance should be held by %s", hodler.EthereumAddress, account, balance, b.LaunchData.flagList.HoldingAccount)
+			}
+			info.Printf("  - %s (%s): %s held by %s, %s\n", account, hodler.EthereumAddress, hodler.Balance, b.LaunchData.flagList.HoldingAccount, flag.Reason)
+
+		case "authority":
+			if err := validateAuthority(acct, "owner", flag.Authority.Owner); err != nil {
+				return fmt.Errorf("flagged %s: %s", hodler.EthereumAddress, err)
+			}
+			if err := validateAuthority(acct, "active", flag.Authority.Active); err != nil {
+				return fmt.Errorf("flagged %s: %s", hodler.EthereumAddress, err)
+			}
+			info.Printf("  - %s (%s): created with the flag's authority, %s\n", account, hodler.EthereumAddress, flag.Reason)
+		}
+	}
+
+	info.Printf("  %d of %d flagged addresses found in the snapshot, all handled as agreed\n", affected, len(b.LaunchData.flagList.Flags))
+	return nil
+}
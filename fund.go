@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/system"
+	"github.com/eoscanada/eos-go/token"
+)
+
+// OpCreateFund creates an `eosio.saving`-style community fund,
+// controlled by a multisig of the Appointed Block Producers, and
+// funds it from `eosio`.
+type OpCreateFund struct {
+	Account eos.AccountName
+	Amount  eos.Asset
+	// Threshold is the number of ABPs required to move funds. Defaults to 2/3+1.
+	Threshold uint32
+	Memo      string
+}
+
+func (op *OpCreateFund) Actions(b *BIOS) (out []*eos.Action, err error) {
+	auth := b.appointedProducersAuthority(op.Threshold)
+	if int(auth.Threshold) > len(auth.Accounts) {
+		return nil, fmt.Errorf("fund threshold %d higher than the %d appointed block producers", auth.Threshold, len(auth.Accounts))
+	}
+
+	newAccount := system.NewNewAccount(AN("eosio"), op.Account, nil)
+	newAccount.Data = eos.NewActionData(system.NewAccount{
+		Creator: AN("eosio"),
+		Name:    op.Account,
+		Owner:   auth,
+		Active:  auth,
+	})
+
+	verbose.Printf("- Creating community fund %q, controlled by %d of %d ABPs\n", op.Account, auth.Threshold, len(auth.Accounts))
+	out = append(out, newAccount)
+
+	if op.Amount.Amount != 0 {
+		out = append(out, token.NewTransfer(AN("eosio"), op.Account, op.Amount, b.memo(op.Memo)))
+	}
+	return
+}
+
+func (op *OpCreateFund) Validate(b *BIOS) error {
+	acct, err := b.validationAPI().GetAccount(op.Account)
+	if err != nil {
+		return fmt.Errorf("get account %s: %s", op.Account, err)
+	}
+
+	auth := b.appointedProducersAuthority(op.Threshold)
+	if err := validateAuthority(acct, "owner", auth); err != nil {
+		return err
+	}
+	if err := validateAuthority(acct, "active", auth); err != nil {
+		return err
+	}
+
+	balance, err := b.getTokenBalance(op.Account)
+	if err != nil {
+		return fmt.Errorf("get balance of %s: %s", op.Account, err)
+	}
+	if balance.Amount != op.Amount.Amount {
+		return fmt.Errorf("%s holds %s, expected %s", op.Account, balance, op.Amount)
+	}
+
+	return nil
+}
@@ -1,7 +1,95 @@
 package main
 
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
 type GenesisJSON struct {
 	InitialTimestamp string `json:"initial_timestamp"`
 	InitialKey       string `json:"initial_key"`
 	InitialChainID   string `json:"initial_chain_id"`
 }
+
+// genesisTime is the genesis timestamp: the config's
+// `genesis_timestamp` when announced for an offline-signed boot (see
+// `offline.go`), otherwise the time of the block seeding the shuffle.
+func (b *BIOS) genesisTime() (time.Time, error) {
+	if b.Config.GenesisTimestamp != "" {
+		t, err := time.Parse("2006-01-02T15:04:05", b.Config.GenesisTimestamp)
+		if err != nil {
+			return t, fmt.Errorf("genesis_timestamp: %s", err)
+		}
+		return t, nil
+	}
+	return b.ShuffleBlock.Time.UTC(), nil
+}
+
+// verifyGenesis recomputes the genesis the boot node must have
+// generated, from the shuffle seed, the kickstart's initial key and
+// the constitution's chain ID, and compares it to the one received,
+// once both are canonicalized.
+func (b *BIOS) verifyGenesis(kickstart KickstartData) error {
+	genesisTime, err := b.genesisTime()
+	if err != nil {
+		return err
+	}
+
+	expected := &GenesisJSON{
+		InitialTimestamp: genesisTime.Format("2006-01-02T15:04:05"),
+		InitialKey:       kickstart.PublicKeyUsed,
+		InitialChainID:   hex.EncodeToString(b.API.ChainID),
+	}
+	if b.Config.Debug.NoShuffle && b.Config.GenesisTimestamp == "" {
+		var received GenesisJSON
+		_ = json.Unmarshal([]byte(kickstart.GenesisJSON), &received)
+		info.Println("DEBUG: no shuffle seed, not verifying the genesis timestamp")
+		expected.InitialTimestamp = received.InitialTimestamp
+	}
+
+	cnt, _ := json.Marshal(expected)
+	return compareGenesisJSON(string(cnt), kickstart.GenesisJSON)
+}
+
+// compareGenesisJSON compares two genesis JSON documents byte for
+// byte, after canonicalization (sorted keys, no whitespace), and
+// names the diverging fields.
+func compareGenesisJSON(expected, received string) error {
+	var expectedFields, receivedFields map[string]interface{}
+	if err := json.Unmarshal([]byte(expected), &expectedFields); err != nil {
+		return fmt.Errorf("expected genesis: %s", err)
+	}
+	if err := json.Unmarshal([]byte(received), &receivedFields); err != nil {
+		return fmt.Errorf("received genesis: %s", err)
+	}
+
+	expectedCanonical, _ := json.Marshal(expectedFields)
+	receivedCanonical, _ := json.Marshal(receivedFields)
+	if string(expectedCanonical) == string(receivedCanonical) {
+		return nil
+	}
+
+	keys := map[string]bool{}
+	for key := range expectedFields {
+		keys[key] = true
+	}
+	for key := range receivedFields {
+		keys[key] = true
+	}
+	var sorted []string
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		if !reflect.DeepEqual(expectedFields[key], receivedFields[key]) {
+			return fmt.Errorf("genesis field %q is %v, expected %v", key, receivedFields[key], expectedFields[key])
+		}
+	}
+	return fmt.Errorf("genesis differs from the expected %s", expectedCanonical)
+}
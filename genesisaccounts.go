@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/system"
+	"github.com/eoscanada/eos-go/token"
+)
+
+// GenesisAccount is a non-producer account (exchange, custodian,
+// foundation...) agreed upon in the launch data, and created at boot
+// by the `genesis.create_accounts` operation.
+type GenesisAccount struct {
+	AccountName eos.AccountName `json:"account_name"`
+
+	Authority struct {
+		Owner  eos.Authority `json:"owner"`
+		Active eos.Authority `json:"active"`
+	} `json:"authority"`
+
+	Balance eos.Asset `json:"balance"`
+
+	// CarveOutFrom is the Ethereum address of a snapshot row from
+	// which `balance` is deducted. When empty, `balance` is added on
+	// top of the snapshot totals.
+	CarveOutFrom string `json:"carve_out_from"`
+}
+
+func validateGenesisAccounts(launch *LaunchData) error {
+	seen := map[eos.AccountName]bool{}
+	for _, prod := range launch.Producers {
+		seen[prod.AccountName] = true
+	}
+
+	for _, acct := range launch.GenesisAccounts {
+		if seen[acct.AccountName] {
+			return fmt.Errorf("genesis account %q is duplicated, or conflicts with a producer", acct.AccountName)
+		}
+		seen[acct.AccountName] = true
+
+		if acct.Balance.Amount < 0 {
+			return fmt.Errorf("genesis account %q has a negative balance", acct.AccountName)
+		}
+	}
+
+	return nil
+}
+
+// reconcileGenesisAccounts deducts the carved out balances from the
+// snapshot rows they come from, so the total supply stays identical.
+func reconcileGenesisAccounts(launch *LaunchData, snapshot Snapshot) (out Snapshot, err error) {
+	out = append(out, snapshot...)
+
+	for _, acct := range launch.GenesisAccounts {
+		if acct.CarveOutFrom == "" {
+			continue
+		}
+
+		found := false
+		for idx := range out {
+			if out[idx].EthereumAddress != acct.CarveOutFrom {
+				continue
+			}
+
+			found = true
+			if out[idx].Balance.Amount < acct.Balance.Amount {
+				return nil, fmt.Errorf("genesis account %q carves out %s from %s, which only holds %s", acct.AccountName, acct.Balance, acct.CarveOutFrom, out[idx].Balance)
+			}
+			out[idx].Balance.Amount -= acct.Balance.Amount
+			break
+		}
+
+		if !found {
+			return nil, fmt.Errorf("genesis account %q carves out from %s, not found in snapshot", acct.AccountName, acct.CarveOutFrom)
+		}
+	}
+
+	return out, nil
+}
+
+//
+
+type OpCreateGenesisAccounts struct{}
+
+func (op *OpCreateGenesisAccounts) Actions(b *BIOS) (out []*eos.Action, err error) {
+	for _, acct := range b.LaunchData.GenesisAccounts {
+		newAccount := system.NewNewAccount(AN("eosio"), acct.AccountName, nil)
+		newAccount.Data = eos.NewActionData(system.NewAccount{
+			Creator: AN("eosio"),
+			Name:    acct.AccountName,
+			Owner:   acct.Authority.Owner,
+			Active:  acct.Authority.Active,
+		})
+
+		verbose.Printf("- Creating genesis account %q\n", acct.AccountName)
+		out = append(out, newAccount)
+
+		if acct.Balance.Amount != 0 {
+			memo := "Genesis account"
+			if acct.CarveOutFrom != "" {
+				memo = "Genesis account, carved out from " + acct.CarveOutFrom
+			}
+			out = append(out, token.NewTransfer(AN("eosio"), acct.AccountName, acct.Balance, b.memo(memo)))
+		}
+	}
+	return
+}
+
+// Validate checks each genesis account's authorities and balance
+// against the launch data.
+func (op *OpCreateGenesisAccounts) Validate(b *BIOS) error {
+	for _, genesisAcct := range b.LaunchData.GenesisAccounts {
+		acct, err := b.validationAPI().GetAccount(genesisAcct.AccountName)
+		if err != nil {
+			return fmt.Errorf("get account %s: %s", genesisAcct.AccountName, err)
+		}
+
+		if err := validateAuthority(acct, "owner", genesisAcct.Authority.Owner); err != nil {
+			return err
+		}
+		if err := validateAuthority(acct, "active", genesisAcct.Authority.Active); err != nil {
+			return err
+		}
+
+		balance, err := b.getTokenBalance(genesisAcct.AccountName)
+		if err != nil {
+			return fmt.Errorf("get balance of %s: %s", genesisAcct.AccountName, err)
+		}
+		if balance.Amount != genesisAcct.Balance.Amount {
+			return fmt.Errorf("%s holds %s, expected %s", genesisAcct.AccountName, balance, genesisAcct.Balance)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateGenesisAccountsValidate(t *testing.T) {
+	key, err := ecc.NewRandomPrivateKey()
+	require.NoError(t, err)
+	auth := eos.Authority{Threshold: 1, Keys: []eos.KeyWeight{{PublicKey: key.PublicKey(), Weight: 1}}}
+
+	onChainAuth := auth
+	onChainBalance := "10.0000 EOS"
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/chain/get_account":
+			json.NewEncoder(w).Encode(&eos.AccountResp{
+				AccountName: AN("exchange"),
+				Permissions: []eos.Permission{
+					{PermName: "owner", RequiredAuth: onChainAuth},
+					{PermName: "active", Parent: "owner", RequiredAuth: auth},
+				},
+			})
+		case "/v1/chain/get_table_rows":
+			w.Write([]byte(`{"rows": [{"balance": "` + onChainBalance + `"}], "more": false}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer node.Close()
+
+	nodeURL, err := url.Parse(node.URL)
+	require.NoError(t, err)
+	acct := &GenesisAccount{AccountName: AN("exchange"), Balance: eos.Asset{Amount: 100000, Symbol: eos.EOSSymbol}}
+	acct.Authority.Owner = auth
+	acct.Authority.Active = auth
+	b := &BIOS{
+		API:        eos.New(nodeURL, make([]byte, 32)),
+		LaunchData: &LaunchData{GenesisAccounts: []*GenesisAccount{acct}},
+	}
+
+	op := &OpCreateGenesisAccounts{}
+	assert.NoError(t, op.Validate(b))
+
+	onChainBalance = "9.0000 EOS"
+	assert.Error(t, op.Validate(b))
+
+	onChainBalance = "10.0000 EOS"
+	onChainAuth = eos.Authority{Threshold: 1, Accounts: []eos.PermissionLevelWeight{{Permission: eos.PermissionLevel{Actor: AN("eosio"), Permission: PN("active")}, Weight: 1}}}
+	assert.Error(t, op.Validate(b))
+}
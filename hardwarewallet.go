@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/karalabe/hid"
+)
+
+// The producer's own actions (`regproducer`, and later
+// `claimrewards`) can be signed by a Ledger device running the EOS
+// app, so the organization's account keys never leave the hardware.
+// Everything else eos-bios signs (the ephemeral `eosio` key, ...)
+// stays in the regular key bag.
+
+const (
+	ledgerVendorID = 0x2c97
+
+	ledgerCLA          = 0xd4
+	ledgerInsGetPubKey = 0x02
+	ledgerInsSign      = 0x04
+
+	ledgerDefaultPath = "44'/194'/0'/0/0"
+)
+
+type ledgerSigner struct {
+	device    *hid.Device
+	path      []uint32
+	publicKey ecc.PublicKey
+
+	lock sync.Mutex
+}
+
+func newLedgerSigner(derivationPath string) (*ledgerSigner, error) {
+	if derivationPath == "" {
+		derivationPath = ledgerDefaultPath
+	}
+	path, err := parseDerivationPath(derivationPath)
+	if err != nil {
+		return nil, fmt.Errorf("derivation path %q: %s", derivationPath, err)
+	}
+
+	if !hid.Supported() {
+		return nil, fmt.Errorf("USB HID is not supported on this platform")
+	}
+
+	var device *hid.Device
+	for _, devInfo := range hid.Enumerate(ledgerVendorID, 0) {
+		// The EOS app talks on the first interface, or usage page 0xffa0 on macOS.
+		if devInfo.Interface != 0 && devInfo.UsagePage != 0xffa0 {
+			continue
+		}
+		device, err = devInfo.Open()
+		if err != nil {
+			return nil, fmt.Errorf("opening Ledger device: %s", err)
+		}
+		break
+	}
+	if device == nil {
+		return nil, fmt.Errorf("no Ledger device found, is it plugged in and unlocked?")
+	}
+
+	s := &ledgerSigner{device: device, path: path}
+
+	s.publicKey, err = s.getPublicKey()
+	if err != nil {
+		device.Close()
+		return nil, fmt.Errorf("is the EOS app open? %s", err)
+	}
+
+	return s, nil
+}
+
+func (s *ledgerSigner) AvailableKeys() (out []ecc.PublicKey, err error) {
+	return []ecc.PublicKey{s.publicKey}, nil
+}
+
+func (s *ledgerSigner) ImportPrivateKey(wifPrivKey string) error {
+	return fmt.Errorf("the Ledger signer doesn't accept private keys, they stay on the device")
+}
+
+func (s *ledgerSigner) Sign(tx *eos.SignedTransaction, chainID []byte, requiredKeys ...ecc.PublicKey) (*eos.SignedTransaction, error) {
+	found := false
+	for _, key := range requiredKeys {
+		if key.String() == s.publicKey.String() {
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("the Ledger key %s is not among the required keys", s.publicKey)
+	}
+
+	payload, err := ledgerSerializeTransaction(tx, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("serializing for the Ledger: %s", err)
+	}
+
+	milestone.Printf("Review and approve the transaction on your Ledger device (%d action(s))\n", len(tx.Actions))
+
+	data := append(ledgerPathBytes(s.path), payload...)
+	var reply []byte
+	for i := 0; len(data) > 0; i++ {
+		chunk := data
+		if len(chunk) > 150 {
+			chunk = chunk[:150]
+		}
+		data = data[len(chunk):]
+
+		p1 := byte(0x80)
+		if i == 0 {
+			p1 = 0x00
+		}
+		reply, err = s.exchange(ledgerInsSign, p1, 0x00, chunk)
+		if err != nil {
+			return nil, fmt.Errorf("signing on Ledger: %s", err)
+		}
+	}
+
+	if len(reply) != 65 {
+		return nil, fmt.Errorf("signing on Ledger: unexpected signature length %d", len(reply))
+	}
+
+	tx.Signatures = append(tx.Signatures, ecc.Signature(reply))
+	return tx, nil
+}
+
+func (s *ledgerSigner) getPublicKey() (ecc.PublicKey, error) {
+	reply, err := s.exchange(ledgerInsGetPubKey, 0x00, 0x00, ledgerPathBytes(s.path))
+	if err != nil {
+		return nil, err
+	}
+
+	// reply: [pubkey len][uncompressed pubkey][address len][address]
+	if len(reply) < 1 || len(reply) < 1+int(reply[0])+1 {
+		return nil, fmt.Errorf("malformed public key reply")
+	}
+	rest := reply[1+int(reply[0]):]
+	if len(rest) < 1+int(rest[0]) {
+		return nil, fmt.Errorf("malformed public key reply")
+	}
+
+	return ecc.NewPublicKey(string(rest[1 : 1+int(rest[0])]))
+}
+
+// exchange sends an APDU over the Ledger HID framing, and returns the
+// reply stripped of its status word.
+func (s *ledgerSigner) exchange(ins, p1, p2 byte, data []byte) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	apdu := append([]byte{ledgerCLA, ins, p1, p2, byte(len(data))}, data...)
+	apdu = append([]byte{byte(len(apdu) >> 8), byte(len(apdu))}, apdu...)
+
+	header := []byte{0x01, 0x01, 0x05, 0x00, 0x00}
+	for seq := 0; len(apdu) > 0; seq++ {
+		header[3], header[4] = byte(seq>>8), byte(seq)
+
+		packet := make([]byte, 64)
+		copy(packet, header)
+		n := copy(packet[len(header):], apdu)
+		apdu = apdu[n:]
+
+		// A leading report ID of zero is expected by the HID layer.
+		if _, err := s.device.Write(append([]byte{0x00}, packet...)); err != nil {
+			return nil, err
+		}
+	}
+
+	var reply []byte
+	var expected int
+	packet := make([]byte, 64)
+	for seq := 0; ; seq++ {
+		if _, err := s.device.Read(packet); err != nil {
+			return nil, err
+		}
+		if packet[0] != 0x01 || packet[1] != 0x01 || packet[2] != 0x05 {
+			return nil, fmt.Errorf("invalid reply header %x", packet[:3])
+		}
+		if int(packet[3])<<8|int(packet[4]) != seq {
+			return nil, fmt.Errorf("out-of-order reply packet")
+		}
+
+		payload := packet[5:]
+		if seq == 0 {
+			expected = int(payload[0])<<8 | int(payload[1])
+			payload = payload[2:]
+		}
+		reply = append(reply, payload...)
+		if len(reply) >= expected {
+			reply = reply[:expected]
+			break
+		}
+	}
+
+	if len(reply) < 2 {
+		return nil, fmt.Errorf("reply too short")
+	}
+	status := reply[len(reply)-2:]
+	if status[0] != 0x90 || status[1] != 0x00 {
+		if status[0] == 0x69 && status[1] == 0x85 {
+			return nil, fmt.Errorf("rejected on the device")
+		}
+		return nil, fmt.Errorf("device returned status %x", status)
+	}
+
+	return reply[:len(reply)-2], nil
+}
+
+// ledgerSerializeTransaction encodes the transaction as the
+// tag-length-value fields the EOS app parses and displays before
+// signing.
+func ledgerSerializeTransaction(tx *eos.SignedTransaction, chainID []byte) ([]byte, error) {
+	if len(tx.ContextFreeActions) != 0 {
+		return nil, fmt.Errorf("context free actions are not supported by the Ledger")
+	}
+
+	buf := &bytes.Buffer{}
+	field := func(value []byte) {
+		buf.WriteByte(0x04)
+		buf.Write(ledgerLength(len(value)))
+		buf.Write(value)
+	}
+	name := func(in string) error {
+		val, err := eos.StringToName(in)
+		if err != nil {
+			return fmt.Errorf("name %q: %s", in, err)
+		}
+		out := make([]byte, 8)
+		binary.LittleEndian.PutUint64(out, val)
+		field(out)
+		return nil
+	}
+
+	field(chainID)
+
+	u32 := make([]byte, 4)
+	binary.LittleEndian.PutUint32(u32, uint32(tx.Expiration.Unix()))
+	field(u32)
+	u16 := make([]byte, 2)
+	binary.LittleEndian.PutUint16(u16, tx.RefBlockNum)
+	field(u16)
+	u32 = make([]byte, 4)
+	binary.LittleEndian.PutUint32(u32, tx.RefBlockPrefix)
+	field(u32)
+	field(varuint32(uint32(tx.MaxNetUsageWords)))
+	field([]byte{tx.MaxCPUUsageMS})
+	field(varuint32(uint32(tx.DelaySec)))
+
+	field(varuint32(0)) // context free actions
+	field(varuint32(uint32(len(tx.Actions))))
+	for _, act := range tx.Actions {
+		if err := name(string(act.Account)); err != nil {
+			return nil, err
+		}
+		if err := name(string(act.Name)); err != nil {
+			return nil, err
+		}
+		field(varuint32(uint32(len(act.Authorization))))
+		for _, auth := range act.Authorization {
+			if err := name(string(auth.Actor)); err != nil {
+				return nil, err
+			}
+			if err := name(string(auth.Permission)); err != nil {
+				return nil, err
+			}
+		}
+
+		data := []byte(act.HexData)
+		if len(data) == 0 && act.Data != nil {
+			var err error
+			data, err = eos.MarshalBinary(act.Data)
+			if err != nil {
+				return nil, fmt.Errorf("action %s::%s: %s", act.Account, act.Name, err)
+			}
+		}
+		field(varuint32(uint32(len(data))))
+		field(data)
+	}
+
+	field(varuint32(uint32(len(tx.Extensions))))
+	field(make([]byte, 32)) // context free data digest, none here
+
+	return buf.Bytes(), nil
+}
+
+// parseDerivationPath parses BIP32 paths like "44'/194'/0'/0/0".
+func parseDerivationPath(path string) ([]uint32, error) {
+	var out []uint32
+	for _, el := range strings.Split(strings.TrimPrefix(path, "m/"), "/") {
+		hardened := strings.HasSuffix(el, "'")
+		val, err := strconv.ParseUint(strings.TrimSuffix(el, "'"), 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid element %q", el)
+		}
+		if hardened {
+			val |= 0x80000000
+		}
+		out = append(out, uint32(val))
+	}
+	return out, nil
+}
+
+func ledgerPathBytes(path []uint32) []byte {
+	out := []byte{byte(len(path))}
+	for _, el := range path {
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, el)
+		out = append(out, b...)
+	}
+	return out
+}
+
+func ledgerLength(n int) []byte {
+	switch {
+	case n < 0x80:
+		return []byte{byte(n)}
+	case n <= 0xff:
+		return []byte{0x81, byte(n)}
+	default:
+		return []byte{0x82, byte(n >> 8), byte(n)}
+	}
+}
+
+func varuint32(v uint32) []byte {
+	var out []byte
+	for {
+		b := byte(v & 0x7f)
+		v >>= 7
+		if v != 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if v == 0 {
+			return out
+		}
+	}
+}
+
+// producerAPI returns the API to sign the producer's own actions
+// with: the Ledger when configured, our regular key bag otherwise.
+func (b *BIOS) producerAPI() (*eos.API, error) {
+	if !b.Config.Producer.Ledger.Enabled {
+		return b.API, nil
+	}
+	if b.hardwareAPI != nil {
+		return b.hardwareAPI, nil
+	}
+
+	signer, err := newLedgerSigner(b.Config.Producer.Ledger.DerivationPath)
+	if err != nil {
+		return nil, fmt.Errorf("ledger: %s", err)
+	}
+	info.Printf("Signing producer actions with the Ledger key %s\n", signer.publicKey)
+
+	api := eos.New(b.Config.Producer.apiAddressURL, b.API.ChainID)
+	api.HttpClient = b.API.HttpClient
+	api.SetSigner(signer)
+	b.hardwareAPI = api
+
+	return api, nil
+}
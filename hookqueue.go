@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HookQueue persists webhook deliveries in the run directory, one file
+// per delivery, and delivers them in order, per hook, until they
+// succeed, in batches of up to `batchSize` deliveries. Anything left
+// over when the program exits is replayed on the next start.
+type HookQueue struct {
+	dir       string
+	deliver   func(hookName string, batch [][]string) error
+	batchSize func(hookName string) int
+
+	lock    sync.Mutex
+	nextSeq map[string]int
+	wakeups map[string]chan struct{}
+}
+
+type queuedHook struct {
+	Hook     string    `json:"hook"`
+	Data     []string  `json:"data"`
+	QueuedAt time.Time `json:"queued_at"`
+}
+
+func OpenHookQueue(runDir string, deliver func(hookName string, batch [][]string) error, batchSize func(hookName string) int) (*HookQueue, error) {
+	q := &HookQueue{
+		dir:       filepath.Join(runDir, "hooks-queue"),
+		deliver:   deliver,
+		batchSize: batchSize,
+		nextSeq:   map[string]int{},
+		wakeups:   map[string]chan struct{}{},
+	}
+
+	if err := os.MkdirAll(q.dir, 0700); err != nil {
+		return nil, err
+	}
+
+	hookDirs, err := ioutil.ReadDir(q.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hookDir := range hookDirs {
+		if !hookDir.IsDir() {
+			continue
+		}
+		files, err := q.queuedFiles(hookDir.Name())
+		if err != nil {
+			return nil, err
+		}
+		if len(files) != 0 {
+			info.Printf("Replaying %d queued deliveries for hook %q\n", len(files), hookDir.Name())
+			q.startWorker(hookDir.Name())
+		}
+	}
+
+	return q, nil
+}
+
+// Enqueue journals the delivery to disk before returning, which gives
+// us at-least-once delivery.
+func (q *HookQueue) Enqueue(hookName string, data []string) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	hookDir := filepath.Join(q.dir, hookName)
+	if err := os.MkdirAll(hookDir, 0700); err != nil {
+		return err
+	}
+
+	if _, found := q.nextSeq[hookName]; !found {
+		files, err := q.queuedFiles(hookName)
+		if err != nil {
+			return err
+		}
+		if len(files) != 0 {
+			last, _ := strconv.Atoi(strings.TrimSuffix(files[len(files)-1], ".json"))
+			q.nextSeq[hookName] = last + 1
+		}
+	}
+
+	seq := q.nextSeq[hookName]
+	q.nextSeq[hookName] = seq + 1
+
+	cnt, err := json.Marshal(&queuedHook{Hook: hookName, Data: data, QueuedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Join(hookDir, fmt.Sprintf("%020d.json", seq))
+	if err := ioutil.WriteFile(filename+".tmp", cnt, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(filename+".tmp", filename); err != nil {
+		return err
+	}
+
+	q.startWorkerLocked(hookName)
+	select {
+	case q.wakeups[hookName] <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Flush waits for all queues to drain, up to `timeout`. Deliveries
+// still pending afterwards stay on disk.
+func (q *HookQueue) Flush(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		pending := 0
+		q.lock.Lock()
+		for hookName := range q.wakeups {
+			files, _ := q.queuedFiles(hookName)
+			pending += len(files)
+		}
+		q.lock.Unlock()
+
+		if pending == 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			info.Printf("Hook queue: %d deliveries still pending, they will be replayed on next start\n", pending)
+			return false
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// Pending is the number of deliveries of `hookName` still on disk.
+func (q *HookQueue) Pending(hookName string) int {
+	files, _ := q.queuedFiles(hookName)
+	return len(files)
+}
+
+func (q *HookQueue) startWorker(hookName string) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.startWorkerLocked(hookName)
+}
+
+func (q *HookQueue) startWorkerLocked(hookName string) {
+	if _, found := q.wakeups[hookName]; found {
+		return
+	}
+
+	wakeup := make(chan struct{}, 1)
+	q.wakeups[hookName] = wakeup
+
+	go q.work(hookName, wakeup)
+}
+
+func (q *HookQueue) work(hookName string, wakeup chan struct{}) {
+	backoff := time.Second
+
+	for {
+		files, err := q.queuedFiles(hookName)
+		if err != nil {
+			info.Printf("Hook queue %q: listing: %s\n", hookName, err)
+		}
+
+		if len(files) == 0 {
+			<-wakeup
+			continue
+		}
+
+		if size := q.batchSize(hookName); len(files) > size {
+			files = files[:size]
+		}
+		delivered, err := q.deliverFiles(hookName, files)
+		if err != nil {
+			info.Printf("Hook queue %q: delivery of %s failed, retrying in %s: %s\n", hookName, strings.Join(files, ", "), backoff, err)
+			time.Sleep(backoff)
+			if backoff < 30*time.Second {
+				backoff *= 2
+			}
+			continue
+		}
+
+		backoff = time.Second
+		for _, filename := range delivered {
+			if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+				info.Printf("Hook queue %q: removing delivered %s: %s\n", hookName, filename, err)
+			}
+		}
+	}
+}
+
+// deliverFiles delivers the queued `files` as one batch, and returns
+// the paths of those delivered.
+func (q *HookQueue) deliverFiles(hookName string, files []string) (delivered []string, err error) {
+	var batch [][]string
+	for _, file := range files {
+		filename := filepath.Join(q.dir, hookName, file)
+		cnt, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		var queued queuedHook
+		if err := json.Unmarshal(cnt, &queued); err != nil {
+			// Set it aside, it would otherwise block the queue forever.
+			info.Printf("Hook queue %q: corrupted entry %s moved aside: %s\n", hookName, filename, err)
+			if err := os.Rename(filename, filename+".corrupt"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		batch = append(batch, queued.Data)
+		delivered = append(delivered, filename)
+	}
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	return delivered, q.deliver(hookName, batch)
+}
+
+func (q *HookQueue) queuedFiles(hookName string) (out []string, err error) {
+	entries, err := ioutil.ReadDir(filepath.Join(q.dir, hookName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".json") {
+			out = append(out, entry.Name())
+		}
+	}
+	sort.Strings(out)
+	return
+}
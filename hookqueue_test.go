@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHookQueueOrderedRedelivery(t *testing.T) {
+	runDir, err := ioutil.TempDir("", "eos-bios-hookqueue")
+	require.NoError(t, err)
+	defer os.RemoveAll(runDir)
+
+	var lock sync.Mutex
+	var delivered []string
+	failures := 1
+	deliver := func(hookName string, batch [][]string) error {
+		lock.Lock()
+		defer lock.Unlock()
+		if failures > 0 {
+			failures--
+			return fmt.Errorf("receiver down")
+		}
+		for _, data := range batch {
+			delivered = append(delivered, data[1])
+		}
+		return nil
+	}
+
+	q, err := OpenHookQueue(runDir, deliver, func(string) int { return 1 })
+	require.NoError(t, err)
+
+	require.NoError(t, q.Enqueue("done", []string{"n", "1"}))
+	require.NoError(t, q.Enqueue("done", []string{"n", "2"}))
+
+	assert.True(t, q.Flush(5*time.Second))
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{"1", "2"}, delivered)
+}
+
+func TestHookQueueBatches(t *testing.T) {
+	runDir, err := ioutil.TempDir("", "eos-bios-hookqueue")
+	require.NoError(t, err)
+	defer os.RemoveAll(runDir)
+
+	for seq := 1; seq <= 5; seq++ {
+		dir := filepath.Join(runDir, "hooks-queue", "ledger_entry")
+		require.NoError(t, os.MkdirAll(dir, 0700))
+		cnt := fmt.Sprintf(`{"hook": "ledger_entry", "data": ["n", "%d"]}`, seq)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%020d.json", seq)), []byte(cnt), 0600))
+	}
+
+	var lock sync.Mutex
+	var batches [][]string
+	deliver := func(hookName string, batch [][]string) error {
+		lock.Lock()
+		defer lock.Unlock()
+		var values []string
+		for _, data := range batch {
+			values = append(values, data[1])
+		}
+		batches = append(batches, values)
+		return nil
+	}
+
+	// Replayed on open.
+	q, err := OpenHookQueue(runDir, deliver, func(string) int { return 2 })
+	require.NoError(t, err)
+	assert.True(t, q.Flush(5*time.Second))
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, [][]string{{"1", "2"}, {"3", "4"}, {"5"}}, batches)
+}
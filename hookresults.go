@@ -0,0 +1,67 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxHookReplySize caps the reply recorded in the run report.
+const maxHookReplySize = 4096
+
+// HookResult is what the receiver of a `wait: true` webhook replied,
+// kept in the run report so the audit trail shows what the
+// operator-side automation reported back, not only that the hook
+// fired. Deliveries in the background (see `hookrunner.go`) are
+// recorded as they complete.
+type HookResult struct {
+	Hook   string    `json:"hook"`
+	Step   int       `json:"step"`
+	At     time.Time `json:"at"`
+	Status int       `json:"status,omitempty"`
+	// Reply is the receiver's body, with key material redacted.
+	Reply string `json:"reply,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+var wifKeyRegexp = regexp.MustCompile(`\b5[HJK][1-9A-HJ-NP-Za-km-z]{49}\b`)
+
+func (b *BIOS) recordHookResult(hookName string, conf *HookConfig, data []string, status int, reply string, err error) {
+	result := &HookResult{
+		Hook:   hookName,
+		Step:   b.currentStep,
+		At:     time.Now().UTC(),
+		Status: status,
+		Reply:  redactHookReply(reply, conf, data),
+	}
+	if err != nil {
+		result.Error = redactHookReply(err.Error(), conf, data)
+	}
+
+	b.hookResultsLock.Lock()
+	defer b.hookResultsLock.Unlock()
+	b.Report.HookResults = append(b.Report.HookResults, result)
+}
+
+// redactHookReply removes from `reply` the private keys sent with the
+// hook, its bearer token, and anything shaped like a WIF private key,
+// in case the receiver echoes them back.
+func redactHookReply(reply string, conf *HookConfig, data []string) string {
+	secrets := []string{conf.token}
+	for i := 0; i < len(data); i += 2 {
+		if strings.Contains(data[i], "private_key") {
+			secrets = append(secrets, data[i+1])
+		}
+	}
+	for _, secret := range secrets {
+		if secret != "" {
+			reply = strings.Replace(reply, secret, "(redacted)", -1)
+		}
+	}
+	reply = wifKeyRegexp.ReplaceAllString(reply, "(redacted)")
+
+	if len(reply) > maxHookReplySize {
+		reply = reply[:maxHookReplySize] + "... (truncated)"
+	}
+	return reply
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// hookRunner delivers a hook's calls in the background, with at most
+// `max_concurrency` in flight (a single one, in order, when
+// `serialize` is set), so a slow receiver doesn't stall the boot.
+type hookRunner struct {
+	name   string
+	calls  chan func() error
+	drop   bool
+	wg     sync.WaitGroup
+	closed bool
+}
+
+func newHookRunner(name string, conf *HookConfig) *hookRunner {
+	workers := conf.MaxConcurrency
+	if conf.Serialize || workers < 1 {
+		workers = 1
+	}
+	buffer := conf.BufferSize
+	if buffer == 0 {
+		buffer = 64
+	}
+
+	r := &hookRunner{
+		name:  name,
+		calls: make(chan func() error, buffer),
+		drop:  conf.OnFull == "drop",
+	}
+
+	for i := 0; i < workers; i++ {
+		r.wg.Add(1)
+		go r.work()
+	}
+
+	return r
+}
+
+func (r *hookRunner) work() {
+	defer r.wg.Done()
+	for call := range r.calls {
+		if err := call(); err != nil {
+			milestone.Printf("WARNING: background delivery of hook %q failed: %s\n", r.name, err)
+		}
+	}
+}
+
+// Submit queues `call`, blocking when the buffer is full unless
+// configured with `on_full: drop`.
+func (r *hookRunner) Submit(call func() error) error {
+	if !r.drop {
+		r.calls <- call
+		return nil
+	}
+
+	select {
+	case r.calls <- call:
+		return nil
+	default:
+		return fmt.Errorf("buffer full, dropping delivery")
+	}
+}
+
+// asyncHook tells whether a hook's calls are delivered by a hookRunner.
+func asyncHook(conf *HookConfig) bool {
+	return conf.Serialize || conf.MaxConcurrency > 0
+}
+
+func (b *BIOS) hookRunner(hookName string, conf *HookConfig) *hookRunner {
+	b.hookRunnersLock.Lock()
+	defer b.hookRunnersLock.Unlock()
+
+	if b.hookRunners == nil {
+		b.hookRunners = map[string]*hookRunner{}
+	}
+	if b.hookRunners[hookName] == nil {
+		b.hookRunners[hookName] = newHookRunner(hookName, conf)
+	}
+	return b.hookRunners[hookName]
+}
+
+// drainHookRunners waits for all background deliveries to complete.
+func (b *BIOS) drainHookRunners() {
+	b.hookRunnersLock.Lock()
+	defer b.hookRunnersLock.Unlock()
+
+	for _, r := range b.hookRunners {
+		if !r.closed {
+			close(r.calls)
+			r.closed = true
+		}
+		r.wg.Wait()
+	}
+}
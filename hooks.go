@@ -1,16 +1,21 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/eoscanada/eos-go"
 	shellwords "github.com/mattn/go-shellwords"
 )
 
@@ -18,8 +23,18 @@ var configuredHooks = []HookDef{
 	HookDef{"init", "Dispatch when we start the program."},
 	HookDef{"start_bios_boot", "Dispatched when we are BIOS Node, and our keys and node config is ready. Should trigger a config update and a restart."},
 	HookDef{"publish_kickstart_data", "Dispatched with the contents of the (usually encrypted) Kickstart data, to be published to your social / web properties."},
-	HookDef{"connect_as_abp", "Dispatched by ABPs with the decrypted contents of the Kickstart data.  Use this to initiate a connect from your BP node to the BIOS Node's p2p address."},
-	HookDef{"connect_as_participant", "Dispatched by all remaining participants (not BIOS Boot nor ABP) with the decrypted contents of the Kickstart data.  Use this to initiate a connect from your BP node to any of the Appointed Block Producers once they validated everything."},
+	HookDef{"connect_as_abp", "Dispatched by ABPs with the decrypted contents of the Kickstart data.  Use this to initiate a connect from your BP node to the BIOS Node's p2p address, and to the `preferred_peers` (the nearest other ABPs, by region and latency group)."},
+	HookDef{"connect_as_participant", "Dispatched by all remaining participants (not BIOS Boot nor ABP) with the decrypted contents of the Kickstart data.  Use this to initiate a connect from your BP node to any of the Appointed Block Producers once they validated everything, preferably the `preferred_peers`."},
+	HookDef{"publish_boot_manifest", "Dispatched by the boot node at the end of the boot sequence with the PGP-signed boot manifest (steps, transaction IDs, contract hashes, snapshot hash, genesis and shuffle seed), to be published as the record of the launch."},
+	HookDef{"publish_state_manifest", "Dispatched by the boot node before the boot sequence with the root of the state manifest, the chain state the boot must leave, for other participants to compare with the one they compute with `eos-bios state-manifest`."},
+	HookDef{"validation_failed", "Dispatched by ABPs whose validations found the chain deviates from the launch data, with the signed failure report, to be published to the other participants. The ABP refuses to register, and disables its producer accounts with `on_validation_failure: sabotage`."},
+	HookDef{"publish_readiness", "Dispatched by `eos-bios attest-ready` with the signed readiness attestation, to be published to the other participants."},
+	HookDef{"key_revoked", "Dispatched by `eos-bios revoke` once the response to a key compromise was pushed, to notify the other participants."},
+	HookDef{"stalled", "Dispatched by the watchdog when the boot makes no progress, or the head block is stuck, for `watchdog.stall_after`."},
+	HookDef{"unexpected_schedule_change", "Dispatched by the monitor when the producer schedule changes before voting activation, a possible attack or misconfiguration."},
+	HookDef{"voting_activated", "Dispatched by the monitor when 15% of the supply voted, activating the chain. Time to celebrate!"},
+	HookDef{"ledger_entry", "Dispatched by the boot node for each boot transaction accepted by the chain, with its decoded actions, to stream the ledger to an external auditor. `url` deliveries are always queued, so auditor downtime doesn't block the boot."},
+	HookDef{"cleanup", "Dispatched after a failed run with `cleanup.execute`, or by `eos-bios cleanup --execute`, with the cleanup plan (see `cleanup.go`): wipe nodeos' `data_dir`, remove the `public_keys` from the keosd `wallet`, and retract the published kickstart data (`kickstart_references`). eos-bios archives the run directory once it returns."},
 	HookDef{"done", "When your process it done"},
 }
 
@@ -53,9 +68,38 @@ func (b *BIOS) DispatchConnectAsABP(kickstart KickstartData, producerDefs []*Pro
 		"genesis_json", kickstart.GenesisJSON,
 		"producer_name_statements", "producer-name = " + strings.Join(names, "\nproducer-name = "),
 		"producer_names", strings.Join(names, ","),
+		"signature_provider_statements", b.signatureProviderStatements(producerDefs),
+		"preferred_peers", producerNames(b.appointedPeers(producerDefs[0])),
 	}, nil)
 }
 
+// signatureProviderStatements returns the `signature-provider` lines
+// for `config.ini`, one for each distinct signing key of
+// `producerDefs`. Clones share the key of the producer they were
+// cloned from, which must be our own block signing key.
+func (b *BIOS) signatureProviderStatements(producerDefs []*ProducerDef) string {
+	myPubKey := b.Config.Producer.BlockSigningPublicKey.String()
+	myPrivKey := b.Config.Producer.blockSigningPrivateKey.String()
+
+	seen := map[string]bool{}
+	var statements []string
+	for _, prod := range producerDefs {
+		pubKey := prod.InitialBlockSigningPublicKey.String()
+		if seen[pubKey] {
+			continue
+		}
+		seen[pubKey] = true
+
+		if pubKey != myPubKey {
+			milestone.Printf("WARNING: %s signs with %s, which isn't our block_signing_public_key, add its signature-provider by hand\n", prod.AccountName, pubKey)
+			continue
+		}
+		statements = append(statements, fmt.Sprintf("signature-provider = %s=KEY:%s", myPubKey, myPrivKey))
+	}
+
+	return strings.Join(statements, "\n")
+}
+
 func (b *BIOS) DispatchConnectAsParticipant(kickstart KickstartData, myProducer *ProducerDef) error {
 	return b.dispatch("connect_as_participant", []string{
 		"p2p_address", kickstart.BIOSP2PAddress,
@@ -63,6 +107,7 @@ func (b *BIOS) DispatchConnectAsParticipant(kickstart KickstartData, myProducer
 		"private_key_used", b.Config.Producer.blockSigningPrivateKey.String(),
 		"genesis_json", kickstart.GenesisJSON,
 		"producer_name", string(myProducer.AccountName),
+		"preferred_peers", producerNames(b.appointedPeers(myProducer)),
 	}, nil)
 }
 
@@ -72,8 +117,121 @@ func (b *BIOS) DispatchPublishKickstartData(kickstartData string) error {
 	}, nil)
 }
 
+func (b *BIOS) DispatchPublishReadiness(attestation string) error {
+	return b.dispatch("publish_readiness", []string{
+		"attestation", attestation,
+	}, nil)
+}
+
+func (b *BIOS) DispatchPublishBootManifest(manifest string) error {
+	return b.dispatch("publish_boot_manifest", []string{
+		"manifest", manifest,
+	}, nil)
+}
+
+func (b *BIOS) DispatchPublishStateManifest(root string) error {
+	return b.dispatch("publish_state_manifest", []string{
+		"root", root,
+	}, nil)
+}
+
+func (b *BIOS) DispatchCleanup(plan *CleanupPlan) error {
+	cnt, err := json.Marshal(plan)
+	if err != nil {
+		return err
+	}
+
+	var dataDir, wallet string
+	var publicKeys, kickstartReferences []string
+	for _, step := range plan.Steps {
+		switch step.Kind {
+		case "wipe_data_dir":
+			dataDir = step.Target
+		case "remove_keys":
+			wallet = step.Target
+			publicKeys = append(publicKeys, step.PublicKeys...)
+		case "invalidate_kickstart":
+			kickstartReferences = append(kickstartReferences, step.Target)
+		}
+	}
+
+	return b.dispatch("cleanup", []string{
+		"plan", string(cnt),
+		"data_dir", dataDir,
+		"wallet", wallet,
+		"public_keys", strings.Join(publicKeys, ","),
+		"kickstart_references", strings.Join(kickstartReferences, ","),
+	}, nil)
+}
+
+func (b *BIOS) DispatchValidationFailed(report string) error {
+	return b.dispatch("validation_failed", []string{
+		"report", report,
+	}, nil)
+}
+
+func (b *BIOS) DispatchKeyRevoked(scenario string, account eos.AccountName, newKey, transactionID string) error {
+	return b.dispatch("key_revoked", []string{
+		"scenario", scenario,
+		"account", string(account),
+		"new_key", newKey,
+		"transaction_id", transactionID,
+	}, nil)
+}
+
+func (b *BIOS) DispatchStalled(kind, message string) error {
+	return b.dispatch("stalled", []string{
+		"kind", kind,
+		"message", message,
+	}, nil)
+}
+
+func (b *BIOS) DispatchUnexpectedScheduleChange(change string) error {
+	return b.dispatch("unexpected_schedule_change", []string{
+		"change", change,
+	}, nil)
+}
+
+func (b *BIOS) DispatchVotingActivated(percent float64) error {
+	return b.dispatch("voting_activated", []string{
+		"percent", fmt.Sprintf("%.2f", percent),
+	}, nil)
+}
+
+func (b *BIOS) DispatchLedgerEntry(entry *LedgerEntry, actions []*ReportAction) error {
+	decoded, err := json.Marshal(actions)
+	if err != nil {
+		return err
+	}
+
+	err = b.dispatch("ledger_entry", []string{
+		"step", strconv.Itoa(entry.Step),
+		"op", entry.Op,
+		"chunk", strconv.Itoa(entry.Chunk),
+		"transaction_id", entry.TransactionID,
+		"block_num", strconv.FormatUint(uint64(entry.BlockNum), 10),
+		"actions", string(decoded),
+	}, nil)
+
+	if b.HookQueue != nil {
+		if pending := b.HookQueue.Pending("ledger_entry"); pending != 0 && pending%100 == 0 {
+			info.Printf("WARNING: the auditor is falling behind, %d ledger entries queued\n", pending)
+		}
+	}
+
+	return err
+}
+
 func (b *BIOS) DispatchDone() error {
-	return b.dispatch("done", []string{}, nil)
+	err := b.dispatch("done", []string{}, nil)
+
+	b.drainHookRunners()
+
+	if b.HookQueue != nil {
+		b.HookQueue.Flush(30 * time.Second)
+	}
+
+	return err
 }
 
 // dispatch to both exec calls, and remote web hooks.
@@ -83,30 +241,87 @@ func (b *BIOS) dispatch(hookName string, data []string, f func() error) error {
 		return nil
 	}
 
-	fmt.Printf("Dispatching hook %q\n", hookName)
+	info.Printf("Dispatching hook %q\n", hookName)
 
 	if len(data)%2 != 0 {
 		return fmt.Errorf("data should be pairs of key and values, cannot have %d elements", len(data))
 	}
 
-	if conf.Exec != "" {
-		if err := b.execCall(conf, data); err != nil {
-			return err
+	call := func() error {
+		if conf.Exec != "" {
+			if err := b.execCall(conf, data); err != nil {
+				return err
+			}
+		}
+		if conf.URL != "" {
+			if conf.Queue && b.HookQueue != nil {
+				if err := b.HookQueue.Enqueue(hookName, data); err != nil {
+					return fmt.Errorf("queueing hook: %s", err)
+				}
+			} else {
+				status, reply, err := b.webhookCall(conf, data)
+				if conf.Wait {
+					b.recordHookResult(hookName, conf, data, status, reply, err)
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	if conf.Optional {
+		mandatory := call
+		call = func() error {
+			if err := b.breaker("hook " + hookName).Call(mandatory); err != nil {
+				milestone.Printf("WARNING: optional hook %q failed, continuing: %s\n", hookName, err)
+			}
+			return nil
 		}
 	}
-	if conf.URL != "" {
-		if err := b.webhookCall(conf, data); err != nil {
-			return err
+
+	if asyncHook(conf) {
+		if err := b.hookRunner(hookName, conf).Submit(call); err != nil {
+			milestone.Printf("WARNING: hook %q: %s\n", hookName, err)
 		}
+	} else if err := call(); err != nil {
+		return err
 	}
 	if conf.Wait {
-		fmt.Printf("Press ENTER to continue... ")
-		_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+		b.operatorGate("hook " + hookName)
 	}
 
 	return nil
 }
 
+// deliverQueuedHook is called by the HookQueue, possibly for hooks
+// queued by a previous run. With a `batch_size`, the calls are sent
+// together, as a JSON array of calls.
+func (b *BIOS) deliverQueuedHook(hookName string, batch [][]string) error {
+	conf := b.Config.Hooks[hookName]
+	if conf == nil || conf.URL == "" {
+		return fmt.Errorf("hook %q not configured with a url anymore", hookName)
+	}
+
+	var err error
+	if conf.BatchSize > 1 {
+		_, _, err = b.webhookCall(conf, batch)
+	} else {
+		_, _, err = b.webhookCall(conf, batch[0])
+	}
+	return err
+}
+
+// queuedHookBatchSize is the number of queued calls of `hookName`
+// delivered together.
+func (b *BIOS) queuedHookBatchSize(hookName string) int {
+	if conf := b.Config.Hooks[hookName]; conf != nil && conf.BatchSize > 1 {
+		return conf.BatchSize
+	}
+	return 1
+}
+
 func (b *BIOS) execCall(conf *HookConfig, data []string) error {
 	p := shellwords.NewParser()
 	p.ParseEnv = true
@@ -131,20 +346,79 @@ func (b *BIOS) execCall(conf *HookConfig, data []string) error {
 	cmd.Stdin = os.Stdin
 	cmd.Env = os.Environ()
 
-	fmt.Printf("  Executing hook: %q\n", cmd.Args)
+	verbose.Printf("  Executing hook: %q\n", cmd.Args)
 
 	return cmd.Run()
 }
 
-func (b *BIOS) webhookCall(conf *HookConfig, data []string) error {
-	jsonBody, err := enc(data)
-	if err != nil {
-		return err
+// webhookChecksumHeader carries the sha256 of the uncompressed body.
+const webhookChecksumHeader = "X-EOS-BIOS-SHA256"
+
+// webhookCall POSTs `data` to the hook's `url`, and returns the
+// receiver's status and reply. `data` is a call's key/value pairs, or
+// a batch of queued calls.
+func (b *BIOS) webhookCall(conf *HookConfig, data interface{}) (status int, reply string, err error) {
+	var body io.Reader
+	var checksum string
+	// Declared before the request, set once the body is written.
+	trailer := http.Header{http.CanonicalHeaderKey(webhookChecksumHeader): nil}
+	if conf.Stream {
+		body = streamWebhookBody(conf, data, trailer)
+	} else {
+		jsonBody, err := json.Marshal(data)
+		if err != nil {
+			return 0, "", err
+		}
+
+		if conf.MaxBodySize != 0 && int64(len(jsonBody)) > conf.MaxBodySize {
+			return 0, "", fmt.Errorf("body of %d bytes exceeds max_body_size of %d", len(jsonBody), conf.MaxBodySize)
+		}
+
+		sum := sha256.Sum256(jsonBody)
+		checksum = hex.EncodeToString(sum[:])
+
+		body = bytes.NewReader(jsonBody)
+		if conf.Gzip {
+			var compressed bytes.Buffer
+			gz := gzip.NewWriter(&compressed)
+			if _, err := gz.Write(jsonBody); err != nil {
+				return 0, "", fmt.Errorf("gzip: %s", err)
+			}
+			if err := gz.Close(); err != nil {
+				return 0, "", fmt.Errorf("gzip: %s", err)
+			}
+			body = &compressed
+		}
 	}
 
-	req, err := http.NewRequest("POST", conf.URL, jsonBody)
+	req, err := http.NewRequest("POST", conf.URL, body)
 	if err != nil {
-		return fmt.Errorf("NewRequest: %s", err)
+		return 0, "", fmt.Errorf("NewRequest: %s", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if conf.Stream {
+		req.Trailer = trailer
+	} else {
+		req.Header.Set(webhookChecksumHeader, checksum)
+	}
+	if conf.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if batch, ok := data.([][]string); ok {
+		req.Header.Set("X-EOS-BIOS-Batch", strconv.Itoa(len(batch)))
+	}
+	if conf.token != "" {
+		req.Header.Set("Authorization", "Bearer "+conf.token)
+	}
+
+	client := http.DefaultClient
+	if conf.Timeout != "" {
+		timeout, err := time.ParseDuration(conf.Timeout)
+		if err != nil {
+			return 0, "", fmt.Errorf("timeout: %s", err)
+		}
+		client = &http.Client{Timeout: timeout}
 	}
 
 	// // Useful when debugging API calls
@@ -154,38 +428,71 @@ func (b *BIOS) webhookCall(conf *HookConfig, data []string) error {
 	// }
 	// fmt.Println(string(requestDump))
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("Do: %s", err)
+		return 0, "", fmt.Errorf("Do: %s", err)
 	}
 	defer resp.Body.Close()
 
 	var cnt bytes.Buffer
 	_, err = io.Copy(&cnt, resp.Body)
 	if err != nil {
-		return fmt.Errorf("Copy: %s", err)
+		return resp.StatusCode, "", fmt.Errorf("Copy: %s", err)
 	}
 
 	if resp.StatusCode > 299 {
-		return fmt.Errorf("status code=%d, body=%s", resp.StatusCode, cnt.String())
+		return resp.StatusCode, cnt.String(), fmt.Errorf("status code=%d, body=%s", resp.StatusCode, cnt.String())
 	}
 
 	// fmt.Println("SERVER RESPONSE", cnt.String())
 
-	return nil
+	return resp.StatusCode, cnt.String(), nil
 }
 
-func enc(v interface{}) (io.Reader, error) {
-	if v == nil {
-		return nil, nil
-	}
+// streamWebhookBody encodes (and gzips) `data` while the request
+// reads it, without holding the body in memory. The checksum of the
+// uncompressed body is set in `trailer` once it's all written.
+func streamWebhookBody(conf *HookConfig, data interface{}, trailer http.Header) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		checksum := sha256.New()
+		var out io.Writer = pw
+		var gz *gzip.Writer
+		if conf.Gzip {
+			gz = gzip.NewWriter(pw)
+			out = gz
+		}
 
-	cnt, err := json.Marshal(v)
-	if err != nil {
-		return nil, err
-	}
+		// The size limit comes first, so an oversized body is cut
+		// before its excess is sent.
+		limit := &bodySizeLimit{max: conf.MaxBodySize}
+		if err := json.NewEncoder(io.MultiWriter(limit, checksum, out)).Encode(data); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if gz != nil {
+			if err := gz.Close(); err != nil {
+				pw.CloseWithError(fmt.Errorf("gzip: %s", err))
+				return
+			}
+		}
+
+		trailer.Set(webhookChecksumHeader, hex.EncodeToString(checksum.Sum(nil)))
+		pw.Close()
+	}()
+	return pr
+}
 
-	//fmt.Println("BODY", string(cnt))
+// bodySizeLimit fails writes past `max` bytes, 0 meaning no limit.
+type bodySizeLimit struct {
+	max     int64
+	written int64
+}
 
-	return bytes.NewReader(cnt), nil
+func (l *bodySizeLimit) Write(p []byte) (int, error) {
+	l.written += int64(len(p))
+	if l.max != 0 && l.written > l.max {
+		return 0, fmt.Errorf("body exceeds max_body_size of %d", l.max)
+	}
+	return len(p), nil
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookCallStream(t *testing.T) {
+	var received []string
+	var checksum, expected string
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		cnt, err := ioutil.ReadAll(gz)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sum := sha256.Sum256(cnt)
+		expected = hex.EncodeToString(sum[:])
+		checksum = r.Trailer.Get(webhookChecksumHeader)
+		json.Unmarshal(cnt, &received)
+	}))
+	defer node.Close()
+
+	b := &BIOS{}
+	data := []string{"kickstart", strings.Repeat("x", 100000)}
+	_, _, err := b.webhookCall(&HookConfig{URL: node.URL, Stream: true, Gzip: true}, data)
+	require.NoError(t, err)
+	assert.Equal(t, data, received)
+	assert.Equal(t, expected, checksum)
+
+	_, _, err = b.webhookCall(&HookConfig{URL: node.URL, Stream: true, Gzip: true, MaxBodySize: 1000}, data)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "max_body_size")
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+)
+
+// OpInjectSnapshotBulk injects the snapshot through an injector
+// contract, deployed earlier in the boot sequence, which creates
+// hundreds of accounts and their transfers per action.
+type OpInjectSnapshotBulk struct {
+	// Contract is the account the injector contract is deployed on.
+	Contract eos.AccountName
+	// Action is the injector's action name, defaults to `inject`.
+	Action eos.ActionName
+	// RowsPerAction defaults to 200.
+	RowsPerAction int `json:"rows_per_action"`
+	// ValidateEvery checks one row out of N on chain, defaults to all of them.
+	ValidateEvery int `json:"validate_every"`
+}
+
+// InjectorRow is the packed representation of a snapshot row, as
+// expected by the injector contract.
+type InjectorRow struct {
+	Account   eos.AccountName `json:"account"`
+	PublicKey ecc.PublicKey   `json:"public_key"`
+	Balance   eos.Asset       `json:"balance"`
+}
+
+type InjectorAction struct {
+	Rows []InjectorRow `json:"rows"`
+}
+
+func (op *OpInjectSnapshotBulk) rows(b *BIOS) (out []InjectorRow) {
+	for idx, hodler := range b.injectedSnapshot() {
+		out = append(out, InjectorRow{
+			Account:   snapshotAccountName(idx),
+			PublicKey: hodler.EOSPublicKey,
+			Balance:   hodler.Balance,
+		})
+	}
+	return
+}
+
+func (op *OpInjectSnapshotBulk) Actions(b *BIOS) (out []*eos.Action, err error) {
+	if op.Contract == "" {
+		return nil, fmt.Errorf("snapshot.inject_bulk needs the injector `contract` account")
+	}
+
+	actionName := op.Action
+	if actionName == "" {
+		actionName = ActN("inject")
+	}
+	perAction := op.RowsPerAction
+	if perAction <= 0 {
+		perAction = 200
+	}
+
+	rows := op.rows(b)
+	if len(rows) < len(b.Snapshot) {
+		info.Printf("- DEBUG: truncated snapshot at %d rows\n", len(rows))
+	}
+	for start := 0; start < len(rows); start += perAction {
+		end := start + perAction
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		act := &eos.Action{
+			Account: op.Contract,
+			Name:    actionName,
+			Authorization: []eos.PermissionLevel{
+				{Actor: AN("eosio"), Permission: PN("active")},
+			},
+		}
+		act.Data = eos.NewActionData(InjectorAction{Rows: rows[start:end]})
+
+		trace.Printf("- Injecting rows %d to %d through %s\n", start, end-1, op.Contract)
+		out = append(out, act)
+	}
+
+	info.Printf("- Injecting %d snapshot rows in %d bulk actions\n", len(rows), len(out))
+	return
+}
+
+// Validate expands the packed rows exactly as the contract would,
+// and verifies the resulting accounts and balances.
+func (op *OpInjectSnapshotBulk) Validate(b *BIOS) error {
+	every := op.ValidateEvery
+	if every <= 0 {
+		every = 1
+	}
+
+	for idx, row := range op.rows(b) {
+		if idx%every != 0 {
+			continue
+		}
+
+		acct, err := b.validationAPI().GetAccount(row.Account)
+		if err != nil {
+			return fmt.Errorf("snapshot row %d: get account %s: %s", idx, row.Account, err)
+		}
+
+		expected := eos.Authority{
+			Threshold: 1,
+			Keys:      []eos.KeyWeight{{PublicKey: row.PublicKey, Weight: 1}},
+		}
+		if err := validateAuthority(acct, "owner", expected); err != nil {
+			return fmt.Errorf("snapshot row %d: %s", idx, err)
+		}
+
+		balance, err := b.getTokenBalance(row.Account)
+		if err != nil {
+			return fmt.Errorf("snapshot row %d: get balance of %s: %s", idx, row.Account, err)
+		}
+		if balance.Amount != row.Balance.Amount {
+			return fmt.Errorf("snapshot row %d: %s holds %s, expected %s", idx, row.Account, balance, row.Balance)
+		}
+	}
+
+	return nil
+}
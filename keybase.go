@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// keybaseKindPrefix tags our messages in a team channel, so we can
+// pick them out of the conversation.
+const keybaseKindPrefix = "eos-bios:"
+
+// keybaseTransport posts to, and reads from, a Keybase team channel,
+// through the `keybase chat api` of a logged-in Keybase client. Only
+// messages sent by the `keybase_user` of a producer of the launch
+// file are received.
+type keybaseTransport struct {
+	path         string
+	channel      map[string]string
+	pollInterval time.Duration
+	senders      map[string]bool
+	lastID       int
+}
+
+func newKeybaseTransport(config *Config, launch *LaunchData) (Transport, error) {
+	conf := config.Transport.Keybase
+	if conf.Team == "" {
+		return nil, fmt.Errorf("the keybase transport requires a team")
+	}
+
+	topic := conf.Channel
+	if topic == "" {
+		topic = "general"
+	}
+	path := conf.Path
+	if path == "" {
+		path = "keybase"
+	}
+
+	pollInterval := 10 * time.Second
+	if conf.PollInterval != "" {
+		var err error
+		pollInterval, err = time.ParseDuration(conf.PollInterval)
+		if err != nil {
+			return nil, fmt.Errorf("transport.keybase.poll_interval: %s", err)
+		}
+	}
+
+	senders := map[string]bool{}
+	for _, prod := range launch.Producers {
+		if prod.KeybaseUser != "" {
+			senders[strings.ToLower(prod.KeybaseUser)] = true
+		}
+	}
+
+	return &keybaseTransport{
+		path: path,
+		channel: map[string]string{
+			"name":         conf.Team,
+			"members_type": "team",
+			"topic_name":   topic,
+		},
+		pollInterval: pollInterval,
+		senders:      senders,
+	}, nil
+}
+
+func (t *keybaseTransport) Publish(kind, payload string) error {
+	return t.call("send", map[string]interface{}{
+		"channel": t.channel,
+		"message": map[string]string{"body": keybaseKindPrefix + kind + "\n" + payload},
+	}, nil)
+}
+
+func (t *keybaseTransport) Receive(kind string) ([]string, error) {
+	for {
+		var resp struct {
+			Messages []struct {
+				Msg struct {
+					ID     int `json:"id"`
+					Sender struct {
+						Username string `json:"username"`
+					} `json:"sender"`
+					Content struct {
+						Type string `json:"type"`
+						Text struct {
+							Body string `json:"body"`
+						} `json:"text"`
+					} `json:"content"`
+				} `json:"msg"`
+			} `json:"messages"`
+		}
+		err := t.call("read", map[string]interface{}{
+			"channel":    t.channel,
+			"pagination": map[string]int{"num": 100},
+		}, &resp)
+		if err != nil {
+			return nil, err
+		}
+
+		// Messages come newest first.
+		var out []string
+		lastID := t.lastID
+		for i := len(resp.Messages) - 1; i >= 0; i-- {
+			msg := resp.Messages[i].Msg
+			if msg.ID <= t.lastID {
+				continue
+			}
+			if msg.ID > lastID {
+				lastID = msg.ID
+			}
+			if msg.Content.Type != "text" {
+				continue
+			}
+
+			body := msg.Content.Text.Body
+			header := keybaseKindPrefix + kind + "\n"
+			if !strings.HasPrefix(body, header) {
+				continue
+			}
+			if !t.senders[strings.ToLower(msg.Sender.Username)] {
+				info.Printf("Ignoring %s sent on Keybase by %s, who isn't a producer of the launch file\n", kind, msg.Sender.Username)
+				continue
+			}
+			out = append(out, strings.TrimPrefix(body, header))
+		}
+		t.lastID = lastID
+
+		if len(out) != 0 {
+			return out, nil
+		}
+		time.Sleep(t.pollInterval)
+	}
+}
+
+func (t *keybaseTransport) call(method string, options map[string]interface{}, out interface{}) error {
+	request, _ := json.Marshal(map[string]interface{}{
+		"method": method,
+		"params": map[string]interface{}{"options": options},
+	})
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(t.path, "chat", "api")
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("keybase chat api %s: %s: %s", method, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return fmt.Errorf("keybase chat api %s: %s", method, err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("keybase chat api %s: %s", method, resp.Error.Message)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, out)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/eoscanada/eos-go/ecc"
+	"golang.org/x/crypto/ripemd160"
+)
+
+const keysUsage = `usage: eos-bios keys <command>
+
+Commands:
+  generate [count]   generate new key pairs (block signing, producer accounts, ...)
+  public <wif>       derive the public key of a private key
+  convert <key>      convert a key between the legacy (EOS..., 5...) and the
+                     new (PUB_K1_..., PVT_K1_...) formats
+  check              validate all keys found in --local-config and --launch-data
+`
+
+// runKeys implements the `eos-bios keys` subcommand, to avoid
+// juggling with `cleos` and `keosd` while preparing for a launch.
+func runKeys(args []string) error {
+	if len(args) == 0 {
+		fmt.Print(keysUsage)
+		return nil
+	}
+
+	switch args[0] {
+	case "generate":
+		count := 1
+		if len(args) > 1 {
+			var err error
+			count, err = strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid count %q: %s", args[1], err)
+			}
+		}
+		for i := 0; i < count; i++ {
+			privKey, err := ecc.NewRandomPrivateKey()
+			if err != nil {
+				return err
+			}
+			fmt.Println("Private key:", privKey.String())
+			fmt.Println("Public key: ", privKey.PublicKey().String())
+			fmt.Println("")
+		}
+	case "public":
+		if len(args) != 2 {
+			return fmt.Errorf("public takes a single private key")
+		}
+		privKey, err := ecc.NewPrivateKey(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid private key: %s", err)
+		}
+		fmt.Println(privKey.PublicKey().String())
+	case "convert":
+		if len(args) != 2 {
+			return fmt.Errorf("convert takes a single key")
+		}
+		converted, err := convertKey(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Println(converted)
+	case "check":
+		return checkKeys()
+	default:
+		fmt.Print(keysUsage)
+		return fmt.Errorf("unknown keys command %q", args[0])
+	}
+
+	return nil
+}
+
+// convertKey flips a key between its legacy and `K1` string
+// representations, preserving the key material.
+func convertKey(key string) (string, error) {
+	switch {
+	case strings.HasPrefix(key, "PUB_K1_"):
+		raw, err := decodeK1(strings.TrimPrefix(key, "PUB_K1_"), 33)
+		if err != nil {
+			return "", fmt.Errorf("public key: %s", err)
+		}
+		return "EOS" + base58.Encode(append(raw, ripemd160Sum(raw)[:4]...)), nil
+
+	case strings.HasPrefix(key, "EOS"):
+		decoded := base58.Decode(strings.TrimPrefix(key, "EOS"))
+		if len(decoded) != 37 {
+			return "", fmt.Errorf("public key: invalid length %d", len(decoded))
+		}
+		raw := decoded[:33]
+		if !bytes.Equal(ripemd160Sum(raw)[:4], decoded[33:]) {
+			return "", fmt.Errorf("public key: checksum mismatch")
+		}
+		return "PUB_K1_" + encodeK1(raw), nil
+
+	case strings.HasPrefix(key, "PVT_K1_"):
+		raw, err := decodeK1(strings.TrimPrefix(key, "PVT_K1_"), 32)
+		if err != nil {
+			return "", fmt.Errorf("private key: %s", err)
+		}
+		payload := append([]byte{0x80}, raw...)
+		return base58.Encode(append(payload, doubleSHA256(payload)[:4]...)), nil
+
+	default:
+		decoded := base58.Decode(key)
+		if len(decoded) != 37 || decoded[0] != 0x80 {
+			return "", fmt.Errorf("unrecognized key format")
+		}
+		if !bytes.Equal(doubleSHA256(decoded[:33])[:4], decoded[33:]) {
+			return "", fmt.Errorf("private key: checksum mismatch")
+		}
+		return "PVT_K1_" + encodeK1(decoded[1:33]), nil
+	}
+}
+
+func encodeK1(raw []byte) string {
+	checksum := ripemd160Sum(append(append([]byte{}, raw...), 'K', '1'))
+	return base58.Encode(append(append([]byte{}, raw...), checksum[:4]...))
+}
+
+func decodeK1(encoded string, size int) ([]byte, error) {
+	decoded := base58.Decode(encoded)
+	if len(decoded) != size+4 {
+		return nil, fmt.Errorf("invalid length %d", len(decoded))
+	}
+	raw := decoded[:size]
+	checksum := ripemd160Sum(append(append([]byte{}, raw...), 'K', '1'))
+	if !bytes.Equal(checksum[:4], decoded[size:]) {
+		return nil, fmt.Errorf("checksum mismatch")
+	}
+	return raw, nil
+}
+
+func ripemd160Sum(in []byte) []byte {
+	h := ripemd160.New()
+	_, _ = h.Write(in)
+	return h.Sum(nil)
+}
+
+func doubleSHA256(in []byte) []byte {
+	first := sha256.Sum256(in)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+// checkKeys loads the local config and launch data without any of
+// the hash verifications, and reports on every key found. The config
+// and the block signing private key go through the same loading as a
+// launch (encrypted files, secrets, networks).
+func checkKeys() error {
+	var failures int
+
+	if *localConfig != "" {
+		c, err := readLocalConfig(*localConfig, *networkFlag)
+		if err != nil {
+			return fmt.Errorf("local config: %s", err)
+		}
+
+		source := "producer.block_signing_private_key_path"
+		if c.Producer.BlockSigningPrivateKeySecret != "" {
+			source = "producer.block_signing_private_key_secret"
+		}
+
+		privKeyCnt, err := readBlockSigningPrivateKey(c)
+		if err != nil {
+			return fmt.Errorf("%s: %s", source, err)
+		}
+
+		if privKeyCnt == "" {
+			fmt.Printf("- %s: NONE, observer only\n", source)
+		} else if privKey, err := ecc.NewPrivateKey(privKeyCnt); err != nil {
+			failures++
+			fmt.Printf("- %s: INVALID (%s)\n", source, err)
+		} else if privKey.PublicKey().String() != c.Producer.BlockSigningPublicKey.String() {
+			failures++
+			fmt.Printf("- producer.block_signing_public_key: MISMATCH, private key yields %s\n", privKey.PublicKey().String())
+		} else {
+			fmt.Printf("- producer.block_signing_public_key: OKAY, matches %s\n", source)
+		}
+	}
+
+	if *launchData != "" {
+		var launch *LaunchData
+		cnt, err := readMaybeEncrypted(*launchData)
+		if err != nil {
+			return err
+		}
+		// Invalid keys fail unmarshalling, so we report with the YAML error itself.
+		if err = yamlUnmarshal(cnt, &launch); err != nil {
+			return fmt.Errorf("launch data: %s", err)
+		}
+
+		for _, prod := range launch.Producers {
+			if len(prod.InitialBlockSigningPublicKey) == 0 {
+				failures++
+				fmt.Printf("- %s: MISSING initial_block_signing_key\n", prod.AccountName)
+				continue
+			}
+			if len(prod.Authority.Owner.Keys) == 0 && len(prod.Authority.Owner.Accounts) == 0 {
+				failures++
+				fmt.Printf("- %s: EMPTY owner authority\n", prod.AccountName)
+				continue
+			}
+			fmt.Printf("- %s: OKAY\n", prod.AccountName)
+		}
+	}
+
+	if failures != 0 {
+		return fmt.Errorf("%d key problem(s) found", failures)
+	}
+	return nil
+}
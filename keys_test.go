@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertKeyRoundTrip(t *testing.T) {
+	for _, key := range []string{
+		"EOS6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5GDW5CV",
+		"5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP79zkvFD3",
+	} {
+		converted, err := convertKey(key)
+		require.NoError(t, err)
+		assert.NotEqual(t, key, converted)
+
+		back, err := convertKey(converted)
+		require.NoError(t, err)
+		assert.Equal(t, key, back)
+	}
+}
+
+func TestConvertKeyInvalid(t *testing.T) {
+	_, err := convertKey("EOS6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5GDW5CW")
+	assert.Error(t, err)
+}
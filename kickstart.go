@@ -1,8 +1,162 @@
 package main
 
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/eoscanada/eos-go/ecc"
+)
+
+// kickstartVersion is the version of the kickstart data schema we
+// publish, and the only one we accept. Version 2 added `issued_at`
+// and `ttl`, version 3 `state_manifest_root`.
+const kickstartVersion = 3
+
+// kickstartClockSkew is how far in the future `issued_at` may be,
+// for boot nodes whose clock runs a bit ahead.
+const kickstartClockSkew = 2 * time.Minute
+
 type KickstartData struct {
+	Version        int    `json:"version"`
 	BIOSP2PAddress string `json:"bios_p2p_address"`
 	PrivateKeyUsed string `json:"private_key_used"`
 	PublicKeyUsed  string `json:"public_key_used"`
 	GenesisJSON    string `json:"genesis_json"`
+
+	// IssuedAt and TTL (like "30m") bound the time the kickstart
+	// data is accepted, so the data of an aborted launch attempt
+	// can't be replayed. See `CheckFreshness`.
+	IssuedAt time.Time `json:"issued_at"`
+	TTL      string    `json:"ttl"`
+
+	// StateManifestRoot is the root of the state manifest the boot
+	// node computed before booting, ABPs fail the launch when theirs
+	// differs. See `statemanifest.go`.
+	StateManifestRoot string `json:"state_manifest_root"`
+}
+
+var hostnameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?$`)
+
+// decodeKickstartData rejects unknown fields, so a payload of another
+// schema doesn't half-load.
+func decodeKickstartData(raw []byte) (kickstart KickstartData, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&kickstart)
+	return
+}
+
+// Validate checks every field of the kickstart data, before anything
+// is done with it.
+func (k *KickstartData) Validate() error {
+	if k.Version != kickstartVersion {
+		return fmt.Errorf("unsupported kickstart data version %d, this eos-bios only accepts version %d", k.Version, kickstartVersion)
+	}
+
+	if err := validateP2PAddress(k.BIOSP2PAddress); err != nil {
+		return fmt.Errorf("bios_p2p_address: %s", err)
+	}
+
+	privKey, err := ecc.NewPrivateKey(k.PrivateKeyUsed)
+	if err != nil {
+		return fmt.Errorf("private_key_used isn't a valid WIF private key: %s", err)
+	}
+	if _, err := ecc.NewPublicKey(k.PublicKeyUsed); err != nil {
+		return fmt.Errorf("public_key_used: %s", err)
+	}
+	if pubKey := privKey.PublicKey().String(); pubKey != k.PublicKeyUsed {
+		return fmt.Errorf("private key used corresponds to %s, not to the public key used %s", pubKey, k.PublicKeyUsed)
+	}
+
+	if err := validateGenesisJSON(k.GenesisJSON, k.PublicKeyUsed); err != nil {
+		return fmt.Errorf("genesis_json: %s", err)
+	}
+
+	if k.IssuedAt.IsZero() {
+		return fmt.Errorf("issued_at missing")
+	}
+	if ttl, err := time.ParseDuration(k.TTL); err != nil || ttl <= 0 {
+		return fmt.Errorf("ttl %q isn't a positive duration", k.TTL)
+	}
+
+	if root, err := hex.DecodeString(k.StateManifestRoot); err != nil || len(root) != 32 {
+		return fmt.Errorf("state_manifest_root %q isn't a sha256 hash", k.StateManifestRoot)
+	}
+
+	return nil
+}
+
+// CheckFreshness refuses kickstart data past its TTL at `now`, or
+// older than `maxAge` when it is not zero, whichever is stricter.
+func (k *KickstartData) CheckFreshness(now time.Time, maxAge time.Duration) error {
+	ttl, err := time.ParseDuration(k.TTL)
+	if err != nil {
+		return fmt.Errorf("ttl: %s", err)
+	}
+
+	if k.IssuedAt.After(now.Add(kickstartClockSkew)) {
+		return fmt.Errorf("issued at %s, in the future: check the clocks of this machine and the boot node", k.IssuedAt.Format(time.RFC3339))
+	}
+
+	age := now.Sub(k.IssuedAt)
+	if age > ttl {
+		return fmt.Errorf("stale: issued at %s, %s ago, past its ttl of %s", k.IssuedAt.Format(time.RFC3339), age.Truncate(time.Second), ttl)
+	}
+	if maxAge != 0 && age > maxAge {
+		return fmt.Errorf("stale: issued at %s, %s ago, older than kickstart_max_age of %s", k.IssuedAt.Format(time.RFC3339), age.Truncate(time.Second), maxAge)
+	}
+
+	return nil
+}
+
+// validateP2PAddress checks `addr` is a "host:port", the host being
+// an IP address or a hostname.
+func validateP2PAddress(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+
+	if net.ParseIP(host) == nil && !hostnameRegexp.MatchString(host) {
+		return fmt.Errorf("invalid host %q", host)
+	}
+
+	portNum, err := strconv.Atoi(port)
+	if err != nil || portNum < 1 || portNum > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+
+	return nil
+}
+
+// validateGenesisJSON checks the genesis holds exactly the fields of
+// `GenesisJSON`, well formed, with `initialKey` as its initial key.
+func validateGenesisJSON(genesisJSON, initialKey string) error {
+	var genesis GenesisJSON
+	decoder := json.NewDecoder(bytes.NewReader([]byte(genesisJSON)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&genesis); err != nil {
+		return err
+	}
+
+	if _, err := time.Parse("2006-01-02T15:04:05", genesis.InitialTimestamp); err != nil {
+		return fmt.Errorf("initial_timestamp: %s", err)
+	}
+
+	if genesis.InitialKey != initialKey {
+		return fmt.Errorf("initial_key %s isn't the public key used %s", genesis.InitialKey, initialKey)
+	}
+
+	chainID, err := hex.DecodeString(genesis.InitialChainID)
+	if err != nil || len(chainID) != 32 {
+		return fmt.Errorf("initial_chain_id %q isn't 32 bytes of hex", genesis.InitialChainID)
+	}
+
+	return nil
 }
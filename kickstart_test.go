@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testGenesisKey = "EOS6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5GDW5CV"
+
+func TestValidateP2PAddress(t *testing.T) {
+	for _, addr := range []string{"1.2.3.4:9876", "[::1]:9876", "boot.example.com:9876"} {
+		assert.NoError(t, validateP2PAddress(addr), addr)
+	}
+	for _, addr := range []string{"", "1.2.3.4", "boot.example.com:0", "boot.example.com:99999", "-boot:9876", "boot_node:9876"} {
+		assert.Error(t, validateP2PAddress(addr), addr)
+	}
+}
+
+func TestValidateGenesisJSON(t *testing.T) {
+	valid := `{"initial_timestamp":"2018-06-01T12:00:00","initial_key":"` + testGenesisKey + `","initial_chain_id":"0000000000000000000000000000000000000000000000000000000000000000"}`
+	require.NoError(t, validateGenesisJSON(valid, testGenesisKey))
+
+	for name, genesis := range map[string]string{
+		"unknown field": `{"initial_timestamp":"2018-06-01T12:00:00","initial_key":"` + testGenesisKey + `","initial_chain_id":"0000000000000000000000000000000000000000000000000000000000000000","extra":1}`,
+		"timestamp":     `{"initial_timestamp":"June 1st","initial_key":"` + testGenesisKey + `","initial_chain_id":"0000000000000000000000000000000000000000000000000000000000000000"}`,
+		"other key":     `{"initial_timestamp":"2018-06-01T12:00:00","initial_key":"EOS5","initial_chain_id":"0000000000000000000000000000000000000000000000000000000000000000"}`,
+		"chain id":      `{"initial_timestamp":"2018-06-01T12:00:00","initial_key":"` + testGenesisKey + `","initial_chain_id":"00"}`,
+		"not json":      `initial_key=` + testGenesisKey,
+	} {
+		assert.Error(t, validateGenesisJSON(genesis, testGenesisKey), name)
+	}
+}
+
+func TestKickstartDataVersion(t *testing.T) {
+	k := &KickstartData{BIOSP2PAddress: "1.2.3.4:9876"}
+	assert.Contains(t, k.Validate().Error(), "unsupported kickstart data version 0")
+
+	_, err := decodeKickstartData([]byte(`{"version":1,"bios_p2p_address":"1.2.3.4:9876","extra":true}`))
+	assert.Error(t, err)
+}
+
+func TestKickstartDataFreshness(t *testing.T) {
+	issued := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	k := &KickstartData{IssuedAt: issued, TTL: "30m"}
+
+	assert.NoError(t, k.CheckFreshness(issued.Add(10*time.Minute), 0))
+	assert.NoError(t, k.CheckFreshness(issued.Add(-time.Minute), 0))
+	assert.Contains(t, k.CheckFreshness(issued.Add(31*time.Minute), 0).Error(), "past its ttl")
+	assert.Contains(t, k.CheckFreshness(issued.Add(10*time.Minute), 5*time.Minute).Error(), "older than kickstart_max_age")
+	assert.Contains(t, k.CheckFreshness(issued.Add(-time.Hour), 0).Error(), "in the future")
+}
+
+func TestCompactKickstartRoundTrip(t *testing.T) {
+	privKey, err := ecc.NewRandomPrivateKey()
+	require.NoError(t, err)
+	pubKey := privKey.PublicKey().String()
+
+	genesis, _ := json.Marshal(&GenesisJSON{
+		InitialTimestamp: "2018-06-01T12:00:00",
+		InitialKey:       pubKey,
+		InitialChainID:   "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	})
+	k := &KickstartData{
+		Version:           kickstartVersion,
+		BIOSP2PAddress:    "1.2.3.4:9876",
+		PrivateKeyUsed:    privKey.String(),
+		PublicKeyUsed:     pubKey,
+		GenesisJSON:       string(genesis),
+		IssuedAt:          time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC),
+		TTL:               "30m",
+		StateManifestRoot: "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210",
+	}
+
+	compact, err := encodeCompactKickstart(k)
+	require.NoError(t, err)
+	full, _ := json.Marshal(k)
+	assert.True(t, len(compact) < len(full)/2, "compact %d bytes, json %d bytes", len(compact), len(full))
+
+	decoded, err := decodeKickstartPayload(compact)
+	require.NoError(t, err)
+	assert.Equal(t, *k, decoded)
+}
+
+func TestCompareGenesisJSON(t *testing.T) {
+	expected := `{"initial_timestamp":"2018-06-01T12:00:00","initial_key":"` + testGenesisKey + `","initial_chain_id":"00"}`
+
+	assert.NoError(t, compareGenesisJSON(expected, `{ "initial_chain_id": "00", "initial_key": "`+testGenesisKey+`", "initial_timestamp": "2018-06-01T12:00:00" }`))
+	assert.Contains(t, compareGenesisJSON(expected, `{"initial_timestamp":"2018-06-01T12:00:01","initial_key":"`+testGenesisKey+`","initial_chain_id":"00"}`).Error(), `"initial_timestamp"`)
+	assert.Contains(t, compareGenesisJSON(expected, `{"initial_timestamp":"2018-06-01T12:00:00","initial_key":"`+testGenesisKey+`"}`).Error(), `"initial_chain_id"`)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/ugorji/go/codec"
+)
+
+// With `kickstart_compact`, the kickstart data is published in a
+// compact encoding, small enough for a QR code or to be read over a
+// voice call: a CBOR array, deflated, behind two magic bytes. The
+// public key and the genesis JSON are not sent, but derived from the
+// private key, the genesis timestamp and the chain ID. Receivers
+// accept both encodings, cleartext or encrypted.
+
+var compactKickstartMagic = []byte{0xeb, 0x01}
+
+type compactKickstart struct {
+	_struct           bool `codec:",toarray"`
+	Version           int
+	BIOSP2PAddress    string
+	PrivateKeyUsed    string
+	InitialTimestamp  string
+	InitialChainID    []byte
+	IssuedAt          int64
+	TTL               string
+	StateManifestRoot []byte
+}
+
+func encodeCompactKickstart(k *KickstartData) ([]byte, error) {
+	var genesis GenesisJSON
+	if err := json.Unmarshal([]byte(k.GenesisJSON), &genesis); err != nil {
+		return nil, fmt.Errorf("genesis_json: %s", err)
+	}
+	chainID, err := hex.DecodeString(genesis.InitialChainID)
+	if err != nil {
+		return nil, fmt.Errorf("initial_chain_id: %s", err)
+	}
+	stateManifestRoot, err := hex.DecodeString(k.StateManifestRoot)
+	if err != nil {
+		return nil, fmt.Errorf("state_manifest_root: %s", err)
+	}
+
+	compact := &compactKickstart{
+		Version:           k.Version,
+		BIOSP2PAddress:    k.BIOSP2PAddress,
+		PrivateKeyUsed:    k.PrivateKeyUsed,
+		InitialTimestamp:  genesis.InitialTimestamp,
+		InitialChainID:    chainID,
+		IssuedAt:          k.IssuedAt.Unix(),
+		TTL:               k.TTL,
+		StateManifestRoot: stateManifestRoot,
+	}
+
+	var encoded []byte
+	if err := codec.NewEncoderBytes(&encoded, &codec.CborHandle{}).Encode(compact); err != nil {
+		return nil, fmt.Errorf("cbor: %s", err)
+	}
+
+	out := bytes.NewBuffer(append([]byte{}, compactKickstartMagic...))
+	deflater, err := flate.NewWriter(out, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := deflater.Write(encoded); err != nil {
+		return nil, err
+	}
+	if err := deflater.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func decodeCompactKickstart(raw []byte) (kickstart KickstartData, err error) {
+	encoded, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(raw[len(compactKickstartMagic):])))
+	if err != nil {
+		return kickstart, fmt.Errorf("inflate: %s", err)
+	}
+
+	var compact compactKickstart
+	if err := codec.NewDecoderBytes(encoded, &codec.CborHandle{}).Decode(&compact); err != nil {
+		return kickstart, fmt.Errorf("cbor: %s", err)
+	}
+
+	privKey, err := ecc.NewPrivateKey(compact.PrivateKeyUsed)
+	if err != nil {
+		return kickstart, fmt.Errorf("private_key_used isn't a valid WIF private key: %s", err)
+	}
+	pubKey := privKey.PublicKey().String()
+
+	genesis, _ := json.Marshal(&GenesisJSON{
+		InitialTimestamp: compact.InitialTimestamp,
+		InitialKey:       pubKey,
+		InitialChainID:   hex.EncodeToString(compact.InitialChainID),
+	})
+
+	return KickstartData{
+		Version:           compact.Version,
+		BIOSP2PAddress:    compact.BIOSP2PAddress,
+		PrivateKeyUsed:    compact.PrivateKeyUsed,
+		PublicKeyUsed:     pubKey,
+		GenesisJSON:       string(genesis),
+		IssuedAt:          time.Unix(compact.IssuedAt, 0).UTC(),
+		TTL:               compact.TTL,
+		StateManifestRoot: hex.EncodeToString(compact.StateManifestRoot),
+	}, nil
+}
+
+// decodeKickstartPayload decodes the kickstart data in either
+// encoding.
+func decodeKickstartPayload(raw []byte) (KickstartData, error) {
+	if bytes.HasPrefix(raw, compactKickstartMagic) {
+		return decodeCompactKickstart(raw)
+	}
+	return decodeKickstartData(raw)
+}
+
+// readKickstartFile waits for `kickstart_input_file`, holding the
+// text scanned from the QR code (or pasted by hand), until it holds
+// valid kickstart data.
+func (b *BIOS) readKickstartFile() (kickstart KickstartData, err error) {
+	filename := b.Config.KickstartInputFile
+	lastInvalid := ""
+
+	milestone.Printf("Waiting for kickstart data in %s, write the scanned QR code there\n", filename)
+	for {
+		cnt, err := ioutil.ReadFile(filename)
+		if err != nil {
+			verbose.Println("Reading kickstart file:", err)
+		} else if text := strings.TrimSpace(string(cnt)); text != "" && text != lastInvalid {
+			kickstart, err = b.parseKickstartData(text)
+			if err == nil {
+				return kickstart, nil
+			}
+			info.Println("Ignoring invalid kickstart data in file:", err)
+			lastInvalid = text
+		}
+
+		time.Sleep(2 * time.Second)
+	}
+}
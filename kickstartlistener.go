@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// waitOnKickstartHTTP serves `POST /kickstart` on the configured
+// address until valid kickstart data is posted, with the shared token
+// as `Authorization: Bearer <token>`. The body is the kickstart data
+// as published (base64, or the armored PGP message).
+func (b *BIOS) waitOnKickstartHTTP() (kickstart KickstartData, err error) {
+	conf := b.Config.KickstartListener
+	if conf.TokenPath == "" {
+		return kickstart, fmt.Errorf("kickstart_listener requires a token_path")
+	}
+	token, err := readMaybeEncrypted(conf.TokenPath)
+	if err != nil {
+		return kickstart, fmt.Errorf("reading kickstart_listener token: %s", err)
+	}
+	expected := []byte("Bearer " + strings.TrimSpace(string(token)))
+
+	received := make(chan KickstartData, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/kickstart", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "POST the kickstart data", http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		parsed, err := b.parseKickstartData(string(body))
+		if err != nil {
+			info.Println("Ignoring invalid kickstart data posted:", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		select {
+		case received <- parsed:
+			_, _ = w.Write([]byte("kickstart data accepted\n"))
+		default:
+			http.Error(w, "kickstart data already received", http.StatusConflict)
+		}
+	})
+
+	server := &http.Server{Addr: conf.ListenAddress, Handler: mux}
+	failed := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			failed <- err
+		}
+	}()
+
+	milestone.Printf("Waiting for kickstart data on http://%s/kickstart\n", conf.ListenAddress)
+	select {
+	case kickstart = <-received:
+	case err = <-failed:
+		return kickstart, fmt.Errorf("kickstart listener: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = server.Shutdown(ctx)
+
+	return kickstart, nil
+}
+
+// pollKickstartURL GETs `kickstart_poll_url` until it serves valid
+// kickstart data. Anything else (not found yet, stale data of a
+// rehearsal, ...) is retried.
+func (b *BIOS) pollKickstartURL() (kickstart KickstartData, err error) {
+	interval := 15 * time.Second
+	if b.Config.KickstartPollInterval != "" {
+		interval, err = time.ParseDuration(b.Config.KickstartPollInterval)
+		if err != nil {
+			return kickstart, fmt.Errorf("kickstart_poll_interval: %s", err)
+		}
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	lastInvalid := ""
+
+	milestone.Printf("Polling %s for kickstart data, every %s\n", b.Config.KickstartPollURL, interval)
+	for {
+		body, err := fetchKickstartURL(client, b.Config.KickstartPollURL)
+		if err != nil {
+			verbose.Println("Polling kickstart data:", err)
+		} else if hash := sha256Hex(body); hash != lastInvalid {
+			kickstart, err = b.parseKickstartData(string(body))
+			if err == nil {
+				return kickstart, nil
+			}
+			info.Println("Ignoring invalid kickstart data polled:", err)
+			lastInvalid = hash
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+func fetchKickstartURL(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
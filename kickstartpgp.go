@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+// The kickstart data holds the ephemeral private key and the secret
+// p2p address of the BIOS Boot node. With `pgp.program` configured,
+// it is published encrypted to the `pgp_public_key` of every
+// Appointed Block Producer, and signed by the boot node's. `gpg`
+// shells out to GnuPG (keys in its own keyring), `native` does it in
+// process with `pgp.private_key_path`.
+
+const pgpMessageHeader = "-----BEGIN PGP MESSAGE-----"
+
+// kickstartRecipients are the distinct PGP keys of the ABPs.
+func (b *BIOS) kickstartRecipients() (out []string, err error) {
+	seen := map[string]bool{}
+	for i := 1; i < 22 && len(b.ShuffledProducers) > i; i++ {
+		prod := b.ShuffledProducers[i]
+		if prod.PGPPublicKey == "" {
+			return nil, fmt.Errorf("ABP %s has no pgp_public_key, they couldn't decrypt the kickstart data", prod.AccountName)
+		}
+		if !seen[prod.PGPPublicKey] {
+			seen[prod.PGPPublicKey] = true
+			out = append(out, prod.PGPPublicKey)
+		}
+	}
+	return
+}
+
+func (b *BIOS) encryptKickstart(plain []byte) (string, error) {
+	recipientKeys, err := b.kickstartRecipients()
+	if err != nil {
+		return "", err
+	}
+
+	switch b.Config.PGP.Program {
+	case "gpg":
+		return gpgEncrypt(b.gpgPath(), recipientKeys, plain)
+
+	case "native":
+		var recipients openpgp.EntityList
+		for _, key := range recipientKeys {
+			keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key))
+			if err != nil {
+				return "", fmt.Errorf("reading ABP PGP key: %s", err)
+			}
+			recipients = append(recipients, keyring...)
+		}
+
+		signer, err := readArmoredKeyFile(b.Config.PGP.PrivateKeyPath)
+		if err != nil {
+			return "", err
+		}
+		return pgpEncrypt(plain, recipients, signer[0])
+	}
+
+	return "", fmt.Errorf("unknown pgp.program %q, use `gpg` or `native`", b.Config.PGP.Program)
+}
+
+// decryptKickstart decrypts the kickstart data, and checks it was
+// signed with the boot node's key.
+func (b *BIOS) decryptKickstart(message string) ([]byte, error) {
+	bootNode := b.ShuffledProducers[0]
+	trusted, err := openpgp.ReadArmoredKeyRing(strings.NewReader(bootNode.PGPPublicKey))
+	if err != nil {
+		return nil, fmt.Errorf("pgp_public_key of the boot node %s: %s", bootNode.AccountName, err)
+	}
+
+	switch b.Config.PGP.Program {
+	case "gpg":
+		plain, signer, err := gpgDecrypt(b.gpgPath(), message)
+		if err != nil {
+			return nil, err
+		}
+		for _, entity := range trusted {
+			if strings.EqualFold(signer, fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)) {
+				return plain, nil
+			}
+		}
+		return nil, fmt.Errorf("kickstart data signed by %s, not the boot node %s", signer, bootNode.AccountName)
+
+	case "native":
+		keyring, err := readArmoredKeyFile(b.Config.PGP.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		return pgpDecrypt(message, keyring, trusted)
+	}
+
+	return nil, fmt.Errorf("kickstart data is PGP encrypted, configure `pgp.program` to decrypt it")
+}
+
+func (b *BIOS) gpgPath() string {
+	if b.Config.PGP.Path != "" {
+		return b.Config.PGP.Path
+	}
+	return "gpg"
+}
+
+// gpgEncrypt signs with gpg's default key, and encrypts to the
+// armored `recipientKeys`, which needn't be in its keyring.
+func gpgEncrypt(path string, recipientKeys []string, plain []byte) (string, error) {
+	dir, err := ioutil.TempDir("", "eos-bios-gpg")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{"--batch", "--yes", "--armor", "--trust-model", "always", "--sign", "--encrypt"}
+	for idx, key := range recipientKeys {
+		filename := filepath.Join(dir, fmt.Sprintf("recipient-%d.asc", idx))
+		if err := ioutil.WriteFile(filename, []byte(key), 0600); err != nil {
+			return "", err
+		}
+		args = append(args, "--recipient-file", filename)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = bytes.NewReader(plain)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("gpg encrypt: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+// gpgDecrypt returns the plaintext, and the fingerprint of the key
+// that signed it (from gpg's `VALIDSIG` status).
+func gpgDecrypt(path string, message string) (plain []byte, signer string, err error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(path, "--batch", "--status-fd", "2", "--decrypt")
+	cmd.Stdin = strings.NewReader(message)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, "", fmt.Errorf("gpg decrypt: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	for _, line := range strings.Split(stderr.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "[GNUPG:]" && fields[1] == "VALIDSIG" {
+			// The primary key's fingerprint is last, the signing subkey's first.
+			signer = fields[len(fields)-1]
+		}
+	}
+	if signer == "" {
+		return nil, "", fmt.Errorf("gpg decrypt: kickstart data isn't validly signed")
+	}
+
+	return stdout.Bytes(), signer, nil
+}
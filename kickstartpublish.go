@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// KickstartPublication is the outcome of publishing the kickstart
+// data on one of the `kickstart_publish` channels.
+type KickstartPublication struct {
+	Channel string `json:"channel"`
+	// Reference locates what was published: URL, IPFS hash, file path, ...
+	Reference string `json:"reference,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type kickstartChannel struct {
+	name    string
+	publish func(ksdata string) (reference string, err error)
+}
+
+// kickstartChannels are the channels configured in `kickstart_publish`.
+func (b *BIOS) kickstartChannels() (out []*kickstartChannel) {
+	conf := b.Config.KickstartPublish
+
+	if conf.Webhook.URL != "" {
+		out = append(out, &kickstartChannel{"webhook", b.publishKickstartWebhook})
+	}
+	if conf.Keybase {
+		out = append(out, &kickstartChannel{"keybase", b.publishKickstartKeybase})
+	}
+	if conf.IPFS.APIAddress != "" {
+		out = append(out, &kickstartChannel{"ipfs", b.publishKickstartIPFS})
+	}
+	if conf.File.Path != "" {
+		out = append(out, &kickstartChannel{"file", b.publishKickstartFile})
+	}
+	return
+}
+
+// fanOutKickstart publishes the kickstart data on every configured
+// channel, and reports how each went. It only fails when all of them
+// did.
+func (b *BIOS) fanOutKickstart(ksdata string) error {
+	channels := b.kickstartChannels()
+	if len(channels) == 0 {
+		return nil
+	}
+
+	failed := 0
+	for _, channel := range channels {
+		result := &KickstartPublication{Channel: channel.name}
+
+		reference, err := channel.publish(ksdata)
+		if err != nil {
+			failed++
+			result.Error = err.Error()
+			milestone.Printf("- Kickstart data on %s: FAILED: %s\n", channel.name, err)
+		} else {
+			result.Reference = reference
+			milestone.Printf("- Kickstart data on %s: published %s\n", channel.name, reference)
+		}
+
+		b.Report.KickstartPublications = append(b.Report.KickstartPublications, result)
+	}
+
+	if failed == len(channels) {
+		return fmt.Errorf("kickstart data couldn't be published on any of the %d channels", len(channels))
+	}
+	return nil
+}
+
+func (b *BIOS) publishKickstartWebhook(ksdata string) (string, error) {
+	conf := b.Config.KickstartPublish.Webhook
+
+	req, err := http.NewRequest("POST", conf.URL, strings.NewReader(ksdata))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	if conf.TokenSecret != "" {
+		token, err := resolveSecret(b.Config, conf.TokenSecret)
+		if err != nil {
+			return "", fmt.Errorf("token_secret: %s", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := kickstartPublishClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("POST %s: %s", conf.URL, resp.Status)
+	}
+	return conf.URL, nil
+}
+
+// publishKickstartKeybase posts to the team channel configured for
+// the keybase transport, even if another transport is in use.
+func (b *BIOS) publishKickstartKeybase(ksdata string) (string, error) {
+	transport, err := newKeybaseTransport(b.Config, b.LaunchData)
+	if err != nil {
+		return "", err
+	}
+	if err := transport.Publish("kickstart", ksdata); err != nil {
+		return "", err
+	}
+
+	conf := b.Config.Transport.Keybase
+	channel := conf.Channel
+	if channel == "" {
+		channel = "general"
+	}
+	return fmt.Sprintf("%s#%s", conf.Team, channel), nil
+}
+
+// publishKickstartIPFS adds the kickstart data to an IPFS node, through
+// its HTTP API, and returns its hash.
+func (b *BIOS) publishKickstartIPFS(ksdata string) (string, error) {
+	conf := b.Config.KickstartPublish.IPFS
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "kickstart.txt")
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write([]byte(ksdata)); err != nil {
+		return "", err
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	url := strings.TrimRight(conf.APIAddress, "/") + "/api/v0/add?pin=true"
+	resp, err := kickstartPublishClient.Post(url, form.FormDataContentType(), &body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("ipfs add: %s", resp.Status)
+	}
+
+	var added struct {
+		Hash string `json:"Hash"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&added); err != nil {
+		return "", fmt.Errorf("ipfs add: %s", err)
+	}
+	return "/ipfs/" + added.Hash, nil
+}
+
+func (b *BIOS) publishKickstartFile(ksdata string) (string, error) {
+	path := b.Config.KickstartPublish.File.Path
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+
+	// Written aside then renamed, so whoever polls the file never reads half of it.
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(ksdata+"\n"), 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+var kickstartPublishClient = &http.Client{Timeout: 30 * time.Second}
@@ -3,10 +3,13 @@ package main
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"strings"
+	"time"
 
+	"github.com/bronze1man/go-yaml2json"
 	"github.com/eoscanada/eos-go"
 	"github.com/eoscanada/eos-go/ecc"
 )
@@ -16,9 +19,88 @@ type LaunchData struct {
 	OpeningBalancesSnapshotHash string            `json:"opening_balances_snapshot_hash"`
 	ContractHashes              map[string]string `json:"contract_hashes"`
 
+	// LaunchEthereumBlockHeight and LaunchNISTBeaconTime are used
+	// when `shuffle_entropy` lists "ethereum" or "nist_beacon".
+	LaunchEthereumBlockHeight uint64    `json:"launch_eth_block_height"`
+	LaunchNISTBeaconTime      time.Time `json:"launch_nist_beacon_time"`
+	// ShuffleEntropy lists the randomness sources mixed into the
+	// shuffle seed, defaults to "bitcoin". See `shuffleentropy.go`.
+	ShuffleEntropy []string `json:"shuffle_entropy"`
+
+	// EOSBios pins the eos-bios build every participant must run. See `release.go`.
+	EOSBios struct {
+		Version string `json:"version"`
+		Commit  string `json:"commit"`
+		// BinaryHashes are the sha256 of release binaries, keyed by "os_arch".
+		BinaryHashes map[string]string `json:"binary_hashes"`
+	} `json:"eos_bios"`
+
+	// ConstitutionHash is the sha256 of the agreed constitution (or
+	// launch agreement) document. It becomes the chain ID, and is
+	// anchored on chain by the `system.anchor_constitution` op.
+	ConstitutionHash string `json:"constitution_hash"`
+
+	// Readiness, when required, restricts the shuffle to producers
+	// who published a signed readiness attestation within the
+	// window. See `readiness.go`.
+	Readiness struct {
+		Required bool `json:"required"`
+		// AttestationsHash pins the list of received attestations, so everyone derives the same pool.
+		AttestationsHash string    `json:"attestations_hash"`
+		WindowStart      time.Time `json:"window_start"`
+		WindowEnd        time.Time `json:"window_end"`
+	} `json:"readiness"`
+
+	// ShuffleAlgorithm picks one of the `Shuffler`s in `shuffle.go`,
+	// defaults to `fisher_yates`.
+	ShuffleAlgorithm string `json:"shuffle_algorithm"`
+
+	// Timeline holds the agreed deadlines of the launch, shown to
+	// each role by the banner. See `banner.go`.
+	Timeline struct {
+		// KickstartBy is when the boot node must have published the kickstart data.
+		KickstartBy time.Time `json:"kickstart_by"`
+		// ValidationBy is when the ABPs must have validated the chain.
+		ValidationBy time.Time `json:"validation_by"`
+		// PublicJoinAt is when the other participants can expect to join.
+		PublicJoinAt time.Time `json:"public_join_at"`
+	} `json:"timeline"`
+
 	BootSequence []*OperationType `json:"boot_sequence"`
 
+	// ScheduledActions extend the launch past block 1: governance
+	// steps run by the monitor once their condition is met. See
+	// `stage2.go`.
+	ScheduledActions []*ScheduledAction `json:"scheduled_actions"`
+
+	// ProvenanceTags appends a short marker (launch file hash
+	// prefix, step index) to the memos of boot-generated actions,
+	// and adds it as a nonce to every boot transaction, for the
+	// actions without a memo. See `provenance.go`.
+	ProvenanceTags bool `json:"provenance_tags"`
+
+	// DeterministicTransactions makes the boot transactions
+	// byte-identical across boot nodes given the same inputs. See
+	// `sequence.go`.
+	DeterministicTransactions bool `json:"deterministic_transactions"`
+
 	Producers []*ProducerDef `json:"producers"`
+
+	// GenesisAccounts are non-producer accounts created by the
+	// `genesis.create_accounts` op. See `genesisaccounts.go`.
+	GenesisAccounts []*GenesisAccount `json:"genesis_accounts"`
+
+	// SnapshotSegments apply other authorities than the holder's key
+	// to some snapshot accounts. See `snapshotsegments.go`.
+	SnapshotSegments []*SnapshotSegment `json:"snapshot_segments"`
+
+	// FlagListHash pins the signed list of flagged snapshot
+	// addresses, see `flaglist.go`.
+	FlagListHash string `json:"flag_list_hash"`
+	flagList     *FlagList
+
+	// fileHash is the sha256 of the launch file, as loaded.
+	fileHash string
 }
 
 type ProducerDef struct {
@@ -47,12 +129,26 @@ type ProducerDef struct {
 	KeybaseUser  string `json:"keybase_user"`
 	PGPPublicKey string `json:"pgp_public_key"`
 
+	// Email receives the PGP-encrypted payloads of the `email`
+	// transport, encrypted to PGPPublicKey. See `email.go`.
+	Email string `json:"email"`
+
 	// OrganizationName is the block producer's name in plain text.
 	OrganizationName string `json:"organization_name"`
 
 	// Timezone, from https://en.wikipedia.org/wiki/List_of_tz_database_time_zones (column TZ)
 	Timezone string `json:"timezone"`
 
+	// Region (like "eu-west") and LatencyGroup (producers with
+	// low-latency links between them, like "frankfurt") make early
+	// peering prefer nearby producers. See `mesh.go`.
+	Region       string `json:"region"`
+	LatencyGroup string `json:"latency_group"`
+
+	// APIEndpoint is the producer's public API, checked by `eos-bios
+	// verify-rollout` once the chain is launched.
+	APIEndpoint string `json:"api_endpoint"`
+
 	// Candidate producers are better off specifying a few URLs and social media properties, to avoid a single point of failure if they need to communicate with the world.
 	URLs []string `json:"urls"`
 
@@ -65,17 +161,33 @@ func (p *ProducerDef) String() string {
 
 // snapshotPath, codePath, abiPath string
 func loadLaunchFile(filename string, config *Config) (out *LaunchData, err error) {
-	cnt, err := ioutil.ReadFile(filename)
+	cnt, err := readMaybeEncrypted(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := yamlUnmarshal(cnt, &out); err != nil {
+	jsonCnt, err := yaml2json.Convert(cnt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(jsonCnt, &out); err != nil {
+		return nil, err
+	}
+	if err := checkUnknownFields("launch data", jsonCnt, out, config.strictDecoding()); err != nil {
 		return nil, err
 	}
 
-	if out.LaunchBitcoinBlockHeight == 0 {
-		return nil, fmt.Errorf("launch_btc_block_height unspecified (or 0)")
+	launchHash := sha256.Sum256(cnt)
+	out.fileHash = hex.EncodeToString(launchHash[:])
+
+	if err := validateShuffleEntropy(out); err != nil {
+		return nil, err
+	}
+
+	if config.OpeningBalances.DeltaPath != "" {
+		if err := applySnapshotDeltaFile(config, out.OpeningBalancesSnapshotHash); err != nil {
+			return nil, err
+		}
 	}
 
 	snapshotHash, err := hashFile(config.OpeningBalances.SnapshotPath)
@@ -83,12 +195,36 @@ func loadLaunchFile(filename string, config *Config) (out *LaunchData, err error
 		return nil, err
 	}
 
-	fmt.Printf("Hash of %q: %s\n", config.OpeningBalances.SnapshotPath, snapshotHash)
+	info.Printf("Hash of %q: %s\n", config.OpeningBalances.SnapshotPath, snapshotHash)
 
 	if snapshotHash != out.OpeningBalancesSnapshotHash {
 		return nil, fmt.Errorf("snapshot hash doesn't match launch data")
 	}
 
+	if out.ConstitutionHash != "" {
+		if config.ConstitutionPath == "" {
+			return nil, fmt.Errorf("launch data anchors a constitution, but constitution_path isn't configured")
+		}
+
+		constitutionHash, err := hashFile(config.ConstitutionPath)
+		if err != nil {
+			return nil, err
+		}
+
+		info.Printf("Hash of %q: %s\n", config.ConstitutionPath, constitutionHash)
+
+		if constitutionHash != out.ConstitutionHash {
+			return nil, fmt.Errorf("constitution hash doesn't match launch data")
+		}
+	}
+
+	if out.FlagListHash != "" {
+		out.flagList, err = loadFlagList(out, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	for name, loc := range config.Contracts {
 		hash := out.ContractHashes[name]
 
@@ -97,13 +233,25 @@ func loadLaunchFile(filename string, config *Config) (out *LaunchData, err error
 			return nil, fmt.Errorf("error hashing %q contract's code + abi: %s", name, err)
 		}
 
-		fmt.Printf("Hash of %q and %q: %s\n", loc.CodePath, loc.ABIPath, codeHash)
+		info.Printf("Hash of %q and %q: %s\n", loc.CodePath, loc.ABIPath, codeHash)
 
 		if codeHash != hash {
 			return nil, fmt.Errorf("%q contract's code hash don't match", name)
 		}
 	}
 
+	if algo := out.ShuffleAlgorithm; algo != "" && shufflersRegistry[algo] == nil {
+		return nil, fmt.Errorf("shuffle_algorithm %q invalid, use one of: %q", algo, shuffleAlgorithms())
+	}
+
+	if err := validateGenesisAccounts(out); err != nil {
+		return nil, err
+	}
+
+	if err := validateSnapshotSegments(out); err != nil {
+		return nil, err
+	}
+
 	// Check duplicate entries in `launch.yaml`, fail immediately.
 	//    Check the `account_name`
 
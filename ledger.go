@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/system"
+	"github.com/eoscanada/eos-go/token"
+	"golang.org/x/crypto/openpgp"
+)
+
+// Ledger is an append-only journal, one JSON object per line, of
+// everything the boot node pushed. It lives in the run directory,
+// and allows replaying a boot against a fresh node.
+//
+// Entries are chained: each holds the hash of the previous line (of
+// its JSON, before encryption), and the hash of the last one ends up
+// in the run report, so tampering with the journal is detected when
+// it is cross-checked. With `encrypt_ledger`, each line is
+// PGP-encrypted with the passphrase of the encrypted config files
+// (see `encrypted.go`), as "pgp:<base64>".
+type Ledger struct {
+	file    *os.File
+	encrypt bool
+
+	// lock guards appends, from the pusher, the confirmation worker
+	// and operator actions.
+	lock sync.Mutex
+	head string
+}
+
+const ledgerEncryptedPrefix = "pgp:"
+
+type LedgerEntry struct {
+	Type string    `json:"type"` // "start", "chunk", "end", "revoke", "scheduled" or "operator"
+	Time time.Time `json:"time"`
+
+	// start
+	LaunchHash          string `json:"launch_hash,omitempty"`
+	GenesisJSON         string `json:"genesis_json,omitempty"`
+	EphemeralPrivateKey string `json:"ephemeral_private_key,omitempty"`
+	// EntropyCommitments commit to the sources mixed into the
+	// ephemeral key, see `entropy.go`.
+	EntropyCommitments []*EntropyCommitment `json:"entropy_commitments,omitempty"`
+
+	// chunk
+	Step          int           `json:"step"`
+	Op            string        `json:"op,omitempty"`
+	Chunk         int           `json:"chunk"`
+	TransactionID string        `json:"transaction_id,omitempty"`
+	BlockNum      uint32        `json:"block_num,omitempty"`
+	ChunkSize     int           `json:"chunk_size,omitempty"`
+	ChunkBytes    int           `json:"chunk_bytes,omitempty"`
+	Actions       []*eos.Action `json:"actions,omitempty"`
+	// ActionsHash is the sha256 of the actions' JSON, as pushed.
+	ActionsHash string `json:"actions_hash,omitempty"`
+
+	// end
+	StateAccounts []eos.AccountName `json:"state_accounts,omitempty"`
+	StateHash     string            `json:"state_hash,omitempty"`
+
+	// operator
+	Operator *OperatorAction `json:"operator,omitempty"`
+
+	// PrevHash is the sha256 of the previous entry's JSON line.
+	PrevHash string `json:"prev_hash,omitempty"`
+}
+
+func OpenLedger(runDir string, encrypt bool) (*Ledger, error) {
+	if err := os.MkdirAll(runDir, 0700); err != nil {
+		return nil, err
+	}
+
+	filename := filepath.Join(runDir, "ledger.jsonl")
+	_, head, err := readLedgerChain(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	fl, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Ledger{file: fl, encrypt: encrypt, head: head}, nil
+}
+
+// Append writes `entry`, and syncs it to disk right away.
+func (l *Ledger) Append(entry *LedgerEntry) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	entry.PrevHash = l.head
+
+	cnt, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	head := sha256Hex(cnt)
+
+	if l.encrypt {
+		cnt, err = encryptLedgerLine(cnt)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := l.file.Write(append(cnt, '\n')); err != nil {
+		return err
+	}
+	l.head = head
+
+	return l.file.Sync()
+}
+
+// Head is the hash of the last entry, recorded in the run report.
+func (l *Ledger) Head() string {
+	return l.head
+}
+
+func (l *Ledger) Close() error {
+	return l.file.Close()
+}
+
+// ReadLedger reads all entries, decrypting them as needed, and fails
+// if the chain of hashes is broken.
+func ReadLedger(filename string) (out []*LedgerEntry, err error) {
+	out, _, err = readLedgerChain(filename)
+	return
+}
+
+// readLedgerChain also returns the hash of the last entry. Ledgers
+// written before entries were chained are accepted, until the first
+// chained entry.
+func readLedgerChain(filename string) (out []*LedgerEntry, head string, err error) {
+	fl, err := os.Open(filename)
+	if err != nil {
+		return nil, "", err
+	}
+	defer fl.Close()
+
+	chained := false
+	scanner := bufio.NewScanner(fl)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if bytes.HasPrefix(line, []byte(ledgerEncryptedPrefix)) {
+			line, err = decryptLedgerLine(line)
+			if err != nil {
+				return nil, "", fmt.Errorf("ledger line %d: %s", len(out)+1, err)
+			}
+		}
+
+		entry := &LedgerEntry{}
+		if err := json.Unmarshal(line, entry); err != nil {
+			return nil, "", fmt.Errorf("ledger line %d: %s", len(out)+1, err)
+		}
+
+		if entry.PrevHash != "" {
+			chained = true
+		}
+		if entry.PrevHash != head && (chained || len(out) == 0) {
+			return nil, "", fmt.Errorf("ledger line %d: doesn't chain to the previous entry, the ledger was tampered with", len(out)+1)
+		}
+
+		head = sha256Hex(line)
+		out = append(out, entry)
+	}
+
+	return out, head, scanner.Err()
+}
+
+func encryptLedgerLine(plain []byte) ([]byte, error) {
+	passphrase, err := getPassphrase("the ledger")
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w, err := openpgp.SymmetricallyEncrypt(&buf, passphrase, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plain); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return []byte(ledgerEncryptedPrefix + base64.StdEncoding.EncodeToString(buf.Bytes())), nil
+}
+
+func decryptLedgerLine(line []byte) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(string(bytes.TrimPrefix(line, []byte(ledgerEncryptedPrefix))))
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := 0
+	prompt := func(keys []openpgp.Key, symmetric bool) ([]byte, error) {
+		attempts++
+		if attempts > 3 {
+			return nil, fmt.Errorf("wrong passphrase")
+		}
+		if attempts > 1 {
+			cachedPassphrase = nil
+		}
+		return getPassphrase("the ledger")
+	}
+
+	md, err := openpgp.ReadMessage(bytes.NewReader(raw), nil, prompt, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting: %s", err)
+	}
+	return ioutil.ReadAll(md.UnverifiedBody)
+}
+
+// ledgerAppend is a no-op when no run directory is configured.
+func (b *BIOS) ledgerAppend(entry *LedgerEntry) error {
+	if b.Ledger == nil {
+		return nil
+	}
+	return b.Ledger.Append(entry)
+}
+
+// StateAccounts lists the accounts whose state is summarized by the
+// state hash: all contracts that were called, the accounts created
+// and those receiving or sending tokens, and the accounts defined in
+// the launch data.
+func (b *BIOS) StateAccounts(actions []*eos.Action) (out []eos.AccountName) {
+	seen := map[eos.AccountName]bool{AN("eosio"): true}
+	for _, act := range actions {
+		seen[act.Account] = true
+
+		switch {
+		case act.Account == AN("eosio") && act.Name == ActN("newaccount"):
+			var newAccount system.NewAccount
+			if err := decodeActionData(act, &newAccount); err == nil {
+				seen[newAccount.Name] = true
+			}
+		case act.Account == AN("eosio.token") && act.Name == ActN("issue"):
+			var issue token.Issue
+			if err := decodeActionData(act, &issue); err == nil {
+				seen[issue.To] = true
+			}
+		case act.Account == AN("eosio.token") && act.Name == ActN("transfer"):
+			var transfer token.Transfer
+			if err := decodeActionData(act, &transfer); err == nil {
+				seen[transfer.From] = true
+				seen[transfer.To] = true
+			}
+		}
+	}
+	for _, prod := range b.ShuffledProducers {
+		seen[prod.AccountName] = true
+	}
+	for _, acct := range b.LaunchData.GenesisAccounts {
+		seen[acct.AccountName] = true
+	}
+
+	for name := range seen {
+		out = append(out, name)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return
+}
+
+// computeStateHash hashes the permissions, code hash and token
+// balance of each account, in order.
+func computeStateHash(api *eos.API, accounts []eos.AccountName) (string, error) {
+	h := sha256.New()
+	for _, name := range accounts {
+		acct, err := api.GetAccount(name)
+		if err != nil {
+			return "", fmt.Errorf("get account %s: %s", name, err)
+		}
+		perms, _ := json.Marshal(acct.Permissions)
+
+		code, err := api.GetCode(name)
+		if err != nil {
+			return "", fmt.Errorf("get code %s: %s", name, err)
+		}
+
+		// Only the balance rows themselves are hashed, not the paging metadata.
+		balances, err := api.GetTableRows(eos.GetTableRowsRequest{JSON: true, Code: "eosio.token", Scope: string(name), Table: "accounts"})
+		if err != nil {
+			return "", fmt.Errorf("get balances %s: %s", name, err)
+		}
+
+		fmt.Fprintf(h, "%s:%s:%s:%s\n", name, perms, code.CodeHash, balances.Rows)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
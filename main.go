@@ -13,40 +13,138 @@ import (
 var localConfig = flag.String("local-config", "", "Local .yaml configuration file.")
 var launchData = flag.String("launch-data", "launch.yaml", "Path to a launch.yaml file, your community-agreed ignition configuration.")
 var versionFlag = flag.Bool("version", false, "Show the version and quit. Hint hint, it's: "+version)
+var quietFlag = flag.Bool("quiet", false, "Only print milestones and errors to the console.")
+var verboseFlag = flag.Bool("v", false, "Verbose console output.")
+var veryVerboseFlag = flag.Bool("vv", false, "Very verbose console output, including per-row details.")
+var logFilePath = flag.String("log-file", "", "Path to a structured (JSON lines) log file, receiving all output regardless of console verbosity.")
+var passphraseFile = flag.String("passphrase-file", "", "File holding the passphrase unlocking encrypted config and launch files (otherwise read from $EOS_BIOS_PASSPHRASE, or prompted).")
+var decryptionKeyPath = flag.String("decryption-key", "", "Armored PGP private key to decrypt config and launch files encrypted to a key rather than a passphrase.")
+var networkFlag = flag.String("network", "", "Name of the network to participate in, among the `networks` of your local config.")
+var importBundleFlag = flag.String("import-bundle", "", "Import a starter bundle from the launch coordinator into --local-config, decrypting it with --decryption-key (see `eos-bios bundle-configs`).")
+var dryRunFlag = flag.Bool("dry-run", false, "Build every action of the boot sequence, and print what would be pushed, without pushing anything.")
+var resumeFlag = flag.Bool("resume", false, "Resume the interrupted boot recorded in the run_dir, possibly restored on another machine, against the same nodeos.")
 var version string
+var commit string
+var date string
 
 func main() {
 	flag.Parse()
 
 	if *versionFlag {
 		fmt.Println("eos-bios version:", version)
+		fmt.Println("commit:", commit)
+		fmt.Println("built:", date)
 		os.Exit(0)
 	}
 
+	if err := setupOutput(*quietFlag, *verboseFlag, *veryVerboseFlag, *logFilePath); err != nil {
+		log.Fatalln("log file:", err)
+	}
+
+	switch flag.Arg(0) {
+	case "keys":
+		if err := runKeys(flag.Args()[1:]); err != nil {
+			log.Fatalln("keys:", err)
+		}
+		return
+	case "replay-ledger":
+		if err := runReplayLedger(flag.Args()[1:]); err != nil {
+			log.Fatalln("replay-ledger:", err)
+		}
+		return
+	case "push":
+		if err := runPush(flag.Args()[1:]); err != nil {
+			log.Fatalln("push:", err)
+		}
+		return
+	case "report-diff":
+		if err := runReportDiff(flag.Args()[1:]); err != nil {
+			log.Fatalln("report-diff:", err)
+		}
+		return
+	case "shuffle-vectors":
+		if err := runShuffleVectors(flag.Args()[1:]); err != nil {
+			log.Fatalln("shuffle-vectors:", err)
+		}
+		return
+	}
+
+	if *importBundleFlag != "" {
+		if err := importBundle(*importBundleFlag, *launchData, *localConfig); err != nil {
+			log.Fatalln("import-bundle:", err)
+		}
+		return
+	}
+
 	if *localConfig == "" || *launchData == "" {
 		log.Fatalln("missing --launch-data or --local-config")
 	}
 
-	config, err := LoadLocalConfig(*localConfig)
+	config, err := LoadLocalConfig(*localConfig, *networkFlag)
 	if err != nil {
 		log.Fatalln("local config load error:", err)
 	}
+	setOperatorName(config.Operator.Name)
+
+	if flag.Arg(0) == "history" {
+		if err := runHistory(config, flag.Args()[1:]); err != nil {
+			log.Fatalln("history:", err)
+		}
+		return
+	}
 
-	launch, err := loadLaunchFile(*launchData, config)
+	launchDataPath := *launchData
+	if config.LaunchData != "" && !flagPassed("launch-data") {
+		launchDataPath = config.LaunchData
+	}
+	if config.Network != "" {
+		milestone.Printf("Participating in network %q, with launch data %q\n", config.Network, launchDataPath)
+	}
+
+	config.Resume = *resumeFlag
+
+	launch, err := loadLaunchFile(launchDataPath, config)
 	if err != nil {
 		log.Fatalln("launch data error:", err)
 	}
 
-	_ = launch.LaunchBitcoinBlockHeight
-	// Implement the Bitcoin block fetcher, and merkle root checker..
-	//    Implement 3 sources, connect to BTC node, use one of the block explorers, check their APIs.
-	// Seed `rand.Seed`
+	if err := verifyReleasePin(launch, config); err != nil {
+		log.Fatalln("release pin:", err)
+	}
 
-	// chainID will become the HASH of the Constitution? We could
-	// start with a sample constitution and hash it ? waddayouthink ?
-	chainID := make([]byte, 32, 32)
+	if err := checkReleaseUpdate(config); err != nil {
+		log.Fatalln("release update:", err)
+	}
+
+	if flag.Arg(0) == "registry" {
+		if err := runRegistry(launch, config, flag.Args()[1:]); err != nil {
+			log.Fatalln("registry:", err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "bundle-configs" {
+		if err := runBundleConfigs(launch, config, flag.Args()[1:]); err != nil {
+			log.Fatalln("bundle-configs:", err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "attest-ready" {
+		if err := runAttestReady(config, launch); err != nil {
+			log.Fatalln("attest-ready:", err)
+		}
+		return
+	}
+
+	chainID, err := constitutionChainID(launch)
+	if err != nil {
+		log.Fatalln("launch data error:", err)
+	}
 
 	api := eos.New(config.Producer.apiAddressURL, chainID)
+	var apiEndpoints []*apiEndpoint
+	api.HttpClient, apiEndpoints, err = newAPIClient(config)
 	if err != nil {
 		log.Fatalln("producer node error:", err)
 	}
@@ -59,22 +157,194 @@ func main() {
 		log.Fatalln("Failed loading snapshot csv:", err)
 	}
 
+	snapshotData, err = reconcileGenesisAccounts(launch, snapshotData)
+	if err != nil {
+		log.Fatalln("Failed reconciling genesis accounts:", err)
+	}
+
+	if err := checkSymbols(launch, snapshotData); err != nil {
+		log.Fatalln("Token symbols mismatch:", err)
+	}
+
+	if sampling := config.Debug.SnapshotSampling; sampling.Mode != "" || sampling.ScaleBalances != 0 {
+		snapshotData, err = snapshotData.Sample(sampling)
+		if err != nil {
+			log.Fatalln("Failed sampling snapshot:", err)
+		}
+		info.Printf("DEBUG: sampled snapshot down to %d rows (mode %q)\n", len(snapshotData), sampling.Mode)
+	}
+
 	// Start BIOS
 	bios := NewBIOS(launch, config, snapshotData, api)
+	bios.PushLanes = newPushLanes(api, apiEndpoints)
+
+	bios.Transport, err = newTransport(config, launch)
+	if err != nil {
+		log.Fatalln("transport:", err)
+	}
+
+	if config.RunDir != "" {
+		bios.Ledger, err = OpenLedger(config.RunDir, config.EncryptLedger)
+		if err != nil {
+			log.Fatalln("Failed opening ledger:", err)
+		}
+		defer bios.Ledger.Close()
+		bios.ledgerOperatorActions()
+
+		bios.HookQueue, err = OpenHookQueue(config.RunDir, bios.deliverQueuedHook, bios.queuedHookBatchSize)
+		if err != nil {
+			log.Fatalln("Failed opening hook queue:", err)
+		}
+	}
 
-	// FIXME: replace by the BTC data.
-	err = bios.ShuffleProducers([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, time.Now().UTC())
+	if launch.Readiness.Required {
+		bios.ReadyAccounts, err = loadReadyAccounts(launch, config.Readiness.AttestationsPath)
+		if err != nil {
+			log.Fatalln("Failed loading readiness attestations:", err)
+		}
+	}
+
+	if flag.Arg(0) == "revoke" {
+		if err := bios.RunRevoke(flag.Args()[1:]); err != nil {
+			log.Fatalln("revoke:", err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "export-authorities" {
+		if err := bios.runExportAuthorities(flag.Args()[1:]); err != nil {
+			log.Fatalln("export-authorities:", err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "self-test" {
+		if err := bios.RunSelfTest(); err != nil {
+			log.Fatalln("self-test:", err)
+		}
+		return
+	}
+
+	// Only a boot prebuilds, not `describe`, `observe`, `verify-rollout` nor a dry run.
+	if flag.Arg(0) == "" && !*dryRunFlag {
+		if err := bios.startPrebuild(); err != nil {
+			log.Fatalln("prebuild:", err)
+		}
+	}
+
+	seed := &entropyValue{Value: make([]byte, 32), Time: time.Now().UTC()}
+	if !config.Debug.NoShuffle {
+		// `describe`, `cleos-script`, `shell` and `--dry-run` can be run before the seed is known.
+		inspecting := flag.Arg(0) == "describe" || flag.Arg(0) == "cleos-script" || flag.Arg(0) == "shell" || *dryRunFlag
+		fetched, err := bios.fetchShuffleEntropy(!inspecting)
+		if err == nil {
+			seed = fetched
+		} else if inspecting {
+			milestone.Printf("Shuffle entropy not available yet (%s), using a zero seed\n", err)
+		} else {
+			log.Fatalln("Failed fetching the shuffle entropy:", err)
+		}
+	}
+
+	err = bios.ShuffleProducers(seed.Value, seed.Time)
 	if err != nil {
 		log.Fatalln("Failed shuffling:", err)
 	}
 
+	if flag.Arg(0) == "describe" {
+		bios.Describe()
+		return
+	}
+
+	if flag.Arg(0) == "cleos-script" {
+		if err := bios.RunCleosScript(flag.Args()[1:]); err != nil {
+			log.Fatalln("cleos-script:", err)
+		}
+		return
+	}
+
+	if *dryRunFlag {
+		if err := bios.RunDryRun(); err != nil {
+			log.Fatalln("dry run:", err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "sign-offline" {
+		if err := bios.RunSignOffline(flag.Args()[1:]); err != nil {
+			log.Fatalln("sign-offline:", err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "shell" {
+		if err := bios.RunShell(); err != nil {
+			log.Fatalln("shell:", err)
+		}
+		return
+	}
+
+	if config.Observer.APIAddress != "" {
+		bios.ValidationAPI, err = newObserverAPI(config, chainID)
+		if err != nil {
+			log.Fatalln("observer:", err)
+		}
+	}
+
+	if flag.Arg(0) == "observe" {
+		if err := bios.RunObserver(); err != nil {
+			log.Fatalln("observe:", err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "cleanup" {
+		if err := bios.RunCleanup(flag.Args()[1:]); err != nil {
+			log.Fatalln("cleanup:", err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "state-manifest" {
+		if err := bios.RunStateManifest(flag.Args()[1:]); err != nil {
+			log.Fatalln("state-manifest:", err)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "verify-rollout" {
+		if err := bios.RunVerifyRollout(); err != nil {
+			log.Fatalln("verify-rollout:", err)
+		}
+		return
+	}
+
 	if err = bios.setMyProducerDefs(); err != nil {
 		log.Fatalln("Failed to get my producer definition:", err)
 	}
 
-	if err := bios.Run(); err != nil {
-		log.Fatalf("ERROR RUNNING BIOS: %s", err)
+	startedAt := time.Now()
+	runErr := bios.Run()
+
+	if err := bios.WriteRunReport(startedAt, runErr); err != nil {
+		log.Println("Failed writing run report:", err)
 	}
 
-	fmt.Printf("Done at UTC %s\n", time.Now().UTC())
+	if runErr != nil {
+		if err := bios.RunFailureCleanup(runErr); err != nil {
+			log.Println("Failed cleaning up:", err)
+		}
+		log.Fatalf("ERROR RUNNING BIOS: %s", runErr)
+	}
+
+	milestone.Printf("Done at UTC %s\n", time.Now().UTC())
+}
+
+func flagPassed(name string) (found bool) {
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return
 }
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/openpgp/clearsign"
+)
+
+// At the end of its stage 1, the boot node produces the boot
+// manifest: a verifiable record of the launch, with every step and
+// its transaction IDs, the contracts' hashes (from the launch data,
+// and as set on chain), the snapshot hash, the genesis and the
+// shuffle seed. It's clear-signed with our PGP key (per
+// `pgp.program`), written to `run_dir/boot-manifest.asc`, and
+// published through the `publish_boot_manifest` hook and the
+// transport.
+
+type BootManifest struct {
+	LaunchHash   string          `json:"launch_hash"`
+	Producer     string          `json:"producer"`
+	Timestamp    time.Time       `json:"timestamp"`
+	GenesisJSON  string          `json:"genesis_json"`
+	SnapshotHash string          `json:"snapshot_hash"`
+	ShuffleSeed  ManifestSeed    `json:"shuffle_seed"`
+	Steps        []*ManifestStep `json:"steps"`
+	// ContractHashes are the launch data's, OnChainCodeHashes the
+	// chain's `code_hash` of each account a contract was set on.
+	ContractHashes    map[string]string `json:"contract_hashes"`
+	OnChainCodeHashes map[string]string `json:"on_chain_code_hashes"`
+	StateHash         string            `json:"state_hash,omitempty"`
+	StateManifestRoot string            `json:"state_manifest_root,omitempty"`
+}
+
+type ManifestSeed struct {
+	MerkleRoot string    `json:"merkle_root"`
+	BlockTime  time.Time `json:"block_time"`
+}
+
+type ManifestStep struct {
+	Step           int      `json:"step"`
+	Op             string   `json:"op"`
+	Label          string   `json:"label"`
+	TransactionIDs []string `json:"transaction_ids"`
+}
+
+func (b *BIOS) buildBootManifest(genesisJSON string) *BootManifest {
+	manifest := &BootManifest{
+		LaunchHash:   b.LaunchData.fileHash,
+		Producer:     b.Config.Producer.MyAccount,
+		Timestamp:    time.Now().UTC().Truncate(time.Second),
+		GenesisJSON:  genesisJSON,
+		SnapshotHash: b.LaunchData.OpeningBalancesSnapshotHash,
+		ShuffleSeed: ManifestSeed{
+			MerkleRoot: hex.EncodeToString(b.ShuffleBlock.MerkleRoot),
+			BlockTime:  b.ShuffleBlock.Time.UTC(),
+		},
+		ContractHashes:    b.LaunchData.ContractHashes,
+		OnChainCodeHashes: map[string]string{},
+		StateHash:         b.Report.StateHash,
+		StateManifestRoot: b.Report.StateManifestRoot,
+	}
+
+	for idx, step := range b.LaunchData.BootSequence {
+		entry := &ManifestStep{Step: idx, Op: step.Op, Label: step.Label}
+		for _, tx := range b.Report.Transactions {
+			if tx.Step == idx {
+				entry.TransactionIDs = append(entry.TransactionIDs, tx.TransactionID)
+			}
+		}
+		manifest.Steps = append(manifest.Steps, entry)
+
+		if setCode, ok := step.Data.(*OpSetCode); ok {
+			code, err := b.API.GetCode(setCode.Account)
+			if err != nil {
+				info.Printf("WARNING: boot manifest: get code of %s: %s\n", setCode.Account, err)
+				continue
+			}
+			manifest.OnChainCodeHashes[string(setCode.Account)] = code.CodeHash
+		}
+	}
+
+	return manifest
+}
+
+// publishBootManifest signs and publishes the boot manifest.
+func (b *BIOS) publishBootManifest(genesisJSON string) error {
+	cnt, err := json.MarshalIndent(b.buildBootManifest(genesisJSON), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	signed, err := b.clearSign(cnt)
+	if err != nil {
+		return fmt.Errorf("signing: %s", err)
+	}
+
+	if b.Config.RunDir != "" {
+		filename := filepath.Join(b.Config.RunDir, "boot-manifest.asc")
+		if err := ioutil.WriteFile(filename, []byte(signed), 0644); err != nil {
+			return err
+		}
+		info.Println("Boot manifest written to", filename)
+	}
+
+	if err := b.publish("boot_manifest", signed); err != nil {
+		info.Println("WARNING: failed publishing the boot manifest:", err)
+	}
+	return b.DispatchPublishBootManifest(signed)
+}
+
+// clearSign signs `cnt` with our PGP key, per `pgp.program`. Without
+// one, the manifest is published unsigned.
+func (b *BIOS) clearSign(cnt []byte) (string, error) {
+	switch b.Config.PGP.Program {
+	case "gpg":
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(b.gpgPath(), "--batch", "--yes", "--clearsign")
+		cmd.Stdin = bytes.NewReader(cnt)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("gpg clearsign: %s: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return stdout.String(), nil
+
+	case "native":
+		keyring, err := readArmoredKeyFile(b.Config.PGP.PrivateKeyPath)
+		if err != nil {
+			return "", err
+		}
+		var out bytes.Buffer
+		plain, err := clearsign.Encode(&out, keyring[0].PrivateKey, nil)
+		if err != nil {
+			return "", err
+		}
+		if _, err := plain.Write(cnt); err != nil {
+			return "", err
+		}
+		if err := plain.Close(); err != nil {
+			return "", err
+		}
+		return out.String(), nil
+
+	case "":
+		milestone.Println("WARNING: no `pgp.program` configured, the boot manifest is NOT signed")
+		return string(cnt), nil
+	}
+
+	return "", fmt.Errorf("unknown pgp.program %q, use `gpg` or `native`", b.Config.PGP.Program)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// matrixKindField tags our messages in a room, so we can pick them
+// out of the conversation.
+const matrixKindField = "io.eos-bios.kind"
+
+// matrixTransport posts to, and reads from, a Matrix room. For end-to-end
+// encrypted rooms, point `homeserver` to an E2E-aware proxy like
+// pantalaimon, which encrypts and decrypts on our behalf.
+type matrixTransport struct {
+	homeserver  string
+	roomID      string
+	accessToken string
+	client      *http.Client
+	since       string
+}
+
+func newMatrixTransport(config *Config, launch *LaunchData) (Transport, error) {
+	conf := config.Transport.Matrix
+	if conf.Homeserver == "" || conf.RoomID == "" || conf.AccessTokenPath == "" {
+		return nil, fmt.Errorf("the matrix transport requires homeserver, room_id and access_token_path")
+	}
+
+	token, err := ioutil.ReadFile(conf.AccessTokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading matrix access token: %s", err)
+	}
+
+	return &matrixTransport{
+		homeserver:  strings.TrimRight(conf.Homeserver, "/"),
+		roomID:      conf.RoomID,
+		accessToken: strings.TrimSpace(string(token)),
+		client:      &http.Client{Timeout: 60 * time.Second},
+	}, nil
+}
+
+func (t *matrixTransport) Publish(kind, payload string) error {
+	body, _ := json.Marshal(map[string]string{
+		"msgtype":       "m.text",
+		"body":          payload,
+		matrixKindField: kind,
+	})
+
+	txnID := fmt.Sprintf("eos-bios-%d", time.Now().UnixNano())
+	path := fmt.Sprintf("/_matrix/client/r0/rooms/%s/send/m.room.message/%s", url.PathEscape(t.roomID), txnID)
+
+	return t.call("PUT", path, body, nil)
+}
+
+func (t *matrixTransport) Receive(kind string) ([]string, error) {
+	filter, _ := json.Marshal(map[string]interface{}{
+		"room": map[string]interface{}{
+			"rooms":    []string{t.roomID},
+			"timeline": map[string]interface{}{"limit": 100},
+		},
+	})
+
+	for {
+		params := url.Values{}
+		params.Set("timeout", "30000")
+		params.Set("filter", string(filter))
+		if t.since != "" {
+			params.Set("since", t.since)
+		}
+
+		var resp struct {
+			NextBatch string `json:"next_batch"`
+			Rooms     struct {
+				Join map[string]struct {
+					Timeline struct {
+						Events []struct {
+							Type    string                 `json:"type"`
+							Content map[string]interface{} `json:"content"`
+						} `json:"events"`
+					} `json:"timeline"`
+				} `json:"join"`
+			} `json:"rooms"`
+		}
+		if err := t.call("GET", "/_matrix/client/r0/sync?"+params.Encode(), nil, &resp); err != nil {
+			return nil, err
+		}
+		t.since = resp.NextBatch
+
+		var out []string
+		for _, event := range resp.Rooms.Join[t.roomID].Timeline.Events {
+			if event.Type != "m.room.message" || event.Content[matrixKindField] != kind {
+				continue
+			}
+			if body, ok := event.Content["body"].(string); ok {
+				out = append(out, body)
+			}
+		}
+
+		if len(out) != 0 {
+			return out, nil
+		}
+	}
+}
+
+func (t *matrixTransport) call(method, path string, body []byte, out interface{}) error {
+	req, err := http.NewRequest(method, t.homeserver+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+t.accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("matrix: %s", err)
+	}
+	defer resp.Body.Close()
+
+	cnt, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("matrix: %s", err)
+	}
+
+	if resp.StatusCode > 299 {
+		return fmt.Errorf("matrix: status code=%d, body=%s", resp.StatusCode, cnt)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(cnt, out)
+}
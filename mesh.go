@@ -0,0 +1,87 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Producers declare a `region` and a `latency_group` in the launch
+// data. Early peering prefers producers of the same latency group,
+// then of the same region, so blocks propagate quickly over short
+// links before crossing oceans.
+
+// peerAffinity ranks how close two producers are expected to be.
+func peerAffinity(a, b *ProducerDef) int {
+	switch {
+	case a.LatencyGroup != "" && a.LatencyGroup == b.LatencyGroup:
+		return 2
+	case a.Region != "" && a.Region == b.Region:
+		return 1
+	}
+	return 0
+}
+
+// preferredPeers orders `candidates` (minus `me`) by affinity, keeping
+// their original (shuffled) order among equals, and returns the first
+// `count` of them.
+func preferredPeers(me *ProducerDef, candidates []*ProducerDef, count int) (out []*ProducerDef) {
+	for _, prod := range candidates {
+		if prod.AccountName != me.AccountName {
+			out = append(out, prod)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return peerAffinity(me, out[i]) > peerAffinity(me, out[j])
+	})
+
+	if len(out) > count {
+		out = out[:count]
+	}
+	return
+}
+
+// peerCount suggests how many peers to connect to: the whole latency
+// group, plus a couple of links out of it, within reason.
+func peerCount(me *ProducerDef, candidates []*ProducerDef) int {
+	group := 0
+	others := 0
+	for _, prod := range candidates {
+		if prod.AccountName == me.AccountName {
+			continue
+		}
+		others++
+		if peerAffinity(me, prod) == 2 {
+			group++
+		}
+	}
+
+	count := group + 2
+	if count < 4 {
+		count = 4
+	}
+	if count > 12 {
+		count = 12
+	}
+	if count > others {
+		count = others
+	}
+	return count
+}
+
+// appointedPeers are the ABPs we should connect to first.
+func (b *BIOS) appointedPeers(me *ProducerDef) []*ProducerDef {
+	var abps []*ProducerDef
+	for i := 1; i < 22 && len(b.ShuffledProducers) > i; i++ {
+		abps = append(abps, b.ShuffledProducers[i])
+	}
+	return preferredPeers(me, abps, peerCount(me, abps))
+}
+
+func producerNames(prods []*ProducerDef) string {
+	var names []string
+	for _, prod := range prods {
+		names = append(names, string(prod.AccountName))
+	}
+	return strings.Join(names, ",")
+}
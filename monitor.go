@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// blocksPerRound is a full schedule of 21 producers, each producing
+// 12 consecutive blocks.
+const blocksPerRound = 21 * 12
+
+// RunMonitor follows the chain after we registered, and tracks
+// block production for each of our identities, including the clones
+// set up by `setMyProducerDefs`. It also watches for producer
+// schedule changes (see `schedule.go`), and runs the launch file's
+// `scheduled_actions` (see `stage2.go`), going on until ours are done.
+func (b *BIOS) RunMonitor() error {
+	var duration time.Duration
+	if b.Config.Monitor.Duration != "" {
+		var err error
+		duration, err = time.ParseDuration(b.Config.Monitor.Duration)
+		if err != nil {
+			return fmt.Errorf("monitor.duration: %s", err)
+		}
+	}
+
+	scheduled, err := newScheduledRunner(b)
+	if err != nil {
+		return err
+	}
+
+	produced := map[eos.AccountName]int{}
+	for _, prod := range b.MyProducerDefs {
+		produced[prod.AccountName] = 0
+	}
+
+	chainInfo, err := b.API.GetInfo()
+	if err != nil {
+		return fmt.Errorf("get info: %s", err)
+	}
+
+	milestone.Printf("Monitoring block production of %d identities for %s\n", len(produced), duration)
+
+	schedule := &scheduleWatch{b: b}
+	voting := &votingTracker{b: b}
+
+	startBlock := chainInfo.HeadBlockNum
+	lastBlock := startBlock
+	lastReport := startBlock
+	deadline := time.Now().Add(duration)
+	if b.Config.Monitor.UntilVotingActivated {
+		milestone.Println("Monitoring until voting activates")
+	}
+
+	for monitoring(deadline, scheduled, b.Config.Monitor.UntilVotingActivated && !voting.activated) {
+		time.Sleep(1 * time.Second)
+
+		chainInfo, err := b.API.GetInfo()
+		if err != nil {
+			verbose.Printf("e")
+			continue
+		}
+
+		for lastBlock < chainInfo.HeadBlockNum {
+			block, err := b.API.GetBlockByNum(lastBlock + 1)
+			if err != nil {
+				verbose.Printf("e")
+				break
+			}
+			lastBlock++
+
+			schedule.onBlock(block)
+
+			if _, found := produced[block.Producer]; found {
+				produced[block.Producer]++
+			}
+		}
+
+		scheduled.onHead(lastBlock)
+
+		if lastBlock-lastReport >= blocksPerRound {
+			lastReport = lastBlock
+			printProduction(produced, (lastBlock-startBlock)/blocksPerRound)
+
+			if err := voting.check(); err != nil {
+				verbose.Println("Voting status unavailable:", err)
+			}
+			if voting.activated {
+				scheduled.onEvent("voting_activated")
+			}
+		}
+
+	}
+
+	printProduction(produced, (lastBlock-startBlock)/blocksPerRound)
+
+	return nil
+}
+
+// monitoring tells whether the monitor goes on: until the deadline,
+// which is already past without `monitor.duration`, and past it while
+// our scheduled actions are pending, or `awaitingVoting` activation.
+func monitoring(deadline time.Time, scheduled *scheduledRunner, awaitingVoting bool) bool {
+	return time.Now().Before(deadline) || scheduled.pending() || awaitingVoting
+}
+
+func printProduction(produced map[eos.AccountName]int, rounds uint32) {
+	var names []string
+	for name := range produced {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	info.Printf("Block production after %d round(s):\n", rounds)
+	for _, name := range names {
+		count := produced[AN(name)]
+		if rounds > 0 && count == 0 {
+			milestone.Printf("- %s: NO BLOCKS produced, check your `producer-name` and `signature-provider` settings\n", name)
+		} else {
+			info.Printf("- %s: %d blocks\n", name, count)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonitoringScheduledWithoutDuration(t *testing.T) {
+	bios := testBIOS(t, `
+producers:
+- account_name: mama
+- account_name: papa
+scheduled_actions:
+- when:
+    block_height: 1000
+  step:
+    op: system.setprods
+    label: Hand over the schedule
+`, `
+producer:
+  my_account: mama
+debug:
+  no_shuffle: true
+`)
+
+	scheduled, err := newScheduledRunner(bios)
+	require.NoError(t, err)
+
+	// No `monitor.duration`: the deadline is now.
+	deadline := time.Now()
+	assert.True(t, monitoring(deadline, scheduled, false))
+
+	scheduled.done[0] = true
+	assert.False(t, monitoring(deadline, scheduled, false))
+	assert.True(t, monitoring(deadline, scheduled, true))
+}
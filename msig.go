@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// Post-launch governance goes through `eosio.msig`, once `eosio` is
+// handed over to the producers. These ops are meant for the
+// `scheduled_actions` of the launch file, see `stage2.go`.
+
+// MsigPropose is the payload of `eosio.msig::propose`. Proposal names
+// are encoded like account names.
+type MsigPropose struct {
+	Proposer     eos.AccountName       `json:"proposer"`
+	ProposalName eos.AccountName       `json:"proposal_name"`
+	Requested    []eos.PermissionLevel `json:"requested"`
+	Transaction  *eos.Transaction      `json:"trx"`
+}
+
+type MsigApprove struct {
+	Proposer     eos.AccountName     `json:"proposer"`
+	ProposalName eos.AccountName     `json:"proposal_name"`
+	Level        eos.PermissionLevel `json:"level"`
+}
+
+type MsigExec struct {
+	Proposer     eos.AccountName `json:"proposer"`
+	ProposalName eos.AccountName `json:"proposal_name"`
+	Executer     eos.AccountName `json:"executer"`
+}
+
+func newMsigAction(name string, actor eos.AccountName, data interface{}) *eos.Action {
+	act := &eos.Action{
+		Account: AN("eosio.msig"),
+		Name:    ActN(name),
+		Authorization: []eos.PermissionLevel{
+			{Actor: actor, Permission: PN("active")},
+		},
+	}
+	act.Data = eos.NewActionData(data)
+	return act
+}
+
+// orMyAccount defaults to our own producer account.
+func orMyAccount(b *BIOS, account eos.AccountName) eos.AccountName {
+	if account == "" {
+		return AN(b.Config.Producer.MyAccount)
+	}
+	return account
+}
+
+//
+
+type OpMsigPropose struct {
+	// Proposer defaults to our own account.
+	Proposer     eos.AccountName
+	ProposalName eos.AccountName `json:"proposal_name"`
+	// Requested is either "abps" for the non-clone Appointed Block
+	// Producers' `active`, or a list of "account@permission".
+	Requested []string
+	// Step is the op whose actions are proposed.
+	Step *OperationType
+	// Expiration of the proposed transaction, defaults to "168h".
+	Expiration string
+}
+
+func (op *OpMsigPropose) Actions(b *BIOS) ([]*eos.Action, error) {
+	if op.Step == nil {
+		return nil, fmt.Errorf("msig.propose: missing step to propose")
+	}
+
+	requested, err := op.requested(b)
+	if err != nil {
+		return nil, err
+	}
+
+	expiration := 7 * 24 * time.Hour
+	if op.Expiration != "" {
+		if expiration, err = time.ParseDuration(op.Expiration); err != nil {
+			return nil, fmt.Errorf("expiration: %s", err)
+		}
+	}
+
+	proposed, err := op.Step.Data.Actions(b)
+	if err != nil {
+		return nil, fmt.Errorf("proposed step %q: %s", op.Step.Op, err)
+	}
+
+	// TaPoS fields are irrelevant to proposed transactions.
+	tx := eos.NewTransaction(proposed, &eos.TxOptions{})
+	tx.SetExpiration(expiration)
+
+	proposer := orMyAccount(b, op.Proposer)
+	return []*eos.Action{newMsigAction("propose", proposer, MsigPropose{
+		Proposer:     proposer,
+		ProposalName: op.ProposalName,
+		Requested:    requested,
+		Transaction:  tx,
+	})}, nil
+}
+
+func (op *OpMsigPropose) requested(b *BIOS) (out []eos.PermissionLevel, err error) {
+	if len(op.Requested) == 1 && op.Requested[0] == "abps" {
+		for _, acct := range b.appointedProducersAuthority(0).Accounts {
+			out = append(out, acct.Permission)
+		}
+		return
+	}
+
+	for _, level := range op.Requested {
+		perm, err := parsePermissionLevel(level)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, perm)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("msig.propose: no requested approvals")
+	}
+	return
+}
+
+func parsePermissionLevel(in string) (eos.PermissionLevel, error) {
+	parts := strings.SplitN(in, "@", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return eos.PermissionLevel{}, fmt.Errorf("invalid permission level %q, use account@permission", in)
+	}
+	return eos.PermissionLevel{Actor: AN(parts[0]), Permission: PN(parts[1])}, nil
+}
+
+// Validate checks the proposal made it on chain.
+func (op *OpMsigPropose) Validate(b *BIOS) error {
+	resp, err := b.validationAPI().GetTableRows(eos.GetTableRowsRequest{
+		JSON:       true,
+		Code:       "eosio.msig",
+		Scope:      string(orMyAccount(b, op.Proposer)),
+		Table:      "proposal",
+		LowerBound: string(op.ProposalName),
+		Limit:      1,
+	})
+	if err != nil {
+		return fmt.Errorf("get proposals: %s", err)
+	}
+
+	var rows []struct {
+		ProposalName string `json:"proposal_name"`
+	}
+	if err := json.Unmarshal(resp.Rows, &rows); err != nil {
+		return fmt.Errorf("decoding proposal rows: %s", err)
+	}
+	if len(rows) == 0 || rows[0].ProposalName != string(op.ProposalName) {
+		return fmt.Errorf("proposal %s not found", op.ProposalName)
+	}
+	return nil
+}
+
+//
+
+type OpMsigApprove struct {
+	Proposer     eos.AccountName
+	ProposalName eos.AccountName `json:"proposal_name"`
+	// Approver defaults to our own account's `active`.
+	Approver eos.AccountName
+}
+
+func (op *OpMsigApprove) Actions(b *BIOS) ([]*eos.Action, error) {
+	approver := orMyAccount(b, op.Approver)
+	return []*eos.Action{newMsigAction("approve", approver, MsigApprove{
+		Proposer:     op.Proposer,
+		ProposalName: op.ProposalName,
+		Level:        eos.PermissionLevel{Actor: approver, Permission: PN("active")},
+	})}, nil
+}
+
+//
+
+type OpMsigExec struct {
+	Proposer     eos.AccountName
+	ProposalName eos.AccountName `json:"proposal_name"`
+	// Executer defaults to our own account.
+	Executer eos.AccountName
+}
+
+func (op *OpMsigExec) Actions(b *BIOS) ([]*eos.Action, error) {
+	executer := orMyAccount(b, op.Executer)
+	return []*eos.Action{newMsigAction("exec", executer, MsigExec{
+		Proposer:     op.Proposer,
+		ProposalName: op.ProposalName,
+		Executer:     executer,
+	})}, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// selectNetwork overlays the `networks.<name>` section of a JSON
+// config over its top-level settings, so one config can describe
+// several simultaneous launches (mainnet rehearsal, community
+// testnet, ...). Unless the network sets its own `run_dir`, it gets
+// `<run_dir>/<name>`, to keep runs isolated.
+func selectNetwork(jsonCnt []byte, network string) ([]byte, error) {
+	var base map[string]interface{}
+	if err := json.Unmarshal(jsonCnt, &base); err != nil {
+		return nil, err
+	}
+
+	networks, _ := base["networks"].(map[string]interface{})
+	delete(base, "networks")
+
+	if network == "" {
+		if len(networks) != 0 {
+			return nil, fmt.Errorf("config defines several networks, select one with --network: %q", networkNames(networks))
+		}
+		return jsonCnt, nil
+	}
+
+	overlay, found := networks[network].(map[string]interface{})
+	if !found {
+		return nil, fmt.Errorf("network %q not found in config, use one of: %q", network, networkNames(networks))
+	}
+
+	if _, found := overlay["run_dir"]; !found {
+		if runDir, ok := base["run_dir"].(string); ok && runDir != "" {
+			overlay["run_dir"] = filepath.Join(runDir, network)
+		}
+	}
+
+	return json.Marshal(deepMerge(base, overlay))
+}
+
+func networkNames(networks map[string]interface{}) (out []string) {
+	for name := range networks {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return
+}
+
+// deepMerge returns `base` with the values of `overlay`, recursing into
+// objects present in both.
+func deepMerge(base, overlay map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range base {
+		out[k] = v
+	}
+
+	for k, v := range overlay {
+		baseMap, baseIsMap := out[k].(map[string]interface{})
+		overlayMap, overlayIsMap := v.(map[string]interface{})
+		if baseIsMap && overlayIsMap {
+			out[k] = deepMerge(baseMap, overlayMap)
+		} else {
+			out[k] = v
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNetworksConfig = `{
+  "run_dir": "runs",
+  "producer": {"my_account": "eoscanadacom", "api_address": "http://localhost:8888"},
+  "networks": {
+    "rehearsal": {"producer": {"api_address": "http://localhost:9888"}},
+    "testnet": {"run_dir": "/srv/testnet", "producer": {"my_account": "eoscanadatst"}}
+  }
+}`
+
+func TestSelectNetwork(t *testing.T) {
+	cnt, err := selectNetwork([]byte(testNetworksConfig), "rehearsal")
+	require.NoError(t, err)
+
+	var c *Config
+	require.NoError(t, json.Unmarshal(cnt, &c))
+	assert.Equal(t, "runs/rehearsal", c.RunDir)
+	assert.Equal(t, "eoscanadacom", c.Producer.MyAccount)
+	assert.Equal(t, APIAddresses{"http://localhost:9888"}, c.Producer.APIAddresses)
+
+	cnt, err = selectNetwork([]byte(testNetworksConfig), "testnet")
+	require.NoError(t, err)
+
+	c = nil
+	require.NoError(t, json.Unmarshal(cnt, &c))
+	assert.Equal(t, "/srv/testnet", c.RunDir)
+	assert.Equal(t, "eoscanadatst", c.Producer.MyAccount)
+	assert.Equal(t, APIAddresses{"http://localhost:8888"}, c.Producer.APIAddresses)
+}
+
+func TestSelectNetworkRequired(t *testing.T) {
+	_, err := selectNetwork([]byte(testNetworksConfig), "")
+	assert.Error(t, err)
+
+	_, err = selectNetwork([]byte(testNetworksConfig), "mainnet")
+	assert.Error(t, err)
+
+	cnt, err := selectNetwork([]byte(`{"run_dir": "runs"}`), "")
+	require.NoError(t, err)
+	assert.Equal(t, `{"run_dir": "runs"}`, string(cnt))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// Nonce is the payload of `eosio::nonce`, an action that does nothing
+// but record its value on chain.
+type Nonce struct {
+	Value string `json:"value"`
+}
+
+func newNonce(value string) *eos.Action {
+	act := &eos.Action{
+		Account: AN("eosio"),
+		Name:    ActN("nonce"),
+		Authorization: []eos.PermissionLevel{
+			{Actor: AN("eosio"), Permission: PN("active")},
+		},
+	}
+	act.Data = eos.NewActionData(Nonce{Value: value})
+	return act
+}
+
+// forEachBlockAction walks the actions of all blocks from `startBlock`
+// up to the current head block.
+func (b *BIOS) forEachBlockAction(startBlock uint32, f func(blockNum uint32, act *eos.Action) error) error {
+	chainInfo, err := b.validationAPI().GetInfo()
+	if err != nil {
+		return fmt.Errorf("get info: %s", err)
+	}
+
+	return b.forEachBlockActionIn(startBlock, chainInfo.HeadBlockNum, f)
+}
+
+// forEachBlockActionIn walks the actions of the blocks from
+// `startBlock` to `endBlock`, included.
+func (b *BIOS) forEachBlockActionIn(startBlock, endBlock uint32, f func(blockNum uint32, act *eos.Action) error) error {
+	for blockNum := startBlock; blockNum <= endBlock; blockNum++ {
+		block, err := b.validationAPI().GetBlockByNum(blockNum)
+		if err != nil {
+			return fmt.Errorf("get block %d: %s", blockNum, err)
+		}
+
+		for _, receipt := range block.Transactions {
+			if receipt.Transaction.Packed == nil {
+				continue
+			}
+
+			tx, err := receipt.Transaction.Packed.Unpack()
+			if err != nil {
+				return fmt.Errorf("unpacking transaction in block %d: %s", blockNum, err)
+			}
+
+			for _, act := range tx.Actions {
+				if err := f(blockNum, act); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// findNonce returns the block number of the first `eosio::nonce`
+// action whose value is `value`, or 0 when none is found.
+func (b *BIOS) findNonce(value string) (found uint32, err error) {
+	chainInfo, err := b.validationAPI().GetInfo()
+	if err != nil {
+		return 0, fmt.Errorf("get info: %s", err)
+	}
+	return b.findNonceIn(1, chainInfo.HeadBlockNum, value)
+}
+
+// findNonceIn is `findNonce` over the blocks from `startBlock` to
+// `endBlock`, included.
+func (b *BIOS) findNonceIn(startBlock, endBlock uint32, value string) (found uint32, err error) {
+	errFound := fmt.Errorf("found")
+	err = b.forEachBlockActionIn(startBlock, endBlock, func(blockNum uint32, act *eos.Action) error {
+		if act.Account != AN("eosio") || act.Name != ActN("nonce") {
+			return nil
+		}
+
+		var nonce Nonce
+		if err := eos.UnmarshalBinary(act.HexData, &nonce); err != nil {
+			return nil
+		}
+
+		if strings.TrimSpace(nonce.Value) == value {
+			found = blockNum
+			return errFound
+		}
+		return nil
+	})
+	if err == errFound {
+		err = nil
+	}
+	return
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// rateLimitedTransport spaces out requests, to be a good citizen on
+// public API endpoints.
+type rateLimitedTransport struct {
+	next   http.RoundTripper
+	ticker *time.Ticker
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	<-t.ticker.C
+	return t.next.RoundTrip(req)
+}
+
+// newObserverAPI returns the API used for validations, when
+// `observer.api_address` points to a remote public endpoint.
+func newObserverAPI(config *Config, chainID []byte) (*eos.API, error) {
+	apiURL, err := url.Parse(config.Observer.APIAddress)
+	if err != nil {
+		return nil, fmt.Errorf("observer.api_address: %s", err)
+	}
+
+	api := eos.New(apiURL, chainID)
+
+	if rps := config.Observer.RequestsPerSecond; rps > 0 {
+		api.HttpClient = &http.Client{
+			Transport: &rateLimitedTransport{
+				next:   http.DefaultTransport,
+				ticker: time.NewTicker(time.Duration(float64(time.Second) / rps)),
+			},
+		}
+	}
+
+	return api, nil
+}
+
+// validationAPI is the API validations are run against: the observer
+// endpoint when configured, our local node otherwise.
+func (b *BIOS) validationAPI() *eos.API {
+	if b.ValidationAPI != nil {
+		return b.ValidationAPI
+	}
+	return b.API
+}
+
+// validationAPIAddress is the address of `validationAPI`, handed
+// to validation hooks.
+func (b *BIOS) validationAPIAddress() string {
+	if b.Config.Observer.APIAddress != "" {
+		return b.Config.Observer.APIAddress
+	}
+	return b.Config.Producer.APIAddress
+}
+
+// getAllTableRows pages through a table, using `keyField` of the last
+// row as the next lower bound.
+func (b *BIOS) getAllTableRows(req eos.GetTableRowsRequest, keyField string) (out []json.RawMessage, err error) {
+	if req.Limit == 0 {
+		req.Limit = b.Config.Observer.PageSize
+	}
+	if req.Limit == 0 {
+		req.Limit = 100
+	}
+
+	for {
+		resp, err := b.validationAPI().GetTableRows(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var rows []map[string]json.RawMessage
+		if err := json.Unmarshal(resp.Rows, &rows); err != nil {
+			return nil, fmt.Errorf("decoding rows of %s/%s: %s", req.Code, req.Table, err)
+		}
+
+		for idx, row := range rows {
+			// The lower bound is inclusive, skip the row we already have.
+			if idx == 0 && len(out) != 0 && req.LowerBound != "" {
+				continue
+			}
+			cnt, _ := json.Marshal(row)
+			out = append(out, cnt)
+		}
+
+		if !resp.More || len(rows) == 0 {
+			return out, nil
+		}
+
+		if keyField == "" {
+			return nil, fmt.Errorf("paging %s/%s: more rows than a page, and no key field to page with", req.Code, req.Table)
+		}
+		lastKey, err := decodeTableValue(rows[len(rows)-1][keyField])
+		if err != nil {
+			return nil, fmt.Errorf("paging %s/%s: no %q in rows", req.Code, req.Table, keyField)
+		}
+		req.LowerBound = fmt.Sprintf("%v", lastKey)
+	}
+}
+
+// decodeTableValue decodes a field of a table row, keeping numbers as
+// written: 64-bit keys don't survive a float64.
+func decodeTableValue(raw json.RawMessage) (value interface{}, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	err = decoder.Decode(&value)
+	return
+}
+
+// RunObserver runs all validations against the (possibly remote)
+// validation API, without taking part in the launch. Auditors
+// without infrastructure can verify the launch this way.
+func (b *BIOS) RunObserver() error {
+	milestone.Println("Observing the launch, read-only, against", b.Config.Observer.APIAddress)
+
+	acct, err := b.validationAPI().GetAccount(AN("eosio"))
+	if err != nil {
+		return fmt.Errorf("get account eosio: %s", err)
+	}
+
+	info.Printf("- Verifying the `eosio` system account was properly disabled: ")
+	if len(acct.Permissions) != 2 || acct.Permissions[0].RequiredAuth.Threshold != 0 || acct.Permissions[1].RequiredAuth.Threshold != 0 {
+		info.Println("FAILED")
+		return fmt.Errorf("eosio account not disabled")
+	}
+	info.Println("OKAY")
+
+	if err := b.RunStepValidations(); err != nil {
+		return err
+	}
+
+	milestone.Println("All validations passed")
+	return nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeTableValueKeepsKeys(t *testing.T) {
+	key, err := decodeTableValue(json.RawMessage(`1234567890123456789`))
+	require.NoError(t, err)
+	assert.Equal(t, "1234567890123456789", fmt.Sprintf("%v", key))
+
+	key, err = decodeTableValue(json.RawMessage(`"eosio.token"`))
+	require.NoError(t, err)
+	assert.Equal(t, "eosio.token", fmt.Sprintf("%v", key))
+
+	_, err = decodeTableValue(nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// `eos-bios sign-offline` builds and signs every transaction of the
+// boot sequence on an air-gapped machine, for a genesis timestamp
+// chosen in advance, and writes them to a file. `eos-bios push` later
+// replays that file against the boot node, from an online machine
+// holding no key.
+//
+// Without a chain to reference, transactions use TaPoS block 0,
+// whose summary stays zero until block 65536, and expire within the
+// transaction lifetime after the genesis timestamp: the node must be
+// started with the genesis of the file, on time, and the push done
+// before `expires_at`. Chunks are always sequenced (see
+// `sequence.go`), so no two transactions are the same.
+
+type OfflineBoot struct {
+	LaunchHash   string                `json:"launch_hash"`
+	GenesisJSON  string                `json:"genesis_json"`
+	ExpiresAt    time.Time             `json:"expires_at"`
+	Transactions []*OfflineTransaction `json:"transactions"`
+}
+
+type OfflineTransaction struct {
+	Step          int                    `json:"step"`
+	Op            string                 `json:"op"`
+	Chunk         int                    `json:"chunk"`
+	TransactionID string                 `json:"transaction_id"`
+	Packed        *eos.PackedTransaction `json:"packed"`
+}
+
+// RunSignOffline implements `eos-bios sign-offline`.
+func (b *BIOS) RunSignOffline(args []string) error {
+	fs := flag.NewFlagSet("sign-offline", flag.ExitOnError)
+	genesisTimestamp := fs.String("genesis-timestamp", b.Config.GenesisTimestamp, "Genesis timestamp of the chain, UTC, like 2018-06-01T12:00:00, defaults to the config's `genesis_timestamp`. The boot node must be started with it, and ABPs configured with it.")
+	out := fs.String("out", "boot-transactions.json", "File to write the signed transactions to.")
+	keyOut := fs.String("key-out", "boot-transactions.key", "File to write the ephemeral private key to, needed for the kickstart data.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	genesisTime, err := time.Parse("2006-01-02T15:04:05", *genesisTimestamp)
+	if err != nil {
+		return fmt.Errorf("--genesis-timestamp: %s", err)
+	}
+
+	ephemeralPrivateKey, err := b.GenerateEphemeralPrivKey()
+	if err != nil {
+		return err
+	}
+	b.EphemeralPrivateKey = ephemeralPrivateKey
+	if err := b.API.Signer.ImportPrivateKey(ephemeralPrivateKey.String()); err != nil {
+		return fmt.Errorf("ImportWIF: %s", err)
+	}
+
+	pubKey := ephemeralPrivateKey.PublicKey()
+	genesis, _ := json.Marshal(&GenesisJSON{
+		InitialTimestamp: genesisTime.Format("2006-01-02T15:04:05"),
+		InitialKey:       pubKey.String(),
+		InitialChainID:   hex.EncodeToString(b.API.ChainID),
+	})
+
+	boot := &OfflineBoot{
+		LaunchHash:  b.LaunchData.fileHash,
+		GenesisJSON: string(genesis),
+		ExpiresAt:   genesisTime.Add(maxTransactionLifetime - time.Minute).UTC(),
+	}
+	opts := &eos.TxOptions{
+		ChainID:     b.API.ChainID,
+		HeadBlockID: make(eos.SHA256Bytes, 32),
+	}
+
+	milestone.Println("Signing the boot sequence offline, for genesis timestamp", genesisTime.Format(time.RFC3339))
+
+	seen := map[string]bool{}
+	lastStep := len(b.LaunchData.BootSequence) - 1
+	for idx, step := range b.LaunchData.BootSequence {
+		b.currentStep = idx
+
+		if b.requiresCoSign(step.Op) {
+			return fmt.Errorf("step %q is co-signed by the ABPs, it can't be signed offline", step.Op)
+		}
+		acts, err := step.Data.Actions(b)
+		if err != nil {
+			return fmt.Errorf("getting actions for step %q: %s", step.Op, err)
+		}
+
+		var chunks [][]*eos.Action
+		if len(acts) != 0 {
+			chunks = b.tagChunks(chunkifyActions(acts, b.chunkLimits(step.Op)))
+		}
+		if idx == lastStep {
+			if len(chunks) == 0 {
+				chunks = [][]*eos.Action{{}}
+			}
+			chunks = b.appendEndMarker(chunks)
+		}
+		chunks = b.sequenceChunks(idx, chunks)
+
+		for chunkIdx, chunk := range chunks {
+			tx := eos.NewTransaction(chunk, opts)
+			tx.Expiration = eos.JSONTime{Time: boot.ExpiresAt}
+
+			signed, err := b.API.Signer.Sign(eos.NewSignedTransaction(tx), b.API.ChainID, pubKey)
+			if err != nil {
+				return fmt.Errorf("signing step %d, chunk %d: %s", idx, chunkIdx, err)
+			}
+			packed, err := signed.Pack(eos.CompressionNone)
+			if err != nil {
+				return fmt.Errorf("packing step %d, chunk %d: %s", idx, chunkIdx, err)
+			}
+			id, err := packed.ID()
+			if err != nil {
+				return err
+			}
+
+			txID := hex.EncodeToString(id)
+			if seen[txID] {
+				return fmt.Errorf("step %d, chunk %d has the same transaction ID %s as a previous one", idx, chunkIdx, txID)
+			}
+			seen[txID] = true
+
+			boot.Transactions = append(boot.Transactions, &OfflineTransaction{
+				Step:          idx,
+				Op:            step.Op,
+				Chunk:         chunkIdx,
+				TransactionID: txID,
+				Packed:        packed,
+			})
+		}
+		milestone.Printf("%d. %s [%s]: %d actions in %d transactions\n", idx, step.Label, step.Op, len(acts), len(chunks))
+	}
+
+	cnt, err := json.MarshalIndent(boot, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(*out, cnt, 0644); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(*keyOut, []byte(ephemeralPrivateKey.String()+"\n"), 0600); err != nil {
+		return err
+	}
+
+	milestone.Printf("Signed %d transactions, written to %s\n", len(boot.Transactions), *out)
+	milestone.Printf("Start the boot node with this genesis, at %s, and push before %s:\n", genesisTime.Format(time.RFC3339), boot.ExpiresAt.Format(time.RFC3339))
+	milestone.Println(string(genesis))
+	milestone.Printf("The ephemeral private key is in %s: keep it offline until the push is done, it goes in the kickstart data\n", *keyOut)
+	return nil
+}
+
+// runPush implements `eos-bios push`: it pushes the transactions
+// signed by `sign-offline`, in order, and checks each made it on
+// chain under the expected ID.
+func runPush(args []string) error {
+	fs := flag.NewFlagSet("push", flag.ExitOnError)
+	apiAddress := fs.String("api-address", "http://localhost:8888", "API endpoint of the boot node, started with the genesis of the signed file.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: eos-bios push [--api-address URL] path/to/boot-transactions.json")
+	}
+
+	cnt, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var boot *OfflineBoot
+	if err := json.Unmarshal(cnt, &boot); err != nil {
+		return fmt.Errorf("decoding %q: %s", fs.Arg(0), err)
+	}
+
+	if time.Now().After(boot.ExpiresAt) {
+		return fmt.Errorf("the signed transactions expired at %s, sign them again for a later genesis timestamp", boot.ExpiresAt.Format(time.RFC3339))
+	}
+
+	apiURL, err := url.Parse(*apiAddress)
+	if err != nil {
+		return err
+	}
+
+	var genesis GenesisJSON
+	if err := json.Unmarshal([]byte(boot.GenesisJSON), &genesis); err != nil {
+		return fmt.Errorf("genesis_json: %s", err)
+	}
+	chainID, err := hex.DecodeString(genesis.InitialChainID)
+	if err != nil {
+		return fmt.Errorf("initial_chain_id: %s", err)
+	}
+
+	b := &BIOS{API: eos.New(apiURL, chainID)}
+
+	chainInfo, err := b.API.GetInfo()
+	if err != nil {
+		return fmt.Errorf("get info: %s", err)
+	}
+	if hex.EncodeToString(chainInfo.ChainID) != genesis.InitialChainID {
+		return fmt.Errorf("node runs chain %x, the transactions were signed for %s", chainInfo.ChainID, genesis.InitialChainID)
+	}
+
+	fmt.Println("Pushing the offline-signed boot of launch", boot.LaunchHash)
+	for _, t := range boot.Transactions {
+		fmt.Printf("- Step %d [%s], chunk %d: %s\n", t.Step, t.Op, t.Chunk, t.TransactionID)
+
+		resp, err := b.API.PushTransaction(t.Packed)
+		if err != nil {
+			return fmt.Errorf("pushing step %d, chunk %d: %s", t.Step, t.Chunk, err)
+		}
+		if resp.TransactionID != t.TransactionID {
+			return fmt.Errorf("node accepted transaction %s, expected %s", resp.TransactionID, t.TransactionID)
+		}
+		if err := b.verifyInclusion(resp.BlockNum, t.TransactionID); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("All transactions pushed, end-of-boot marker included. Publish the kickstart data with the ephemeral private key.")
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Every interactive gate (a `wait: true` hook, the outdated build
+// prompt) and every manual input (a passphrase, pasted kickstart
+// data, a co-signature, a shell `repush`) is recorded with the human
+// operator behind it, in the ledger as "operator" entries and in the
+// run report, for teams whose controls require knowing who did what.
+//
+// The operator is $EOS_BIOS_OPERATOR, or `operator.name` in the
+// config, or else the login user, with the SSH client's address.
+// With `operator.ask`, gates ask for the operator's name instead of
+// ENTER, for several people taking turns on the same session.
+
+type OperatorAction struct {
+	Time     time.Time `json:"time"`
+	Operator string    `json:"operator"`
+	// Kind is "gate" or "input".
+	Kind string `json:"kind"`
+	What string `json:"what"`
+	// Answer to a gate. The content of an input is never recorded.
+	Answer string `json:"answer,omitempty"`
+}
+
+var operatorLog struct {
+	sync.Mutex
+	name    string
+	actions []*OperatorAction
+	// ledgered actions are already in the ledger.
+	ledgered int
+	// ledgering serializes the writers of the ledger entries.
+	ledgering sync.Mutex
+}
+
+// setOperatorName sets `operator.name`, once the config is loaded.
+func setOperatorName(name string) {
+	operatorLog.Lock()
+	defer operatorLog.Unlock()
+	operatorLog.name = name
+}
+
+func operatorIdentity() string {
+	if name := os.Getenv("EOS_BIOS_OPERATOR"); name != "" {
+		return name
+	}
+
+	operatorLog.Lock()
+	name := operatorLog.name
+	operatorLog.Unlock()
+	if name != "" {
+		return name
+	}
+
+	name = os.Getenv("SUDO_USER")
+	if name == "" {
+		if current, err := user.Current(); err == nil {
+			name = current.Username
+		} else {
+			name = "unknown"
+		}
+	}
+	if client := strings.Fields(os.Getenv("SSH_CLIENT")); len(client) != 0 {
+		name += " (ssh from " + client[0] + ")"
+	}
+	return name
+}
+
+// recordOperatorAction records an action of `operator`, the current
+// operator when empty.
+func recordOperatorAction(operator, kind, what, answer string) *OperatorAction {
+	if operator == "" {
+		operator = operatorIdentity()
+	}
+	action := &OperatorAction{
+		Time:     time.Now().UTC(),
+		Operator: operator,
+		Kind:     kind,
+		What:     what,
+		Answer:   answer,
+	}
+	info.Printf("Operator %s: %s %q\n", action.Operator, kind, what)
+
+	operatorLog.Lock()
+	operatorLog.actions = append(operatorLog.actions, action)
+	operatorLog.Unlock()
+	return action
+}
+
+func operatorActions() []*OperatorAction {
+	operatorLog.Lock()
+	defer operatorLog.Unlock()
+	return append([]*OperatorAction{}, operatorLog.actions...)
+}
+
+// recordOperatorAction records an action, and writes it to the ledger.
+func (b *BIOS) recordOperatorAction(operator, kind, what, answer string) {
+	recordOperatorAction(operator, kind, what, answer)
+	b.ledgerOperatorActions()
+}
+
+// ledgerOperatorActions writes the actions recorded so far to the
+// ledger, including those of before it was opened. Appending may
+// prompt for the ledger's passphrase, itself an operator action, so
+// it's done outside of operatorLog's lock.
+func (b *BIOS) ledgerOperatorActions() {
+	if b.Ledger == nil {
+		return
+	}
+
+	operatorLog.ledgering.Lock()
+	defer operatorLog.ledgering.Unlock()
+
+	for {
+		operatorLog.Lock()
+		pending := append([]*OperatorAction{}, operatorLog.actions[operatorLog.ledgered:]...)
+		operatorLog.Unlock()
+		if len(pending) == 0 {
+			return
+		}
+
+		for _, action := range pending {
+			if err := b.ledgerAppend(&LedgerEntry{Type: "operator", Time: action.Time, Operator: action}); err != nil {
+				milestone.Println("WARNING: recording operator action in the ledger:", err)
+				return
+			}
+			operatorLog.Lock()
+			operatorLog.ledgered++
+			operatorLog.Unlock()
+		}
+	}
+}
+
+// operatorGate waits for the operator to confirm, by pressing ENTER,
+// or giving their name with `operator.ask`.
+func (b *BIOS) operatorGate(what string) {
+	reader := bufio.NewReader(os.Stdin)
+	if !b.Config.Operator.Ask {
+		milestone.Printf("Press ENTER to continue... ")
+		_, _ = reader.ReadString('\n')
+		b.recordOperatorAction("", "gate", what, "continued")
+		return
+	}
+
+	for {
+		milestone.Printf("Type your name and press ENTER to continue... ")
+		name, err := reader.ReadString('\n')
+		name = strings.TrimSpace(name)
+		if name != "" || err != nil {
+			b.recordOperatorAction(name, "gate", what, "continued")
+			return
+		}
+	}
+}
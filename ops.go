@@ -15,13 +15,18 @@ type OperationType struct {
 	Op    string
 	Label string
 	Data  Operation
+
+	// ValidateExec is an external command run by ABPs to validate
+	// this step, after the built-in validations. See `validate.go`.
+	ValidateExec string
 }
 
 func (o *OperationType) UnmarshalJSON(data []byte) error {
 	opData := struct {
-		Op    string
-		Label string
-		Data  json.RawMessage
+		Op           string
+		Label        string
+		Data         json.RawMessage
+		ValidateExec string `json:"validate_exec"`
 	}{}
 	if err := json.Unmarshal(data, &opData); err != nil {
 		return err
@@ -50,9 +55,10 @@ func (o *OperationType) UnmarshalJSON(data []byte) error {
 	}
 
 	*o = OperationType{
-		Op:    opData.Op,
-		Label: opData.Label,
-		Data:  opIface,
+		Op:           opData.Op,
+		Label:        opData.Label,
+		Data:         opIface,
+		ValidateExec: opData.ValidateExec,
 	}
 
 	return nil
@@ -63,15 +69,32 @@ type Operation interface {
 }
 
 var operationsRegistry = map[string]Operation{
-	"system.setcode":            &OpSetCode{},
-	"system.newaccount":         &OpNewAccount{},
-	"system.setpriv":            &OpSetPriv{},
-	"token.create":              &OpCreateToken{},
-	"token.issue":               &OpIssueToken{},
-	"producers.create_accounts": &OpCreateProducers{},
-	"system.setprods":           &OpSetProds{},
-	"snapshot.inject":           &OpInjectSnapshot{},
-	"system.destroy_accounts":   &OpDestroyAccounts{},
+	"system.setcode":             &OpSetCode{},
+	"system.newaccount":          &OpNewAccount{},
+	"system.setpriv":             &OpSetPriv{},
+	"token.create":               &OpCreateToken{},
+	"token.issue":                &OpIssueToken{},
+	"producers.create_accounts":  &OpCreateProducers{},
+	"system.setprods":            &OpSetProds{},
+	"snapshot.inject":            &OpInjectSnapshot{},
+	"system.destroy_accounts":    &OpDestroyAccounts{},
+	"genesis.create_accounts":    &OpCreateGenesisAccounts{},
+	"system.create_fund":         &OpCreateFund{},
+	"system.wire_permissions":    &OpWirePermissions{},
+	"system.anchor_constitution": &OpAnchorConstitution{},
+	"snapshot.inject_bulk":       &OpInjectSnapshotBulk{},
+	"snapshot.transfer_packed":   &OpTransferPacked{},
+	"system.setup_wrap":          &OpSetupWrap{},
+	"msig.propose":               &OpMsigPropose{},
+	"msig.approve":               &OpMsigApprove{},
+	"msig.exec":                  &OpMsigExec{},
+	"debug.sabotage_code":        &OpSabotageCode{},
+	"debug.sabotage_account":     &OpSabotageAccount{},
+	"debug.sabotage_balance":     &OpSabotageBalance{},
+	"debug.enrich_producers":     &OpEnrichProducers{},
+	"wait.for_block":             &OpWaitForBlock{},
+	"wait.for_account":           &OpWaitForAccount{},
+	"wait.for_table_row":         &OpWaitForTableRow{},
 }
 
 //
@@ -94,6 +117,11 @@ func (op OpSetCode) Actions(b *BIOS) ([]*eos.Action, error) {
 	return setCode.Actions, nil
 }
 
+// Validate checks the ABI on chain is the launch contract's.
+func (op OpSetCode) Validate(b *BIOS) error {
+	return b.abis().CrossCheck(op.Account)
+}
+
 //
 
 type OpNewAccount struct {
@@ -149,7 +177,7 @@ type OpIssueToken struct {
 }
 
 func (op *OpIssueToken) Actions(b *BIOS) (out []*eos.Action, err error) {
-	act := token.NewIssue(op.Account, op.Amount, op.Memo)
+	act := token.NewIssue(op.Account, op.Amount, b.memo(op.Memo))
 	return append(out, act), nil
 }
 
@@ -167,12 +195,12 @@ func (op *OpCreateProducers) Actions(b *BIOS) (out []*eos.Action, err error) {
 			Active:  prod.Authority.Active,
 		})
 
-		fmt.Printf("- Creating new account %q\n", prod.AccountName)
+		verbose.Printf("- Creating new account %q\n", prod.AccountName)
 		out = append(out, newAccount)
 
 		if b.Config.Debug.EnrichProducers {
-			fmt.Printf("  DEBUG: Enriching producer %q\n", prod.AccountName)
-			out = append(out, token.NewTransfer(AN("eosio"), prod.AccountName, eos.NewEOSAsset(1000000000), "Hey, make good use of it!"))
+			verbose.Printf("  DEBUG: Enriching producer %q\n", prod.AccountName)
+			out = append(out, token.NewTransfer(AN("eosio"), prod.AccountName, eos.NewEOSAsset(1000000000), b.memo("Hey, make good use of it!")))
 		}
 	}
 	return
@@ -183,22 +211,25 @@ func (op *OpCreateProducers) Actions(b *BIOS) (out []*eos.Action, err error) {
 type OpInjectSnapshot struct{}
 
 func (op *OpInjectSnapshot) Actions(b *BIOS) (out []*eos.Action, err error) {
-	for idx, hodler := range b.Snapshot {
-		flipped := flipEndianness(uint64(idx + 1))
-		destAccount := AN("genesis." + eos.NameToString(flipped))
-		fmt.Println("Transfer", hodler, destAccount)
+	snapshot := b.injectedSnapshot()
+	if len(snapshot) < len(b.Snapshot) {
+		info.Printf("- DEBUG: truncated snapshot at %d rows\n", len(snapshot))
+	}
 
-		out = append(out, system.NewNewAccount(AN("eosio"), destAccount, hodler.EOSPublicKey))
+	for idx, hodler := range snapshot {
+		destAccount := snapshotAccountName(idx)
+		trace.Println("Transfer", hodler, destAccount)
 
-		memo := "Welcome " + hodler.EthereumAddress[len(hodler.EthereumAddress)-6:]
+		out = append(out, b.newSnapshotAccount(hodler, destAccount))
 
-		out = append(out, token.NewTransfer(AN("eosio"), destAccount, hodler.Balance, memo))
+		memo := "Welcome " + hodler.EthereumAddress[len(hodler.EthereumAddress)-6:]
 
-		if trunc := b.Config.Debug.TruncateSnapshot; trunc != 0 {
-			if idx == trunc {
-				fmt.Printf("- DEBUG: truncated snapshot at %d rows\n", trunc)
-				break
-			}
+		if flag := b.flaggedAddress(hodler); flag != nil && flag.Treatment == "hold" {
+			holding := b.LaunchData.flagList.HoldingAccount
+			memo = fmt.Sprintf("Held for flagged %s (%s)", hodler.EthereumAddress, destAccount)
+			out = append(out, token.NewTransfer(AN("eosio"), holding, hodler.Balance, b.memo(memo)))
+		} else {
+			out = append(out, token.NewTransfer(AN("eosio"), destAccount, hodler.Balance, b.memo(memo)))
 		}
 
 		// TODO: stake 50% bandwidth, 50% cpu for all new accounts
@@ -208,6 +239,16 @@ func (op *OpInjectSnapshot) Actions(b *BIOS) (out []*eos.Action, err error) {
 	return
 }
 
+// Validate checks the accounts of the snapshot segments were created
+// with their templates, and the flagged addresses were handled as
+// agreed.
+func (op *OpInjectSnapshot) Validate(b *BIOS) error {
+	if err := b.validateSnapshotSegmentAccounts(); err != nil {
+		return err
+	}
+	return b.validateFlaggedAccounts()
+}
+
 //
 
 type OpSetProds struct{}
@@ -237,7 +278,7 @@ type OpDestroyAccounts struct {
 
 func (op *OpDestroyAccounts) Actions(b *BIOS) (out []*eos.Action, err error) {
 	if b.Config.Debug.KeepSystemAccount {
-		fmt.Println("DEBUG: Keeping system account around, for testing purposes.")
+		info.Println("DEBUG: Keeping system account around, for testing purposes.")
 		return
 	}
 
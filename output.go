@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Console verbosity levels, see `--quiet`, `-v` and `-vv`.
+const (
+	levelQuiet = iota
+	levelNormal
+	levelVerbose
+	levelTrace
+)
+
+var levelNames = []string{"milestone", "info", "verbose", "trace"}
+
+// consoleLevel is the maximum level printed to stdout. The log file,
+// when configured, always receives everything.
+var consoleLevel = levelNormal
+
+var logFile io.Writer
+var outputLock sync.Mutex
+
+// console prints at a given verbosity level. Use `milestone` for the
+// few lines an automated pipeline cares about, `info` for regular
+// progress, `verbose` for details, and `trace` for per-row noise.
+type console struct {
+	level int
+}
+
+var (
+	milestone = console{levelQuiet}
+	info      = console{levelNormal}
+	verbose   = console{levelVerbose}
+	trace     = console{levelTrace}
+)
+
+func (c console) Printf(format string, args ...interface{}) {
+	c.write(fmt.Sprintf(format, args...))
+}
+
+func (c console) Println(args ...interface{}) {
+	c.write(fmt.Sprintln(args...))
+}
+
+func (c console) write(msg string) {
+	outputLock.Lock()
+	defer outputLock.Unlock()
+
+	if c.level <= consoleLevel {
+		fmt.Print(msg)
+	}
+
+	if logFile != nil {
+		if strings.TrimSpace(msg) == "" {
+			return
+		}
+		line, _ := json.Marshal(struct {
+			Time  time.Time `json:"time"`
+			Level string    `json:"level"`
+			Msg   string    `json:"msg"`
+		}{time.Now().UTC(), levelNames[c.level], strings.TrimRight(msg, "\n")})
+		_, _ = logFile.Write(append(line, '\n'))
+	}
+}
+
+// setupOutput applies the verbosity flags, and opens the structured
+// log file if requested.
+func setupOutput(quiet, verbose, veryVerbose bool, logFilePath string) error {
+	switch {
+	case quiet:
+		consoleLevel = levelQuiet
+	case veryVerbose:
+		consoleLevel = levelTrace
+	case verbose:
+		consoleLevel = levelVerbose
+	}
+
+	if logFilePath != "" {
+		fl, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		logFile = fl
+		// Errors are reported through `log`, make sure they're in the file too.
+		log.SetOutput(io.MultiWriter(os.Stderr, fl))
+	}
+
+	return nil
+}
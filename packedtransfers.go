@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/system"
+)
+
+// OpTransferPacked distributes the snapshot balances through a
+// transfer contract, deployed earlier in the boot sequence, whose
+// action takes packed arrays: recipients as 8-byte account names and
+// amounts as 8-byte integers (both little endian, like the chain
+// serializes them), all in the core symbol. Much smaller actions
+// than as many `transfer`s, at the cost of a custom contract.
+type OpTransferPacked struct {
+	// Contract is the account the transfer contract is deployed on.
+	Contract eos.AccountName
+	// Action is the contract's action name, defaults to `transfers`.
+	Action eos.ActionName
+	// CreateAccounts also creates the snapshot accounts, with plain
+	// `newaccount` actions, before the transfers.
+	CreateAccounts bool `json:"create_accounts"`
+	// RowsPerAction defaults to 1000.
+	RowsPerAction int `json:"rows_per_action"`
+	// ValidateEvery checks one row out of N on chain, defaults to all of them.
+	ValidateEvery int `json:"validate_every"`
+}
+
+func (op *OpTransferPacked) actionName() eos.ActionName {
+	if op.Action == "" {
+		return ActN("transfers")
+	}
+	return op.Action
+}
+
+// PackedTransfersAction is the data of the contract's action.
+type PackedTransfersAction struct {
+	From       eos.AccountName `json:"from"`
+	Symbol     eos.Symbol      `json:"symbol"`
+	Recipients eos.HexBytes    `json:"recipients"`
+	Amounts    eos.HexBytes    `json:"amounts"`
+}
+
+type packedTransfer struct {
+	To     eos.AccountName
+	Amount int64
+}
+
+func (op *OpTransferPacked) Actions(b *BIOS) (out []*eos.Action, err error) {
+	if op.Contract == "" {
+		return nil, fmt.Errorf("snapshot.transfer_packed needs the transfer `contract` account")
+	}
+
+	actionName := op.actionName()
+
+	rows := (&OpInjectSnapshotBulk{}).rows(b)
+
+	if op.CreateAccounts {
+		for _, row := range rows {
+			out = append(out, system.NewNewAccount(AN("eosio"), row.Account, row.PublicKey))
+		}
+	}
+
+	batches, err := op.batches(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, data := range batches {
+		act := &eos.Action{
+			Account: op.Contract,
+			Name:    actionName,
+			Authorization: []eos.PermissionLevel{
+				{Actor: AN("eosio"), Permission: PN("active")},
+			},
+		}
+		act.Data = eos.NewActionData(*data)
+		out = append(out, act)
+	}
+
+	info.Printf("- Transferring %d snapshot balances in %d packed actions\n", len(rows), len(batches))
+	return
+}
+
+func (op *OpTransferPacked) batches(rows []InjectorRow) (out []*PackedTransfersAction, err error) {
+	perAction := op.RowsPerAction
+	if perAction <= 0 {
+		perAction = 1000
+	}
+
+	for start := 0; start < len(rows); start += perAction {
+		end := start + perAction
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		var transfers []packedTransfer
+		for _, row := range rows[start:end] {
+			if row.Balance.Symbol != eos.EOSSymbol {
+				return nil, fmt.Errorf("%s: packed transfers only carry %s, not %s", row.Account, eos.EOSSymbol.Symbol, row.Balance.Symbol.Symbol)
+			}
+			transfers = append(transfers, packedTransfer{To: row.Account, Amount: row.Balance.Amount})
+		}
+
+		data, err := packTransfers(transfers)
+		if err != nil {
+			return nil, fmt.Errorf("rows %d to %d: %s", start, end-1, err)
+		}
+		out = append(out, data)
+	}
+	return
+}
+
+func packTransfers(transfers []packedTransfer) (*PackedTransfersAction, error) {
+	data := &PackedTransfersAction{
+		From:       AN("eosio"),
+		Symbol:     eos.EOSSymbol,
+		Recipients: make([]byte, 8*len(transfers)),
+		Amounts:    make([]byte, 8*len(transfers)),
+	}
+	for idx, transfer := range transfers {
+		name, err := eos.StringToName(string(transfer.To))
+		if err != nil {
+			return nil, fmt.Errorf("account %q: %s", transfer.To, err)
+		}
+		if transfer.Amount <= 0 {
+			return nil, fmt.Errorf("account %q: transfers must be positive", transfer.To)
+		}
+		binary.LittleEndian.PutUint64(data.Recipients[8*idx:], name)
+		binary.LittleEndian.PutUint64(data.Amounts[8*idx:], uint64(transfer.Amount))
+	}
+	return data, nil
+}
+
+// expandPackedTransfers unpacks the arrays exactly as the contract
+// does.
+func expandPackedTransfers(data *PackedTransfersAction) (out []packedTransfer, err error) {
+	if len(data.Recipients)%8 != 0 || len(data.Recipients) != len(data.Amounts) {
+		return nil, fmt.Errorf("packed arrays of %d and %d bytes, expected the same multiple of 8", len(data.Recipients), len(data.Amounts))
+	}
+	for i := 0; i < len(data.Recipients); i += 8 {
+		name := binary.LittleEndian.Uint64(data.Recipients[i:])
+		amount := int64(binary.LittleEndian.Uint64(data.Amounts[i:]))
+		out = append(out, packedTransfer{To: AN(eos.NameToString(name)), Amount: amount})
+	}
+	return
+}
+
+// Validate expands the packed arrays like the contract, checks they
+// match the snapshot, and verifies the resulting accounts and
+// balances on chain.
+func (op *OpTransferPacked) Validate(b *BIOS) error {
+	every := op.ValidateEvery
+	if every <= 0 {
+		every = 1
+	}
+
+	rows := (&OpInjectSnapshotBulk{}).rows(b)
+	batches, err := op.batches(rows)
+	if err != nil {
+		return err
+	}
+
+	expected := map[eos.AccountName]int64{}
+	var order []eos.AccountName
+	for batchIdx, data := range batches {
+		transfers, err := expandPackedTransfers(data)
+		if err != nil {
+			return fmt.Errorf("packed action %d: %s", batchIdx, err)
+		}
+		for _, transfer := range transfers {
+			if _, found := expected[transfer.To]; !found {
+				order = append(order, transfer.To)
+			}
+			expected[transfer.To] += transfer.Amount
+		}
+	}
+
+	if len(order) != len(rows) {
+		return fmt.Errorf("packed actions expand to %d recipients, the snapshot has %d rows", len(order), len(rows))
+	}
+
+	for idx, row := range rows {
+		if order[idx] != row.Account || expected[row.Account] != row.Balance.Amount {
+			return fmt.Errorf("snapshot row %d: packed actions expand to %d for %s, expected %s for %s", idx, expected[order[idx]], order[idx], row.Balance, row.Account)
+		}
+		if idx%every != 0 {
+			continue
+		}
+
+		if op.CreateAccounts {
+			acct, err := b.validationAPI().GetAccount(row.Account)
+			if err != nil {
+				return fmt.Errorf("snapshot row %d: get account %s: %s", idx, row.Account, err)
+			}
+			authority := eos.Authority{
+				Threshold: 1,
+				Keys:      []eos.KeyWeight{{PublicKey: row.PublicKey, Weight: 1}},
+			}
+			if err := validateAuthority(acct, "owner", authority); err != nil {
+				return fmt.Errorf("snapshot row %d: %s", idx, err)
+			}
+		}
+
+		balance, err := b.getTokenBalance(row.Account)
+		if err != nil {
+			return fmt.Errorf("snapshot row %d: get balance of %s: %s", idx, row.Account, err)
+		}
+		if balance.Amount != row.Balance.Amount {
+			return fmt.Errorf("snapshot row %d: %s holds %s, expected %s", idx, row.Account, balance, row.Balance)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/system"
+)
+
+// OpWirePermissions applies a declarative permission tree on an
+// account, and links actions to those permissions.
+//
+// Permissions are applied in the order listed, so parents must come
+// before their children.
+type OpWirePermissions struct {
+	Account     eos.AccountName
+	Permissions []PermissionDef
+	Links       []LinkDef
+}
+
+type PermissionDef struct {
+	Name      eos.PermissionName `json:"name"`
+	Parent    eos.PermissionName `json:"parent"`
+	Authority eos.Authority      `json:"authority"`
+}
+
+type LinkDef struct {
+	Code       eos.AccountName    `json:"code"`
+	Action     eos.ActionName     `json:"action"`
+	Permission eos.PermissionName `json:"permission"`
+}
+
+// Actions are built from the launch data alone, so the chain audit,
+// offline signing and dry runs get the same ones: permissions already
+// set are updated again, to the same authority.
+func (op *OpWirePermissions) Actions(b *BIOS) (out []*eos.Action, err error) {
+	for _, perm := range op.Permissions {
+		verbose.Printf("- Setting permission %s@%s (parent %q)\n", op.Account, perm.Name, perm.Parent)
+		// Updating `owner` requires the `owner` permission itself.
+		using := PN("active")
+		if perm.Name == PN("owner") {
+			using = PN("owner")
+		}
+		out = append(out, system.NewUpdateAuth(op.Account, perm.Name, perm.Parent, perm.Authority, using))
+	}
+
+	for _, link := range op.Links {
+		verbose.Printf("- Linking %s::%s to %s@%s\n", link.Code, link.Action, op.Account, link.Permission)
+		out = append(out, system.NewLinkAuth(op.Account, link.Code, link.Action, link.Permission))
+	}
+
+	return
+}
+
+func (op *OpWirePermissions) Validate(b *BIOS) error {
+	acct, err := b.validationAPI().GetAccount(op.Account)
+	if err != nil {
+		return fmt.Errorf("get account %s: %s", op.Account, err)
+	}
+
+	for _, perm := range op.Permissions {
+		if err := validateAuthority(acct, string(perm.Name), perm.Authority); err != nil {
+			return err
+		}
+		if err := validatePermissionParent(acct, perm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validatePermissionParent(acct *eos.AccountResp, perm PermissionDef) error {
+	for _, actual := range acct.Permissions {
+		if actual.PermName != string(perm.Name) {
+			continue
+		}
+		if actual.Parent != string(perm.Parent) {
+			return fmt.Errorf("%s@%s has parent %q, expected %q", acct.AccountName, perm.Name, actual.Parent, perm.Parent)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// readArmoredKeyFile reads a keyring, unlocking its private keys if
+// they are protected by a passphrase.
+func readArmoredKeyFile(filename string) (openpgp.EntityList, error) {
+	fl, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fl.Close()
+
+	keyring, err := openpgp.ReadArmoredKeyRing(fl)
+	if err != nil {
+		return nil, fmt.Errorf("reading PGP keys in %q: %s", filename, err)
+	}
+
+	for _, entity := range keyring {
+		privKeys := []*packet.PrivateKey{entity.PrivateKey}
+		for _, subkey := range entity.Subkeys {
+			privKeys = append(privKeys, subkey.PrivateKey)
+		}
+
+		for _, privKey := range privKeys {
+			if privKey == nil || !privKey.Encrypted {
+				continue
+			}
+			passphrase, err := getPassphrase(filename)
+			if err != nil {
+				return nil, err
+			}
+			if err := privKey.Decrypt(passphrase); err != nil {
+				return nil, fmt.Errorf("unlocking PGP key in %q: %s", filename, err)
+			}
+		}
+	}
+
+	return keyring, nil
+}
+
+// producersPGPKeys parses the `pgp_public_key` of all producers in
+// the launch file, keyed by account name.
+func producersPGPKeys(launch *LaunchData) (map[string]openpgp.EntityList, error) {
+	out := map[string]openpgp.EntityList{}
+	for _, prod := range launch.Producers {
+		if prod.PGPPublicKey == "" {
+			continue
+		}
+		keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(prod.PGPPublicKey))
+		if err != nil {
+			return nil, fmt.Errorf("pgp_public_key of %s: %s", prod.AccountName, err)
+		}
+		out[string(prod.AccountName)] = keyring
+	}
+	return out, nil
+}
+
+// pgpEncrypt encrypts `payload` to `recipients`, signed by `signer`,
+// as an armored message.
+func pgpEncrypt(payload []byte, recipients openpgp.EntityList, signer *openpgp.Entity) (string, error) {
+	var out bytes.Buffer
+	armored, err := armor.Encode(&out, "PGP MESSAGE", nil)
+	if err != nil {
+		return "", err
+	}
+
+	plain, err := openpgp.Encrypt(armored, recipients, signer, nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("encrypting: %s", err)
+	}
+	if _, err := plain.Write(payload); err != nil {
+		return "", err
+	}
+	if err := plain.Close(); err != nil {
+		return "", err
+	}
+	if err := armored.Close(); err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+// pgpDecrypt decrypts an armored message with the private keys of
+// `keyring`, and requires it to be signed by one of `trusted`.
+func pgpDecrypt(message string, keyring openpgp.EntityList, trusted openpgp.EntityList) ([]byte, error) {
+	block, err := armor.Decode(strings.NewReader(message))
+	if err != nil {
+		return nil, fmt.Errorf("decoding armor: %s", err)
+	}
+
+	md, err := openpgp.ReadMessage(block.Body, append(append(openpgp.EntityList{}, keyring...), trusted...), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting: %s", err)
+	}
+
+	plain, err := ioutil.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting: %s", err)
+	}
+
+	if !md.IsSigned || md.SignedBy == nil {
+		return nil, fmt.Errorf("message isn't signed by a known key")
+	}
+	if md.SignatureError != nil {
+		return nil, fmt.Errorf("invalid signature: %s", md.SignatureError)
+	}
+
+	signer := md.SignedBy.Entity.PrimaryKey.Fingerprint
+	for _, entity := range trusted {
+		if entity.PrimaryKey.Fingerprint == signer {
+			return plain, nil
+		}
+	}
+
+	return nil, fmt.Errorf("message signed by %X, which isn't an expected fingerprint", signer)
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// With `prebuild` enabled, the heavy steps of the boot sequence have
+// their actions built in the background as soon as the launch data is
+// loaded, while we wait for the seed block. They are packed to
+// `run_dir/spool/`, with their hashes in `spool/index.json`, so an
+// interrupted run reuses them instead of building them again, as long
+// as what they're built from is unchanged (see `spoolKey`).
+//
+// Only steps that depend on neither the shuffle nor the ephemeral key
+// can be prebuilt.
+var prebuildOps = map[string]bool{
+	"system.setcode":           true,
+	"snapshot.inject":          true,
+	"snapshot.inject_bulk":     true,
+	"snapshot.transfer_packed": true,
+	"genesis.create_accounts":  true,
+}
+
+type SpoolIndex struct {
+	LaunchHash string `json:"launch_hash"`
+	// InputsHash is the `spoolKey` of the spooled steps.
+	InputsHash string       `json:"inputs_hash"`
+	Steps      []*SpoolStep `json:"steps"`
+}
+
+type SpoolStep struct {
+	Step    int    `json:"step"`
+	Op      string `json:"op"`
+	File    string `json:"file"`
+	Hash    string `json:"hash"`
+	Actions int    `json:"actions"`
+}
+
+// spooledAction is an action with its data packed.
+type spooledAction struct {
+	Account       eos.AccountName       `json:"account"`
+	Name          eos.ActionName        `json:"name"`
+	Authorization []eos.PermissionLevel `json:"authorization"`
+	HexData       eos.HexBytes          `json:"hex_data"`
+}
+
+type prebuildSpool struct {
+	dir   string
+	done  chan struct{}
+	steps map[int][]*eos.Action
+	err   error
+}
+
+// startPrebuild starts the background pass, when configured.
+func (b *BIOS) startPrebuild() error {
+	if !b.Config.Prebuild.Enabled {
+		return nil
+	}
+	if b.Config.RunDir == "" {
+		return fmt.Errorf("prebuild needs a `run_dir` to spool to")
+	}
+
+	spool := &prebuildSpool{
+		dir:   filepath.Join(b.Config.RunDir, "spool"),
+		done:  make(chan struct{}),
+		steps: map[int][]*eos.Action{},
+	}
+	b.prebuild = spool
+
+	go func() {
+		defer close(spool.done)
+		spool.err = b.runPrebuild(spool)
+		if spool.err != nil {
+			milestone.Println("WARNING: prebuild failed, steps will be built as they come:", spool.err)
+		}
+	}()
+	return nil
+}
+
+func (b *BIOS) runPrebuild(spool *prebuildSpool) error {
+	if err := os.MkdirAll(spool.dir, 0755); err != nil {
+		return err
+	}
+
+	key, err := b.spoolKey()
+	if err != nil {
+		return err
+	}
+
+	previous := map[int]*SpoolStep{}
+	if cnt, err := ioutil.ReadFile(filepath.Join(spool.dir, "index.json")); err == nil {
+		var index SpoolIndex
+		if err := json.Unmarshal(cnt, &index); err != nil {
+			return fmt.Errorf("spool index: %s", err)
+		}
+		if index.InputsHash == key {
+			for _, step := range index.Steps {
+				previous[step.Step] = step
+			}
+		} else {
+			info.Println("Prebuild: the launch data, snapshot, contracts or settings changed since the spool was built, building it again")
+		}
+	}
+
+	// `currentStep` tags the memos, the boot sequence doesn't touch it
+	// before the prebuild is done.
+	defer func() { b.currentStep = 0 }()
+
+	index := &SpoolIndex{LaunchHash: b.LaunchData.fileHash, InputsHash: key}
+	for idx, step := range b.LaunchData.BootSequence {
+		if !prebuildOps[step.Op] {
+			continue
+		}
+
+		if prev := previous[idx]; prev != nil && prev.Op == step.Op {
+			acts, err := readSpoolFile(filepath.Join(spool.dir, prev.File), prev.Hash)
+			if err == nil {
+				verbose.Printf("Prebuild: step %d [%s] read from the spool\n", idx, step.Op)
+				spool.steps[idx] = acts
+				index.Steps = append(index.Steps, prev)
+				continue
+			}
+			info.Printf("Prebuild: spooled step %d [%s] unusable, building it again: %s\n", idx, step.Op, err)
+		}
+
+		b.currentStep = idx
+		acts, err := step.Data.Actions(b)
+		if err != nil {
+			return fmt.Errorf("step %d [%s]: %s", idx, step.Op, err)
+		}
+
+		spooled, err := spoolActions(acts)
+		if err != nil {
+			return fmt.Errorf("step %d [%s]: %s", idx, step.Op, err)
+		}
+
+		cnt, err := json.Marshal(spooled)
+		if err != nil {
+			return err
+		}
+		entry := &SpoolStep{
+			Step:    idx,
+			Op:      step.Op,
+			File:    fmt.Sprintf("step-%03d.json", idx),
+			Hash:    sha256Hex(cnt),
+			Actions: len(acts),
+		}
+		if err := ioutil.WriteFile(filepath.Join(spool.dir, entry.File), cnt, 0644); err != nil {
+			return err
+		}
+
+		// Push what was spooled, so a resumed run's chunks hash the same.
+		spool.steps[idx] = unspoolActions(spooled)
+		index.Steps = append(index.Steps, entry)
+		info.Printf("Prebuild: step %d [%s] built, %d actions\n", idx, step.Op, len(acts))
+	}
+
+	cnt, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(spool.dir, "index.json"), cnt, 0644)
+}
+
+// spoolKey hashes what the prebuilt steps are built from: the launch
+// data, the snapshot as loaded (revised by a delta, sampled, with the
+// genesis accounts carved out), the contracts, and the debug settings
+// shaping the steps.
+func (b *BIOS) spoolKey() (string, error) {
+	snapshot, err := json.Marshal(b.Snapshot)
+	if err != nil {
+		return "", err
+	}
+
+	inputs := []string{b.LaunchData.fileHash, sha256Hex(snapshot), fmt.Sprintf("truncate_snapshot=%d", b.Config.Debug.TruncateSnapshot)}
+
+	var names []string
+	for name := range b.Config.Contracts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		contract := b.Config.Contracts[name]
+		for _, filename := range []string{contract.CodePath, contract.ABIPath} {
+			if filename == "" {
+				continue
+			}
+			cnt, err := ioutil.ReadFile(filename)
+			if err != nil {
+				return "", fmt.Errorf("contract %q: %s", name, err)
+			}
+			inputs = append(inputs, name+"="+sha256Hex(cnt))
+		}
+	}
+
+	return sha256Hex([]byte(strings.Join(inputs, "\n"))), nil
+}
+
+// waitPrebuild blocks until the background pass is over.
+func (b *BIOS) waitPrebuild() {
+	if b.prebuild == nil {
+		return
+	}
+	select {
+	case <-b.prebuild.done:
+	default:
+		info.Println("Waiting for the prebuild to finish")
+		<-b.prebuild.done
+	}
+}
+
+// stepActions returns the prebuilt actions of a step, or builds them.
+func (b *BIOS) stepActions(idx int, step *OperationType) ([]*eos.Action, error) {
+	if b.prebuild != nil {
+		if acts, found := b.prebuild.steps[idx]; found {
+			verbose.Printf("Using the %d prebuilt actions of step %d\n", len(acts), idx)
+			return acts, nil
+		}
+	}
+	return step.Data.Actions(b)
+}
+
+func spoolActions(acts []*eos.Action) (out []*spooledAction, err error) {
+	for _, act := range acts {
+		data := []byte(act.HexData)
+		if len(data) == 0 && act.Data != nil {
+			data, err = eos.MarshalBinary(act.Data)
+			if err != nil {
+				return nil, fmt.Errorf("packing %s::%s: %s", act.Account, act.Name, err)
+			}
+		}
+		out = append(out, &spooledAction{
+			Account:       act.Account,
+			Name:          act.Name,
+			Authorization: act.Authorization,
+			HexData:       data,
+		})
+	}
+	return
+}
+
+func unspoolActions(spooled []*spooledAction) (out []*eos.Action) {
+	for _, act := range spooled {
+		out = append(out, &eos.Action{
+			Account:       act.Account,
+			Name:          act.Name,
+			Authorization: act.Authorization,
+			ActionData:    eos.ActionData{HexData: act.HexData},
+		})
+	}
+	return
+}
+
+func readSpoolFile(filename, hash string) ([]*eos.Action, error) {
+	cnt, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if sha256Hex(cnt) != hash {
+		return nil, fmt.Errorf("%s doesn't match its hash in the index", filename)
+	}
+
+	var spooled []*spooledAction
+	if err := json.Unmarshal(cnt, &spooled); err != nil {
+		return nil, err
+	}
+	return unspoolActions(spooled), nil
+}
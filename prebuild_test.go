@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpoolKeyCoversSnapshot(t *testing.T) {
+	b := &BIOS{LaunchData: &LaunchData{}, Config: &Config{}}
+	balance, err := eos.NewEOSAssetFromString("10.0000 EOS")
+	require.NoError(t, err)
+	b.Snapshot = Snapshot{{EthereumAddress: "0x01", Balance: balance}}
+
+	key, err := b.spoolKey()
+	require.NoError(t, err)
+
+	b.Snapshot[0].Balance.Amount++
+	revised, err := b.spoolKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, revised)
+
+	b.Config.Debug.TruncateSnapshot = 10
+	truncated, err := b.spoolKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, revised, truncated)
+}
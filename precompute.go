@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// With `precompute_ids` enabled, the transactions of each step are
+// built before its first push, all referencing the head block at
+// that time and expiring at a fixed time. Their IDs are then known in
+// advance and published in the preview (`run_dir/preview.json` and
+// the "preview" transport message), so third parties can follow the
+// boot in real time, and spot any transaction that isn't the
+// expected one.
+
+type TxPreview struct {
+	LaunchHash string          `json:"launch_hash"`
+	Chunks     []*PreviewChunk `json:"chunks"`
+}
+
+type PreviewChunk struct {
+	Step           int       `json:"step"`
+	Op             string    `json:"op"`
+	Chunk          int       `json:"chunk"`
+	TransactionID  string    `json:"transaction_id"`
+	RefBlockNum    uint16    `json:"ref_block_num"`
+	RefBlockPrefix uint32    `json:"ref_block_prefix"`
+	Expiration     time.Time `json:"expiration"`
+	ActionsHash    string    `json:"actions_hash"`
+}
+
+type precomputedTx struct {
+	tx *eos.Transaction
+	id string
+	// packed is set when signed ahead by the signing pool.
+	packed *eos.PackedTransaction
+}
+
+// precomputeChunks builds the transactions of a step's chunks, and
+// publishes their IDs. Chunks already pushed (when resuming) are
+// previewed with the ID the ledger recorded, and yield a nil entry.
+func (b *BIOS) precomputeChunks(step int, op string, chunks [][]*eos.Action, resume *resumeState) ([]*precomputedTx, error) {
+	expiration := 55 * time.Minute
+	if b.Config.PrecomputeIDs.Expiration != "" {
+		var err error
+		expiration, err = time.ParseDuration(b.Config.PrecomputeIDs.Expiration)
+		if err != nil {
+			return nil, fmt.Errorf("precompute_ids.expiration: %s", err)
+		}
+		if expiration > maxTransactionLifetime {
+			return nil, fmt.Errorf("precompute_ids.expiration can't exceed the transaction lifetime of %s", maxTransactionLifetime)
+		}
+	}
+
+	chainInfo, err := b.API.GetInfo()
+	if err != nil {
+		return nil, fmt.Errorf("get info: %s", err)
+	}
+	expiresAt := chainInfo.HeadBlockTime.Add(expiration).UTC().Truncate(time.Second)
+
+	opts := &eos.TxOptions{
+		ChainID:     b.API.ChainID,
+		HeadBlockID: chainInfo.HeadBlockID,
+	}
+
+	if b.LaunchData.DeterministicTransactions {
+		expiresAt = deterministicExpiration(b.ShuffleBlock.Time.Truncate(time.Second), chainInfo.HeadBlockTime.Time)
+		opts.HeadBlockID, err = b.deterministicRefBlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]*precomputedTx, len(chunks))
+	seen := map[string]bool{}
+	for chunkIdx, chunk := range chunks {
+		previewed := &PreviewChunk{
+			Step:        step,
+			Op:          op,
+			Chunk:       chunkIdx,
+			ActionsHash: actionsHash(chunk),
+		}
+
+		pushed, err := resume.pushedChunk(step, chunkIdx, chunk)
+		if err != nil {
+			return nil, err
+		}
+		if pushed != nil {
+			previewed.TransactionID = pushed.TransactionID
+			b.Preview.Chunks = append(b.Preview.Chunks, previewed)
+			continue
+		}
+
+		tx := eos.NewTransaction(chunk, opts)
+		tx.Expiration = eos.JSONTime{Time: expiresAt}
+
+		packed, err := eos.NewSignedTransaction(tx).Pack(eos.CompressionNone)
+		if err != nil {
+			return nil, fmt.Errorf("packing chunk %d: %s", chunkIdx, err)
+		}
+		id, err := packed.ID()
+		if err != nil {
+			return nil, fmt.Errorf("computing ID of chunk %d: %s", chunkIdx, err)
+		}
+
+		previewed.TransactionID = hex.EncodeToString(id)
+		if seen[previewed.TransactionID] {
+			return nil, fmt.Errorf("chunks of step %d have the same transaction ID %s", step, previewed.TransactionID)
+		}
+		seen[previewed.TransactionID] = true
+
+		previewed.RefBlockNum = tx.RefBlockNum
+		previewed.RefBlockPrefix = tx.RefBlockPrefix
+		previewed.Expiration = expiresAt
+		b.Preview.Chunks = append(b.Preview.Chunks, previewed)
+
+		out[chunkIdx] = &precomputedTx{tx: tx, id: previewed.TransactionID}
+	}
+
+	// The signing pool precomputes too, but only publishes with `precompute_ids`.
+	if !b.Config.PrecomputeIDs.Enabled {
+		return out, nil
+	}
+
+	b.Preview.LaunchHash = b.LaunchData.fileHash
+	cnt, _ := json.MarshalIndent(b.Preview, "", "  ")
+	if b.Config.RunDir != "" {
+		if err := ioutil.WriteFile(filepath.Join(b.Config.RunDir, "preview.json"), cnt, 0644); err != nil {
+			return nil, fmt.Errorf("writing preview: %s", err)
+		}
+	}
+	if err := b.publish("preview", string(cnt)); err != nil {
+		info.Println("WARNING: failed publishing the preview:", err)
+	}
+
+	info.Printf("Precomputed %d transaction ID(s) for step %d, expiring at %s\n", len(chunks), step, expiresAt)
+
+	return out, nil
+}
+
+// pushPrecomputed signs and pushes a precomputed transaction through
+// `api`, and checks it made it on chain under the expected ID. What's
+// pushed through other lanes than the boot node's is checked once
+// the boot node includes it, see `pushlanes.go`.
+func (b *BIOS) pushPrecomputed(p *precomputedTx, api *eos.API) (*eos.PushTransactionFullResp, error) {
+	if time.Now().After(p.tx.Expiration.Time) {
+		return nil, fmt.Errorf("precomputed transaction %s expired at %s, raise `precompute_ids.expiration` and resume", p.id, p.tx.Expiration.Time)
+	}
+
+	packed := p.packed
+	if packed == nil {
+		var err error
+		_, packed, err = b.API.SignTransaction(p.tx, b.API.ChainID, eos.CompressionNone)
+		if err != nil {
+			return nil, fmt.Errorf("signing: %s", err)
+		}
+	}
+
+	resp, err := api.PushTransaction(packed)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.TransactionID != p.id {
+		return nil, fmt.Errorf("node accepted transaction %s, expected %s", resp.TransactionID, p.id)
+	}
+	if api != b.API {
+		return resp, nil
+	}
+
+	if err := b.verifyInclusion(resp.BlockNum, p.id); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// verifyInclusion waits for block `blockNum` and checks it contains
+// transaction `id`.
+func (b *BIOS) verifyInclusion(blockNum uint32, id string) error {
+	expected, err := hex.DecodeString(id)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		block, err := b.API.GetBlockByNum(blockNum)
+		if err == nil {
+			for _, receipt := range block.Transactions {
+				if bytes.Equal(receipt.Transaction.ID, expected) {
+					return nil
+				}
+			}
+			return fmt.Errorf("transaction %s not found in block %d", id, blockNum)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("block %d: %s", blockNum, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
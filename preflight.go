@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// Rough per-snapshot-row costs, for a `newaccount` and a `transfer`.
+	preflightDiskPerRow   = 3 * 1024
+	preflightMemoryPerRow = 2 * 1024
+
+	preflightBaseDisk   = 1024 * 1024 * 1024
+	preflightBaseMemory = 2 * 1024 * 1024 * 1024
+
+	preflightDefaultMinOpenFiles = 8192
+)
+
+// errPreflightUnsupported is returned by the checks not implemented
+// on this platform, their result is then unknown.
+var errPreflightUnsupported = errors.New("not supported on this platform")
+
+// RunPreflightChecks validates the environment before stage 1, so we
+// fail early with remediation hints rather than dying in the middle
+// of snapshot injection.
+func (b *BIOS) RunPreflightChecks() error {
+	conf := b.Config.Preflight
+	if conf.Skip {
+		info.Println("DEBUG: Skipping pre-flight checks")
+		return nil
+	}
+
+	info.Println("Running pre-flight checks")
+
+	var failures []string
+	rows := uint64(len(b.Snapshot))
+
+	if conf.DataDir != "" {
+		free, err := diskFree(conf.DataDir)
+		switch {
+		case err == errPreflightUnsupported:
+			info.Printf("- Disk free in %q: unknown, %s\n", conf.DataDir, err)
+		case err != nil:
+			return fmt.Errorf("checking disk space of %q: %s", conf.DataDir, err)
+		default:
+			needed := preflightBaseDisk + rows*preflightDiskPerRow
+			info.Printf("- Disk free in %q: %d MB (need ~%d MB)\n", conf.DataDir, free/1024/1024, needed/1024/1024)
+			if free < needed {
+				failures = append(failures, fmt.Sprintf("not enough disk space in %q for the blocks log: free some space or point `data_dir` to a larger volume", conf.DataDir))
+			}
+		}
+	}
+
+	// Below the default, which is only a recommendation, we warn. A
+	// configured minimum is enforced.
+	minOpenFiles := conf.MinOpenFiles
+	if minOpenFiles == 0 {
+		minOpenFiles = preflightDefaultMinOpenFiles
+	}
+	openFiles, err := openFilesLimit()
+	if err != nil {
+		info.Println("- Open files limit: unknown,", err)
+	} else {
+		info.Printf("- Open files limit: %d (need %d)\n", openFiles, minOpenFiles)
+		if openFiles < minOpenFiles {
+			remediation := fmt.Sprintf("open files limit too low: run `ulimit -n %d` before starting eos-bios and nodeos", minOpenFiles)
+			if conf.MinOpenFiles == 0 {
+				milestone.Println("PRE-FLIGHT WARNING:", remediation)
+			} else {
+				failures = append(failures, remediation)
+			}
+		}
+	}
+
+	available, err := memoryAvailable()
+	if err != nil {
+		info.Println("- Memory available: unknown,", err)
+	} else {
+		needed := preflightBaseMemory + rows*preflightMemoryPerRow
+		info.Printf("- Memory available: %d MB (need ~%d MB)\n", available/1024/1024, needed/1024/1024)
+		if available < needed {
+			failures = append(failures, "not enough memory headroom: stop other services, or add memory")
+		}
+	}
+
+	if len(failures) != 0 {
+		for _, failure := range failures {
+			milestone.Println("PRE-FLIGHT FAILURE:", failure)
+		}
+		return fmt.Errorf("%d pre-flight check(s) failed, set preflight.skip to override", len(failures))
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+func diskFree(path string) (uint64, error) {
+	return 0, errPreflightUnsupported
+}
+
+func openFilesLimit() (uint64, error) {
+	return 0, errPreflightUnsupported
+}
+
+func memoryAvailable() (uint64, error) {
+	return 0, errPreflightUnsupported
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// maxMemoLength is enforced by the `eosio.token` contract on transfers
+// and issues.
+const maxMemoLength = 256
+
+// provenanceTag identifies the launch file and boot step that
+// generated an action, so forensic analysis of the chain can
+// attribute every genesis-era action to a specific step.
+func (b *BIOS) provenanceTag() string {
+	hash := b.LaunchData.fileHash
+	if len(hash) > 8 {
+		hash = hash[:8]
+	}
+	return fmt.Sprintf("[bios:%s:%02d]", hash, b.currentStep)
+}
+
+// tagChunks prepends a nonce carrying the provenance tag to each
+// chunk, when `provenance_tags` is enabled in the launch data, so the
+// actions without a memo (accounts, permissions, contracts, ...) are
+// attributed by the transaction they are pushed in.
+func (b *BIOS) tagChunks(chunks [][]*eos.Action) [][]*eos.Action {
+	if !b.LaunchData.ProvenanceTags {
+		return chunks
+	}
+
+	out := make([][]*eos.Action, len(chunks))
+	for chunkIdx, chunk := range chunks {
+		out[chunkIdx] = append([]*eos.Action{newNonce(b.provenanceTag())}, chunk...)
+	}
+	return out
+}
+
+// memo watermarks `memo` with the provenance tag, when
+// `provenance_tags` is enabled in the launch data.
+func (b *BIOS) memo(memo string) string {
+	if !b.LaunchData.ProvenanceTags {
+		return memo
+	}
+
+	tag := b.provenanceTag()
+	if len(memo)+1+len(tag) > maxMemoLength {
+		// Cut on a rune boundary, the contract rejects invalid UTF-8.
+		cut := maxMemoLength - 1 - len(tag)
+		for cut > 0 && !utf8.RuneStart(memo[cut]) {
+			cut--
+		}
+		memo = memo[:cut]
+	}
+	if memo == "" {
+		return tag
+	}
+	return memo + " " + tag
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoTruncatesOnRuneBoundary(t *testing.T) {
+	b := &BIOS{LaunchData: &LaunchData{ProvenanceTags: true, fileHash: "0123456789abcdef"}}
+
+	for _, long := range []string{strings.Repeat("a", 300), strings.Repeat("é", 150), "a" + strings.Repeat("€", 100)} {
+		memo := b.memo(long)
+		assert.True(t, len(memo) <= maxMemoLength, "%d bytes", len(memo))
+		assert.True(t, utf8.ValidString(memo), memo)
+		assert.True(t, strings.HasSuffix(memo, " "+b.provenanceTag()), memo)
+	}
+}
+
+func TestTagChunks(t *testing.T) {
+	b := &BIOS{LaunchData: &LaunchData{fileHash: "0123456789abcdef"}, currentStep: 3}
+	chunks := [][]*eos.Action{{newNonce("a")}, {newNonce("b"), newNonce("c")}}
+
+	assert.Equal(t, chunks, b.tagChunks(chunks))
+
+	b.LaunchData.ProvenanceTags = true
+	tagged := b.tagChunks(chunks)
+	assert.Len(t, tagged, 2)
+	assert.Len(t, tagged[0], 2)
+	assert.Len(t, tagged[1], 3)
+	for _, chunk := range tagged {
+		assert.Equal(t, eos.NewActionData(Nonce{Value: "[bios:01234567:03]"}), chunk[0].Data)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// With several `producer.api_address`, each endpoint is a push lane,
+// and the chunks pushed in parallel (see `pushpipeline.go`) are spread
+// over the lanes. The first endpoint is the boot node: serial steps
+// are pushed there, and it's where the blocks are produced.
+//
+// Boot transactions depend on each other, and a node only accepts a
+// transaction once it has the ones it depends on. So a chunk depending
+// on other chunks is pushed through the lane of its first dependency,
+// and when one of them went through another lane, waits for it to be
+// included in a block of the boot node, and for its lane's node to
+// have that block. Likewise, a parallel step starts once every lane
+// has the blocks of the steps before it, and the next step once the
+// boot node included all of its chunks.
+//
+// A lane whose circuit is open (see `apiclient.go`) gets no new
+// chunks until it cools down, and the chunk that failed on it is
+// retried once on another lane, where it may be rejected as a
+// duplicate if the unresponsive node accepted it after all.
+
+type pushLane struct {
+	host    string
+	api     *eos.API
+	breaker *circuitBreaker
+
+	lock sync.Mutex
+	// reached is a block the lane's node is known to have.
+	reached uint32
+}
+
+// laneCatchUpTimeout bounds the wait for a lane's node to get a block.
+const laneCatchUpTimeout = 2 * time.Minute
+
+// newPushLanes returns a lane per endpoint, the first one pushing
+// through `api`, once its signer is set.
+func newPushLanes(api *eos.API, endpoints []*apiEndpoint) (out []*pushLane) {
+	for idx, endpoint := range endpoints {
+		lane := &pushLane{host: endpoint.url.Host, api: api, breaker: endpoint.transport.breaker}
+		if idx != 0 {
+			lane.api = eos.New(endpoint.url, api.ChainID)
+			lane.api.HttpClient = &http.Client{Transport: endpoint.transport}
+			lane.api.SetSigner(api.Signer)
+		}
+		out = append(out, lane)
+	}
+	return
+}
+
+// pushLanes are the lanes to push through, only the boot node's
+// without several endpoints.
+func (b *BIOS) pushLanes() []*pushLane {
+	if len(b.PushLanes) == 0 {
+		return []*pushLane{{host: "boot node", api: b.API}}
+	}
+	return b.PushLanes
+}
+
+func (l *pushLane) unresponsive() bool {
+	return l.breaker != nil && l.breaker.Skipping()
+}
+
+// nextLane returns the next responsive lane after `current`, in turn,
+// or nil.
+func nextLane(lanes []*pushLane, current int) (*pushLane, int) {
+	for i := 1; i <= len(lanes); i++ {
+		idx := (current + i) % len(lanes)
+		if !lanes[idx].unresponsive() {
+			return lanes[idx], idx
+		}
+	}
+	return nil, current
+}
+
+// laneAt waits for the lane's node to have block `blockNum`.
+func (b *BIOS) laneAt(lane *pushLane, blockNum uint32) error {
+	lane.lock.Lock()
+	reached := lane.reached
+	lane.lock.Unlock()
+	if blockNum <= reached {
+		return nil
+	}
+
+	deadline := time.Now().Add(laneCatchUpTimeout)
+	for {
+		chainInfo, err := lane.api.GetInfo()
+		if err == nil && chainInfo.HeadBlockNum >= blockNum {
+			lane.lock.Lock()
+			if chainInfo.HeadBlockNum > lane.reached {
+				lane.reached = chainInfo.HeadBlockNum
+			}
+			lane.lock.Unlock()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return fmt.Errorf("push lane %s didn't get block %d after %s: %s", lane.host, blockNum, laneCatchUpTimeout, err)
+			}
+			return fmt.Errorf("push lane %s didn't get block %d after %s, its head is %d", lane.host, blockNum, laneCatchUpTimeout, chainInfo.HeadBlockNum)
+		}
+		b.Watchdog.Progress(fmt.Sprintf("waiting for push lane %s to get block %d", lane.host, blockNum))
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// waitIncluded waits for the boot node to include the transactions,
+// given with the block their lane announced, and returns the last
+// block including them.
+func (b *BIOS) waitIncluded(pushed map[string]uint32) (lastBlockNum uint32, err error) {
+	missing := map[string]bool{}
+	var from uint32
+	for id, blockNum := range pushed {
+		missing[id] = true
+		if from == 0 || blockNum < from {
+			from = blockNum
+		}
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	deadline := time.Now().Add(laneCatchUpTimeout)
+	for blockNum := from; len(missing) != 0; {
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("%d transaction(s) pushed through other lanes not included by block %d after %s", len(missing), blockNum, laneCatchUpTimeout)
+		}
+
+		block, err := b.API.GetBlockByNum(blockNum)
+		if err != nil {
+			// Not produced yet.
+			time.Sleep(250 * time.Millisecond)
+			continue
+		}
+
+		for _, receipt := range block.Transactions {
+			id := hex.EncodeToString(receipt.Transaction.ID)
+			if missing[id] {
+				delete(missing, id)
+				lastBlockNum = blockNum
+			}
+		}
+		blockNum++
+	}
+	return lastBlockNum, nil
+}
+
+// catchUpLanes waits for the lanes' nodes to have the blocks of what
+// was pushed so far, dropping those which don't.
+func (b *BIOS) catchUpLanes(lanes []*pushLane) []*pushLane {
+	out := []*pushLane{lanes[0]}
+	for _, lane := range lanes[1:] {
+		if err := b.laneAt(lane, b.pushedThrough); err != nil {
+			milestone.Printf("WARNING: not pushing through %s: %s\n", lane.host, err)
+			continue
+		}
+		out = append(out, lane)
+	}
+	return out
+}
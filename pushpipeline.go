@@ -0,0 +1,372 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/system"
+)
+
+// With `parallel_push.workers` set, the chunks of the snapshot steps
+// (or of `parallel_push.ops`) are signed and pushed by concurrent
+// workers, over the push lanes (see `pushlanes.go`). Chunks are
+// independent, except a chunk referring to an account created by an
+// earlier chunk of the step (a transfer to a new account), which
+// waits for that chunk to be pushed; references are found by looking
+// for the account's 8-byte name in the actions' data and
+// authorizations. The chunk carrying the end-of-boot marker
+// goes last. Pushed chunks are recorded (report, ledger,
+// confirmation) by the pusher as they come, and failures are
+// collected, not stopping the chunks already handed to workers.
+
+var defaultParallelPushOps = []string{"snapshot.inject", "snapshot.inject_bulk", "snapshot.transfer_packed"}
+
+// pushWorkers defaults to one per push lane.
+func (b *BIOS) pushWorkers() int {
+	if b.Config.ParallelPush.Workers == 0 && len(b.PushLanes) > 1 {
+		return len(b.PushLanes)
+	}
+	return b.Config.ParallelPush.Workers
+}
+
+func (b *BIOS) parallelPush(op string) bool {
+	if b.pushWorkers() < 2 || b.requiresCoSign(op) {
+		return false
+	}
+	ops := b.Config.ParallelPush.Ops
+	if len(ops) == 0 {
+		ops = defaultParallelPushOps
+	}
+	for _, name := range ops {
+		if name == op {
+			return true
+		}
+	}
+	return false
+}
+
+// stepPush is what pushing the chunks of a step needs.
+type stepPush struct {
+	step          int
+	op            string
+	limits        ChunkLimits
+	precomputed   []*precomputedTx
+	signing       *signingPool
+	confirmations *confirmationWorker
+}
+
+// pushChunk signs and pushes a chunk through `api`, the boot node's
+// or a push lane's.
+func (b *BIOS) pushChunk(s *stepPush, chunkIdx int, chunk []*eos.Action, api *eos.API) (resp *eos.PushTransactionFullResp, err error) {
+	pushStart := time.Now()
+	if b.requiresCoSign(s.op) {
+		resp, err = b.coSignPush(s.step, s.op, chunkIdx, chunk)
+	} else if s.precomputed != nil {
+		if s.signing != nil {
+			if err := s.signing.wait(chunkIdx); err != nil {
+				return nil, fmt.Errorf("signing pool, step %q: %s", s.op, err)
+			}
+		}
+		resp, err = b.pushPrecomputed(s.precomputed[chunkIdx], api)
+	} else {
+		resp, err = api.SignPushActions(chunk...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("SignPushActions for step %q, chunk %d: %s", s.op, chunkIdx, err)
+	}
+
+	b.Report.Pipeline.pushed(time.Since(pushStart))
+	b.Watchdog.Progress(fmt.Sprintf("step %d [%s], chunk %d pushed", s.step, s.op, chunkIdx))
+	return resp, nil
+}
+
+// recordChunk adds a pushed chunk to the report, and to the ledger,
+// through the confirmation worker when enabled.
+func (b *BIOS) recordChunk(s *stepPush, chunkIdx int, chunk []*eos.Action, resp *eos.PushTransactionFullResp) error {
+	if resp.BlockNum > b.pushedThrough {
+		b.pushedThrough = resp.BlockNum
+	}
+
+	reported := &ReportTransaction{
+		Step:          s.step,
+		Op:            s.op,
+		Chunk:         chunkIdx,
+		TransactionID: resp.TransactionID,
+		Actions:       b.reportActions(chunk),
+	}
+	b.Report.Transactions = append(b.Report.Transactions, reported)
+
+	entry := &LedgerEntry{
+		Type:          "chunk",
+		Step:          s.step,
+		Op:            s.op,
+		Chunk:         chunkIdx,
+		TransactionID: resp.TransactionID,
+		BlockNum:      resp.BlockNum,
+		ChunkSize:     s.limits.MaxActions,
+		ChunkBytes:    s.limits.MaxBytes,
+		Actions:       chunk,
+		ActionsHash:   actionsHash(chunk),
+	}
+	if s.confirmations != nil {
+		return s.confirmations.enqueue(&pushedChunk{entry: entry, actions: reported.Actions, pushedAt: time.Now()})
+	}
+
+	if err := b.ledgerAppend(entry); err != nil {
+		return fmt.Errorf("ledger: %s", err)
+	}
+
+	if err := b.DispatchLedgerEntry(entry, reported.Actions); err != nil {
+		return fmt.Errorf("dispatch ledger_entry: %s", err)
+	}
+	return nil
+}
+
+// chunkDependencies lists, for each chunk, the earlier chunks creating
+// accounts it refers to.
+func chunkDependencies(chunks [][]*eos.Action) ([][]int, error) {
+	createdIn := map[uint64]int{}
+	deps := make([][]int, len(chunks))
+
+	for chunkIdx, chunk := range chunks {
+		found := map[int]bool{}
+		for _, act := range chunk {
+			data := []byte(act.HexData)
+			if len(data) == 0 && act.Data != nil {
+				var err error
+				data, err = eos.MarshalBinary(act.Data)
+				if err != nil {
+					return nil, fmt.Errorf("packing %s::%s: %s", act.Account, act.Name, err)
+				}
+			}
+
+			refs := []eos.AccountName{act.Account}
+			for _, auth := range act.Authorization {
+				refs = append(refs, auth.Actor)
+			}
+			for _, ref := range refs {
+				name, _ := eos.StringToName(string(ref))
+				if other, ok := createdIn[name]; ok && other != chunkIdx {
+					found[other] = true
+				}
+			}
+			for i := 0; i+8 <= len(data); i++ {
+				if other, ok := createdIn[binary.LittleEndian.Uint64(data[i:])]; ok && other != chunkIdx {
+					found[other] = true
+				}
+			}
+
+			if act.Account == AN("eosio") && act.Name == ActN("newaccount") {
+				var newAccount system.NewAccount
+				if err := eos.UnmarshalBinary(data, &newAccount); err != nil {
+					return nil, fmt.Errorf("decoding newaccount: %s", err)
+				}
+				name, err := eos.StringToName(string(newAccount.Name))
+				if err != nil {
+					return nil, err
+				}
+				createdIn[name] = chunkIdx
+			}
+		}
+
+		for other := range found {
+			deps[chunkIdx] = append(deps[chunkIdx], other)
+		}
+		sort.Ints(deps[chunkIdx])
+	}
+	return deps, nil
+}
+
+type pushResult struct {
+	chunkIdx int
+	lane     *pushLane
+	resp     *eos.PushTransactionFullResp
+	err      error
+}
+
+// pushJob is a chunk to push through a lane, after its dependencies.
+type pushJob struct {
+	chunkIdx int
+	lane     *pushLane
+	deps     []*pushResult
+}
+
+// pushParallel pushes the `pending` chunks of a step with the
+// `parallel_push` workers, spread over the push lanes, and returns the
+// highest block they landed in. With `lastAfterAll`, the last chunk is
+// pushed once all others are.
+func (b *BIOS) pushParallel(s *stepPush, chunks [][]*eos.Action, pending []int, lastAfterAll bool) (lastBlockNum uint32, err error) {
+	deps, err := chunkDependencies(chunks)
+	if err != nil {
+		return 0, fmt.Errorf("step %q: %s", s.op, err)
+	}
+	if lastAfterAll && len(chunks) > 1 {
+		deps[len(chunks)-1] = nil
+		for i := 0; i < len(chunks)-1; i++ {
+			deps[len(chunks)-1] = append(deps[len(chunks)-1], i)
+		}
+	}
+
+	isPending := map[int]bool{}
+	for _, chunkIdx := range pending {
+		isPending[chunkIdx] = true
+	}
+	waiting := map[int]int{}
+	dependents := map[int][]int{}
+	var ready []int
+	for _, chunkIdx := range pending {
+		for _, dep := range deps[chunkIdx] {
+			// Chunks pushed before resuming are done.
+			if isPending[dep] {
+				waiting[chunkIdx]++
+				dependents[dep] = append(dependents[dep], chunkIdx)
+			}
+		}
+		if waiting[chunkIdx] == 0 {
+			ready = append(ready, chunkIdx)
+		}
+	}
+
+	lanes := b.catchUpLanes(b.pushLanes())
+	workers := b.pushWorkers()
+	info.Printf("Pushing %d chunk(s) with %d workers over %d lane(s), %d of them waiting on accounts created by other chunks\n", len(pending), workers, len(lanes), len(pending)-len(ready))
+
+	jobs := make(chan *pushJob, len(chunks))
+	results := make(chan *pushResult, len(chunks))
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range jobs {
+				lane, resp, err := b.pushOnLane(s, job, chunks[job.chunkIdx], lanes)
+				results <- &pushResult{chunkIdx: job.chunkIdx, lane: lane, resp: resp, err: err}
+			}
+		}()
+	}
+	defer close(jobs)
+
+	// Independent chunks take the lanes in turn, dependent ones the
+	// lane of their first dependency.
+	laneIdx := len(lanes) - 1
+	done := map[int]*pushResult{}
+	schedule := func(chunkIdx int) {
+		job := &pushJob{chunkIdx: chunkIdx}
+		for _, dep := range deps[chunkIdx] {
+			if result := done[dep]; result != nil {
+				job.deps = append(job.deps, result)
+			}
+		}
+		if len(job.deps) != 0 && !job.deps[0].lane.unresponsive() {
+			job.lane = job.deps[0].lane
+		} else if lane, idx := nextLane(lanes, laneIdx); lane != nil {
+			job.lane, laneIdx = lane, idx
+		} else {
+			job.lane = lanes[0]
+		}
+		jobs <- job
+	}
+
+	scheduled := 0
+	for _, chunkIdx := range ready {
+		schedule(chunkIdx)
+		scheduled++
+	}
+
+	var failures []string
+	elsewhere := map[string]uint32{}
+	for finished := 0; finished < scheduled; finished++ {
+		result := <-results
+		if result.err == nil {
+			result.err = b.recordChunk(s, result.chunkIdx, chunks[result.chunkIdx], result.resp)
+		}
+		if result.err != nil {
+			failures = append(failures, result.err.Error())
+			continue
+		}
+		done[result.chunkIdx] = result
+		if result.lane != lanes[0] {
+			elsewhere[result.resp.TransactionID] = result.resp.BlockNum
+		}
+
+		if result.resp.BlockNum > lastBlockNum {
+			lastBlockNum = result.resp.BlockNum
+		}
+		// After a failure, the chunks already handed out finish, no new ones start.
+		if len(failures) != 0 {
+			continue
+		}
+		for _, next := range dependents[result.chunkIdx] {
+			waiting[next]--
+			if waiting[next] == 0 {
+				schedule(next)
+				scheduled++
+			}
+		}
+	}
+
+	if len(failures) != 0 {
+		return lastBlockNum, fmt.Errorf("%d chunk(s) failed, %d not pushed: %s", len(failures), len(pending)-scheduled, strings.Join(failures, "; "))
+	}
+
+	// The next steps are pushed on the boot node, which must have
+	// included what went through the other lanes.
+	if len(elsewhere) != 0 {
+		included, err := b.waitIncluded(elsewhere)
+		if err != nil {
+			return lastBlockNum, fmt.Errorf("step %q: %s", s.op, err)
+		}
+		if included > lastBlockNum {
+			lastBlockNum = included
+		}
+		if included > b.pushedThrough {
+			b.pushedThrough = included
+		}
+	}
+	return lastBlockNum, nil
+}
+
+// pushOnLane pushes a chunk through its lane, once the lane's node has
+// the dependencies pushed through other lanes. A chunk failing on a
+// lane gone unresponsive is pushed through the next lane.
+func (b *BIOS) pushOnLane(s *stepPush, job *pushJob, chunk []*eos.Action, lanes []*pushLane) (*pushLane, *eos.PushTransactionFullResp, error) {
+	lane := job.lane
+	resp, err := b.pushAfterDeps(s, job, lane, chunk)
+	if err == nil || !lane.unresponsive() {
+		return lane, resp, err
+	}
+
+	for idx, candidate := range lanes {
+		if candidate != lane {
+			continue
+		}
+		next, _ := nextLane(lanes, idx)
+		if next == nil || next == lane {
+			break
+		}
+		milestone.Printf("WARNING: push lane %s unresponsive, pushing chunk %d through %s\n", lane.host, job.chunkIdx, next.host)
+		resp, err = b.pushAfterDeps(s, job, next, chunk)
+		return next, resp, err
+	}
+	return lane, nil, err
+}
+
+func (b *BIOS) pushAfterDeps(s *stepPush, job *pushJob, lane *pushLane, chunk []*eos.Action) (*eos.PushTransactionFullResp, error) {
+	elsewhere := map[string]uint32{}
+	for _, dep := range job.deps {
+		if dep.lane != lane {
+			elsewhere[dep.resp.TransactionID] = dep.resp.BlockNum
+		}
+	}
+	if len(elsewhere) != 0 {
+		included, err := b.waitIncluded(elsewhere)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d: %s", job.chunkIdx, err)
+		}
+		if err := b.laneAt(lane, included); err != nil {
+			return nil, fmt.Errorf("chunk %d: %s", job.chunkIdx, err)
+		}
+	}
+	return b.pushChunk(s, job.chunkIdx, chunk, lane.api)
+}
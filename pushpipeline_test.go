@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/eoscanada/eos-go/system"
+	"github.com/eoscanada/eos-go/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkDependencies(t *testing.T) {
+	key, err := ecc.NewRandomPrivateKey()
+	require.NoError(t, err)
+	amount, err := eos.NewEOSAssetFromString("1.0000 EOS")
+	require.NoError(t, err)
+
+	chunks := [][]*eos.Action{
+		{system.NewNewAccount(AN("eosio"), AN("alice"), key.PublicKey())},
+		{system.NewNewAccount(AN("eosio"), AN("bob"), key.PublicKey())},
+		{token.NewTransfer(AN("eosio"), AN("bob"), amount, "")},
+		{token.NewTransfer(AN("eosio"), AN("carol"), amount, "")},
+		{token.NewTransfer(AN("alice"), AN("bob"), amount, "")},
+	}
+
+	deps, err := chunkDependencies(chunks)
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{nil, nil, {1}, nil, {0, 1}}, deps)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// printKickstartQR renders the kickstart data as a QR code, on the
+// terminal and/or to a PNG file, for operators coordinating over a
+// video call or with air-gapped machines.
+func (b *BIOS) printKickstartQR(ksdata string) error {
+	conf := b.Config.KickstartQR
+	if !conf.Terminal && conf.PNGPath == "" {
+		return nil
+	}
+
+	code, err := qrcode.New(ksdata, qrcode.Low)
+	if err != nil {
+		return fmt.Errorf("kickstart data doesn't fit in a QR code (%d bytes): %s", len(ksdata), err)
+	}
+
+	if conf.Terminal {
+		milestone.Println("KICKSTART DATA QR CODE:")
+		milestone.Println("")
+		milestone.Printf("%s", renderQR(code.Bitmap(), conf.Inverse))
+		milestone.Println("")
+	}
+
+	if conf.PNGPath != "" {
+		if err := code.WriteFile(1024, conf.PNGPath); err != nil {
+			return fmt.Errorf("writing %s: %s", conf.PNGPath, err)
+		}
+		info.Println("Kickstart data QR code written to", conf.PNGPath)
+	}
+
+	return nil
+}
+
+// renderQR draws two rows of modules per line, with half blocks.
+// Dark modules are drawn, for terminals with a light background,
+// unless `inverse`.
+func renderQR(bitmap [][]bool, inverse bool) string {
+	dark := func(row, col int) bool {
+		if row >= len(bitmap) {
+			return inverse
+		}
+		return bitmap[row][col] != inverse
+	}
+
+	var out bytes.Buffer
+	for row := 0; row < len(bitmap); row += 2 {
+		for col := range bitmap[row] {
+			top, bottom := dark(row, col), dark(row+1, col)
+			switch {
+			case top && bottom:
+				out.WriteString("█")
+			case top:
+				out.WriteString("▀")
+			case bottom:
+				out.WriteString("▄")
+			default:
+				out.WriteString(" ")
+			}
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+)
+
+// ReadinessAttestation is published by each producer, before the seed
+// block, to signal it is ready to take part in the launch. It is
+// signed with the producer's block signing key.
+type ReadinessAttestation struct {
+	Account                  eos.AccountName `json:"account"`
+	LaunchBitcoinBlockHeight int             `json:"launch_btc_block_height"`
+	Timestamp                time.Time       `json:"timestamp"`
+	Signature                ecc.Signature   `json:"signature"`
+}
+
+func (a *ReadinessAttestation) Digest() []byte {
+	h := sha256.Sum256([]byte(fmt.Sprintf("eos-bios-ready:%s:%d:%s", a.Account, a.LaunchBitcoinBlockHeight, a.Timestamp.UTC().Format(time.RFC3339))))
+	return h[:]
+}
+
+// minReadyProducers is the smallest pool the shuffle works with: a
+// boot node, and at least one appointed block producer.
+const minReadyProducers = 2
+
+// loadReadyAccounts reads the agreed-upon attestations file, verifies
+// its hash against the launch data, and returns the accounts whose
+// attestation is valid and within the readiness window.
+func loadReadyAccounts(launch *LaunchData, attestationsPath string) (map[eos.AccountName]bool, error) {
+	if attestationsPath == "" {
+		return nil, fmt.Errorf("launch data requires readiness attestations, but readiness.attestations_path isn't configured")
+	}
+
+	hash, err := hashFile(attestationsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info.Printf("Hash of %q: %s\n", attestationsPath, hash)
+
+	if hash != launch.Readiness.AttestationsHash {
+		return nil, fmt.Errorf("readiness attestations hash doesn't match launch data")
+	}
+
+	cnt, err := ioutil.ReadFile(attestationsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var attestations []*ReadinessAttestation
+	if err := yamlUnmarshal(cnt, &attestations); err != nil {
+		return nil, err
+	}
+
+	producers := map[eos.AccountName]*ProducerDef{}
+	for _, prod := range launch.Producers {
+		producers[prod.AccountName] = prod
+	}
+
+	ready := map[eos.AccountName]bool{}
+	for _, att := range attestations {
+		prod := producers[att.Account]
+		if prod == nil {
+			info.Printf("- Readiness of %q IGNORED: not in launch file\n", att.Account)
+			continue
+		}
+		if att.LaunchBitcoinBlockHeight != launch.LaunchBitcoinBlockHeight {
+			info.Printf("- Readiness of %q IGNORED: for another launch\n", att.Account)
+			continue
+		}
+		if window := launch.Readiness.WindowStart; !window.IsZero() && att.Timestamp.Before(window) {
+			info.Printf("- Readiness of %q IGNORED: before the readiness window\n", att.Account)
+			continue
+		}
+		if window := launch.Readiness.WindowEnd; !window.IsZero() && att.Timestamp.After(window) {
+			info.Printf("- Readiness of %q IGNORED: after the readiness window\n", att.Account)
+			continue
+		}
+		if !att.Signature.Verify(att.Digest(), prod.InitialBlockSigningPublicKey) {
+			info.Printf("- Readiness of %q IGNORED: invalid signature\n", att.Account)
+			continue
+		}
+
+		ready[att.Account] = true
+	}
+
+	info.Printf("%d of %d producers attested their readiness\n", len(ready), len(launch.Producers))
+
+	if len(ready) < minReadyProducers {
+		return nil, fmt.Errorf("only %d producer(s) attested their readiness, the launch needs at least %d", len(ready), minReadyProducers)
+	}
+
+	return ready, nil
+}
+
+// shufflePool is the list of producers entering the shuffle.
+func (b *BIOS) shufflePool() (out []*ProducerDef) {
+	if b.ReadyAccounts == nil {
+		return b.LaunchData.Producers
+	}
+
+	for _, prod := range b.LaunchData.Producers {
+		if b.ReadyAccounts[prod.AccountName] {
+			out = append(out, prod)
+		}
+	}
+	return
+}
+
+// runAttestReady implements `eos-bios attest-ready`, signing a
+// readiness attestation with the local block signing key, to be
+// published to the other participants.
+func runAttestReady(config *Config, launch *LaunchData) error {
+	// Attestations are verified against the launch file's key, so
+	// refuse to publish one nobody will accept.
+	var prod *ProducerDef
+	for _, p := range launch.Producers {
+		if p.AccountName == AN(config.Producer.MyAccount) {
+			prod = p
+		}
+	}
+	if prod == nil {
+		return fmt.Errorf("account %q is not in the launch file", config.Producer.MyAccount)
+	}
+	if config.Producer.blockSigningPrivateKey == nil {
+		return fmt.Errorf("no block signing private key configured")
+	}
+	if pubKey := config.Producer.blockSigningPrivateKey.PublicKey(); pubKey.String() != prod.InitialBlockSigningPublicKey.String() {
+		return fmt.Errorf("block signing key %s does not match the launch file's initial_block_signing_key %s for %q", pubKey, prod.InitialBlockSigningPublicKey, prod.AccountName)
+	}
+
+	att := &ReadinessAttestation{
+		Account:                  AN(config.Producer.MyAccount),
+		LaunchBitcoinBlockHeight: launch.LaunchBitcoinBlockHeight,
+		Timestamp:                time.Now().UTC().Truncate(time.Second),
+	}
+
+	sig, err := config.Producer.blockSigningPrivateKey.Sign(att.Digest())
+	if err != nil {
+		return fmt.Errorf("signing attestation: %s", err)
+	}
+	att.Signature = sig
+
+	cnt, _ := json.Marshal(att)
+
+	fmt.Println("PUBLISH THIS READINESS ATTESTATION:")
+	fmt.Println("")
+	fmt.Println(string(cnt))
+	fmt.Println("")
+
+	transport, err := newTransport(config, launch)
+	if err != nil {
+		return err
+	}
+
+	b := &BIOS{Config: config, LaunchData: launch, Transport: transport}
+	if err := b.publish("readiness", string(cnt)); err != nil {
+		return fmt.Errorf("publishing attestation: %s", err)
+	}
+	return b.DispatchPublishReadiness(string(cnt))
+}
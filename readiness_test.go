@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadReadyAccountsMinimumPool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "eos-bios-readiness")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	launch := &LaunchData{LaunchBitcoinBlockHeight: 500000}
+	var attestations []*ReadinessAttestation
+	for _, account := range []string{"mama", "papa"} {
+		key, err := ecc.NewRandomPrivateKey()
+		require.NoError(t, err)
+		launch.Producers = append(launch.Producers, &ProducerDef{AccountName: AN(account), InitialBlockSigningPublicKey: key.PublicKey()})
+
+		att := &ReadinessAttestation{Account: AN(account), LaunchBitcoinBlockHeight: 500000, Timestamp: time.Now().UTC().Truncate(time.Second)}
+		att.Signature, err = key.Sign(att.Digest())
+		require.NoError(t, err)
+		attestations = append(attestations, att)
+	}
+
+	load := func(attestations []*ReadinessAttestation) (map[eos.AccountName]bool, error) {
+		cnt, err := json.Marshal(attestations)
+		require.NoError(t, err)
+		attestationsPath := filepath.Join(dir, "attestations.json")
+		require.NoError(t, ioutil.WriteFile(attestationsPath, cnt, 0644))
+		launch.Readiness.AttestationsHash, err = hashFile(attestationsPath)
+		require.NoError(t, err)
+		return loadReadyAccounts(launch, attestationsPath)
+	}
+
+	ready, err := load(attestations)
+	require.NoError(t, err)
+	assert.Len(t, ready, 2)
+
+	_, err = load(attestations[:1])
+	assert.Error(t, err)
+
+	_, err = load(nil)
+	assert.Error(t, err)
+}
+
+func TestAttestReadyKeyMismatch(t *testing.T) {
+	key, err := ecc.NewRandomPrivateKey()
+	require.NoError(t, err)
+	other, err := ecc.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	config := &Config{}
+	config.Producer.MyAccount = "mama"
+	config.Producer.blockSigningPrivateKey = key
+	launch := &LaunchData{Producers: []*ProducerDef{{AccountName: AN("mama"), InitialBlockSigningPublicKey: other.PublicKey()}}}
+
+	err = runAttestReady(config, launch)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "initial_block_signing_key")
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RegistryEntry is what we could verify about a producer's published
+// properties, part of the network registry artifact.
+type RegistryEntry struct {
+	AccountName      string      `json:"account_name"`
+	OrganizationName string      `json:"organization_name"`
+	URLs             []*URLCheck `json:"urls"`
+	// BPJSON is the URL of a `bp.json` matching the launch file's identity, if any was found.
+	BPJSON string `json:"bp_json,omitempty"`
+	OK     bool   `json:"ok"`
+}
+
+type URLCheck struct {
+	URL    string `json:"url"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// bpJSON holds the fields we check of the `bp.json` standard.
+type bpJSON struct {
+	ProducerAccountName string `json:"producer_account_name"`
+	Org                 struct {
+		CandidateName string `json:"candidate_name"`
+	} `json:"org"`
+}
+
+var registryClient = &http.Client{Timeout: 15 * time.Second}
+
+// runRegistry implements `eos-bios registry`, checking that every
+// producer's URLs resolve and serve HTTPS, and writing the network
+// registry to the run directory (or `--output`).
+func runRegistry(launch *LaunchData, config *Config, args []string) error {
+	fs := flag.NewFlagSet("registry", flag.ExitOnError)
+	output := fs.String("output", "", "Where to write the registry JSON, defaults to `registry.json` in run_dir")
+	checkBPJSON := fs.Bool("bp-json", false, "Also require a `bp.json` matching the launch file's identity on one of the producer's URLs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filename := *output
+	if filename == "" {
+		if config.RunDir == "" {
+			return fmt.Errorf("specify --output, or run_dir in your config")
+		}
+		filename = filepath.Join(config.RunDir, "registry.json")
+	}
+
+	var entries []*RegistryEntry
+	failures := 0
+	for _, prod := range launch.Producers {
+		entry := checkProducerProperties(prod, *checkBPJSON)
+		if entry.OK {
+			fmt.Printf("- %s: OKAY\n", prod.AccountName)
+		} else {
+			failures++
+			fmt.Printf("- %s: FAILED\n", prod.AccountName)
+		}
+		for _, check := range entry.URLs {
+			if check.Error != "" {
+				fmt.Printf("    %s: %s\n", check.URL, check.Error)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	cnt, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filename, cnt, 0644); err != nil {
+		return err
+	}
+
+	fmt.Println("Registry written to", filename)
+
+	if failures != 0 {
+		return fmt.Errorf("%d producer(s) failed verification", failures)
+	}
+	return nil
+}
+
+func checkProducerProperties(prod *ProducerDef, checkBPJSON bool) *RegistryEntry {
+	entry := &RegistryEntry{
+		AccountName:      string(prod.AccountName),
+		OrganizationName: prod.OrganizationName,
+		OK:               len(prod.URLs) != 0,
+	}
+
+	for _, rawURL := range prod.URLs {
+		check := &URLCheck{URL: rawURL}
+		entry.URLs = append(entry.URLs, check)
+
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			check.Error = fmt.Sprintf("invalid URL: %s", err)
+			entry.OK = false
+			continue
+		}
+		if u.Scheme != "https" {
+			check.Error = "not served over HTTPS"
+			entry.OK = false
+			continue
+		}
+
+		resp, err := registryClient.Get(rawURL)
+		if err != nil {
+			check.Error = err.Error()
+			entry.OK = false
+			continue
+		}
+		resp.Body.Close()
+
+		check.Status = resp.StatusCode
+		if resp.StatusCode >= 400 {
+			check.Error = fmt.Sprintf("HTTP status %d", resp.StatusCode)
+			entry.OK = false
+			continue
+		}
+
+		if entry.BPJSON == "" {
+			if bpURL, err := fetchMatchingBPJSON(u, prod); err == nil {
+				entry.BPJSON = bpURL
+			} else {
+				verbose.Printf("%s: %s\n", prod.AccountName, err)
+			}
+		}
+	}
+
+	if checkBPJSON && entry.BPJSON == "" {
+		entry.OK = false
+	}
+
+	return entry
+}
+
+// fetchMatchingBPJSON looks for `/bp.json` at the root of `u`, and
+// checks it matches the launch file's identity.
+func fetchMatchingBPJSON(u *url.URL, prod *ProducerDef) (string, error) {
+	bpURL := fmt.Sprintf("%s://%s/bp.json", u.Scheme, u.Host)
+
+	resp, err := registryClient.Get(bpURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: HTTP status %d", bpURL, resp.StatusCode)
+	}
+
+	var bp bpJSON
+	if err := json.NewDecoder(resp.Body).Decode(&bp); err != nil {
+		return "", fmt.Errorf("%s: %s", bpURL, err)
+	}
+
+	if bp.ProducerAccountName != string(prod.AccountName) {
+		return "", fmt.Errorf("%s: producer_account_name is %q", bpURL, bp.ProducerAccountName)
+	}
+	if prod.OrganizationName != "" && !strings.EqualFold(bp.Org.CandidateName, prod.OrganizationName) {
+		return "", fmt.Errorf("%s: org.candidate_name is %q", bpURL, bp.Org.CandidateName)
+	}
+
+	return bpURL, nil
+}
+
+func readRegistry(runDir string) (out []*RegistryEntry, err error) {
+	cnt, err := ioutil.ReadFile(filepath.Join(runDir, "registry.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(cnt, &out)
+	return
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"runtime"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+// verifyReleasePin refuses to participate when this binary doesn't
+// match the build pinned in the launch data, ensuring all
+// participants execute identical logic.
+func verifyReleasePin(launch *LaunchData, config *Config) error {
+	pin := launch.EOSBios
+	if pin.Version == "" && pin.Commit == "" && len(pin.BinaryHashes) == 0 {
+		return nil
+	}
+
+	if err := checkReleasePin(pin.Version, pin.Commit, pin.BinaryHashes, config); err != nil {
+		if config.Debug.AllowUnpinnedBuild {
+			info.Println("DEBUG: ignoring release pin mismatch:", err)
+			return nil
+		}
+		return err
+	}
+
+	info.Printf("Build %s (%s) matches the launch data's pin\n", version, commit)
+	return nil
+}
+
+func checkReleasePin(pinVersion, pinCommit string, binaryHashes map[string]string, config *Config) error {
+	if pinVersion != "" && pinVersion != version {
+		return fmt.Errorf("launch data pins eos-bios version %q, this is %q", pinVersion, version)
+	}
+	if pinCommit != "" && pinCommit != commit {
+		return fmt.Errorf("launch data pins eos-bios commit %q, this is %q", pinCommit, commit)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locating own binary: %s", err)
+	}
+
+	if len(binaryHashes) != 0 {
+		platform := runtime.GOOS + "_" + runtime.GOARCH
+		expected, found := binaryHashes[platform]
+		if !found {
+			return fmt.Errorf("launch data pins no binary for %s", platform)
+		}
+
+		hash, err := hashFile(executable)
+		if err != nil {
+			return fmt.Errorf("hashing own binary: %s", err)
+		}
+		if hash != expected {
+			return fmt.Errorf("binary hash %s doesn't match launch data's %s for %s", hash, expected, platform)
+		}
+	}
+
+	if sigPath := config.Release.SignaturePath; sigPath != "" {
+		if err := checkDetachedSignature(executable, sigPath, config.Release.SigningKeyPath); err != nil {
+			return fmt.Errorf("release signature: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func checkDetachedSignature(filename, sigPath, keyPath string) error {
+	signed, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer signed.Close()
+
+	sig, err := ioutil.ReadFile(sigPath)
+	if err != nil {
+		return err
+	}
+
+	return checkDetachedSignatureOf(signed, sig, keyPath)
+}
+
+// checkDetachedSignatureOf checks `sig`, armored or binary, signs
+// `signed` with the key at `keyPath`.
+func checkDetachedSignatureOf(signed io.Reader, sig []byte, keyPath string) error {
+	keyFile, err := os.Open(keyPath)
+	if err != nil {
+		return err
+	}
+	defer keyFile.Close()
+
+	keyring, err := openpgp.ReadArmoredKeyRing(keyFile)
+	if err != nil {
+		return fmt.Errorf("reading signing key: %s", err)
+	}
+
+	if bytes.HasPrefix(sig, []byte("-----BEGIN PGP SIGNATURE-----")) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyring, signed, bytes.NewReader(sig))
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyring, signed, bytes.NewReader(sig))
+	}
+	return err
+}
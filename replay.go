@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// runReplayLedger implements `eos-bios replay-ledger`: it re-pushes
+// every chunk of a previous run's ledger against a clean node, booted
+// with the same genesis, and compares resulting state hashes.
+func runReplayLedger(args []string) error {
+	fs := flag.NewFlagSet("replay-ledger", flag.ExitOnError)
+	apiAddress := fs.String("api-address", "http://localhost:8888", "API endpoint of the clean-slate node to replay against.")
+	reportPath := fs.String("report", "", "Run report of the boot, whose `ledger_hash` must match the ledger's last entry.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: eos-bios replay-ledger [--api-address URL] [--report report.json] path/to/ledger.jsonl")
+	}
+
+	entries, head, err := readLedgerChain(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *reportPath != "" {
+		report, err := readRunReport(*reportPath)
+		if err != nil {
+			return err
+		}
+		if report.LedgerHash != head {
+			return fmt.Errorf("ledger ends with entry %s, the report recorded %s: the ledger was truncated or tampered with", head, report.LedgerHash)
+		}
+		fmt.Println("Ledger matches the run report's ledger_hash")
+	}
+
+	if len(entries) == 0 || entries[0].Type != "start" {
+		return fmt.Errorf("ledger doesn't begin with a start entry")
+	}
+	start := entries[0]
+
+	apiURL, err := url.Parse(*apiAddress)
+	if err != nil {
+		return err
+	}
+
+	chainID, err := genesisChainID(start.GenesisJSON)
+	if err != nil {
+		return fmt.Errorf("start entry: %s", err)
+	}
+
+	api := eos.New(apiURL, chainID)
+	api.SetSigner(eos.NewKeyBag())
+	if err := api.Signer.ImportPrivateKey(start.EphemeralPrivateKey); err != nil {
+		return fmt.Errorf("ImportWIF: %s", err)
+	}
+
+	fmt.Println("Replaying ledger of launch", start.LaunchHash)
+	fmt.Println("Make sure the target node was started with this genesis:")
+	fmt.Println(start.GenesisJSON)
+
+	var end *LedgerEntry
+	for _, entry := range entries[1:] {
+		switch entry.Type {
+		case "chunk":
+			fmt.Printf("- Step %d [%s], chunk %d: %d actions\n", entry.Step, entry.Op, entry.Chunk, len(entry.Actions))
+			if _, err := api.SignPushActions(entry.Actions...); err != nil {
+				return fmt.Errorf("SignPushActions for step %d, chunk %d: %s", entry.Step, entry.Chunk, err)
+			}
+		case "end":
+			end = entry
+		}
+	}
+
+	if end == nil {
+		return fmt.Errorf("ledger has no end entry, the original boot didn't complete")
+	}
+
+	stateHash, err := computeStateHash(api, end.StateAccounts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Original state hash:", end.StateHash)
+	fmt.Println("Replayed state hash:", stateHash)
+
+	if stateHash != end.StateHash {
+		return fmt.Errorf("state hashes differ, the boot is NOT reproducible")
+	}
+
+	fmt.Println("State hashes match, the boot is reproducible.")
+	return nil
+}
+
+// genesisChainID is the chain ID the boot signed with: the genesis'
+// `initial_chain_id`, the constitution hash of anchored launches.
+func genesisChainID(genesisJSON string) ([]byte, error) {
+	var genesis GenesisJSON
+	if err := json.Unmarshal([]byte(genesisJSON), &genesis); err != nil {
+		return nil, fmt.Errorf("genesis: %s", err)
+	}
+	if genesis.InitialChainID == "" {
+		return make([]byte, 32, 32), nil
+	}
+
+	chainID, err := hex.DecodeString(genesis.InitialChainID)
+	if err != nil || len(chainID) != 32 {
+		return nil, fmt.Errorf("genesis initial_chain_id %q should be a hex-encoded sha256", genesis.InitialChainID)
+	}
+	return chainID, nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenesisChainID(t *testing.T) {
+	constitutionHash := "5b6a2e8f4d7c9b3a1e0f2d4c6b8a9e7f1d3c5b7a9e0f2d4c6b8a1e3f5d7c9b0a"
+	chainID, err := genesisChainID(`{"initial_timestamp": "2018-06-01T12:00:00", "initial_key": "EOS...", "initial_chain_id": "` + constitutionHash + `"}`)
+	require.NoError(t, err)
+	assert.Equal(t, constitutionHash, hex.EncodeToString(chainID))
+
+	chainID, err = genesisChainID(`{"initial_timestamp": "2018-06-01T12:00:00"}`)
+	require.NoError(t, err)
+	assert.Equal(t, make([]byte, 32), chainID)
+
+	_, err = genesisChainID(`{"initial_chain_id": "abcd"}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// RunReport summarizes a run, written to `run_dir/report.json` for
+// operators to publish once the launch is over. Compare two reports
+// with `eos-bios report-diff`.
+type RunReport struct {
+	Account    string    `json:"account"`
+	Role       string    `json:"role"`
+	LaunchHash string    `json:"launch_hash"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Error      string    `json:"error,omitempty"`
+
+	// Transactions pushed by the boot node, in order.
+	Transactions []*ReportTransaction `json:"transactions,omitempty"`
+	// Pipeline instruments the boot node's pushes, see `confirm.go`.
+	Pipeline *PipelineStats `json:"pipeline,omitempty"`
+	// StateHash after the boot sequence, see `ledger.go`.
+	StateHash string `json:"state_hash,omitempty"`
+	// StateManifestRoot is the root of the expected chain state, see
+	// `statemanifest.go`.
+	StateManifestRoot string `json:"state_manifest_root,omitempty"`
+	// EntropyCommitments commit to the sources mixed into the boot
+	// node's ephemeral key, see `entropy.go`.
+	EntropyCommitments []*EntropyCommitment `json:"entropy_commitments,omitempty"`
+	// LedgerHash is the hash of the last ledger entry, to cross-check
+	// the ledger with `eos-bios replay-ledger --report`.
+	LedgerHash string `json:"ledger_hash,omitempty"`
+	// Validations are the results of the ABPs' step validations.
+	Validations []*ValidationFinding `json:"validations,omitempty"`
+	// TokenAccounting is the balance sheet of the boot's token
+	// flows, per symbol, see `accounting.go`.
+	TokenAccounting []*TokenBalanceSheet `json:"token_accounting,omitempty"`
+	// Retries counts the failed calls to optional integrations
+	// (hooks, ABI fetches, ...), retried or skipped.
+	Retries int `json:"retries"`
+
+	// KickstartPublications are the outcomes of the boot node's
+	// `kickstart_publish` channels.
+	KickstartPublications []*KickstartPublication `json:"kickstart_publications,omitempty"`
+
+	// OperatorActions are the operators' confirmations and manual
+	// inputs, see `operator.go`.
+	OperatorActions []*OperatorAction `json:"operator_actions,omitempty"`
+
+	// HookResults are the replies to the `wait: true` webhooks.
+	HookResults []*HookResult `json:"hook_results,omitempty"`
+
+	// Registry is the network registry generated by `eos-bios
+	// registry`, when found in the run directory.
+	Registry []*RegistryEntry `json:"registry,omitempty"`
+}
+
+type ReportTransaction struct {
+	Step          int    `json:"step"`
+	Op            string `json:"op"`
+	Chunk         int    `json:"chunk"`
+	TransactionID string `json:"transaction_id"`
+	// Actions are decoded with the contracts' ABIs, see `abicache.go`.
+	Actions []*ReportAction `json:"actions,omitempty"`
+}
+
+type ReportAction struct {
+	Account eos.AccountName `json:"account"`
+	Name    eos.ActionName  `json:"name"`
+	// Data is absent when the action couldn't be decoded.
+	Data interface{} `json:"data,omitempty"`
+}
+
+type ValidationFinding struct {
+	Step int    `json:"step"`
+	Op   string `json:"op"`
+	// Check is either "built-in", "exec", "audit" (see
+	// `chainaudit.go`) or "state-manifest" (see `statemanifest.go`,
+	// with a step of -1), prefixed with "scheduled " for post-launch
+	// steps.
+	Check string `json:"check"`
+	Error string `json:"error,omitempty"`
+}
+
+func (b *BIOS) reportActions(actions []*eos.Action) (out []*ReportAction) {
+	for _, act := range actions {
+		data, err := b.abis().DecodeAction(act)
+		if err != nil {
+			trace.Printf("Not decoding %s::%s for the report: %s\n", act.Account, act.Name, err)
+		}
+		out = append(out, &ReportAction{Account: act.Account, Name: act.Name, Data: data})
+	}
+	return
+}
+
+func (b *BIOS) role() string {
+	switch {
+	case b.AmIBootNode():
+		return "boot"
+	case b.AmIAppointedBlockProducer():
+		return "abp"
+	default:
+		return "participant"
+	}
+}
+
+// WriteRunReport writes the report for a run started at `startedAt`,
+// and which ended with `runErr`.
+func (b *BIOS) WriteRunReport(startedAt time.Time, runErr error) error {
+	if b.Config.RunDir == "" {
+		return nil
+	}
+
+	report := &b.Report
+	report.Account = b.Config.Producer.MyAccount
+	report.Role = b.role()
+	report.LaunchHash = b.LaunchData.fileHash
+	report.StartedAt = startedAt.UTC()
+	report.FinishedAt = time.Now().UTC()
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+	report.Retries = b.integrationFailures()
+	report.OperatorActions = operatorActions()
+	if b.Ledger != nil {
+		report.LedgerHash = b.Ledger.Head()
+	}
+
+	registry, err := readRegistry(b.Config.RunDir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading registry: %s", err)
+	}
+	report.Registry = registry
+
+	// Background hook deliveries may still be recording results.
+	b.hookResultsLock.Lock()
+	cnt, err := json.MarshalIndent(report, "", "  ")
+	b.hookResultsLock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Join(b.Config.RunDir, "report.json")
+	if err := ioutil.WriteFile(filename, cnt, 0644); err != nil {
+		return err
+	}
+
+	info.Println("Run report written to", filename)
+
+	if b.Config.ArchiveDir != "" {
+		if err := archiveRunReport(b.Config.ArchiveDir, b.Config.Network, report); err != nil {
+			return fmt.Errorf("archiving report: %s", err)
+		}
+	}
+
+	return nil
+}
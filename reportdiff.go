@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// runReportDiff implements `eos-bios report-diff a.json b.json`,
+// printing where two participants' run reports disagree.
+func runReportDiff(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: eos-bios report-diff a.json b.json")
+	}
+
+	a, err := readRunReport(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := readRunReport(args[1])
+	if err != nil {
+		return err
+	}
+
+	differences := diffRunReports(a, b)
+
+	fmt.Printf("A: %s (%s), %s\n", a.Account, a.Role, args[0])
+	fmt.Printf("B: %s (%s), %s\n", b.Account, b.Role, args[1])
+	fmt.Println("")
+	fmt.Printf("Durations: A took %s, B took %s\n", a.FinishedAt.Sub(a.StartedAt), b.FinishedAt.Sub(b.StartedAt))
+	fmt.Println("")
+
+	if len(differences) == 0 {
+		fmt.Println("No divergence found.")
+		return nil
+	}
+
+	for _, diff := range differences {
+		fmt.Println("-", diff)
+	}
+
+	return fmt.Errorf("%d divergence(s) found", len(differences))
+}
+
+func readRunReport(filename string) (*RunReport, error) {
+	cnt, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var report *RunReport
+	if err := json.Unmarshal(cnt, &report); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return report, nil
+}
+
+// diffRunReports lists the divergences between two reports. Reports
+// of participants who didn't push anything (or didn't validate) are
+// only compared on what they hold.
+func diffRunReports(a, b *RunReport) (out []string) {
+	if a.LaunchHash != b.LaunchHash {
+		out = append(out, fmt.Sprintf("launch file hash: A has %s, B has %s", a.LaunchHash, b.LaunchHash))
+	}
+
+	if a.StateHash != "" && b.StateHash != "" && a.StateHash != b.StateHash {
+		out = append(out, fmt.Sprintf("state hash: A has %s, B has %s", a.StateHash, b.StateHash))
+	}
+
+	if a.Error != b.Error {
+		out = append(out, fmt.Sprintf("run error: A has %q, B has %q", a.Error, b.Error))
+	}
+
+	if len(a.Transactions) != 0 && len(b.Transactions) != 0 {
+		txs := func(report *RunReport) map[[2]int]*ReportTransaction {
+			out := map[[2]int]*ReportTransaction{}
+			for _, tx := range report.Transactions {
+				out[[2]int{tx.Step, tx.Chunk}] = tx
+			}
+			return out
+		}
+		aTxs, bTxs := txs(a), txs(b)
+
+		for _, tx := range a.Transactions {
+			other := bTxs[[2]int{tx.Step, tx.Chunk}]
+			if other == nil {
+				out = append(out, fmt.Sprintf("step %d [%s], chunk %d: only in A, transaction %s", tx.Step, tx.Op, tx.Chunk, tx.TransactionID))
+			} else if other.TransactionID != tx.TransactionID {
+				out = append(out, fmt.Sprintf("step %d [%s], chunk %d: A pushed %s, B pushed %s", tx.Step, tx.Op, tx.Chunk, tx.TransactionID, other.TransactionID))
+			}
+		}
+		for _, tx := range b.Transactions {
+			if aTxs[[2]int{tx.Step, tx.Chunk}] == nil {
+				out = append(out, fmt.Sprintf("step %d [%s], chunk %d: only in B, transaction %s", tx.Step, tx.Op, tx.Chunk, tx.TransactionID))
+			}
+		}
+	}
+
+	findings := func(report *RunReport) map[string]*ValidationFinding {
+		out := map[string]*ValidationFinding{}
+		for _, finding := range report.Validations {
+			out[fmt.Sprintf("%d/%s", finding.Step, finding.Check)] = finding
+		}
+		return out
+	}
+	bFindings := findings(b)
+	for _, finding := range a.Validations {
+		other := bFindings[fmt.Sprintf("%d/%s", finding.Step, finding.Check)]
+		if other == nil || other.Error == finding.Error {
+			continue
+		}
+		out = append(out, fmt.Sprintf("step %d [%s], %s validation: A found %s, B found %s", finding.Step, finding.Op, finding.Check, findingResult(finding), findingResult(other)))
+	}
+	return
+}
+
+func findingResult(finding *ValidationFinding) string {
+	if finding.Error == "" {
+		return "OKAY"
+	}
+	return fmt.Sprintf("%q", finding.Error)
+}
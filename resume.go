@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// resumeState is what a previous, interrupted boot recorded in the
+// run directory's ledger.
+type resumeState struct {
+	start  *LedgerEntry
+	chunks map[[2]int]*LedgerEntry // keyed by step and chunk index
+}
+
+// loadResumeState returns the state of the last boot recorded in the
+// ledger, or nil if it completed (or none was ever started). The run
+// directory only holds relative state, so it can be restored on
+// another machine.
+func (b *BIOS) loadResumeState() (*resumeState, error) {
+	if b.Config.RunDir == "" {
+		return nil, nil
+	}
+
+	entries, err := ReadLedger(filepath.Join(b.Config.RunDir, "ledger.jsonl"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state *resumeState
+	for _, entry := range entries {
+		switch entry.Type {
+		case "start":
+			state = &resumeState{start: entry, chunks: map[[2]int]*LedgerEntry{}}
+		case "chunk":
+			if state != nil {
+				state.chunks[[2]int{entry.Step, entry.Chunk}] = entry
+			}
+		case "end":
+			state = nil
+		}
+	}
+
+	return state, nil
+}
+
+// checkResumeIntegrity makes sure the node we point to holds what
+// the ledger says we pushed, before continuing on top of it.
+func (b *BIOS) checkResumeIntegrity(state *resumeState) error {
+	if state.start.LaunchHash != b.LaunchData.fileHash {
+		return fmt.Errorf("interrupted boot used launch file %s, we loaded %s", state.start.LaunchHash, b.LaunchData.fileHash)
+	}
+
+	chainInfo, err := b.API.GetInfo()
+	if err != nil {
+		return fmt.Errorf("get info: %s", err)
+	}
+
+	for _, entry := range state.chunks {
+		if entry.BlockNum > chainInfo.HeadBlockNum {
+			return fmt.Errorf("step %d, chunk %d was included in block %d, but the node's head is %d. Is it the same nodeos?", entry.Step, entry.Chunk, entry.BlockNum, chainInfo.HeadBlockNum)
+		}
+
+		block, err := b.API.GetBlockByNum(entry.BlockNum)
+		if err != nil {
+			return fmt.Errorf("get block %d: %s", entry.BlockNum, err)
+		}
+
+		found := false
+		for _, receipt := range block.Transactions {
+			if hex.EncodeToString(receipt.Transaction.ID) == entry.TransactionID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("transaction %s of step %d, chunk %d not found in block %d", entry.TransactionID, entry.Step, entry.Chunk, entry.BlockNum)
+		}
+	}
+
+	info.Printf("Ledger matches the chain: %d chunks already pushed, head block is %d\n", len(state.chunks), chainInfo.HeadBlockNum)
+
+	return nil
+}
+
+// pushedChunk returns the ledger entry of a chunk already pushed,
+// after checking it carries the same actions we would push now.
+func (s *resumeState) pushedChunk(step, chunk int, actions []*eos.Action) (*LedgerEntry, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	entry := s.chunks[[2]int{step, chunk}]
+	if entry == nil {
+		return nil, nil
+	}
+
+	if actionsHash(actions) != entry.ActionsHash {
+		return nil, fmt.Errorf("step %d, chunk %d differs from what the ledger recorded, refusing to resume", step, chunk)
+	}
+
+	return entry, nil
+}
+
+// chunkLimits are the chunk limits used for `step`, not found when
+// none of its chunks were pushed.
+func (s *resumeState) chunkLimits(step int) (ChunkLimits, bool) {
+	if s == nil {
+		return ChunkLimits{}, false
+	}
+
+	for key, entry := range s.chunks {
+		if key[0] == step && entry.ChunkSize != 0 {
+			return ChunkLimits{MaxActions: entry.ChunkSize, MaxBytes: entry.ChunkBytes}, true
+		}
+	}
+	return ChunkLimits{}, false
+}
+
+func actionsHash(actions []*eos.Action) string {
+	cnt, _ := json.Marshal(actions)
+	hash := sha256.Sum256(cnt)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// With `review_dir` set, each step's transactions are precomputed
+// and signed before the step's first push, and written to
+// `review_dir/step-NN-<op>/chunk-NNN.json`, so other producers can
+// audit exactly what the boot node executes. Co-signed steps are
+// built by the ABPs, and are not exported.
+
+type ReviewTransaction struct {
+	Step          int                    `json:"step"`
+	Op            string                 `json:"op"`
+	Chunk         int                    `json:"chunk"`
+	TransactionID string                 `json:"transaction_id"`
+	Transaction   *eos.SignedTransaction `json:"transaction"`
+	// PackedHex is the packed transaction, as pushed, signatures apart.
+	PackedHex string `json:"packed_hex"`
+}
+
+// exportForReview signs the step's precomputed transactions, and
+// writes them to the review directory. Nil entries (chunks already
+// pushed) are skipped.
+func (b *BIOS) exportForReview(step int, op string, txs []*precomputedTx) error {
+	dir := filepath.Join(b.Config.ReviewDir, fmt.Sprintf("step-%02d-%s", step, op))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	pubKey := b.EphemeralPrivateKey.PublicKey()
+	for chunkIdx, p := range txs {
+		if p == nil {
+			continue
+		}
+		if p.packed == nil {
+			if err := b.signAhead(p, pubKey); err != nil {
+				return fmt.Errorf("chunk %d: %s", chunkIdx, err)
+			}
+		}
+
+		signed, err := p.packed.Unpack()
+		if err != nil {
+			return fmt.Errorf("unpacking chunk %d: %s", chunkIdx, err)
+		}
+
+		cnt, err := json.MarshalIndent(&ReviewTransaction{
+			Step:          step,
+			Op:            op,
+			Chunk:         chunkIdx,
+			TransactionID: p.id,
+			Transaction:   signed,
+			PackedHex:     hex.EncodeToString(p.packed.PackedTransaction),
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("chunk-%03d.json", chunkIdx)), cnt, 0644); err != nil {
+			return err
+		}
+	}
+
+	info.Printf("Signed transactions of step %d written to %s for review\n", step, dir)
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/eoscanada/eos-go/system"
+)
+
+const revokeUsage = `usage: eos-bios revoke [options] <scenario>
+
+Scenarios:
+  signing-key    our block signing key leaked: unregprod, or regproducer
+                 with --new-key
+  account-key    a key of our producer account leaked: updateauth the
+                 --permission with --new-key
+  ephemeral      the boot node's ephemeral key leaked: disable the
+                 "eosio" account right away, if still controlled by it
+
+Options:
+`
+
+// RunRevoke implements `eos-bios revoke`, the rehearsed response to
+// a key compromise during the launch.
+func (b *BIOS) RunRevoke(args []string) error {
+	fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+	keyFile := fs.String("key-file", "", "File holding the private key(s) authorizing the response, one per line. For `ephemeral`, defaults to the key found in the run ledger")
+	newKey := fs.String("new-key", "", "Replacement public key")
+	permission := fs.String("permission", "active", "Permission to update, for `account-key`")
+	fs.Usage = func() {
+		fmt.Print(revokeUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("missing scenario")
+	}
+	scenario := fs.Arg(0)
+
+	var replacement ecc.PublicKey
+	if *newKey != "" {
+		var err error
+		replacement, err = ecc.NewPublicKey(*newKey)
+		if err != nil {
+			return fmt.Errorf("--new-key: %s", err)
+		}
+	}
+
+	myAccount := AN(b.Config.Producer.MyAccount)
+	account := myAccount
+	var actions []*eos.Action
+
+	switch scenario {
+	case "signing-key":
+		if replacement == nil {
+			milestone.Printf("Unregistering producer %s\n", myAccount)
+			actions = append(actions, system.NewUnregProducer(myAccount))
+		} else {
+			milestone.Printf("Registering producer %s with new signing key %s\n", myAccount, replacement)
+			actions = append(actions, system.NewRegProducer(myAccount, replacement, b.Config.MyParameters))
+		}
+
+	case "account-key":
+		if replacement == nil {
+			return fmt.Errorf("account-key requires --new-key")
+		}
+		parent := PN("owner")
+		if *permission == "owner" {
+			parent = PN("")
+		}
+		milestone.Printf("Replacing %s@%s with key %s\n", myAccount, *permission, replacement)
+		actions = append(actions, system.NewUpdateAuth(myAccount, PN(*permission), parent, eos.Authority{
+			Threshold: 1,
+			Keys:      []eos.KeyWeight{{PublicKey: replacement, Weight: 1}},
+		}, PN(*permission)))
+
+	case "ephemeral":
+		account = AN("eosio")
+		acct, err := b.API.GetAccount(account)
+		if err != nil {
+			return fmt.Errorf("get account eosio: %s", err)
+		}
+		disabled := true
+		for _, perm := range acct.Permissions {
+			if perm.RequiredAuth.Threshold != 0 {
+				disabled = false
+			}
+		}
+		if disabled {
+			milestone.Println("The `eosio` account is already disabled, the ephemeral key holds no power. Nothing to do.")
+			return nil
+		}
+		milestone.Println("Disabling the `eosio` account, this ABORTS the launch")
+		actions = append(actions,
+			system.NewUpdateAuth(account, PN("active"), PN("owner"), eos.Authority{Threshold: 0}, PN("active")),
+			system.NewUpdateAuth(account, PN("owner"), PN(""), eos.Authority{Threshold: 0}, PN("owner")),
+		)
+
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown scenario %q", scenario)
+	}
+
+	api := b.API
+	if scenario != "ephemeral" && *keyFile == "" && b.Config.Producer.Ledger.Enabled {
+		// Our own account keys live on the Ledger device.
+		var err error
+		api, err = b.producerAPI()
+		if err != nil {
+			return err
+		}
+	} else {
+		keys, err := b.revokeKeys(scenario, *keyFile)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := b.API.Signer.ImportPrivateKey(key); err != nil {
+				return fmt.Errorf("importing key: %s", err)
+			}
+		}
+	}
+
+	resp, err := api.SignPushActions(actions...)
+	if err != nil {
+		return fmt.Errorf("pushing %s response: %s", scenario, err)
+	}
+
+	milestone.Printf("Pushed in transaction %s\n", resp.TransactionID)
+
+	if err := b.ledgerAppend(&LedgerEntry{
+		Type:          "revoke",
+		Op:            scenario,
+		TransactionID: resp.TransactionID,
+		Actions:       actions,
+	}); err != nil {
+		return fmt.Errorf("ledger: %s", err)
+	}
+
+	notice := fmt.Sprintf("%s responded to a %s compromise on %s, in transaction %s", myAccount, scenario, account, resp.TransactionID)
+	if err := b.publish("revocation", notice); err != nil {
+		milestone.Println("WARNING: failed notifying the group:", err)
+	}
+
+	return b.DispatchKeyRevoked(scenario, account, *newKey, resp.TransactionID)
+}
+
+func (b *BIOS) revokeKeys(scenario, keyFile string) ([]string, error) {
+	if keyFile != "" {
+		cnt, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Fields(string(cnt)), nil
+	}
+
+	if scenario != "ephemeral" || b.Config.RunDir == "" {
+		return nil, fmt.Errorf("--key-file is required")
+	}
+
+	entries, err := ReadLedger(filepath.Join(b.Config.RunDir, "ledger.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("reading ledger: %s", err)
+	}
+	for _, entry := range entries {
+		if entry.Type == "start" && entry.EphemeralPrivateKey != "" {
+			return []string{entry.EphemeralPrivateKey}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no ephemeral key found in the ledger, use --key-file")
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// RolloutStatus is what a producer's declared `api_endpoint` serves,
+// compared to our reference node (the validation API).
+type RolloutStatus struct {
+	Account      eos.AccountName `json:"account"`
+	APIEndpoint  string          `json:"api_endpoint"`
+	Status       string          `json:"status"`
+	ChainID      string          `json:"chain_id,omitempty"`
+	HeadBlockNum uint32          `json:"head_block_num,omitempty"`
+	HeadLag      int64           `json:"head_lag,omitempty"`
+	Details      string          `json:"details,omitempty"`
+}
+
+// RolloutReport is published through the transport, for the launch
+// coordinator to follow up with the flagged producers.
+type RolloutReport struct {
+	Time         time.Time        `json:"time"`
+	ChainID      string           `json:"chain_id"`
+	HeadBlockNum uint32           `json:"head_block_num"`
+	Producers    []*RolloutStatus `json:"producers"`
+}
+
+// rolloutSample is a table whose rows every endpoint should serve the
+// same.
+type rolloutSample struct {
+	Code  string `json:"code"`
+	Scope string `json:"scope"`
+	Table string `json:"table"`
+}
+
+var defaultRolloutSamples = []rolloutSample{
+	{Code: "eosio.token", Scope: "EOS", Table: "stat"},
+}
+
+// RunVerifyRollout implements `eos-bios verify-rollout`: after launch,
+// it queries every producer's public API endpoint, and flags those
+// serving another chain (`different_chain`), lagging behind
+// (`stale`), serving other table rows (`diverging`) or not answering
+// (`unreachable`).
+func (b *BIOS) RunVerifyRollout() error {
+	reference, err := b.validationAPI().GetInfo()
+	if err != nil {
+		return fmt.Errorf("get info of the reference node: %s", err)
+	}
+
+	tolerance := b.Config.Rollout.HeadTolerance
+	if tolerance == 0 {
+		tolerance = 2 * blocksPerRound
+	}
+	samples := b.Config.Rollout.SampleTables
+	if len(samples) == 0 {
+		samples = defaultRolloutSamples
+	}
+
+	referenceRows := map[rolloutSample][]byte{}
+	for _, sample := range samples {
+		rows, err := rolloutRows(b.validationAPI(), sample)
+		if err != nil {
+			return fmt.Errorf("rows of %s/%s/%s on the reference node: %s", sample.Code, sample.Scope, sample.Table, err)
+		}
+		referenceRows[sample] = rows
+	}
+
+	report := &RolloutReport{
+		Time:         time.Now().UTC(),
+		ChainID:      hex.EncodeToString(reference.ChainID),
+		HeadBlockNum: reference.HeadBlockNum,
+	}
+
+	milestone.Printf("Verifying the rollout of chain %s across %d producers, against head block %d\n", report.ChainID, len(b.LaunchData.Producers), reference.HeadBlockNum)
+
+	flagged := 0
+	for _, prod := range b.LaunchData.Producers {
+		status := b.checkProducerRollout(prod, report, samples, referenceRows, int64(tolerance))
+		report.Producers = append(report.Producers, status)
+
+		if status.Status != "ok" && status.Status != "undeclared" {
+			flagged++
+		}
+		info.Printf("- %-12s %-16s %s\n", prod.AccountName, status.Status, status.Details)
+	}
+
+	milestone.Printf("%d of %d producers flagged\n", flagged, len(b.LaunchData.Producers))
+
+	cnt, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if err := b.publish("rollout", string(cnt)); err != nil {
+		return fmt.Errorf("publishing rollout report: %s", err)
+	}
+
+	if flagged != 0 && b.Config.Rollout.FailOnFlagged {
+		return fmt.Errorf("%d producers flagged", flagged)
+	}
+	return nil
+}
+
+func (b *BIOS) checkProducerRollout(prod *ProducerDef, report *RolloutReport, samples []rolloutSample, referenceRows map[rolloutSample][]byte, tolerance int64) *RolloutStatus {
+	status := &RolloutStatus{Account: prod.AccountName, APIEndpoint: prod.APIEndpoint}
+	if prod.APIEndpoint == "" {
+		status.Status = "undeclared"
+		status.Details = "no api_endpoint in the launch file"
+		return status
+	}
+
+	apiURL, err := url.Parse(prod.APIEndpoint)
+	if err != nil {
+		status.Status = "unreachable"
+		status.Details = fmt.Sprintf("api_endpoint: %s", err)
+		return status
+	}
+	api := eos.New(apiURL, b.API.ChainID)
+	api.HttpClient = &http.Client{Timeout: 10 * time.Second}
+
+	chainInfo, err := api.GetInfo()
+	if err != nil {
+		status.Status = "unreachable"
+		status.Details = err.Error()
+		return status
+	}
+	status.ChainID = hex.EncodeToString(chainInfo.ChainID)
+	status.HeadBlockNum = chainInfo.HeadBlockNum
+	status.HeadLag = int64(report.HeadBlockNum) - int64(chainInfo.HeadBlockNum)
+
+	if status.ChainID != report.ChainID {
+		status.Status = "different_chain"
+		status.Details = fmt.Sprintf("serving chain %s", status.ChainID)
+		return status
+	}
+	if status.HeadLag > tolerance {
+		status.Status = "stale"
+		status.Details = fmt.Sprintf("head block %d, %d blocks behind", chainInfo.HeadBlockNum, status.HeadLag)
+		return status
+	}
+
+	for _, sample := range samples {
+		rows, err := rolloutRows(api, sample)
+		if err != nil {
+			status.Status = "unreachable"
+			status.Details = fmt.Sprintf("rows of %s/%s/%s: %s", sample.Code, sample.Scope, sample.Table, err)
+			return status
+		}
+		if !bytes.Equal(rows, referenceRows[sample]) {
+			status.Status = "diverging"
+			status.Details = fmt.Sprintf("rows of %s/%s/%s differ", sample.Code, sample.Scope, sample.Table)
+			return status
+		}
+	}
+
+	status.Status = "ok"
+	status.Details = fmt.Sprintf("head block %d", chainInfo.HeadBlockNum)
+	return status
+}
+
+// rolloutRows returns the first rows of a sampled table, compacted
+// so endpoints compare byte for byte.
+func rolloutRows(api *eos.API, sample rolloutSample) ([]byte, error) {
+	resp, err := api.GetTableRows(eos.GetTableRowsRequest{
+		JSON:  true,
+		Code:  sample.Code,
+		Scope: sample.Scope,
+		Table: sample.Table,
+		Limit: 10,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := json.Compact(&out, resp.Rows); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/eoscanada/eos-go/system"
+	"github.com/eoscanada/eos-go/token"
+)
+
+// The `debug.sabotage_*` ops deliberately deviate from an honest
+// boot, for rehearsals: ABP teams check their validations and
+// sabotage tooling do catch a malicious boot node. They refuse to
+// run unless the boot node's config sets `debug.allow_sabotage`.
+
+func checkSabotageAllowed(b *BIOS, op string) error {
+	if !b.Config.Debug.AllowSabotage {
+		return fmt.Errorf("%s is a rehearsal op, refusing to run without `debug.allow_sabotage` in the config", op)
+	}
+	milestone.Printf("DEBUG: SABOTAGING the boot with %s, for rehearsal purposes\n", op)
+	return nil
+}
+
+// OpSabotageCode sets the code of another contract on an account,
+// yielding an unexpected code hash.
+type OpSabotageCode struct {
+	Account         eos.AccountName
+	ContractNameRef string `json:"contract_name_ref"`
+}
+
+func (op *OpSabotageCode) Actions(b *BIOS) ([]*eos.Action, error) {
+	if err := checkSabotageAllowed(b, "debug.sabotage_code"); err != nil {
+		return nil, err
+	}
+
+	contract, found := b.Config.Contracts[op.ContractNameRef]
+	if !found {
+		return nil, fmt.Errorf("contract %q not found in your config's `contracts`", op.ContractNameRef)
+	}
+
+	setCode, err := system.NewSetCodeTx(op.Account, contract.CodePath, contract.ABIPath)
+	if err != nil {
+		return nil, fmt.Errorf("NewSetCodeTx %s: %s", op.ContractNameRef, err)
+	}
+	return setCode.Actions, nil
+}
+
+// OpSabotageAccount creates an account not found in the launch data,
+// controlled by a key of the boot node's choosing.
+type OpSabotageAccount struct {
+	Account eos.AccountName
+	// Pubkey defaults to the ephemeral key.
+	Pubkey string
+}
+
+func (op *OpSabotageAccount) Actions(b *BIOS) ([]*eos.Action, error) {
+	if err := checkSabotageAllowed(b, "debug.sabotage_account"); err != nil {
+		return nil, err
+	}
+
+	pubkey := b.EphemeralPrivateKey.PublicKey()
+	if op.Pubkey != "" {
+		var err error
+		pubkey, err = ecc.NewPublicKey(op.Pubkey)
+		if err != nil {
+			return nil, fmt.Errorf("reading pubkey: %s", err)
+		}
+	}
+
+	return []*eos.Action{system.NewNewAccount(AN("eosio"), op.Account, pubkey)}, nil
+}
+
+// OpSabotageBalance transfers tokens from `eosio` to an account,
+// which then holds more than the snapshot or launch data grant it.
+type OpSabotageBalance struct {
+	Account eos.AccountName
+	Amount  eos.Asset
+}
+
+func (op *OpSabotageBalance) Actions(b *BIOS) ([]*eos.Action, error) {
+	if err := checkSabotageAllowed(b, "debug.sabotage_balance"); err != nil {
+		return nil, err
+	}
+
+	return []*eos.Action{token.NewTransfer(AN("eosio"), op.Account, op.Amount, "")}, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/eoscanada/eos-go/system"
+)
+
+// When an ABP's validations find the chain deviates from the launch
+// data, the network must not quietly proceed: the ABP refuses to
+// register as a producer, and publishes a failure report signed with
+// its block signing key. With `on_validation_failure: sabotage`, it
+// also sabotages the network as described in the README: its
+// producer accounts are disabled, like `eosio`, so the compromised
+// chain can't count on them.
+
+// ValidationFailureReport lists the failed validations of an ABP.
+type ValidationFailureReport struct {
+	Account    eos.AccountName      `json:"account"`
+	LaunchHash string               `json:"launch_hash"`
+	Failures   []*ValidationFinding `json:"failures"`
+	Sabotaged  bool                 `json:"sabotaged"`
+	Timestamp  time.Time            `json:"timestamp"`
+	Signature  ecc.Signature        `json:"signature"`
+}
+
+func (r *ValidationFailureReport) Digest() []byte {
+	failures, _ := json.Marshal(r.Failures)
+	h := sha256.Sum256([]byte(fmt.Sprintf("eos-bios-validation-failed:%s:%s:%t:%s:%s", r.Account, r.LaunchHash, r.Sabotaged, r.Timestamp.UTC().Format(time.RFC3339), failures)))
+	return h[:]
+}
+
+// handleValidationFailure sabotages when configured, publishes the
+// signed failure report, and returns the error aborting the run
+// before `regproducer`.
+func (b *BIOS) handleValidationFailure(validationErr error) error {
+	milestone.Println("###############################################################################################")
+	milestone.Println("VALIDATION FAILED, the chain deviates from the launch data:", validationErr)
+	milestone.Println("Refusing to register as a producer on this chain.")
+
+	report := &ValidationFailureReport{
+		Account:    AN(b.Config.Producer.MyAccount),
+		LaunchHash: b.LaunchData.fileHash,
+		Timestamp:  time.Now().UTC().Truncate(time.Second),
+	}
+	for _, finding := range b.Report.Validations {
+		if finding.Error != "" {
+			report.Failures = append(report.Failures, finding)
+		}
+	}
+
+	if b.Config.OnValidationFailure == "sabotage" {
+		if err := b.sabotageNetwork(); err != nil {
+			milestone.Println("WARNING: sabotaging the network failed:", err)
+		} else {
+			report.Sabotaged = true
+		}
+	}
+
+	sig, err := b.Config.Producer.blockSigningPrivateKey.Sign(report.Digest())
+	if err != nil {
+		return fmt.Errorf("%s, and signing the failure report failed: %s", validationErr, err)
+	}
+	report.Signature = sig
+
+	cnt, _ := json.Marshal(report)
+
+	milestone.Println("PUBLISH THIS VALIDATION FAILURE REPORT:")
+	milestone.Println("")
+	milestone.Println(string(cnt))
+	milestone.Println("")
+
+	if err := b.publish("validation_failed", string(cnt)); err != nil {
+		milestone.Println("WARNING: publishing the failure report:", err)
+	}
+	if err := b.DispatchValidationFailed(string(cnt)); err != nil {
+		milestone.Println("WARNING: dispatch validation_failed:", err)
+	}
+
+	return fmt.Errorf("validations failed, not registering: %s", validationErr)
+}
+
+// sabotageNetwork disables our producer accounts, clones included,
+// as they are all ours.
+func (b *BIOS) sabotageNetwork() error {
+	api, err := b.producerAPI()
+	if err != nil {
+		return err
+	}
+
+	for _, prod := range b.MyProducerDefs {
+		acct := prod.AccountName
+		milestone.Printf("SABOTAGING: disabling our producer account %s\n", acct)
+
+		_, err := api.SignPushActions(
+			system.NewUpdateAuth(acct, PN("active"), PN("owner"), eos.Authority{Threshold: 0}, PN("active")),
+			system.NewUpdateAuth(acct, PN("owner"), PN(""), eos.Authority{Threshold: 0}, PN("owner")),
+		)
+		if err != nil {
+			return fmt.Errorf("disabling %s: %s", acct, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// SystemGlobalState holds the fields we follow of the system
+// contract's `global` table.
+type SystemGlobalState struct {
+	TotalActivatedStake int64
+	// ThreshActivatedStakeTime is set once 15% of the tokens voted,
+	// activating the chain.
+	ThreshActivatedStakeTime uint64
+}
+
+func (s *SystemGlobalState) VotingActivated() bool {
+	return s.ThreshActivatedStakeTime != 0
+}
+
+func (b *BIOS) getSystemGlobalState() (*SystemGlobalState, error) {
+	resp, err := b.validationAPI().GetTableRows(eos.GetTableRowsRequest{
+		JSON:  true,
+		Code:  "eosio",
+		Scope: "eosio",
+		Table: "global",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal(resp.Rows, &rows); err != nil {
+		return nil, fmt.Errorf("decoding global rows: %s", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no global state, is the system contract set?")
+	}
+
+	state := &SystemGlobalState{}
+	// 64 bits integers are rendered as strings by some nodeos versions.
+	stake, err := strconv.ParseInt(strings.Trim(string(rows[0]["total_activated_stake"]), `"`), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("total_activated_stake: %s", err)
+	}
+	state.TotalActivatedStake = stake
+
+	activated, err := strconv.ParseUint(strings.Trim(string(rows[0]["thresh_activated_stake_time"]), `"`), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("thresh_activated_stake_time: %s", err)
+	}
+	state.ThreshActivatedStakeTime = activated
+
+	return state, nil
+}
+
+// scheduleWatch follows the producer schedule in block headers, and
+// alerts when it changes before voting activated: nothing but
+// `setprods` during the boot should touch it until then.
+type scheduleWatch struct {
+	b       *BIOS
+	version uint32
+	seen    bool
+}
+
+func (w *scheduleWatch) onBlock(block *eos.BlockResp) {
+	if w.seen && block.ScheduleVersion == w.version && block.NewProducers == nil {
+		return
+	}
+
+	if !w.seen {
+		w.seen = true
+		w.version = block.ScheduleVersion
+		if block.NewProducers == nil {
+			info.Printf("Producer schedule at version %d\n", w.version)
+			return
+		}
+	}
+
+	var change string
+	if block.NewProducers != nil {
+		var names []string
+		for _, prod := range block.NewProducers.Producers {
+			names = append(names, string(prod.ProducerName))
+		}
+		change = fmt.Sprintf("block %d proposes schedule version %d: %s", block.BlockNum, block.NewProducers.Version, strings.Join(names, ", "))
+	} else {
+		change = fmt.Sprintf("block %d moved the schedule from version %d to %d", block.BlockNum, w.version, block.ScheduleVersion)
+		w.version = block.ScheduleVersion
+	}
+
+	state, err := w.b.getSystemGlobalState()
+	if err == nil && state.VotingActivated() {
+		info.Println("Producer schedule change, after voting activation:", change)
+		return
+	}
+
+	milestone.Println("ALERT: UNEXPECTED producer schedule change before voting activation:", change)
+	if err := w.b.DispatchUnexpectedScheduleChange(change); err != nil {
+		milestone.Println("WARNING: dispatch unexpected_schedule_change hook:", err)
+	}
+}
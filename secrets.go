@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// SecretProvider fetches secrets at startup, so boot machines don't
+// need to store long-lived secrets on disk.
+type SecretProvider interface {
+	// Get returns the secret at `path`, whose format depends on the provider.
+	Get(path string) (string, error)
+}
+
+// secretProviders are selected by the prefix of a secret reference,
+// like `vault:secret/data/bp#signing_key`.
+func secretProviders(config *Config) map[string]SecretProvider {
+	return map[string]SecretProvider{
+		"vault": &vaultSecrets{address: config.Secrets.Vault.Address, tokenPath: config.Secrets.Vault.TokenPath},
+		"aws":   &awsSecrets{region: config.Secrets.AWS.Region},
+		"env":   envSecrets{},
+		"file":  fileSecrets{},
+	}
+}
+
+// resolveSecret fetches the secret referenced by `ref`, formatted as
+// `<provider>:<path>`.
+func resolveSecret(config *Config, ref string) (string, error) {
+	chunks := strings.SplitN(ref, ":", 2)
+	if len(chunks) != 2 {
+		return "", fmt.Errorf("secret reference %q should be formatted as <provider>:<path>", ref)
+	}
+
+	provider, found := secretProviders(config)[chunks[0]]
+	if !found {
+		return "", fmt.Errorf("unknown secret provider %q, use one of vault, aws, env or file", chunks[0])
+	}
+
+	secret, err := provider.Get(chunks[1])
+	if err != nil {
+		return "", fmt.Errorf("fetching secret %q: %s", ref, err)
+	}
+
+	return strings.TrimSpace(secret), nil
+}
+
+// splitSecretField splits `path#field`, the field picking a key of a
+// JSON object secret.
+func splitSecretField(path string) (string, string) {
+	if idx := strings.LastIndex(path, "#"); idx != -1 {
+		return path[:idx], path[idx+1:]
+	}
+	return path, ""
+}
+
+func jsonSecretField(cnt []byte, field string) (string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(cnt, &fields); err != nil {
+		return "", err
+	}
+	value, ok := fields[field].(string)
+	if !ok {
+		return "", fmt.Errorf("no string field %q in secret", field)
+	}
+	return value, nil
+}
+
+// vaultSecrets reads from HashiCorp Vault's HTTP API, paths being
+// formatted as `secret/data/bp#field`. Both KV versions are supported.
+type vaultSecrets struct {
+	address   string
+	tokenPath string
+}
+
+func (v *vaultSecrets) Get(path string) (string, error) {
+	address := v.address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return "", fmt.Errorf("set secrets.vault.address, or $VAULT_ADDR")
+	}
+
+	token := os.Getenv("VAULT_TOKEN")
+	if v.tokenPath != "" {
+		cnt, err := ioutil.ReadFile(v.tokenPath)
+		if err != nil {
+			return "", err
+		}
+		token = strings.TrimSpace(string(cnt))
+	}
+
+	secretPath, field := splitSecretField(path)
+	if field == "" {
+		return "", fmt.Errorf("vault paths need a #field")
+	}
+
+	req, err := http.NewRequest("GET", strings.TrimRight(address, "/")+"/v1/"+secretPath, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	cnt, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault status code=%d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(cnt, &body); err != nil {
+		return "", err
+	}
+
+	// KV version 2 nests the secret under `data.data`.
+	data, err := json.Marshal(body.Data)
+	if err != nil {
+		return "", err
+	}
+	if nested, ok := body.Data["data"]; ok {
+		data = nested
+	}
+
+	return jsonSecretField(data, field)
+}
+
+// awsSecrets reads from AWS Secrets Manager through the `aws` CLI,
+// using its usual credentials chain. Paths are a secret ID, with an
+// optional `#field` for JSON secrets.
+type awsSecrets struct {
+	region string
+}
+
+func (a *awsSecrets) Get(path string) (string, error) {
+	secretID, field := splitSecretField(path)
+
+	args := []string{"secretsmanager", "get-secret-value", "--secret-id", secretID, "--query", "SecretString", "--output", "text"}
+	if a.region != "" {
+		args = append(args, "--region", a.region)
+	}
+
+	cmd := exec.Command("aws", args...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("aws cli: %s", err)
+	}
+
+	if field == "" {
+		return string(out), nil
+	}
+	return jsonSecretField(out, field)
+}
+
+type envSecrets struct{}
+
+func (envSecrets) Get(name string) (string, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return "", fmt.Errorf("$%s is empty", name)
+	}
+	return value, nil
+}
+
+type fileSecrets struct{}
+
+func (fileSecrets) Get(path string) (string, error) {
+	cnt, err := ioutil.ReadFile(path)
+	return string(cnt), err
+}
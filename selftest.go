@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pushCostFactor is a rough estimate of how much longer pushing a
+// full chunk takes compared to a `get_info` round-trip, on a clean
+// node.
+const pushCostFactor = 10
+
+// selfTestPayloadSize is the size of the bodies posted to measure the
+// throughput to the local node's API.
+const selfTestPayloadSize = 1024 * 1024
+
+// RunSelfTest measures latency and throughput to the local node's
+// API, latency to the declared peers and the hook endpoints, and
+// warns when snapshot injection is unlikely to complete within the
+// stage deadline.
+func (b *BIOS) RunSelfTest() error {
+	fmt.Println("Running launch-day network self-test")
+
+	warnings := 0
+
+	apiLatency, err := b.measureAPILatency(10)
+	if err != nil {
+		return fmt.Errorf("local node API unreachable: %s", err)
+	}
+	fmt.Printf("- Local node API: median get_info latency %s\n", apiLatency)
+
+	throughput, err := b.measureAPIThroughput(3, apiLatency)
+	if err != nil {
+		return fmt.Errorf("local node API throughput: %s", err)
+	}
+	fmt.Printf("- Local node API: median upload throughput %.1f MB/s\n", throughput/1024/1024)
+
+	for _, peer := range b.Config.SelfTest.Peers {
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", peer, 5*time.Second)
+		if err != nil {
+			warnings++
+			fmt.Printf("- Peer %s: UNREACHABLE (%s)\n", peer, err)
+			continue
+		}
+		conn.Close()
+		fmt.Printf("- Peer %s: connected in %s\n", peer, time.Since(start))
+	}
+
+	for _, hook := range configuredHooks {
+		conf := b.Config.Hooks[hook.Key]
+		if conf == nil || conf.URL == "" {
+			continue
+		}
+
+		start := time.Now()
+		client := &http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Head(conf.URL)
+		if err != nil {
+			warnings++
+			fmt.Printf("- Hook %q endpoint: UNREACHABLE (%s)\n", hook.Key, err)
+			continue
+		}
+		resp.Body.Close()
+		fmt.Printf("- Hook %q endpoint: responded in %s\n", hook.Key, time.Since(start))
+	}
+
+	transactions, size, err := b.snapshotChunks()
+	if err != nil {
+		return fmt.Errorf("chunking the snapshot steps: %s", err)
+	}
+	estimate := time.Duration(transactions)*apiLatency*pushCostFactor + time.Duration(float64(size)/throughput*float64(time.Second))
+	fmt.Printf("- Snapshot injection: %d rows, %d transactions of %d MB, estimated %s\n", len(b.Snapshot), transactions, size/1024/1024, estimate)
+
+	if deadline := b.Config.SelfTest.StageDeadline; deadline != "" {
+		budget, err := time.ParseDuration(deadline)
+		if err != nil {
+			return fmt.Errorf("self_test.stage_deadline: %s", err)
+		}
+		if estimate > budget {
+			warnings++
+			fmt.Printf("  WARNING: estimate exceeds the stage deadline of %s\n", budget)
+		}
+	}
+
+	if warnings != 0 {
+		fmt.Printf("Self-test completed with %d warning(s)\n", warnings)
+	} else {
+		fmt.Println("Self-test completed, all good")
+	}
+
+	return nil
+}
+
+func (b *BIOS) measureAPILatency(samples int) (time.Duration, error) {
+	var latencies []time.Duration
+	for i := 0; i < samples; i++ {
+		start := time.Now()
+		if _, err := b.API.GetInfo(); err != nil {
+			return 0, err
+		}
+		latencies = append(latencies, time.Since(start))
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return latencies[len(latencies)/2], nil
+}
+
+// measureAPIThroughput posts large bodies to the local node's API, and
+// returns the median rate, in bytes per second, not counting the
+// round-trip `latency`.
+func (b *BIOS) measureAPIThroughput(samples int, latency time.Duration) (float64, error) {
+	endpoint := b.Config.Producer.apiAddressURL.ResolveReference(&url.URL{Path: "/v1/chain/get_info"})
+	// A JSON object padded with whitespace, which `get_info` ignores.
+	payload := append(append([]byte("{"), bytes.Repeat([]byte(" "), selfTestPayloadSize-2)...), '}')
+
+	client := b.API.HttpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var rates []float64
+	for i := 0; i < samples; i++ {
+		start := time.Now()
+		resp, err := client.Post(endpoint.String(), "application/json", bytes.NewReader(payload))
+		if err != nil {
+			return 0, err
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		elapsed := time.Since(start) - latency
+		if elapsed < time.Millisecond {
+			elapsed = time.Millisecond
+		}
+		rates = append(rates, float64(len(payload))/elapsed.Seconds())
+	}
+
+	sort.Float64s(rates)
+	return rates[len(rates)/2], nil
+}
+
+// snapshotChunks chunks the actions of the snapshot steps like the
+// boot does, with the configured chunk limits, and returns the number
+// of transactions and their size.
+func (b *BIOS) snapshotChunks() (transactions, size int, err error) {
+	for idx, step := range b.LaunchData.BootSequence {
+		if !strings.HasPrefix(step.Op, "snapshot.") {
+			continue
+		}
+
+		b.currentStep = idx
+		acts, err := step.Data.Actions(b)
+		if err != nil {
+			return 0, 0, fmt.Errorf("step %q: %s", step.Op, err)
+		}
+		for _, chunk := range chunkifyActions(acts, b.chunkLimits(step.Op)) {
+			transactions++
+			for _, act := range chunk {
+				size += actionSize(act)
+			}
+		}
+	}
+	return
+}
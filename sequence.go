@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// With `deterministic_transactions` in the launch data, two honest
+// boot nodes given the same inputs (launch file, shuffle seed,
+// ephemeral key) push byte-identical transactions, so rehearsals with
+// parallel boot nodes can compare their chains in full:
+//
+// - every chunk begins with an `eosio::nonce` of
+//   "eos-bios:seq:<launch hash prefix>:<step>:<chunk>", so no two
+//   transactions of the boot are the same, whatever their actions;
+// - all transactions reference block 1 (TaPoS), which only depends
+//   on the genesis;
+// - a step's transactions all expire at the first slot boundary
+//   (every 20 minutes from the genesis timestamp) at least 30
+//   minutes past the head block time when the step begins;
+// - chunks are never resized by the canary, and boot nodes need the
+//   same `chunking` config.
+//
+// Co-signed steps are built by the ABPs, and can't be made
+// deterministic, so that mode excludes `co_sign`.
+
+const (
+	deterministicExpirySlot   = 20 * time.Minute
+	deterministicExpiryMargin = 30 * time.Minute
+)
+
+func sequenceNonce(launchHash string, step, chunk int) string {
+	if len(launchHash) > 16 {
+		launchHash = launchHash[:16]
+	}
+	return fmt.Sprintf("eos-bios:seq:%s:%d:%d", launchHash, step, chunk)
+}
+
+// sequenceChunks prepends the sequence nonce to each chunk of a step.
+func (b *BIOS) sequenceChunks(step int, chunks [][]*eos.Action) [][]*eos.Action {
+	out := make([][]*eos.Action, len(chunks))
+	for chunkIdx, chunk := range chunks {
+		nonce := newNonce(sequenceNonce(b.LaunchData.fileHash, step, chunkIdx))
+		out[chunkIdx] = append([]*eos.Action{nonce}, chunk...)
+	}
+	return out
+}
+
+// deterministicExpiration is the first slot boundary, counted from
+// `genesisTime`, at least `deterministicExpiryMargin` past `headTime`.
+func deterministicExpiration(genesisTime, headTime time.Time) time.Time {
+	earliest := headTime.Add(deterministicExpiryMargin)
+	slots := earliest.Sub(genesisTime) / deterministicExpirySlot
+	expiresAt := genesisTime.Add(slots * deterministicExpirySlot)
+	if expiresAt.Before(earliest) {
+		expiresAt = expiresAt.Add(deterministicExpirySlot)
+	}
+	return expiresAt.UTC()
+}
+
+// deterministicRefBlock is the ID of block 1, referenced by all boot
+// transactions.
+func (b *BIOS) deterministicRefBlock() (eos.SHA256Bytes, error) {
+	block, err := b.API.GetBlockByNum(1)
+	if err != nil {
+		return nil, fmt.Errorf("get block 1: %s", err)
+	}
+	return block.ID, nil
+}
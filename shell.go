@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+)
+
+// RunShell implements `eos-bios shell`, an interactive prompt
+// attached to the run directory, with the run's launch data, config,
+// ABIs and keys at hand. For troubleshooting rehearsals.
+func (b *BIOS) RunShell() error {
+	if b.Config.RunDir == "" {
+		return fmt.Errorf("shell needs a `run_dir`")
+	}
+
+	fmt.Printf("eos-bios shell on %s, against %s. Type `help` for the commands.\n", b.Config.RunDir, b.Config.Producer.APIAddress)
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("bios> ")
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			fmt.Println("")
+			return nil
+		}
+
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "quit" || args[0] == "exit" {
+			return nil
+		}
+
+		command, found := shellCommands[args[0]]
+		if !found {
+			fmt.Printf("Unknown command %q, type `help`\n", args[0])
+			continue
+		}
+		if len(args)-1 < command.minArgs {
+			fmt.Println("Usage:", args[0], command.usage)
+			continue
+		}
+		if err := command.run(b, args[1:]); err != nil {
+			fmt.Println("ERROR:", err)
+		}
+	}
+}
+
+type shellCommand struct {
+	usage       string
+	description string
+	minArgs     int
+	run         func(b *BIOS, args []string) error
+}
+
+var shellCommands map[string]*shellCommand
+
+func init() {
+	shellCommands = map[string]*shellCommand{
+		"help":    {"", "Lists the commands.", 0, shellHelp},
+		"ledger":  {"[type]", "Lists the ledger entries, optionally of one type (start, chunk, end, ...).", 0, shellLedger},
+		"entry":   {"<index>", "Prints a ledger entry, with its actions decoded.", 1, shellEntry},
+		"steps":   {"", "Lists the boot sequence, with the chunks pushed per step.", 0, shellSteps},
+		"repush":  {"<step> <chunk>", "Builds a step's chunk again, pushes it and records it in the ledger.", 2, shellRepush},
+		"decode":  {"<account> <action> <hex data>", "Decodes action data with the account's ABI.", 3, shellDecode},
+		"info":    {"", "Prints the chain's info.", 0, shellInfo},
+		"account": {"<name>", "Prints an account.", 1, shellAccount},
+		"balance": {"<name>", "Prints an account's core token balance.", 1, shellBalance},
+		"table":   {"<code> <scope> <table>", "Prints the rows of a table.", 3, shellTable},
+	}
+}
+
+func shellHelp(b *BIOS, args []string) error {
+	var names []string
+	for name := range shellCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		command := shellCommands[name]
+		fmt.Printf("  %-32s %s\n", strings.TrimSpace(name+" "+command.usage), command.description)
+	}
+	fmt.Printf("  %-32s %s\n", "quit", "Leaves the shell.")
+	return nil
+}
+
+func (b *BIOS) shellLedgerEntries() ([]*LedgerEntry, error) {
+	return ReadLedger(filepath.Join(b.Config.RunDir, "ledger.jsonl"))
+}
+
+func shellLedger(b *BIOS, args []string) error {
+	entries, err := b.shellLedgerEntries()
+	if err != nil {
+		return err
+	}
+
+	for idx, entry := range entries {
+		if len(args) != 0 && entry.Type != args[0] {
+			continue
+		}
+		details := ""
+		switch entry.Type {
+		case "start":
+			details = "launch " + entry.LaunchHash
+		case "chunk", "scheduled", "revoke":
+			details = fmt.Sprintf("step %d [%s] chunk %d, %d actions, tx %s in block %d", entry.Step, entry.Op, entry.Chunk, len(entry.Actions), entry.TransactionID, entry.BlockNum)
+		case "end":
+			details = "state hash " + entry.StateHash
+		case "operator":
+			if entry.Operator != nil {
+				details = fmt.Sprintf("%s by %s: %s", entry.Operator.Kind, entry.Operator.Operator, entry.Operator.What)
+			}
+		}
+		fmt.Printf("%5d  %s  %-9s  %s\n", idx, entry.Time.Format("15:04:05"), entry.Type, details)
+	}
+	return nil
+}
+
+func shellEntry(b *BIOS, args []string) error {
+	entries, err := b.shellLedgerEntries()
+	if err != nil {
+		return err
+	}
+
+	idx, err := strconv.Atoi(args[0])
+	if err != nil || idx < 0 || idx >= len(entries) {
+		return fmt.Errorf("no ledger entry %q, there are %d", args[0], len(entries))
+	}
+	entry := *entries[idx]
+	actions := entry.Actions
+	entry.Actions = nil
+	if entry.EphemeralPrivateKey != "" {
+		entry.EphemeralPrivateKey = "(redacted)"
+	}
+
+	cnt, _ := json.MarshalIndent(entry, "", "  ")
+	fmt.Println(string(cnt))
+
+	for actIdx, decoded := range b.reportActions(actions) {
+		data, _ := json.Marshal(decoded.Data)
+		fmt.Printf("- action %d: %s::%s %s\n", actIdx, decoded.Account, decoded.Name, data)
+	}
+	return nil
+}
+
+func shellSteps(b *BIOS, args []string) error {
+	entries, err := b.shellLedgerEntries()
+	if err != nil {
+		return err
+	}
+
+	pushed := map[int]int{}
+	for _, entry := range entries {
+		if entry.Type == "chunk" {
+			pushed[entry.Step]++
+		}
+	}
+
+	for idx, step := range b.LaunchData.BootSequence {
+		fmt.Printf("%3d  %-28s %-40s %d chunk(s) pushed\n", idx, step.Op, step.Label, pushed[idx])
+	}
+	return nil
+}
+
+// shellRepush pushes a chunk the way the boot node would have, signed
+// with the ephemeral key of the ledger's last boot.
+func shellRepush(b *BIOS, args []string) error {
+	stepIdx, err := strconv.Atoi(args[0])
+	if err != nil || stepIdx < 0 || stepIdx >= len(b.LaunchData.BootSequence) {
+		return fmt.Errorf("no boot sequence step %q", args[0])
+	}
+	chunkIdx, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid chunk %q", args[1])
+	}
+	step := b.LaunchData.BootSequence[stepIdx]
+
+	resume, err := b.loadResumeState()
+	if err != nil {
+		return err
+	}
+	if resume == nil {
+		return fmt.Errorf("the ledger holds no interrupted boot")
+	}
+	if pushed, found := resume.chunks[[2]int{stepIdx, chunkIdx}]; found {
+		return fmt.Errorf("chunk %d of step %d was already pushed in transaction %s", chunkIdx, stepIdx, pushed.TransactionID)
+	}
+
+	if b.EphemeralPrivateKey == nil {
+		b.EphemeralPrivateKey, err = ecc.NewPrivateKey(resume.start.EphemeralPrivateKey)
+		if err != nil {
+			return fmt.Errorf("ephemeral key of the ledger: %s", err)
+		}
+		if err := b.API.Signer.ImportPrivateKey(resume.start.EphemeralPrivateKey); err != nil {
+			return fmt.Errorf("ImportWIF: %s", err)
+		}
+	}
+
+	b.currentStep = stepIdx
+	acts, err := step.Data.Actions(b)
+	if err != nil {
+		return fmt.Errorf("getting actions: %s", err)
+	}
+	limits, err := b.stepChunkLimits(stepIdx, step.Op, acts, resume)
+	if err != nil {
+		return err
+	}
+	chunks := chunkifyActions(acts, limits)
+	if chunkIdx < 0 || chunkIdx >= len(chunks) {
+		return fmt.Errorf("step %d only has %d chunk(s)", stepIdx, len(chunks))
+	}
+	chunk := chunks[chunkIdx]
+
+	fmt.Printf("Pushing %d action(s) of step %d [%s], chunk %d\n", len(chunk), stepIdx, step.Op, chunkIdx)
+	b.recordOperatorAction("", "input", fmt.Sprintf("shell repush of step %d [%s], chunk %d", stepIdx, step.Op, chunkIdx), "")
+	resp, err := b.API.SignPushActions(chunk...)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Pushed in transaction %s, block %d\n", resp.TransactionID, resp.BlockNum)
+
+	return b.ledgerAppend(&LedgerEntry{
+		Type:          "chunk",
+		Step:          stepIdx,
+		Op:            step.Op,
+		Chunk:         chunkIdx,
+		TransactionID: resp.TransactionID,
+		BlockNum:      resp.BlockNum,
+		ChunkSize:     limits.MaxActions,
+		ChunkBytes:    limits.MaxBytes,
+		Actions:       chunk,
+		ActionsHash:   actionsHash(chunk),
+	})
+}
+
+func shellDecode(b *BIOS, args []string) error {
+	data, err := hex.DecodeString(args[2])
+	if err != nil {
+		return fmt.Errorf("hex data: %s", err)
+	}
+
+	act := &eos.Action{Account: AN(args[0]), Name: ActN(args[1]), ActionData: eos.ActionData{HexData: data}}
+	decoded, err := b.abis().DecodeAction(act)
+	if err != nil {
+		return err
+	}
+	return shellPrint(decoded)
+}
+
+func shellInfo(b *BIOS, args []string) error {
+	chainInfo, err := b.API.GetInfo()
+	if err != nil {
+		return err
+	}
+	return shellPrint(chainInfo)
+}
+
+func shellAccount(b *BIOS, args []string) error {
+	acct, err := b.API.GetAccount(AN(args[0]))
+	if err != nil {
+		return err
+	}
+	return shellPrint(acct)
+}
+
+func shellBalance(b *BIOS, args []string) error {
+	balance, err := b.getTokenBalance(AN(args[0]))
+	if err != nil {
+		return err
+	}
+	fmt.Println(balance)
+	return nil
+}
+
+func shellTable(b *BIOS, args []string) error {
+	resp, err := b.API.GetTableRows(eos.GetTableRowsRequest{
+		JSON:  true,
+		Code:  args[0],
+		Scope: args[1],
+		Table: args[2],
+		Limit: 100,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(resp.Rows))
+	if resp.More {
+		fmt.Println("(more rows)")
+	}
+	return nil
+}
+
+func shellPrint(v interface{}) error {
+	cnt, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(cnt))
+	return nil
+}
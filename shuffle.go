@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// Shuffler deterministically orders producers from a seed. Every
+// implementation must be simple enough to be reimplemented in other
+// languages, and cross-checked with the test vectors.
+type Shuffler interface {
+	Shuffle(producers []*ProducerDef, seed []byte) []*ProducerDef
+}
+
+// defaultShuffleAlgorithm is used when the launch file doesn't pick
+// a `shuffle_algorithm`.
+const defaultShuffleAlgorithm = "fisher_yates"
+
+var shufflersRegistry = map[string]Shuffler{
+	"fisher_yates": &FisherYatesShuffler{},
+	"hash_chain":   &HashChainShuffler{},
+}
+
+// FisherYatesShuffler sorts producers by account name, then runs a
+// Fisher-Yates shuffle drawing from a SHA-256 counter-mode stream:
+// the n-th draw is the first 8 bytes (big endian) of
+// `sha256(seed || uint64_be(n))`. Draws are rejected when they would
+// introduce modulo bias.
+type FisherYatesShuffler struct{}
+
+func (s *FisherYatesShuffler) Shuffle(producers []*ProducerDef, seed []byte) []*ProducerDef {
+	out := sortedByAccountName(producers)
+
+	var counter uint64
+	draw := func(max uint64) uint64 {
+		limit := ^uint64(0) - (^uint64(0) % max)
+		for {
+			buf := make([]byte, 8)
+			binary.BigEndian.PutUint64(buf, counter)
+			counter++
+
+			h := sha256.Sum256(append(append([]byte{}, seed...), buf...))
+			value := binary.BigEndian.Uint64(h[:8])
+			if value < limit {
+				return value % max
+			}
+		}
+	}
+
+	for i := len(out) - 1; i > 0; i-- {
+		j := int(draw(uint64(i + 1)))
+		out[i], out[j] = out[j], out[i]
+	}
+
+	return out
+}
+
+// HashChainShuffler ranks producers by `sha256(seed || account_name)`,
+// in ascending order.
+type HashChainShuffler struct{}
+
+func (s *HashChainShuffler) Shuffle(producers []*ProducerDef, seed []byte) []*ProducerDef {
+	out := sortedByAccountName(producers)
+
+	ranks := map[eos.AccountName][]byte{}
+	for _, prod := range out {
+		h := sha256.Sum256(append(append([]byte{}, seed...), []byte(prod.AccountName)...))
+		ranks[prod.AccountName] = h[:]
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		return bytes.Compare(ranks[out[i].AccountName], ranks[out[j].AccountName]) < 0
+	})
+
+	return out
+}
+
+// shuffleSeed derives the seed of the shuffle from the agreed Bitcoin
+// block: `sha256(merkle_root || uint64_be(block_time_unix_seconds))`.
+func shuffleSeed(merkleRoot []byte, blockTime time.Time) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(blockTime.Unix()))
+
+	h := sha256.Sum256(append(append([]byte{}, merkleRoot...), buf...))
+	return h[:]
+}
+
+func sortedByAccountName(producers []*ProducerDef) []*ProducerDef {
+	out := append([]*ProducerDef{}, producers...)
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].AccountName < out[j].AccountName
+	})
+	return out
+}
+
+// ShuffleTestVector is published so that independent implementations
+// can verify they derive the same order.
+type ShuffleTestVector struct {
+	Algorithm string            `json:"algorithm"`
+	Seed      string            `json:"seed"` // hex
+	Input     []eos.AccountName `json:"input"`
+	Output    []eos.AccountName `json:"output"`
+}
+
+func GenerateShuffleTestVectors(algorithm string, names []eos.AccountName, seeds [][]byte) (out []ShuffleTestVector, err error) {
+	shuffler, found := shufflersRegistry[algorithm]
+	if !found {
+		return nil, fmt.Errorf("shuffle algorithm %q invalid, use one of: %q", algorithm, shuffleAlgorithms())
+	}
+
+	var producers []*ProducerDef
+	for _, name := range names {
+		producers = append(producers, &ProducerDef{AccountName: name})
+	}
+
+	for _, seed := range seeds {
+		vector := ShuffleTestVector{
+			Algorithm: algorithm,
+			Seed:      hex.EncodeToString(seed),
+			Input:     names,
+		}
+		for _, prod := range shuffler.Shuffle(producers, seed) {
+			vector.Output = append(vector.Output, prod.AccountName)
+		}
+		out = append(out, vector)
+	}
+
+	return
+}
+
+func shuffleAlgorithms() (out []string) {
+	for name := range shufflersRegistry {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return
+}
+
+// runShuffleVectors implements `eos-bios shuffle-vectors`.
+func runShuffleVectors(args []string) error {
+	fs := flag.NewFlagSet("shuffle-vectors", flag.ExitOnError)
+	algorithm := fs.String("algorithm", "fisher_yates", fmt.Sprintf("One of %q", shuffleAlgorithms()))
+	names := fs.String("names", "alice,bob,carol,dave,eve,frank,grace,heidi", "Comma-separated account names to shuffle.")
+	seeds := fs.String("seeds", "00,0102030405060708,ffffffffffffffffffffffffffffffff", "Comma-separated hex seeds, or Bitcoin merkle roots with --block-time.")
+	blockTime := fs.Int64("block-time", 0, "Bitcoin block time (unix seconds). When set, seeds are derived from it and each merkle root in --seeds, like a launch does.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var accountNames []eos.AccountName
+	for _, name := range strings.Split(*names, ",") {
+		accountNames = append(accountNames, AN(strings.TrimSpace(name)))
+	}
+
+	var rawSeeds [][]byte
+	for _, seed := range strings.Split(*seeds, ",") {
+		raw, err := hex.DecodeString(strings.TrimSpace(seed))
+		if err != nil {
+			return fmt.Errorf("invalid seed %q: %s", seed, err)
+		}
+		if *blockTime != 0 {
+			raw = shuffleSeed(raw, time.Unix(*blockTime, 0))
+		}
+		rawSeeds = append(rawSeeds, raw)
+	}
+
+	vectors, err := GenerateShuffleTestVectors(*algorithm, accountNames, rawSeeds)
+	if err != nil {
+		return err
+	}
+
+	cnt, _ := json.MarshalIndent(vectors, "", "  ")
+	fmt.Println(string(cnt))
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// These vectors are the reference for other implementations, never
+// change them without changing the algorithm names.
+
+func TestShuffleSeed(t *testing.T) {
+	root, _ := hex.DecodeString("0000000000000000000000000000000000000000000000000000000000000001")
+	seed := shuffleSeed(root, time.Unix(1527000000, 0))
+	assert.Equal(t, "f3017673e171b1bc72769ab2c9154ca6e4e1894891805636e686fcbe63eeb00d", hex.EncodeToString(seed))
+}
+
+func TestShuffleVectors(t *testing.T) {
+	names := []eos.AccountName{"alice", "bob", "carol", "dave", "eve", "frank", "grace", "heidi"}
+	seed, _ := hex.DecodeString("f3017673e171b1bc72769ab2c9154ca6e4e1894891805636e686fcbe63eeb00d")
+
+	tests := []struct {
+		algorithm string
+		seed      []byte
+		expected  []eos.AccountName
+	}{
+		{"fisher_yates", []byte{0x00}, []eos.AccountName{"dave", "alice", "heidi", "eve", "frank", "carol", "grace", "bob"}},
+		{"fisher_yates", seed, []eos.AccountName{"dave", "alice", "eve", "grace", "bob", "heidi", "carol", "frank"}},
+		{"hash_chain", []byte{0x00}, []eos.AccountName{"carol", "alice", "dave", "bob", "frank", "eve", "heidi", "grace"}},
+		{"hash_chain", seed, []eos.AccountName{"carol", "frank", "heidi", "dave", "grace", "alice", "bob", "eve"}},
+	}
+
+	for _, test := range tests {
+		vectors, err := GenerateShuffleTestVectors(test.algorithm, names, [][]byte{test.seed})
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, vectors[0].Output, "%s with seed %x", test.algorithm, test.seed)
+	}
+}
+
+func TestShuffleIgnoresInputOrder(t *testing.T) {
+	a := []*ProducerDef{{AccountName: "alice"}, {AccountName: "bob"}, {AccountName: "carol"}}
+	b := []*ProducerDef{a[2], a[0], a[1]}
+
+	seed := shuffleSeed([]byte{1, 2, 3}, time.Unix(1527000000, 0))
+	for name, shuffler := range shufflersRegistry {
+		assert.Equal(t, shuffler.Shuffle(a, seed), shuffler.Shuffle(b, seed), name)
+	}
+}
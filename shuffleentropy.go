@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The launch file's `shuffle_entropy` lists the randomness sources
+// seeding the shuffle, "bitcoin" by default. With a single source,
+// its value is used as is (the Bitcoin merkle root). With several,
+// their values are mixed in order, as
+// `sha256("label:" || value || ";" ...)`, so no single source
+// controls the shuffle. The seed's time is the latest of theirs.
+
+type entropyValue struct {
+	Value []byte
+	Time  time.Time
+}
+
+var shuffleEntropyRegistry = map[string]func(b *BIOS, wait bool) (*entropyValue, error){
+	"bitcoin":     bitcoinEntropy,
+	"ethereum":    ethereumEntropy,
+	"nist_beacon": nistBeaconEntropy,
+}
+
+func shuffleEntropySources() (out []string) {
+	for name := range shuffleEntropyRegistry {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return
+}
+
+func (launch *LaunchData) shuffleEntropy() []string {
+	if len(launch.ShuffleEntropy) == 0 {
+		return []string{"bitcoin"}
+	}
+	return launch.ShuffleEntropy
+}
+
+func validateShuffleEntropy(launch *LaunchData) error {
+	seen := map[string]bool{}
+	for _, source := range launch.shuffleEntropy() {
+		if shuffleEntropyRegistry[source] == nil {
+			return fmt.Errorf("shuffle_entropy: unknown source %q, use some of: %q", source, shuffleEntropySources())
+		}
+		if seen[source] {
+			return fmt.Errorf("shuffle_entropy: %q listed twice", source)
+		}
+		seen[source] = true
+	}
+
+	switch {
+	case seen["bitcoin"] && launch.LaunchBitcoinBlockHeight == 0:
+		return fmt.Errorf("launch_btc_block_height unspecified (or 0)")
+	case seen["ethereum"] && launch.LaunchEthereumBlockHeight == 0:
+		return fmt.Errorf("shuffle_entropy uses ethereum, but launch_eth_block_height is unspecified (or 0)")
+	case seen["nist_beacon"] && launch.LaunchNISTBeaconTime.IsZero():
+		return fmt.Errorf("shuffle_entropy uses nist_beacon, but launch_nist_beacon_time is unspecified")
+	case seen["nist_beacon"] && launch.LaunchNISTBeaconTime.Second() != 0:
+		return fmt.Errorf("launch_nist_beacon_time must fall on a minute, when the beacon emits pulses")
+	}
+	return nil
+}
+
+// fetchShuffleEntropy gets the values of all of the launch file's
+// sources, and mixes them. Unless `wait` is set, it fails right away
+// when a value isn't available yet.
+func (b *BIOS) fetchShuffleEntropy(wait bool) (*entropyValue, error) {
+	sources := b.LaunchData.shuffleEntropy()
+
+	var values []*entropyValue
+	for _, source := range sources {
+		value, err := shuffleEntropyRegistry[source](b, wait)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", source, err)
+		}
+		info.Printf("Shuffle entropy from %s: %x\n", source, value.Value)
+		values = append(values, value)
+	}
+
+	if len(values) == 1 {
+		return values[0], nil
+	}
+	return mixShuffleEntropy(sources, values), nil
+}
+
+func mixShuffleEntropy(sources []string, values []*entropyValue) *entropyValue {
+	out := &entropyValue{}
+	h := sha256.New()
+	for idx, value := range values {
+		h.Write([]byte(sources[idx]))
+		h.Write([]byte(":"))
+		h.Write(value.Value)
+		h.Write([]byte(";"))
+		if value.Time.After(out.Time) {
+			out.Time = value.Time
+		}
+	}
+	out.Value = h.Sum(nil)
+	return out
+}
+
+func bitcoinEntropy(b *BIOS, wait bool) (*entropyValue, error) {
+	block, err := b.fetchBitcoinBlock(wait)
+	if err != nil {
+		return nil, err
+	}
+	return &entropyValue{Value: block.MerkleRoot, Time: block.Time}, nil
+}
+
+// ethereumEntropy is the hash of the block at
+// `launch_eth_block_height`, as agreed by all `ethereum.rpc_urls`.
+func ethereumEntropy(b *BIOS, wait bool) (*entropyValue, error) {
+	conf := b.Config.Ethereum
+	height := b.LaunchData.LaunchEthereumBlockHeight
+
+	urls := conf.RPCURLs
+	if len(urls) == 0 {
+		urls = []string{"https://cloudflare-eth.com"}
+	}
+	confirmations := conf.Confirmations
+	if confirmations == 0 {
+		confirmations = 12
+	}
+
+	milestone.Printf("Waiting for Ethereum block %d, with %d confirmation(s)\n", height, confirmations)
+	for {
+		value, err := agreeingEthereumBlock(urls, height, uint64(confirmations))
+		if err == nil {
+			return value, nil
+		}
+		if !wait {
+			return nil, err
+		}
+		info.Printf("Ethereum block %d not agreed upon yet: %s\n", height, err)
+		time.Sleep(30 * time.Second)
+	}
+}
+
+func agreeingEthereumBlock(urls []string, height, confirmations uint64) (*entropyValue, error) {
+	var agreed *entropyValue
+	for _, url := range urls {
+		var tip string
+		if err := ethereumCall(url, "eth_blockNumber", &tip); err != nil {
+			return nil, fmt.Errorf("%s: %s", url, err)
+		}
+		tipHeight, err := strconv.ParseUint(strings.TrimPrefix(tip, "0x"), 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: block number %q: %s", url, tip, err)
+		}
+		if tipHeight < height+confirmations-1 {
+			return nil, fmt.Errorf("%s: tip at %d", url, tipHeight)
+		}
+
+		var block struct {
+			Hash      string `json:"hash"`
+			Timestamp string `json:"timestamp"`
+		}
+		if err := ethereumCall(url, "eth_getBlockByNumber", &block, fmt.Sprintf("0x%x", height), false); err != nil {
+			return nil, fmt.Errorf("%s: %s", url, err)
+		}
+		hash, err := hex.DecodeString(strings.TrimPrefix(block.Hash, "0x"))
+		if err != nil || len(hash) != 32 {
+			return nil, fmt.Errorf("%s: invalid block hash %q", url, block.Hash)
+		}
+		timestamp, err := strconv.ParseInt(strings.TrimPrefix(block.Timestamp, "0x"), 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid timestamp %q", url, block.Timestamp)
+		}
+
+		value := &entropyValue{Value: hash, Time: time.Unix(timestamp, 0).UTC()}
+		if agreed != nil && !bytes.Equal(agreed.Value, value.Value) {
+			return nil, fmt.Errorf("ethereum endpoints disagree on block %d: %x and %x (from %s)", height, agreed.Value, value.Value, url)
+		}
+		agreed = value
+	}
+	return agreed, nil
+}
+
+func ethereumCall(url, method string, out interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+
+	resp, err := entropyClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("%s: %s (%s)", method, err, resp.Status)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("%s: %s", method, rpcResp.Error.Message)
+	}
+	if string(rpcResp.Result) == "null" {
+		return fmt.Errorf("%s: not found", method)
+	}
+	return json.Unmarshal(rpcResp.Result, out)
+}
+
+// nistBeaconEntropy is the output value of the NIST randomness beacon
+// pulse at `launch_nist_beacon_time`.
+func nistBeaconEntropy(b *BIOS, wait bool) (*entropyValue, error) {
+	pulseTime := b.LaunchData.LaunchNISTBeaconTime.UTC()
+	url := fmt.Sprintf("https://beacon.nist.gov/beacon/2.0/pulse/time/%d", pulseTime.UnixNano()/int64(time.Millisecond))
+
+	milestone.Printf("Waiting for the NIST beacon pulse of %s\n", pulseTime)
+	for {
+		value, err := fetchNISTPulse(url, pulseTime)
+		if err == nil {
+			return value, nil
+		}
+		if !wait {
+			return nil, err
+		}
+		info.Printf("NIST beacon pulse not available yet: %s\n", err)
+		time.Sleep(30 * time.Second)
+	}
+}
+
+func fetchNISTPulse(url string, pulseTime time.Time) (*entropyValue, error) {
+	if time.Now().Before(pulseTime) {
+		return nil, fmt.Errorf("pulse time %s not reached", pulseTime)
+	}
+
+	resp, err := entropyClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	var pulse struct {
+		Pulse struct {
+			TimeStamp   time.Time `json:"timeStamp"`
+			OutputValue string    `json:"outputValue"`
+		} `json:"pulse"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&pulse); err != nil {
+		return nil, err
+	}
+	if !pulse.Pulse.TimeStamp.Equal(pulseTime) {
+		return nil, fmt.Errorf("beacon returned the pulse of %s, expected %s", pulse.Pulse.TimeStamp, pulseTime)
+	}
+
+	value, err := hex.DecodeString(pulse.Pulse.OutputValue)
+	if err != nil || len(value) != 64 {
+		return nil, fmt.Errorf("invalid pulse output value %q", pulse.Pulse.OutputValue)
+	}
+	return &entropyValue{Value: value, Time: pulseTime}, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+)
+
+// With `signing_pool.workers` set, the transactions of each step are
+// precomputed (see `precompute.go`), then signed with the ephemeral
+// key by concurrent workers, ahead of the pusher. The pusher still
+// pushes them one by one, in order, waiting for a chunk's signature
+// only when it catches up with the workers.
+
+type signingPool struct {
+	results []chan error
+}
+
+// startSigningPool signs `txs` in the background. Nil entries (chunks
+// already pushed) are skipped.
+func (b *BIOS) startSigningPool(txs []*precomputedTx) *signingPool {
+	pool := &signingPool{results: make([]chan error, len(txs))}
+	jobs := make(chan int, len(txs))
+	for idx, p := range txs {
+		pool.results[idx] = make(chan error, 1)
+		if p == nil {
+			close(pool.results[idx])
+			continue
+		}
+		jobs <- idx
+	}
+	close(jobs)
+
+	pubKey := b.EphemeralPrivateKey.PublicKey()
+	workers := b.Config.SigningPool.Workers
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				pool.results[idx] <- b.signAhead(txs[idx], pubKey)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		verbose.Printf("Signing pool done with %d transaction(s)\n", len(txs))
+	}()
+
+	return pool
+}
+
+func (b *BIOS) signAhead(p *precomputedTx, pubKey ecc.PublicKey) error {
+	signed, err := b.API.Signer.Sign(eos.NewSignedTransaction(p.tx), b.API.ChainID, pubKey)
+	if err != nil {
+		return fmt.Errorf("signing: %s", err)
+	}
+
+	packed, err := signed.Pack(eos.CompressionNone)
+	if err != nil {
+		return fmt.Errorf("packing: %s", err)
+	}
+
+	id, err := packed.ID()
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(id) != p.id {
+		return fmt.Errorf("signed transaction has ID %x, precomputed %s", id, p.id)
+	}
+
+	p.packed = packed
+	return nil
+}
+
+// wait blocks until chunk `idx` is signed.
+func (p *signingPool) wait(idx int) error {
+	if err, ok := <-p.results[idx]; ok && err != nil {
+		return fmt.Errorf("chunk %d: %s", idx, err)
+	}
+	return nil
+}
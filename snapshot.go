@@ -3,7 +3,9 @@ package main
 import (
 	"encoding/csv"
 	"fmt"
+	"math/rand"
 	"os"
+	"sort"
 
 	"github.com/eoscanada/eos-go"
 	"github.com/eoscanada/eos-go/ecc"
@@ -31,11 +33,15 @@ func NewSnapshot(filename string) (out Snapshot, err error) {
 
 	//fmt.Println("ALL records", allRecords)
 
-	for _, el := range allRecords {
+	for idx, el := range allRecords {
 		if len(el) != 3 {
 			return nil, fmt.Errorf("should have 3 elements per line")
 		}
 
+		if err := checkAmountPrecision(el[2], eos.EOSSymbol.Precision); err != nil {
+			return out, fmt.Errorf("line %d: %s", idx+1, err)
+		}
+
 		newAsset, err := eos.NewEOSAssetFromString(el[2])
 		if err != nil {
 			return out, err
@@ -51,3 +57,124 @@ func NewSnapshot(filename string) (out Snapshot, err error) {
 
 	return
 }
+
+// injectedSnapshot is the part of the snapshot the boot injects: all
+// of it, or only its first `debug.truncate_snapshot` rows.
+func (b *BIOS) injectedSnapshot() Snapshot {
+	trunc := b.Config.Debug.TruncateSnapshot
+	if trunc <= 0 || trunc >= len(b.Snapshot) {
+		return b.Snapshot
+	}
+	return b.Snapshot[:trunc]
+}
+
+// snapshotAccountName is the account created for the snapshot row at `idx`.
+func snapshotAccountName(idx int) eos.AccountName {
+	flipped := flipEndianness(uint64(idx + 1))
+	return AN("genesis." + eos.NameToString(flipped))
+}
+
+// SnapshotSampling reduces the snapshot to a manageable size for
+// rehearsal networks, while keeping a realistic balance
+// distribution.
+type SnapshotSampling struct {
+	// Mode is one of `top` (the `count` biggest holders), `random`
+	// (`percent` of all rows) or `stratified` (`count` rows spread
+	// evenly across `strata` balance buckets).
+	Mode    string  `json:"mode"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+	Strata  int     `json:"strata"`
+	// Seed makes the `random` and `stratified` modes reproducible between rehearsals.
+	Seed int64 `json:"seed"`
+	// ScaleBalances multiplies every kept balance, 0 leaves them untouched.
+	ScaleBalances float64 `json:"scale_balances"`
+}
+
+// Sample returns a new Snapshot according to `conf`. Rows keep their
+// original relative order, except for the `top` mode which orders
+// them by decreasing balance.
+func (s Snapshot) Sample(conf SnapshotSampling) (out Snapshot, err error) {
+	rnd := rand.New(rand.NewSource(conf.Seed))
+
+	switch conf.Mode {
+	case "":
+		out = append(out, s...)
+	case "top":
+		if conf.Count <= 0 {
+			return nil, fmt.Errorf("top sampling needs a positive count, got %d", conf.Count)
+		}
+		out = append(out, s...)
+		sort.SliceStable(out, func(i, j int) bool {
+			return out[i].Balance.Amount > out[j].Balance.Amount
+		})
+		if conf.Count < len(out) {
+			out = out[:conf.Count]
+		}
+	case "random":
+		if conf.Percent <= 0 || conf.Percent > 100 {
+			return nil, fmt.Errorf("random sampling needs a percent between 0 and 100, got %f", conf.Percent)
+		}
+		for _, line := range s {
+			if rnd.Float64()*100 < conf.Percent {
+				out = append(out, line)
+			}
+		}
+	case "stratified":
+		if conf.Strata <= 0 {
+			return nil, fmt.Errorf("stratified sampling needs a positive strata count")
+		}
+		if conf.Count < conf.Strata {
+			return nil, fmt.Errorf("stratified sampling needs a count of at least one row per stratum, got %d for %d strata", conf.Count, conf.Strata)
+		}
+		out = s.sampleStratified(conf.Count, conf.Strata, rnd)
+	default:
+		return nil, fmt.Errorf("unknown snapshot sampling mode %q, use one of: top, random, stratified", conf.Mode)
+	}
+
+	if conf.ScaleBalances != 0 {
+		for idx := range out {
+			out[idx].Balance = scaleAsset(out[idx].Balance, conf.ScaleBalances)
+		}
+	}
+
+	return out, nil
+}
+
+func (s Snapshot) sampleStratified(count, strata int, rnd *rand.Rand) (out Snapshot) {
+	indexes := make([]int, len(s))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return s[indexes[i]].Balance.Amount < s[indexes[j]].Balance.Amount
+	})
+
+	perStratum := count / strata
+	keep := map[int]bool{}
+	for stratum := 0; stratum < strata; stratum++ {
+		bucket := append([]int{}, indexes[stratum*len(indexes)/strata:(stratum+1)*len(indexes)/strata]...)
+		rnd.Shuffle(len(bucket), func(i, j int) { bucket[i], bucket[j] = bucket[j], bucket[i] })
+		if perStratum < len(bucket) {
+			bucket = bucket[:perStratum]
+		}
+		for _, idx := range bucket {
+			keep[idx] = true
+		}
+	}
+
+	for idx, line := range s {
+		if keep[idx] {
+			out = append(out, line)
+		}
+	}
+	return
+}
+
+func scaleAsset(in eos.Asset, factor float64) eos.Asset {
+	in.Amount = int64(float64(in.Amount) * factor)
+	if in.Amount < 1 {
+		in.Amount = 1
+	}
+	return in
+}
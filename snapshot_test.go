@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSnapshot(balances ...int64) (out Snapshot) {
+	for idx, bal := range balances {
+		out = append(out, SnapshotLine{
+			EthereumAddress: string([]byte{'a' + byte(idx)}),
+			Balance:         eos.Asset{Amount: bal, Symbol: eos.EOSSymbol},
+		})
+	}
+	return
+}
+
+func TestSnapshotSampleTop(t *testing.T) {
+	s := testSnapshot(10, 50, 20, 40)
+
+	out, err := s.Sample(SnapshotSampling{Mode: "top", Count: 2})
+	require.NoError(t, err)
+	require.Len(t, out, 2)
+	assert.Equal(t, int64(50), out[0].Balance.Amount)
+	assert.Equal(t, int64(40), out[1].Balance.Amount)
+}
+
+func TestSnapshotSampleStratified(t *testing.T) {
+	s := testSnapshot(1, 2, 3, 4, 100, 200, 300, 400)
+
+	out, err := s.Sample(SnapshotSampling{Mode: "stratified", Count: 4, Strata: 2, Seed: 1})
+	require.NoError(t, err)
+	require.Len(t, out, 4)
+
+	var small, big int
+	for _, line := range out {
+		if line.Balance.Amount < 100 {
+			small++
+		} else {
+			big++
+		}
+	}
+	assert.Equal(t, 2, small)
+	assert.Equal(t, 2, big)
+}
+
+func TestSnapshotSampleInvalidCount(t *testing.T) {
+	s := testSnapshot(1, 2, 3, 4)
+
+	for name, conf := range map[string]SnapshotSampling{
+		"top, zero":           {Mode: "top"},
+		"top, negative":       {Mode: "top", Count: -1},
+		"stratified, zero":    {Mode: "stratified", Strata: 2},
+		"stratified, too few": {Mode: "stratified", Count: 3, Strata: 4},
+	} {
+		_, err := s.Sample(conf)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestInjectedSnapshotTruncation(t *testing.T) {
+	b := &BIOS{Config: &Config{}, Snapshot: testSnapshot(1, 2, 3, 4)}
+	assert.Len(t, b.injectedSnapshot(), 4)
+
+	b.Config.Debug.TruncateSnapshot = 2
+	assert.Equal(t, b.Snapshot[:2], b.injectedSnapshot())
+	assert.Len(t, (&OpInjectSnapshotBulk{}).rows(b), 2)
+
+	b.Config.Debug.TruncateSnapshot = 10
+	assert.Len(t, b.injectedSnapshot(), 4)
+}
+
+func TestSnapshotSampleScale(t *testing.T) {
+	s := testSnapshot(10000, 3)
+
+	out, err := s.Sample(SnapshotSampling{ScaleBalances: 0.01})
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), out[0].Balance.Amount)
+	assert.Equal(t, int64(1), out[1].Balance.Amount)
+	assert.Equal(t, int64(10000), s[0].Balance.Amount)
+}
+
+func TestApplySnapshotDelta(t *testing.T) {
+	snapshot := []byte("0xa,EOSa,1.0000\n0xb,EOSb,2.0000\n0xc,EOSc,3.0000\n")
+	delta := []byte("base " + sha256Hex(snapshot) + "\n- 0xb\n+ 0xc,EOSc,4.0000\n+ 0xd,EOSd,5.0000\n")
+
+	out, err := applySnapshotDelta(snapshot, delta)
+	require.NoError(t, err)
+	assert.Equal(t, "0xa,EOSa,1.0000\n0xc,EOSc,4.0000\n0xd,EOSd,5.0000\n", string(out))
+
+	_, err = applySnapshotDelta(out, delta)
+	assert.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// A snapshot delta revises the cached `snapshot.csv` without
+// downloading it again. It is a text file, signed (detached PGP) by
+// the snapshot authors:
+//
+//     base <sha256 of the snapshot it applies to>
+//     - <ethereum address>         removes that row
+//     + <raw snapshot.csv line>    replaces the row of the same address, or appends it
+//
+// Rows keep their position (and so their `genesis.*` account), and
+// lines are kept verbatim, so the result hashes exactly like the
+// revised snapshot pinned in the launch data.
+
+// applySnapshotDeltaFile applies the configured delta to the cached
+// snapshot when it doesn't already match `expectedHash`, and writes
+// the revised snapshot in place.
+func applySnapshotDeltaFile(config *Config, expectedHash string) error {
+	conf := config.OpeningBalances
+	snapshotPath := conf.SnapshotPath
+
+	current, err := ioutil.ReadFile(snapshotPath)
+	if err != nil {
+		return err
+	}
+	if sha256Hex(current) == expectedHash {
+		info.Println("Snapshot already revised, not applying", conf.DeltaPath)
+		return nil
+	}
+
+	if conf.DeltaSignaturePath == "" || conf.DeltaSigningKeyPath == "" {
+		return fmt.Errorf("opening_balances: delta_path needs its delta_signature_path and delta_signing_key_path")
+	}
+	if err := checkDetachedSignature(conf.DeltaPath, conf.DeltaSignaturePath, conf.DeltaSigningKeyPath); err != nil {
+		return fmt.Errorf("snapshot delta signature: %s", err)
+	}
+
+	delta, err := ioutil.ReadFile(conf.DeltaPath)
+	if err != nil {
+		return err
+	}
+
+	revised, err := applySnapshotDelta(current, delta)
+	if err != nil {
+		return fmt.Errorf("snapshot delta: %s", err)
+	}
+
+	if hash := sha256Hex(revised); hash != expectedHash {
+		return fmt.Errorf("snapshot revised by delta hashes to %s, launch data expects %s", hash, expectedHash)
+	}
+
+	if err := ioutil.WriteFile(snapshotPath+".orig", current, 0644); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(snapshotPath, revised, 0644); err != nil {
+		return err
+	}
+
+	info.Printf("Applied snapshot delta %q to %q (previous version kept as %q)\n", conf.DeltaPath, snapshotPath, snapshotPath+".orig")
+	return nil
+}
+
+// applySnapshotDelta returns `snapshot` revised by `delta`.
+func applySnapshotDelta(snapshot, delta []byte) ([]byte, error) {
+	deltaLines := strings.Split(strings.TrimRight(string(delta), "\n"), "\n")
+	if len(deltaLines) == 0 || !strings.HasPrefix(deltaLines[0], "base ") {
+		return nil, fmt.Errorf("first line should be `base <sha256>`")
+	}
+	if base := strings.TrimSpace(strings.TrimPrefix(deltaLines[0], "base ")); base != sha256Hex(snapshot) {
+		return nil, fmt.Errorf("applies to snapshot %s, cached snapshot is %s", base, sha256Hex(snapshot))
+	}
+
+	trailingNewline := bytes.HasSuffix(snapshot, []byte("\n"))
+	var lines []string
+	if trimmed := strings.TrimSuffix(string(snapshot), "\n"); trimmed != "" {
+		lines = strings.Split(trimmed, "\n")
+	}
+
+	rows := map[string]int{}
+	for idx, line := range lines {
+		addr, err := snapshotLineAddress(line)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot line %d: %s", idx+1, err)
+		}
+		rows[addr] = idx
+	}
+
+	removed := map[int]bool{}
+	for idx, line := range deltaLines[1:] {
+		if line == "" {
+			continue
+		}
+		if len(line) < 2 || line[1] != ' ' {
+			return nil, fmt.Errorf("delta line %d: expected `- <address>` or `+ <line>`", idx+2)
+		}
+
+		switch op, arg := line[0], line[2:]; op {
+		case '-':
+			row, found := rows[arg]
+			if !found {
+				return nil, fmt.Errorf("delta line %d: removing %s, not in snapshot", idx+2, arg)
+			}
+			removed[row] = true
+			delete(rows, arg)
+
+		case '+':
+			addr, err := snapshotLineAddress(arg)
+			if err != nil {
+				return nil, fmt.Errorf("delta line %d: %s", idx+2, err)
+			}
+			if row, found := rows[addr]; found {
+				lines[row] = arg
+			} else {
+				rows[addr] = len(lines)
+				lines = append(lines, arg)
+			}
+
+		default:
+			return nil, fmt.Errorf("delta line %d: unknown operation %q", idx+2, op)
+		}
+	}
+
+	var out []string
+	for idx, line := range lines {
+		if !removed[idx] {
+			out = append(out, line)
+		}
+	}
+
+	revised := strings.Join(out, "\n")
+	if trailingNewline && len(out) != 0 {
+		revised += "\n"
+	}
+	return []byte(revised), nil
+}
+
+// snapshotLineAddress is the Ethereum address (first column) of a
+// `snapshot.csv` line.
+func snapshotLineAddress(line string) (string, error) {
+	record, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil {
+		return "", err
+	}
+	if len(record) != 3 {
+		return "", fmt.Errorf("should have 3 elements per line")
+	}
+	return record[0], nil
+}
+
+func sha256Hex(cnt []byte) string {
+	hash := sha256.Sum256(cnt)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/eoscanada/eos-go/system"
+)
+
+// SnapshotSegment gives the accounts of some snapshot rows other
+// authorities than the holder's key alone, like a delayed owner for
+// exchange wallets, per agreement. Rows belong to the first segment
+// they match, and the others are created with their key alone.
+type SnapshotSegment struct {
+	Name string `json:"name"`
+
+	// Addresses are the Ethereum addresses of the segment.
+	Addresses []string `json:"addresses"`
+	// MinBalance, when set, also includes every row holding at least that much.
+	MinBalance *eos.Asset `json:"min_balance"`
+
+	Owner  AuthorityTemplate `json:"owner"`
+	Active AuthorityTemplate `json:"active"`
+}
+
+// AuthorityTemplate is an authority, in which the snapshot row's key
+// is included with weight `holder_key_weight` (left out when 0).
+type AuthorityTemplate struct {
+	Threshold       uint32                      `json:"threshold"`
+	HolderKeyWeight uint16                      `json:"holder_key_weight"`
+	Keys            []eos.KeyWeight             `json:"keys"`
+	Accounts        []eos.PermissionLevelWeight `json:"accounts"`
+	Waits           []eos.WaitWeight            `json:"waits"`
+}
+
+// apply returns the authority for `holderKey`, sorted the way the
+// chain expects it.
+func (t *AuthorityTemplate) apply(holderKey ecc.PublicKey) eos.Authority {
+	auth := eos.Authority{Threshold: t.Threshold}
+
+	if t.HolderKeyWeight != 0 {
+		auth.Keys = append(auth.Keys, eos.KeyWeight{PublicKey: holderKey, Weight: t.HolderKeyWeight})
+	}
+	auth.Keys = append(auth.Keys, t.Keys...)
+	sort.SliceStable(auth.Keys, func(i, j int) bool {
+		a, _ := eos.MarshalBinary(auth.Keys[i].PublicKey)
+		b, _ := eos.MarshalBinary(auth.Keys[j].PublicKey)
+		return bytes.Compare(a, b) < 0
+	})
+
+	auth.Accounts = append(auth.Accounts, t.Accounts...)
+	sort.SliceStable(auth.Accounts, func(i, j int) bool {
+		a, b := auth.Accounts[i].Permission, auth.Accounts[j].Permission
+		if a.Actor != b.Actor {
+			return nameValue(string(a.Actor)) < nameValue(string(b.Actor))
+		}
+		return nameValue(string(a.Permission)) < nameValue(string(b.Permission))
+	})
+
+	auth.Waits = append(auth.Waits, t.Waits...)
+	sort.SliceStable(auth.Waits, func(i, j int) bool { return auth.Waits[i].WaitSec < auth.Waits[j].WaitSec })
+
+	return auth
+}
+
+// nameValue orders names the way the chain does, invalid ones first.
+func nameValue(name string) uint64 {
+	val, _ := eos.StringToName(name)
+	return val
+}
+
+func (t *AuthorityTemplate) validate() error {
+	if t.Threshold == 0 {
+		return fmt.Errorf("threshold can't be 0")
+	}
+
+	total := uint32(t.HolderKeyWeight)
+	for _, key := range t.Keys {
+		total += uint32(key.Weight)
+	}
+	for _, acct := range t.Accounts {
+		total += uint32(acct.Weight)
+	}
+	for _, wait := range t.Waits {
+		total += uint32(wait.Weight)
+	}
+	if total < t.Threshold {
+		return fmt.Errorf("weights add up to %d, under the threshold of %d", total, t.Threshold)
+	}
+
+	return nil
+}
+
+// validateSnapshotSegments checks the templates are satisfiable, and
+// that the boot sequence creates the snapshot accounts itself, as the
+// bulk ops create them from the holder's key alone.
+func validateSnapshotSegments(launch *LaunchData) error {
+	if len(launch.SnapshotSegments) == 0 {
+		return nil
+	}
+
+	for _, segment := range launch.SnapshotSegments {
+		if err := segment.Owner.validate(); err != nil {
+			return fmt.Errorf("snapshot segment %q, owner: %s", segment.Name, err)
+		}
+		if err := segment.Active.validate(); err != nil {
+			return fmt.Errorf("snapshot segment %q, active: %s", segment.Name, err)
+		}
+	}
+
+	return checkSnapshotInjectedIndividually(launch, "snapshot_segments")
+}
+
+// checkSnapshotInjectedIndividually fails when the boot sequence
+// creates the snapshot accounts through the bulk ops, which create
+// them from the holder's key alone: `feature` can't apply there.
+func checkSnapshotInjectedIndividually(launch *LaunchData, feature string) error {
+	for _, step := range launch.BootSequence {
+		switch op := step.Data.(type) {
+		case *OpInjectSnapshotBulk:
+			return fmt.Errorf("%s can't be applied by snapshot.inject_bulk, use snapshot.inject", feature)
+		case *OpTransferPacked:
+			if op.CreateAccounts {
+				return fmt.Errorf("%s can't be applied by snapshot.transfer_packed creating accounts, use snapshot.inject", feature)
+			}
+		}
+	}
+	return nil
+}
+
+// snapshotSegment returns the segment of a snapshot row, or nil.
+func (b *BIOS) snapshotSegment(hodler SnapshotLine) *SnapshotSegment {
+	for _, segment := range b.LaunchData.SnapshotSegments {
+		for _, addr := range segment.Addresses {
+			if strings.EqualFold(addr, hodler.EthereumAddress) {
+				return segment
+			}
+		}
+		if segment.MinBalance != nil && hodler.Balance.Amount >= segment.MinBalance.Amount {
+			return segment
+		}
+	}
+	return nil
+}
+
+// newSnapshotAccount creates the account of a snapshot row, with the
+// authorities of its flag (see `flaglist.go`) or its segment.
+func (b *BIOS) newSnapshotAccount(hodler SnapshotLine, account eos.AccountName) *eos.Action {
+	if flag := b.flaggedAddress(hodler); flag != nil && flag.Treatment == "authority" {
+		trace.Printf("- Account %s of flagged %s created with the flag's authority\n", account, hodler.EthereumAddress)
+		return newFlaggedAccount(account, flag)
+	}
+
+	segment := b.snapshotSegment(hodler)
+	if segment == nil {
+		return system.NewNewAccount(AN("eosio"), account, hodler.EOSPublicKey)
+	}
+
+	trace.Printf("- Account %s of %s in snapshot segment %q\n", account, hodler.EthereumAddress, segment.Name)
+	newAccount := system.NewNewAccount(AN("eosio"), account, nil)
+	newAccount.Data = eos.NewActionData(system.NewAccount{
+		Creator: AN("eosio"),
+		Name:    account,
+		Owner:   segment.Owner.apply(hodler.EOSPublicKey),
+		Active:  segment.Active.apply(hodler.EOSPublicKey),
+	})
+	return newAccount
+}
+
+// validateSnapshotSegmentAccounts checks the accounts of segmented
+// rows hold the authorities of their template.
+func (b *BIOS) validateSnapshotSegmentAccounts() error {
+	for idx, hodler := range b.injectedSnapshot() {
+		segment := b.snapshotSegment(hodler)
+		if segment == nil {
+			continue
+		}
+		if flag := b.flaggedAddress(hodler); flag != nil && flag.Treatment == "authority" {
+			continue
+		}
+
+		account := snapshotAccountName(idx)
+		acct, err := b.validationAPI().GetAccount(account)
+		if err != nil {
+			return fmt.Errorf("snapshot row %d: get account %s: %s", idx, account, err)
+		}
+		if err := validateAuthority(acct, "owner", segment.Owner.apply(hodler.EOSPublicKey)); err != nil {
+			return fmt.Errorf("snapshot row %d, segment %q: %s", idx, segment.Name, err)
+		}
+		if err := validateAuthority(acct, "active", segment.Active.apply(hodler.EOSPublicKey)); err != nil {
+			return fmt.Errorf("snapshot row %d, segment %q: %s", idx, segment.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// ScheduledAction is a post-launch step of the launch file (parameter
+// changes, msig proposals and approvals, ...), run by the monitor once
+// its condition is met, signed with the producer's own keys (see
+// `producerAPI`), and validated like boot steps.
+type ScheduledAction struct {
+	When struct {
+		// BlockHeight runs the step once the head block reaches it.
+		BlockHeight uint32 `json:"block_height"`
+		// Event runs the step once it happened. Only "voting_activated" for now.
+		Event string `json:"event"`
+	} `json:"when"`
+
+	// By is who runs the step: "boot" (the default), "abps" (the
+	// Appointed Block Producers), "all", or a single account name.
+	By string `json:"by"`
+
+	Step *OperationType `json:"step"`
+}
+
+// scheduledRunner runs the launch file's `scheduled_actions` from the
+// monitor.
+type scheduledRunner struct {
+	b      *BIOS
+	done   map[int]bool
+	events map[string]bool
+}
+
+func newScheduledRunner(b *BIOS) (*scheduledRunner, error) {
+	r := &scheduledRunner{b: b, done: map[int]bool{}, events: map[string]bool{}}
+
+	for idx, action := range b.LaunchData.ScheduledActions {
+		if action.Step == nil {
+			return nil, fmt.Errorf("scheduled action %d: missing step", idx)
+		}
+		if event := action.When.Event; event != "" && event != "voting_activated" {
+			return nil, fmt.Errorf("scheduled action %d: unknown event %q", idx, event)
+		}
+		if !r.isMine(action) {
+			r.done[idx] = true
+		}
+	}
+
+	// Steps pushed by a previous run of the monitor are recorded in the ledger.
+	if b.Config.RunDir != "" {
+		entries, err := ReadLedger(filepath.Join(b.Config.RunDir, "ledger.jsonl"))
+		if err != nil {
+			return nil, fmt.Errorf("reading ledger: %s", err)
+		}
+		for _, entry := range entries {
+			if entry.Type == "scheduled" {
+				r.done[entry.Step] = true
+			}
+		}
+	}
+
+	return r, nil
+}
+
+func (r *scheduledRunner) isMine(action *ScheduledAction) bool {
+	switch action.By {
+	case "", "boot":
+		return r.b.AmIBootNode()
+	case "abps":
+		return r.b.AmIAppointedBlockProducer()
+	case "all":
+		return true
+	default:
+		return action.By == r.b.Config.Producer.MyAccount
+	}
+}
+
+// pending tells whether some of our steps are still to run.
+func (r *scheduledRunner) pending() bool {
+	return len(r.done) < len(r.b.LaunchData.ScheduledActions)
+}
+
+func (r *scheduledRunner) onEvent(event string) {
+	r.events[event] = true
+}
+
+// onHead runs the steps whose conditions are met at `headBlock`.
+func (r *scheduledRunner) onHead(headBlock uint32) {
+	for idx, action := range r.b.LaunchData.ScheduledActions {
+		if r.done[idx] {
+			continue
+		}
+		if action.When.BlockHeight != 0 && headBlock < action.When.BlockHeight {
+			continue
+		}
+		if action.When.Event != "" && !r.events[action.When.Event] {
+			continue
+		}
+
+		// Failed steps are not retried, they need a human.
+		r.done[idx] = true
+		if err := r.run(idx, action.Step); err != nil {
+			milestone.Printf("WARNING: scheduled step %d, %s [%s] failed: %s\n", idx, action.Step.Label, action.Step.Op, err)
+		}
+	}
+}
+
+func (r *scheduledRunner) run(idx int, step *OperationType) error {
+	b := r.b
+	milestone.Printf("Running scheduled step %d, %s  [%s]\n", idx, step.Label, step.Op)
+
+	acts, err := step.Data.Actions(b)
+	if err != nil {
+		return fmt.Errorf("getting actions: %s", err)
+	}
+
+	api, err := b.producerAPI()
+	if err != nil {
+		return err
+	}
+
+	resp, err := api.SignPushActions(acts...)
+	if err != nil {
+		return fmt.Errorf("pushing: %s", err)
+	}
+	info.Printf("Pushed in transaction %s, block %d\n", resp.TransactionID, resp.BlockNum)
+
+	entry := &LedgerEntry{
+		Type:          "scheduled",
+		Step:          idx,
+		Op:            step.Op,
+		TransactionID: resp.TransactionID,
+		BlockNum:      resp.BlockNum,
+		Actions:       acts,
+		ActionsHash:   actionsHash(acts),
+	}
+	if err := b.ledgerAppend(entry); err != nil {
+		return fmt.Errorf("ledger: %s", err)
+	}
+	if err := b.DispatchLedgerEntry(entry, b.reportActions(acts)); err != nil {
+		milestone.Println("WARNING: dispatch ledger_entry:", err)
+	}
+
+	// Let the transaction land before validating.
+	if _, err := b.waitIrreversible(resp.BlockNum, 5*time.Minute); err != nil {
+		return fmt.Errorf("block %d: %s", resp.BlockNum, err)
+	}
+
+	if failures := b.validateStep(idx, step, "scheduled "); failures != 0 {
+		return fmt.Errorf("%d validation(s) failed", failures)
+	}
+	return nil
+}
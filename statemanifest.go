@@ -0,0 +1,498 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/eoscanada/eos-go/system"
+)
+
+// The state manifest is the chain state the boot sequence must leave,
+// computed from the launch inputs alone: the permissions and code
+// hash of every account the boot creates, every token balance it
+// moves, and the `stat` row of every token. Each is a leaf, hashed
+// into a merkle root anyone can recompute with `eos-bios
+// state-manifest` once the shuffle seed is known, and the boot node
+// publishes before launching, and in the kickstart data. ABPs then
+// check their own root is the published one, and verify the booted
+// chain against it, a single root comparison on top of the step
+// validations.
+//
+// The ephemeral key isn't known in advance, it appears as
+// "EPHEMERAL" in authorities. Actions whose effect can't be predicted
+// (other contracts, `eosio.msig::exec`, ...) are listed as unmodeled,
+// the state they touch isn't covered.
+
+const ephemeralPlaceholder = "EPHEMERAL"
+
+// noCodeHash is the `code_hash` of an account without code.
+const noCodeHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+type StateManifest struct {
+	LaunchHash string       `json:"launch_hash"`
+	Root       string       `json:"root"`
+	Leaves     []*StateLeaf `json:"leaves"`
+	Unmodeled  []string     `json:"unmodeled,omitempty"`
+}
+
+// StateLeaf is a piece of the expected state. Keys are
+// "account:<name>", "balance:<name>:<symbol>" or "stat:<symbol>".
+type StateLeaf struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Hash  string `json:"hash"`
+}
+
+func newStateLeaf(key, value string) *StateLeaf {
+	hash := sha256.Sum256([]byte(key + "\n" + value))
+	return &StateLeaf{Key: key, Value: value, Hash: hex.EncodeToString(hash[:])}
+}
+
+// stateMerkleRoot hashes sorted leaves pairwise up to the root, an
+// odd leaf out carried up as is.
+func stateMerkleRoot(leaves []*StateLeaf) string {
+	if len(leaves) == 0 {
+		return ""
+	}
+
+	var level [][]byte
+	for _, leaf := range leaves {
+		hash, _ := hex.DecodeString(leaf.Hash)
+		level = append(level, hash)
+	}
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			hash := sha256.Sum256(append(append([]byte{}, level[i]...), level[i+1]...))
+			next = append(next, hash[:])
+		}
+		level = next
+	}
+	return hex.EncodeToString(level[0])
+}
+
+type statePermission struct {
+	PermName     string        `json:"perm_name"`
+	Parent       string        `json:"parent"`
+	RequiredAuth eos.Authority `json:"required_auth"`
+}
+
+type stateAccount struct {
+	Permissions []*statePermission `json:"permissions"`
+	CodeHash    string             `json:"code_hash"`
+}
+
+// value is the account's canonical JSON, with the ephemeral key
+// replaced.
+func (a *stateAccount) value(ephemeralKey string) string {
+	sort.Slice(a.Permissions, func(i, j int) bool { return a.Permissions[i].PermName < a.Permissions[j].PermName })
+	for _, perm := range a.Permissions {
+		auth := &perm.RequiredAuth
+		if auth.Keys == nil {
+			auth.Keys = []eos.KeyWeight{}
+		}
+		if auth.Accounts == nil {
+			auth.Accounts = []eos.PermissionLevelWeight{}
+		}
+		if auth.Waits == nil {
+			auth.Waits = []eos.WaitWeight{}
+		}
+	}
+	cnt, _ := json.Marshal(a)
+	return strings.Replace(string(cnt), ephemeralKey, ephemeralPlaceholder, -1)
+}
+
+func (a *stateAccount) setPermission(name, parent eos.PermissionName, auth eos.Authority) {
+	for _, perm := range a.Permissions {
+		if perm.PermName == string(name) {
+			perm.Parent = string(parent)
+			perm.RequiredAuth = auth
+			return
+		}
+	}
+	a.Permissions = append(a.Permissions, &statePermission{PermName: string(name), Parent: string(parent), RequiredAuth: auth})
+}
+
+// stateNeutralActions don't change the state the manifest covers.
+var stateNeutralActions = map[string]bool{
+	"eosio::setabi":         true,
+	"eosio::setpriv":        true,
+	"eosio::setprods":       true,
+	"eosio::setglobal":      true,
+	"eosio::setparams":      true,
+	"eosio::regproducer":    true,
+	"eosio::unregprod":      true,
+	"eosio::linkauth":       true,
+	"eosio::unlinkauth":     true,
+	"eosio::nonce":          true,
+	"eosio::delegatebw":     true,
+	"eosio.token::issue":    true,
+	"eosio.token::transfer": true,
+	"eosio.msig::propose":   true,
+	"eosio.msig::approve":   true,
+}
+
+type tokenStat struct {
+	issuer    eos.AccountName
+	maxSupply eos.Asset
+}
+
+type stateModel struct {
+	accounts  map[eos.AccountName]*stateAccount
+	unknown   map[eos.AccountName]bool
+	stats     map[string]*tokenStat
+	tokens    *tokenAccounting
+	unmodeled map[string]bool
+}
+
+// apply plays a step's actions on the model.
+func (m *stateModel) apply(idx int, step *OperationType, acts []*eos.Action) error {
+	if err := m.tokens.addStep(idx, step, acts); err != nil {
+		return err
+	}
+	packed, _ := step.Data.(*OpTransferPacked)
+
+	for _, act := range acts {
+		kind := fmt.Sprintf("%s::%s", act.Account, act.Name)
+		switch {
+		case kind == "eosio::newaccount":
+			var data system.NewAccount
+			if err := decodeActionData(act, &data); err != nil {
+				return err
+			}
+			account := &stateAccount{CodeHash: noCodeHash}
+			account.setPermission(PN("owner"), PN(""), data.Owner)
+			account.setPermission(PN("active"), PN("owner"), data.Active)
+			m.accounts[data.Name] = account
+
+		case kind == "eosio::updateauth":
+			var data struct {
+				Account    eos.AccountName
+				Permission eos.PermissionName
+				Parent     eos.PermissionName
+				Auth       eos.Authority
+			}
+			if err := decodeActionData(act, &data); err != nil {
+				return err
+			}
+			account := m.accounts[data.Account]
+			if account == nil {
+				m.unknown[data.Account] = true
+				m.unmodeled[fmt.Sprintf("%s on %s, not created by the boot", kind, data.Account)] = true
+				continue
+			}
+			account.setPermission(data.Permission, data.Parent, data.Auth)
+
+		case kind == "eosio::setcode":
+			var data struct {
+				Account   eos.AccountName
+				VMType    byte
+				VMVersion byte
+				Code      eos.HexBytes
+			}
+			if err := decodeActionData(act, &data); err != nil {
+				return err
+			}
+			account := m.accounts[data.Account]
+			if account == nil {
+				m.unknown[data.Account] = true
+				m.unmodeled[fmt.Sprintf("%s on %s, not created by the boot", kind, data.Account)] = true
+				continue
+			}
+			hash := sha256.Sum256(data.Code)
+			account.CodeHash = hex.EncodeToString(hash[:])
+
+		case kind == "eosio.token::create":
+			var data struct {
+				Issuer        eos.AccountName
+				MaximumSupply eos.Asset
+				CanFreeze     bool
+				CanRecall     bool
+				CanWhitelist  bool
+			}
+			if err := decodeActionData(act, &data); err != nil {
+				return err
+			}
+			m.stats[data.MaximumSupply.Symbol.Symbol] = &tokenStat{issuer: data.Issuer, maxSupply: data.MaximumSupply}
+
+		case stateNeutralActions[kind]:
+		case packed != nil && act.Account == packed.Contract && act.Name == packed.actionName():
+
+		default:
+			m.unmodeled[kind] = true
+		}
+	}
+	return nil
+}
+
+// buildStateManifest plays the boot sequence on an empty chain whose
+// `eosio` holds the ephemeral key, like the genesis sets it.
+func (b *BIOS) buildStateManifest() (*StateManifest, error) {
+	ephemeralKey := b.EphemeralPrivateKey.PublicKey()
+	model := &stateModel{
+		accounts:  map[eos.AccountName]*stateAccount{},
+		unknown:   map[eos.AccountName]bool{},
+		stats:     map[string]*tokenStat{},
+		tokens:    &tokenAccounting{sheets: map[string]*TokenBalanceSheet{}},
+		unmodeled: map[string]bool{},
+	}
+	genesisAuth := eos.Authority{Threshold: 1, Keys: []eos.KeyWeight{{PublicKey: ephemeralKey, Weight: 1}}}
+	eosio := &stateAccount{CodeHash: noCodeHash}
+	eosio.setPermission(PN("owner"), PN(""), genesisAuth)
+	eosio.setPermission(PN("active"), PN("owner"), genesisAuth)
+	model.accounts[AN("eosio")] = eosio
+
+	for idx, step := range b.LaunchData.BootSequence {
+		b.currentStep = idx
+		acts, err := b.stepActions(idx, step)
+		if err != nil {
+			return nil, fmt.Errorf("getting actions for step %q: %s", step.Op, err)
+		}
+		if err := model.apply(idx, step, acts); err != nil {
+			return nil, fmt.Errorf("step %q: %s", step.Op, err)
+		}
+	}
+
+	manifest := &StateManifest{LaunchHash: b.LaunchData.fileHash}
+	for name, account := range model.accounts {
+		if model.unknown[name] {
+			continue
+		}
+		manifest.Leaves = append(manifest.Leaves, newStateLeaf("account:"+string(name), account.value(ephemeralKey.String())))
+	}
+	for symbol, sheet := range model.tokens.sheets {
+		sheet.total()
+		for holder, amount := range sheet.holdings {
+			balance := eos.Asset{Amount: amount, Symbol: sheet.Issued.Symbol}
+			manifest.Leaves = append(manifest.Leaves, newStateLeaf(fmt.Sprintf("balance:%s:%s", holder, symbol), balance.String()))
+		}
+	}
+	for symbol, stat := range model.stats {
+		supply := eos.Asset{Symbol: stat.maxSupply.Symbol}
+		if sheet := model.tokens.sheets[symbol]; sheet != nil {
+			supply.Amount = sheet.Issued.Amount
+		}
+		manifest.Leaves = append(manifest.Leaves, newStateLeaf("stat:"+symbol, tokenStatValue(supply, stat.maxSupply, stat.issuer)))
+	}
+	sort.Slice(manifest.Leaves, func(i, j int) bool { return manifest.Leaves[i].Key < manifest.Leaves[j].Key })
+	manifest.Root = stateMerkleRoot(manifest.Leaves)
+
+	for kind := range model.unmodeled {
+		manifest.Unmodeled = append(manifest.Unmodeled, kind)
+	}
+	sort.Strings(manifest.Unmodeled)
+
+	return manifest, nil
+}
+
+func tokenStatValue(supply, maxSupply eos.Asset, issuer eos.AccountName) string {
+	return fmt.Sprintf("supply=%s max_supply=%s issuer=%s", supply, maxSupply, issuer)
+}
+
+// onChainStateValue reads the chain's value of a leaf.
+func (b *BIOS) onChainStateValue(key string, ephemeralKey string) (string, error) {
+	api := b.validationAPI()
+	parts := strings.Split(key, ":")
+
+	switch {
+	case parts[0] == "account" && len(parts) == 2:
+		acct, err := api.GetAccount(AN(parts[1]))
+		if err != nil {
+			return "", fmt.Errorf("get account: %s", err)
+		}
+		code, err := api.GetCode(AN(parts[1]))
+		if err != nil {
+			return "", fmt.Errorf("get code: %s", err)
+		}
+		account := &stateAccount{CodeHash: code.CodeHash}
+		for _, perm := range acct.Permissions {
+			account.Permissions = append(account.Permissions, &statePermission{PermName: perm.PermName, Parent: perm.Parent, RequiredAuth: perm.RequiredAuth})
+		}
+		return account.value(ephemeralKey), nil
+
+	case parts[0] == "balance" && len(parts) == 3:
+		resp, err := api.GetTableRows(eos.GetTableRowsRequest{JSON: true, Code: "eosio.token", Scope: parts[1], Table: "accounts"})
+		if err != nil {
+			return "", fmt.Errorf("get balances: %s", err)
+		}
+		var rows []struct {
+			Balance eos.Asset `json:"balance"`
+		}
+		if err := json.Unmarshal(resp.Rows, &rows); err != nil {
+			return "", fmt.Errorf("decoding balance rows: %s", err)
+		}
+		for _, row := range rows {
+			if row.Balance.Symbol.Symbol == parts[2] {
+				return row.Balance.String(), nil
+			}
+		}
+		return "(no balance)", nil
+
+	case parts[0] == "stat" && len(parts) == 2:
+		resp, err := api.GetTableRows(eos.GetTableRowsRequest{JSON: true, Code: "eosio.token", Scope: parts[1], Table: "stat"})
+		if err != nil {
+			return "", fmt.Errorf("get stat: %s", err)
+		}
+		var rows []struct {
+			Supply    eos.Asset       `json:"supply"`
+			MaxSupply eos.Asset       `json:"max_supply"`
+			Issuer    eos.AccountName `json:"issuer"`
+		}
+		if err := json.Unmarshal(resp.Rows, &rows); err != nil {
+			return "", fmt.Errorf("decoding stat rows: %s", err)
+		}
+		if len(rows) == 0 {
+			return "(no stat)", nil
+		}
+		return tokenStatValue(rows[0].Supply, rows[0].MaxSupply, rows[0].Issuer), nil
+	}
+
+	return "", fmt.Errorf("unknown state leaf %q", key)
+}
+
+// verifyStateManifest reads every leaf of `manifest` on chain, and
+// compares the chain's root with the manifest's. `ephemeralKey` is
+// the genesis' `initial_key`.
+func (b *BIOS) verifyStateManifest(manifest *StateManifest, ephemeralKey string) error {
+	var onChain []*StateLeaf
+	var mismatches []string
+	for idx, leaf := range manifest.Leaves {
+		value, err := b.onChainStateValue(leaf.Key, ephemeralKey)
+		if err != nil {
+			return fmt.Errorf("%s: %s", leaf.Key, err)
+		}
+		actual := newStateLeaf(leaf.Key, value)
+		if actual.Hash != leaf.Hash {
+			mismatches = append(mismatches, fmt.Sprintf("%s is %s, expected %s", leaf.Key, value, leaf.Value))
+		}
+		onChain = append(onChain, actual)
+
+		if idx%1000 == 999 {
+			verbose.Printf("  %d/%d state leaves read\n", idx+1, len(manifest.Leaves))
+			b.Watchdog.Progress("verifying the state manifest")
+		}
+	}
+
+	root := stateMerkleRoot(onChain)
+	if root == manifest.Root {
+		return nil
+	}
+
+	if len(mismatches) > 10 {
+		mismatches = append(mismatches[:10], fmt.Sprintf("and %d more", len(mismatches)-10))
+	}
+	return fmt.Errorf("state root on chain is %s, expected %s: %s", root, manifest.Root, strings.Join(mismatches, "; "))
+}
+
+// PublishStateManifest computes the state manifest before the boot
+// sequence, and publishes its root.
+func (b *BIOS) PublishStateManifest() (*StateManifest, error) {
+	manifest, err := b.buildStateManifest()
+	if err != nil {
+		return nil, err
+	}
+	milestone.Printf("State manifest root: %s (%d leaves)\n", manifest.Root, len(manifest.Leaves))
+	b.Report.StateManifestRoot = manifest.Root
+
+	if err := b.publish("state_manifest_root", manifest.Root); err != nil {
+		info.Println("WARNING: failed publishing the state manifest root:", err)
+	}
+	return manifest, b.DispatchPublishStateManifest(manifest.Root)
+}
+
+// RunStateManifestVerification verifies the booted chain against the
+// state manifest, and records the result with the validations. Given
+// the root the boot node published, a manifest computed locally must
+// have the same.
+func (b *BIOS) RunStateManifestVerification(manifest *StateManifest, publishedRoot string) error {
+	if manifest == nil {
+		var err error
+		manifest, err = b.buildStateManifest()
+		if err != nil {
+			return fmt.Errorf("state manifest: %s", err)
+		}
+		b.Report.StateManifestRoot = manifest.Root
+	}
+
+	info.Printf("- Verifying the chain against the state manifest, root %s, %d leaves: ", manifest.Root, len(manifest.Leaves))
+	finding := &ValidationFinding{Step: -1, Op: "state_manifest", Check: "state-manifest"}
+	b.Report.Validations = append(b.Report.Validations, finding)
+	if publishedRoot != "" && publishedRoot != manifest.Root {
+		err := fmt.Errorf("the boot node published the state manifest root %s, computed %s from the launch data", publishedRoot, manifest.Root)
+		info.Println("FAILED:", err)
+		finding.Error = err.Error()
+		return err
+	}
+	if err := b.verifyStateManifest(manifest, b.EphemeralPrivateKey.PublicKey().String()); err != nil {
+		info.Println("FAILED:", err)
+		finding.Error = err.Error()
+		return err
+	}
+	info.Println("OKAY")
+	for _, kind := range manifest.Unmodeled {
+		verbose.Println("  Not covered by the state manifest:", kind)
+	}
+	return nil
+}
+
+// RunStateManifest implements `eos-bios state-manifest`.
+func (b *BIOS) RunStateManifest(args []string) error {
+	fs := flag.NewFlagSet("state-manifest", flag.ExitOnError)
+	out := fs.String("out", "state-manifest.json", "File to write the state manifest to.")
+	verify := fs.Bool("verify", false, "Verify the chain at `producer.api_address` (or the observer) against it.")
+	initialKey := fs.String("initial-key", "", "With --verify, the genesis' `initial_key`, the boot's ephemeral public key.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *verify && *initialKey == "" {
+		return fmt.Errorf("--verify needs the genesis' --initial-key")
+	}
+
+	if b.EphemeralPrivateKey == nil {
+		// A throw-away key, it appears as EPHEMERAL in the manifest.
+		ephemeralPrivateKey, err := ecc.NewRandomPrivateKey()
+		if err != nil {
+			return err
+		}
+		b.EphemeralPrivateKey = ephemeralPrivateKey
+	}
+
+	manifest, err := b.buildStateManifest()
+	if err != nil {
+		return err
+	}
+
+	cnt, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(*out, cnt, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("State manifest of %d leaves written to %s\n", len(manifest.Leaves), *out)
+	fmt.Println("Root:", manifest.Root)
+	for _, kind := range manifest.Unmodeled {
+		fmt.Println("Not covered:", kind)
+	}
+
+	if !*verify {
+		return nil
+	}
+	if err := b.verifyStateManifest(manifest, *initialKey); err != nil {
+		return err
+	}
+	fmt.Println("The chain matches the state manifest")
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateMerkleRoot(t *testing.T) {
+	a := newStateLeaf("account:eosio", "a")
+	b := newStateLeaf("balance:eosio:EOS", "b")
+	c := newStateLeaf("stat:EOS", "c")
+
+	hash := func(left, right string) string {
+		l, _ := hex.DecodeString(left)
+		r, _ := hex.DecodeString(right)
+		sum := sha256.Sum256(append(l, r...))
+		return hex.EncodeToString(sum[:])
+	}
+
+	assert.Equal(t, "", stateMerkleRoot(nil))
+	assert.Equal(t, a.Hash, stateMerkleRoot([]*StateLeaf{a}))
+	assert.Equal(t, hash(hash(a.Hash, b.Hash), c.Hash), stateMerkleRoot([]*StateLeaf{a, b, c}))
+}
+
+func TestStateAccountValue(t *testing.T) {
+	key, err := ecc.NewRandomPrivateKey()
+	require.NoError(t, err)
+	auth := eos.Authority{Threshold: 1, Keys: []eos.KeyWeight{{PublicKey: key.PublicKey(), Weight: 1}}}
+
+	modeled := &stateAccount{CodeHash: noCodeHash}
+	modeled.setPermission(PN("owner"), PN(""), auth)
+	modeled.setPermission(PN("active"), PN("owner"), auth)
+
+	// As read from the chain: other order, empty lists.
+	onChain := &stateAccount{CodeHash: noCodeHash, Permissions: []*statePermission{
+		{PermName: "owner", RequiredAuth: eos.Authority{Threshold: 1, Keys: auth.Keys, Accounts: []eos.PermissionLevelWeight{}}},
+		{PermName: "active", Parent: "owner", RequiredAuth: auth},
+	}}
+
+	value := modeled.value(key.PublicKey().String())
+	assert.Equal(t, value, onChain.value(key.PublicKey().String()))
+	assert.Contains(t, value, ephemeralPlaceholder)
+	assert.NotContains(t, value, key.PublicKey().String())
+}
+
+func TestStateManifestPublishedRoot(t *testing.T) {
+	b := &BIOS{}
+	manifest := &StateManifest{Root: stateMerkleRoot([]*StateLeaf{newStateLeaf("stat:EOS", "a")})}
+
+	err := b.RunStateManifestVerification(manifest, stateMerkleRoot([]*StateLeaf{newStateLeaf("stat:EOS", "b")}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "published the state manifest root")
+	require.Len(t, b.Report.Validations, 1)
+	assert.Equal(t, err.Error(), b.Report.Validations[0].Error)
+}
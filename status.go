@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Status is what the status API exposes about a run, updated as it
+// progresses.
+type Status struct {
+	lock sync.Mutex
+
+	Account string        `json:"account"`
+	Network string        `json:"network,omitempty"`
+	Stage   string        `json:"stage"`
+	Voting  *VotingStatus `json:"voting,omitempty"`
+	// Integrations is the circuit state of optional integrations, see `breaker.go`.
+	Integrations map[string]string `json:"integrations,omitempty"`
+}
+
+func (s *Status) Update(f func(s *Status)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	f(s)
+}
+
+func (b *BIOS) setStage(stage string) {
+	b.Status.Update(func(s *Status) {
+		s.Stage = stage
+	})
+}
+
+// startStatusServer serves `/status` as JSON, and `/metrics` in the
+// Prometheus text format.
+func (b *BIOS) startStatusServer() {
+	b.Status.Update(func(s *Status) {
+		s.Account = b.Config.Producer.MyAccount
+		s.Network = b.Config.Network
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		b.refreshIntegrationsStatus()
+
+		b.Status.lock.Lock()
+		cnt, err := json.MarshalIndent(&b.Status, "", "  ")
+		b.Status.lock.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(cnt)
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		b.Status.lock.Lock()
+		defer b.Status.lock.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if voting := b.Status.Voting; voting != nil {
+			activated := 0
+			if voting.Activated {
+				activated = 1
+			}
+			fmt.Fprintf(w, "eos_bios_voting_activated_stake %d\n", voting.ActivatedStake)
+			fmt.Fprintf(w, "eos_bios_voting_supply %d\n", voting.Supply)
+			fmt.Fprintf(w, "eos_bios_voting_percent %f\n", voting.Percent)
+			fmt.Fprintf(w, "eos_bios_voting_activated %d\n", activated)
+		}
+	})
+
+	info.Println("Status API listening on", b.Config.Status.ListenAddress)
+	go func() {
+		if err := http.ListenAndServe(b.Config.Status.ListenAddress, mux); err != nil {
+			milestone.Println("WARNING: status API stopped:", err)
+		}
+	}()
+}
+
+func (b *BIOS) refreshIntegrationsStatus() {
+	b.breakersLock.Lock()
+	var names []string
+	for name := range b.breakers {
+		names = append(names, name)
+	}
+	breakers := b.breakers
+	b.breakersLock.Unlock()
+
+	sort.Strings(names)
+	integrations := map[string]string{}
+	for _, name := range names {
+		state, _ := breakers[name].Status()
+		integrations[name] = state
+	}
+
+	b.Status.Update(func(s *Status) {
+		s.Integrations = integrations
+	})
+}
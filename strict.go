@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Unknown fields of the local config and the launch data are errors
+// in strict mode, so a typo like `no_shufle` doesn't go unnoticed,
+// and warnings otherwise. Strict mode is the default in production,
+// that is when no `debug` setting is used, and can be forced either
+// way with the config's `strict`.
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// strictDecoding tells whether unknown fields are errors.
+func (c *Config) strictDecoding() bool {
+	if c.Strict != nil {
+		return *c.Strict
+	}
+	return reflect.DeepEqual(c.Debug, reflect.Zero(reflect.TypeOf(c.Debug)).Interface())
+}
+
+// checkUnknownFields reports the fields of the JSON document `cnt`
+// that decoding into `v` ignores, by path.
+func checkUnknownFields(what string, cnt []byte, v interface{}, strict bool) error {
+	var doc interface{}
+	if err := json.Unmarshal(cnt, &doc); err != nil {
+		return err
+	}
+
+	var unknown []string
+	walkUnknownFields("", doc, reflect.TypeOf(v), &unknown)
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+
+	if strict {
+		return fmt.Errorf("%s: unknown field(s) %s (set `strict: false` to only warn)", what, strings.Join(unknown, ", "))
+	}
+	for _, path := range unknown {
+		info.Printf("WARNING: %s: unknown field %s, ignored\n", what, path)
+	}
+	return nil
+}
+
+func walkUnknownFields(path string, doc interface{}, t reflect.Type, out *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(OperationType{}) {
+		walkUnknownOperation(path, doc, out)
+		return
+	}
+	if reflect.PtrTo(t).Implements(jsonUnmarshalerType) {
+		// Decodes itself, we can't tell what it ignores.
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for key, value := range obj {
+			field, found := lookupJSONField(fields, key)
+			if !found {
+				*out = append(*out, joinFieldPath(path, key))
+				continue
+			}
+			walkUnknownFields(joinFieldPath(path, key), value, field.Type, out)
+		}
+
+	case reflect.Map:
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for key, value := range obj {
+			walkUnknownFields(fmt.Sprintf("%s[%q]", path, key), value, t.Elem(), out)
+		}
+
+	case reflect.Slice, reflect.Array:
+		list, ok := doc.([]interface{})
+		if !ok {
+			return
+		}
+		for idx, value := range list {
+			walkUnknownFields(fmt.Sprintf("%s[%d]", path, idx), value, t.Elem(), out)
+		}
+	}
+}
+
+// walkUnknownOperation checks a boot sequence step, whose `data`
+// decodes into the type registered for its `op`.
+func walkUnknownOperation(path string, doc interface{}, out *[]string) {
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	var op string
+	for key, value := range obj {
+		switch strings.ToLower(key) {
+		case "op":
+			op, _ = value.(string)
+		case "label", "data", "validate_exec":
+		default:
+			*out = append(*out, joinFieldPath(path, key))
+		}
+	}
+
+	opType, found := operationsRegistry[op]
+	if !found {
+		return
+	}
+	for key, value := range obj {
+		if strings.ToLower(key) == "data" {
+			walkUnknownFields(joinFieldPath(path, key), value, reflect.TypeOf(opType), out)
+		}
+	}
+}
+
+// jsonFields maps the JSON names of a struct's fields, flattening
+// embedded structs, like `encoding/json` does.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	out := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for n, f := range jsonFields(embedded) {
+					if _, found := out[n]; !found {
+						out[n] = f
+					}
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		out[name] = field
+	}
+	return out
+}
+
+// lookupJSONField matches a key to a field, case-insensitively as
+// `encoding/json` does.
+func lookupJSONField(fields map[string]reflect.StructField, key string) (reflect.StructField, bool) {
+	if field, found := fields[key]; found {
+		return field, true
+	}
+	for name, field := range fields {
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func joinFieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
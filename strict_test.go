@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckUnknownFields(t *testing.T) {
+	config := `{"run_dir":"run","debug":{"no_shufle":true},"hooks":{"init":{"url":"http://x","wiat":true}}}`
+	err := checkUnknownFields("local config", []byte(config), &Config{}, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `debug.no_shufle, hooks["init"].wiat`)
+
+	assert.NoError(t, checkUnknownFields("local config", []byte(config), &Config{}, false))
+
+	launch := `{"boot_sequence":[{"op":"system.setcode","label":"Set code","data":{"account":"eosio","contract_name_ref":"bios","contract":"x"}}]}`
+	err = checkUnknownFields("launch data", []byte(launch), &LaunchData{}, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boot_sequence[0].data.contract")
+	assert.NotContains(t, err.Error(), "contract_name_ref")
+}
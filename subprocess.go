@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	shellwords "github.com/mattn/go-shellwords"
+)
+
+// subprocessTransport hands payloads to an external executable, so
+// transports we don't ship (Signal, WeChat bridges, ...) can be
+// plugged in. The executable is run once per call, with a
+// `SubprocessRequest` as JSON on stdin, and answers a
+// `SubprocessResponse` as JSON on stdout:
+//
+//	{"method": "publish", "kind": "kickstart", "payload": "...", "options": {...}}
+//	{}
+//
+//	{"method": "receive", "kind": "kickstart", "cursor": "...", "options": {...}}
+//	{"payloads": ["..."], "cursor": "..."}
+//
+// The `cursor` is opaque to us: whatever the executable returned on
+// the previous `receive` of that kind, so it only returns newer
+// payloads. It may block until some arrive, or return none, in which
+// case we ask again after `poll_interval`. A non-empty `error`, or a
+// non-zero exit, fails the call.
+type subprocessTransport struct {
+	args         []string
+	options      map[string]string
+	pollInterval time.Duration
+	cursors      map[string]string
+}
+
+type SubprocessRequest struct {
+	Method  string            `json:"method"`
+	Kind    string            `json:"kind"`
+	Payload string            `json:"payload,omitempty"`
+	Cursor  string            `json:"cursor,omitempty"`
+	Options map[string]string `json:"options"`
+}
+
+type SubprocessResponse struct {
+	Payloads []string `json:"payloads"`
+	Cursor   string   `json:"cursor"`
+	Error    string   `json:"error"`
+}
+
+func newSubprocessTransport(config *Config, launch *LaunchData) (Transport, error) {
+	conf := config.Transport.Subprocess
+	if conf.Exec == "" {
+		return nil, fmt.Errorf("the subprocess transport requires an exec")
+	}
+
+	p := shellwords.NewParser()
+	p.ParseEnv = true
+	args, err := p.Parse(conf.Exec)
+	if err != nil {
+		return nil, fmt.Errorf("transport.subprocess.exec: %s", err)
+	}
+
+	pollInterval := 10 * time.Second
+	if conf.PollInterval != "" {
+		pollInterval, err = time.ParseDuration(conf.PollInterval)
+		if err != nil {
+			return nil, fmt.Errorf("transport.subprocess.poll_interval: %s", err)
+		}
+	}
+
+	return &subprocessTransport{
+		args:         args,
+		options:      conf.Options,
+		pollInterval: pollInterval,
+		cursors:      map[string]string{},
+	}, nil
+}
+
+func (t *subprocessTransport) Publish(kind, payload string) error {
+	_, err := t.call(&SubprocessRequest{Method: "publish", Kind: kind, Payload: payload})
+	return err
+}
+
+func (t *subprocessTransport) Receive(kind string) ([]string, error) {
+	for {
+		resp, err := t.call(&SubprocessRequest{Method: "receive", Kind: kind, Cursor: t.cursors[kind]})
+		if err != nil {
+			return nil, err
+		}
+		if resp.Cursor != "" {
+			t.cursors[kind] = resp.Cursor
+		}
+
+		if len(resp.Payloads) != 0 {
+			return resp.Payloads, nil
+		}
+		time.Sleep(t.pollInterval)
+	}
+}
+
+func (t *subprocessTransport) call(req *SubprocessRequest) (*SubprocessResponse, error) {
+	req.Options = t.options
+	request, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(t.args[0], t.args[1:]...)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("transport %s %s: %s: %s", t.args[0], req.Method, err, strings.TrimSpace(stderr.String()))
+	}
+
+	resp := &SubprocessResponse{}
+	if out := bytes.TrimSpace(stdout.Bytes()); len(out) != 0 {
+		if err := json.Unmarshal(out, resp); err != nil {
+			return nil, fmt.Errorf("transport %s %s: invalid response: %s", t.args[0], req.Method, err)
+		}
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("transport %s %s: %s", t.args[0], req.Method, resp.Error)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// The core token is `eos.EOSSymbol` throughout: the snapshot is parsed
+// with it, the voting tracker reads its supply, and the system
+// contract stakes it. checkSymbols makes sure the launch data agrees,
+// so a stray "1000.00 EOS" fails at load time, rather than as an
+// assertion on chain in the middle of the boot.
+
+var memoAssetRegexp = regexp.MustCompile(`\b([0-9]+)(?:\.([0-9]+))? ([A-Z]{1,7})\b`)
+
+// checkSymbols verifies all assets of the launch data and snapshot
+// (including those in memos) use the core symbol and precision, or a
+// token created by the boot sequence with its own precision.
+func checkSymbols(launch *LaunchData, snapshot Snapshot) error {
+	symbols := map[string]eos.Symbol{eos.EOSSymbol.Symbol: eos.EOSSymbol}
+
+	for idx, step := range launch.BootSequence {
+		create, ok := step.Data.(*OpCreateToken)
+		if !ok {
+			continue
+		}
+		sym := create.Amount.Symbol
+		if known, found := symbols[sym.Symbol]; found && known.Precision != sym.Precision {
+			return fmt.Errorf("step %d [%s]: %s created with precision %d, expected %d", idx, step.Op, sym.Symbol, sym.Precision, known.Precision)
+		}
+		symbols[sym.Symbol] = sym
+	}
+
+	var problems []string
+	check := func(where string, sym eos.Symbol) {
+		known, found := symbols[sym.Symbol]
+		switch {
+		case !found:
+			problems = append(problems, fmt.Sprintf("%s: unknown symbol %q, no token.create step creates it", where, sym.Symbol))
+		case known.Precision != sym.Precision:
+			problems = append(problems, fmt.Sprintf("%s: %s with precision %d, expected %d", where, sym.Symbol, sym.Precision, known.Precision))
+		}
+	}
+
+	for idx, step := range launch.BootSequence {
+		walkAssets(reflect.ValueOf(step.Data), fmt.Sprintf("step %d [%s]", idx, step.Op), check)
+	}
+	for idx, action := range launch.ScheduledActions {
+		if action.Step != nil {
+			walkAssets(reflect.ValueOf(action.Step.Data), fmt.Sprintf("scheduled step %d [%s]", idx, action.Step.Op), check)
+		}
+	}
+
+	for _, acct := range launch.GenesisAccounts {
+		check(fmt.Sprintf("genesis account %s", acct.AccountName), acct.Balance.Symbol)
+	}
+
+	for idx, line := range snapshot {
+		if line.Balance.Symbol != eos.EOSSymbol {
+			check(fmt.Sprintf("snapshot line %d", idx+1), line.Balance.Symbol)
+		}
+	}
+
+	if len(problems) != 0 {
+		return fmt.Errorf("%d token symbol problem(s):\n- %s", len(problems), strings.Join(problems, "\n- "))
+	}
+	return nil
+}
+
+// walkAssets calls `check` for every asset found in `val`, and every
+// amount written out in its `Memo` fields.
+func walkAssets(val reflect.Value, where string, check func(where string, sym eos.Symbol)) {
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !val.IsNil() {
+			walkAssets(val.Elem(), where, check)
+		}
+
+	case reflect.Slice:
+		for i := 0; i < val.Len(); i++ {
+			walkAssets(val.Index(i), where, check)
+		}
+
+	case reflect.Struct:
+		if asset, ok := val.Interface().(eos.Asset); ok {
+			if asset.Amount != 0 || asset.Symbol.Symbol != "" {
+				check(where, asset.Symbol)
+			}
+			return
+		}
+
+		for i := 0; i < val.NumField(); i++ {
+			field := val.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			if field.Name == "Memo" && field.Type.Kind() == reflect.String {
+				for _, sym := range memoSymbols(val.Field(i).String()) {
+					check(where+" memo", sym)
+				}
+				continue
+			}
+			walkAssets(val.Field(i), where, check)
+		}
+	}
+}
+
+// memoSymbols extracts the symbols of amounts like "10.0000 EOS" in a
+// memo, with the precision they're written with.
+func memoSymbols(memo string) (out []eos.Symbol) {
+	for _, match := range memoAssetRegexp.FindAllStringSubmatch(memo, -1) {
+		out = append(out, eos.Symbol{Precision: uint8(len(match[2])), Symbol: match[3]})
+	}
+	return
+}
+
+// checkAmountPrecision refuses amounts with more decimals than
+// `precision`, which would otherwise be silently rounded.
+func checkAmountPrecision(amount string, precision uint8) error {
+	idx := strings.Index(amount, ".")
+	if idx == -1 {
+		return nil
+	}
+	decimals := strings.TrimSpace(amount[idx+1:])
+	if space := strings.Index(decimals, " "); space != -1 {
+		decimals = decimals[:space]
+	}
+	if len(decimals) > int(precision) {
+		return fmt.Errorf("amount %q has %d decimals, the core token only has %d", amount, len(decimals), precision)
+	}
+	return nil
+}
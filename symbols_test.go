@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoSymbols(t *testing.T) {
+	assert.Equal(t, []eos.Symbol{
+		{Precision: 4, Symbol: "EOS"},
+		{Precision: 0, Symbol: "SYS"},
+	}, memoSymbols("Here's 10.0000 EOS, and 3 SYS for you"))
+	assert.Empty(t, memoSymbols("Welcome aboard!"))
+}
+
+func TestCheckAmountPrecision(t *testing.T) {
+	assert.NoError(t, checkAmountPrecision("10", 4))
+	assert.NoError(t, checkAmountPrecision("10.1234", 4))
+	assert.NoError(t, checkAmountPrecision("10.12 EOS", 4))
+	assert.Error(t, checkAmountPrecision("10.12345", 4))
+}
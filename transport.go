@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Transport carries payloads between participants, as an
+// alternative to copy-pasting: kickstart data, readiness
+// attestations, notifications.
+type Transport interface {
+	// Publish sends `payload` to the group, tagged with its `kind`
+	// ("kickstart", "readiness", ...).
+	Publish(kind, payload string) error
+	// Receive blocks until payloads of `kind` arrive, and returns
+	// those received since the previous call.
+	Receive(kind string) ([]string, error)
+}
+
+var transportsRegistry = map[string]func(config *Config, launch *LaunchData) (Transport, error){
+	"matrix":     newMatrixTransport,
+	"email":      newEmailTransport,
+	"keybase":    newKeybaseTransport,
+	"subprocess": newSubprocessTransport,
+}
+
+func transportTypes() (out []string) {
+	for name := range transportsRegistry {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return
+}
+
+// newTransport returns the configured transport, or nil when none is.
+func newTransport(config *Config, launch *LaunchData) (Transport, error) {
+	if config.Transport.Type == "" {
+		return nil, nil
+	}
+
+	factory, found := transportsRegistry[config.Transport.Type]
+	if !found {
+		return nil, fmt.Errorf("transport type %q invalid, use one of: %q", config.Transport.Type, transportTypes())
+	}
+
+	return factory(config, launch)
+}
+
+// publish sends `payload` through the transport, if any is configured.
+func (b *BIOS) publish(kind, payload string) error {
+	if b.Transport == nil {
+		return nil
+	}
+
+	info.Printf("Publishing %s through the %s transport\n", kind, b.Config.Transport.Type)
+
+	return b.Transport.Publish(kind, payload)
+}
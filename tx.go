@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// signTransaction builds a transaction for `actions` and signs it
+// with the keys of our signer, without pushing it. `expiration` is
+// how long it stays valid, zero keeping eos-go's default.
+func (b *BIOS) signTransaction(actions []*eos.Action, expiration time.Duration) (*eos.SignedTransaction, *eos.PackedTransaction, error) {
+	opts := &eos.TxOptions{}
+	if err := opts.FillFromChain(b.API); err != nil {
+		return nil, nil, fmt.Errorf("filling transaction options: %s", err)
+	}
+
+	tx := eos.NewTransaction(actions, opts)
+	if expiration != 0 {
+		tx.SetExpiration(expiration)
+	}
+
+	return b.API.SignTransaction(tx, opts.ChainID, eos.CompressionNone)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ReleaseManifest announces the eos-bios build participants should
+// run, when a last-minute fix is coordinated after the launch data
+// was pinned. It is served at `release.manifest_url`, and signed
+// (detached PGP) with `release.signing_key_path`.
+type ReleaseManifest struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	// BinaryHashes are the sha256 of the release binaries, keyed by "os_arch".
+	BinaryHashes map[string]string `json:"binary_hashes"`
+	// URL to download the release from.
+	URL   string `json:"url"`
+	Notes string `json:"notes"`
+}
+
+var releaseManifestClient = &http.Client{Timeout: 20 * time.Second}
+
+// checkReleaseUpdate fetches the signed release manifest, and warns,
+// prompts or aborts (per `release.update_policy`) when it announces
+// another build than this one.
+func checkReleaseUpdate(config *Config) error {
+	conf := config.Release
+	if conf.ManifestURL == "" {
+		return nil
+	}
+
+	switch conf.UpdatePolicy {
+	case "", "warn", "prompt", "abort":
+	default:
+		return fmt.Errorf("release.update_policy %q invalid, use `warn`, `prompt` or `abort`", conf.UpdatePolicy)
+	}
+	if conf.SigningKeyPath == "" {
+		return fmt.Errorf("release.manifest_url needs release.signing_key_path, to verify the manifest")
+	}
+
+	manifest, err := fetchReleaseManifest(conf.ManifestURL, conf.SigningKeyPath)
+	if err != nil {
+		return err
+	}
+
+	outdated, err := manifest.outdated()
+	if err != nil {
+		return err
+	}
+	if outdated == "" {
+		info.Printf("Build %s (%s) is the one announced by the release manifest\n", version, commit)
+		return nil
+	}
+
+	milestone.Println("###############################################################################################")
+	milestone.Println("A NEWER EOS-BIOS BUILD IS ANNOUNCED:", outdated)
+	milestone.Printf("  Announced: version %s, commit %s\n", manifest.Version, manifest.Commit)
+	if manifest.URL != "" {
+		milestone.Println("  Download:", manifest.URL)
+	}
+	if manifest.Notes != "" {
+		milestone.Println("  Notes:", manifest.Notes)
+	}
+	milestone.Println("###############################################################################################")
+
+	switch conf.UpdatePolicy {
+	case "abort":
+		return fmt.Errorf("refusing to run an outdated build, per release.update_policy")
+	case "prompt":
+		milestone.Printf("Continue with this outdated build anyway? [y/N] ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		recordOperatorAction("", "gate", "continue with the outdated build "+version, strings.TrimSpace(answer))
+		if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+			return fmt.Errorf("aborted, upgrade to the announced build")
+		}
+		milestone.Println("WARNING: continuing with an outdated build")
+	default:
+		milestone.Println("WARNING: continuing with an outdated build, set release.update_policy to `prompt` or `abort` to be stopped")
+	}
+
+	return nil
+}
+
+func fetchReleaseManifest(manifestURL, keyPath string) (*ReleaseManifest, error) {
+	cnt, err := fetchReleaseFile(manifestURL)
+	if err != nil {
+		return nil, fmt.Errorf("release manifest: %s", err)
+	}
+	sig, err := fetchReleaseFile(manifestURL + ".sig")
+	if err != nil {
+		return nil, fmt.Errorf("release manifest signature: %s", err)
+	}
+
+	if err := checkDetachedSignatureOf(bytes.NewReader(cnt), sig, keyPath); err != nil {
+		return nil, fmt.Errorf("release manifest signature: %s", err)
+	}
+
+	var manifest *ReleaseManifest
+	if err := yamlUnmarshal(cnt, &manifest); err != nil {
+		return nil, fmt.Errorf("release manifest: %s", err)
+	}
+	if manifest == nil || (manifest.Version == "" && manifest.Commit == "") {
+		return nil, fmt.Errorf("release manifest announces no version nor commit")
+	}
+	return manifest, nil
+}
+
+func fetchReleaseFile(url string) ([]byte, error) {
+	resp, err := releaseManifestClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// outdated describes how this build differs from the announced one,
+// or is empty when it is the announced build.
+func (m *ReleaseManifest) outdated() (string, error) {
+	if m.Version != "" && m.Version != version {
+		return fmt.Sprintf("this is version %s, not %s", version, m.Version), nil
+	}
+	if m.Commit != "" && m.Commit != commit {
+		return fmt.Sprintf("this is commit %s, not %s", commit, m.Commit), nil
+	}
+
+	platform := runtime.GOOS + "_" + runtime.GOARCH
+	if expected, found := m.BinaryHashes[platform]; found {
+		executable, err := os.Executable()
+		if err != nil {
+			return "", fmt.Errorf("locating own binary: %s", err)
+		}
+		hash, err := hashFile(executable)
+		if err != nil {
+			return "", fmt.Errorf("hashing own binary: %s", err)
+		}
+		if hash != expected {
+			return fmt.Sprintf("this binary's hash is %s, not %s", hash, expected), nil
+		}
+	}
+
+	return "", nil
+}
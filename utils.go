@@ -22,6 +22,11 @@ func ScanLinesUntilBlank() (out string, err error) {
 
 		out += text
 
+		// Armored PGP messages hold blank lines, read them to their end.
+		if strings.HasPrefix(strings.TrimSpace(out), "-----BEGIN PGP") && !strings.Contains(out, "-----END PGP") {
+			continue
+		}
+
 		if text == "\n" {
 			return strings.TrimSpace(out), nil
 		}
@@ -34,6 +39,9 @@ var AN = eos.AN
 // PN is a shortcut to create a PermissionName
 var PN = eos.PN
 
+// ActN is a shortcut to create an ActionName
+var ActN = eos.ActN
+
 func flipEndianness(in uint64) (out uint64) {
 	buf := []byte{0, 0, 0, 0, 0, 0, 0, 0}
 	binary.LittleEndian.PutUint64(buf, in)
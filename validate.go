@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+
+	"github.com/eoscanada/eos-go"
+	shellwords "github.com/mattn/go-shellwords"
+)
+
+// ValidatableOperation is implemented by operations that can verify,
+// from an Appointed Block Producer's point of view, that the chain
+// reflects what the boot node was supposed to do for that step.
+type ValidatableOperation interface {
+	Validate(b *BIOS) error
+}
+
+// RunStepValidations runs the validations of all boot sequence steps
+// that support it, and fails if any of them fails.
+func (b *BIOS) RunStepValidations() error {
+	failures := 0
+	for idx, step := range b.LaunchData.BootSequence {
+		b.currentStep = idx
+		failures += b.validateStep(idx, step, "")
+	}
+
+	if failures != 0 {
+		return fmt.Errorf("%d boot step(s) failed validation", failures)
+	}
+	return nil
+}
+
+// validateStep runs the built-in and custom validations of one step,
+// records them in the report, and returns the number of failures.
+// `kind` prefixes the checks, to tell scheduled steps apart (see
+// `stage2.go`).
+func (b *BIOS) validateStep(idx int, step *OperationType, kind string) (failures int) {
+	if validatable, ok := step.Data.(ValidatableOperation); ok {
+		info.Printf("- Validating step %d, %s [%s]: ", idx, step.Label, step.Op)
+		finding := &ValidationFinding{Step: idx, Op: step.Op, Check: kind + "built-in"}
+		if err := validatable.Validate(b); err != nil {
+			info.Println("FAILED:", err)
+			finding.Error = err.Error()
+			failures++
+		} else {
+			info.Println("OKAY")
+		}
+		b.Report.Validations = append(b.Report.Validations, finding)
+	}
+
+	if step.ValidateExec != "" {
+		info.Printf("- Running custom validation for step %d, %s [%s]\n", idx, step.Label, step.Op)
+		finding := &ValidationFinding{Step: idx, Op: step.Op, Check: kind + "exec"}
+		if err := b.execValidation(idx, step); err != nil {
+			info.Println("  FAILED:", err)
+			finding.Error = err.Error()
+			failures++
+		} else {
+			info.Println("  OKAY")
+		}
+		b.Report.Validations = append(b.Report.Validations, finding)
+	}
+
+	return
+}
+
+// execValidation runs a step's `validate_exec` command. It receives
+// the chain endpoint and the step's context as arguments (in that
+// order) and as environment variables, and must exit with a non-zero
+// status on failure.
+func (b *BIOS) execValidation(idx int, step *OperationType) error {
+	p := shellwords.NewParser()
+	p.ParseEnv = true
+	args, err := p.Parse(step.ValidateExec)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("empty validate_exec")
+	}
+
+	stepContext := []string{
+		"API_ADDRESS", b.validationAPIAddress(),
+		"STEP_INDEX", strconv.Itoa(idx),
+		"STEP_OP", step.Op,
+		"STEP_LABEL", step.Label,
+		"LAUNCH_HASH", b.LaunchData.fileHash,
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = os.Environ()
+	for i := 0; i < len(stepContext); i += 2 {
+		cmd.Args = append(cmd.Args, stepContext[i+1])
+		cmd.Env = append(cmd.Env, "EOS_BIOS_"+stepContext[i]+"="+stepContext[i+1])
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	verbose.Printf("  Executing validation: %q\n", cmd.Args)
+
+	return cmd.Run()
+}
+
+// getTokenBalance fetches the `eosio.token` balance of `account`.
+func (b *BIOS) getTokenBalance(account eos.AccountName) (eos.Asset, error) {
+	resp, err := b.validationAPI().GetTableRows(eos.GetTableRowsRequest{
+		JSON:  true,
+		Code:  "eosio.token",
+		Scope: string(account),
+		Table: "accounts",
+	})
+	if err != nil {
+		return eos.Asset{}, err
+	}
+
+	var rows []struct {
+		Balance eos.Asset `json:"balance"`
+	}
+	if err := json.Unmarshal(resp.Rows, &rows); err != nil {
+		return eos.Asset{}, fmt.Errorf("decoding balance rows: %s", err)
+	}
+
+	if len(rows) == 0 {
+		return eos.Asset{Symbol: eos.EOSSymbol}, nil
+	}
+	return rows[0].Balance, nil
+}
+
+// appointedProducersAuthority is an authority satisfied by
+// `threshold` Appointed Block Producers' `active` permissions. Clones
+// are excluded, as they are controlled by the same teams. A zero
+// threshold means 2/3+1 of the ABPs.
+func (b *BIOS) appointedProducersAuthority(threshold uint32) eos.Authority {
+	var names []string
+	for i := 1; i < 22 && len(b.ShuffledProducers) > i; i++ {
+		prod := b.ShuffledProducers[i]
+		if prod.clonedFrom != "" {
+			continue
+		}
+		names = append(names, string(prod.AccountName))
+	}
+	// The chain requires authorities to be sorted
+	sort.Strings(names)
+
+	if threshold == 0 {
+		threshold = uint32(len(names)*2/3 + 1)
+	}
+
+	auth := eos.Authority{Threshold: threshold}
+	for _, name := range names {
+		auth.Accounts = append(auth.Accounts, eos.PermissionLevelWeight{
+			Permission: eos.PermissionLevel{Actor: AN(name), Permission: PN("active")},
+			Weight:     1,
+		})
+	}
+	return auth
+}
+
+// validateAuthority checks that `permission` on `acct` matches `expected`.
+func validateAuthority(acct *eos.AccountResp, permission string, expected eos.Authority) error {
+	for _, perm := range acct.Permissions {
+		if perm.PermName != permission {
+			continue
+		}
+
+		actual, _ := json.Marshal(perm.RequiredAuth)
+		wanted, _ := json.Marshal(expected)
+		if string(actual) != string(wanted) {
+			return fmt.Errorf("%s@%s authority is %s, expected %s", acct.AccountName, permission, actual, wanted)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%s has no %q permission", acct.AccountName, permission)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// votingActivationPercent of the supply must vote to activate the chain.
+const votingActivationPercent = 15.0
+
+type VotingStatus struct {
+	ActivatedStake int64   `json:"activated_stake"`
+	Supply         int64   `json:"supply"`
+	Percent        float64 `json:"percent"`
+	Activated      bool    `json:"activated"`
+}
+
+// votingTracker follows the staked vote percentage toward activation,
+// and fires the `voting_activated` hook once it happens.
+type votingTracker struct {
+	b         *BIOS
+	activated bool
+}
+
+func (t *votingTracker) check() error {
+	state, err := t.b.getSystemGlobalState()
+	if err != nil {
+		return err
+	}
+
+	supply, err := t.b.getTokenSupply(eos.EOSSymbol)
+	if err != nil {
+		return fmt.Errorf("get supply: %s", err)
+	}
+
+	voting := &VotingStatus{
+		ActivatedStake: state.TotalActivatedStake,
+		Supply:         supply.Amount,
+		Activated:      state.VotingActivated(),
+	}
+	if supply.Amount != 0 {
+		voting.Percent = float64(state.TotalActivatedStake) * 100 / float64(supply.Amount)
+	}
+
+	t.b.Status.Update(func(s *Status) {
+		s.Voting = voting
+	})
+
+	info.Printf("Voting: %.2f%% of the supply staked to vote, %.0f%% required\n", voting.Percent, votingActivationPercent)
+
+	if voting.Activated && !t.activated {
+		t.activated = true
+		milestone.Println("VOTING ACTIVATED! The chain is live.")
+		if err := t.b.DispatchVotingActivated(voting.Percent); err != nil {
+			milestone.Println("WARNING: dispatch voting_activated hook:", err)
+		}
+	}
+
+	return nil
+}
+
+// getTokenSupply reads the current supply of `symbol` from `eosio.token`.
+func (b *BIOS) getTokenSupply(symbol eos.Symbol) (eos.Asset, error) {
+	resp, err := b.validationAPI().GetTableRows(eos.GetTableRowsRequest{
+		JSON:  true,
+		Code:  "eosio.token",
+		Scope: symbol.Symbol,
+		Table: "stat",
+	})
+	if err != nil {
+		return eos.Asset{}, err
+	}
+
+	var rows []struct {
+		Supply eos.Asset `json:"supply"`
+	}
+	if err := json.Unmarshal(resp.Rows, &rows); err != nil {
+		return eos.Asset{}, fmt.Errorf("decoding stat rows: %s", err)
+	}
+	if len(rows) == 0 {
+		return eos.Asset{}, fmt.Errorf("no %s token created", symbol.Symbol)
+	}
+
+	return rows[0].Supply, nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/eoscanada/eos-go"
+)
+
+// WaitOperation is implemented by the `wait.*` ops: they push
+// nothing, but hold the boot sequence until a condition holds on
+// chain, like the `eosio.token` ABI being served before issuing.
+// Only the boot node waits, the ABPs' validations and audit see
+// steps without actions.
+type WaitOperation interface {
+	Wait(b *BIOS) error
+}
+
+// WaitTimeout is shared by the wait ops.
+type WaitTimeout struct {
+	// Timeout defaults to "2m".
+	Timeout string `json:"timeout"`
+}
+
+func (w WaitTimeout) duration() (time.Duration, error) {
+	if w.Timeout == "" {
+		return 2 * time.Minute, nil
+	}
+	timeout, err := time.ParseDuration(w.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("timeout: %s", err)
+	}
+	return timeout, nil
+}
+
+// waitFor polls `check` every second until it holds, or `timeout`
+// elapses. `check` errors are only retried, the last one reported.
+func (b *BIOS) waitFor(what string, timeout time.Duration, check func() (bool, error)) error {
+	start := time.Now()
+	var lastErr error
+	for {
+		done, err := check()
+		if err == nil && done {
+			info.Printf("Waited %s for %s\n", time.Since(start).Truncate(time.Millisecond), what)
+			return nil
+		}
+		if err != nil {
+			verbose.Printf("Waiting for %s: %s\n", what, err)
+			lastErr = err
+		}
+
+		if time.Since(start) > timeout {
+			if lastErr != nil {
+				return fmt.Errorf("%s not reached after %s, last error: %s", what, timeout, lastErr)
+			}
+			return fmt.Errorf("%s not reached after %s", what, timeout)
+		}
+		b.Watchdog.Progress("waiting for " + what)
+		time.Sleep(1 * time.Second)
+	}
+}
+
+// OpWaitForBlock waits for a block number, absolute or relative to
+// the head block when the step begins.
+type OpWaitForBlock struct {
+	WaitTimeout
+	Block uint32 `json:"block"`
+	// Blocks past the head block, when Block isn't set.
+	Blocks uint32 `json:"blocks"`
+	// Irreversible waits for the block to be irreversible.
+	Irreversible bool `json:"irreversible"`
+}
+
+func (op *OpWaitForBlock) Actions(b *BIOS) ([]*eos.Action, error) {
+	return nil, nil
+}
+
+func (op *OpWaitForBlock) Wait(b *BIOS) error {
+	timeout, err := op.duration()
+	if err != nil {
+		return err
+	}
+
+	target := op.Block
+	if target == 0 {
+		chainInfo, err := b.validationAPI().GetInfo()
+		if err != nil {
+			return fmt.Errorf("get info: %s", err)
+		}
+		target = chainInfo.HeadBlockNum + op.Blocks
+	}
+
+	what := fmt.Sprintf("block %d", target)
+	if op.Irreversible {
+		what = fmt.Sprintf("irreversible block %d", target)
+	}
+	return b.waitFor(what, timeout, func() (bool, error) {
+		chainInfo, err := b.validationAPI().GetInfo()
+		if err != nil {
+			return false, err
+		}
+		if op.Irreversible {
+			return chainInfo.LastIrreversibleBlockNum >= target, nil
+		}
+		return chainInfo.HeadBlockNum >= target, nil
+	})
+}
+
+// OpWaitForAccount waits for an account to exist, and optionally for
+// its contract's ABI to be served.
+type OpWaitForAccount struct {
+	WaitTimeout
+	Account eos.AccountName `json:"account"`
+	HasABI  bool            `json:"has_abi"`
+}
+
+func (op *OpWaitForAccount) Actions(b *BIOS) ([]*eos.Action, error) {
+	return nil, nil
+}
+
+func (op *OpWaitForAccount) Wait(b *BIOS) error {
+	timeout, err := op.duration()
+	if err != nil {
+		return err
+	}
+
+	what := fmt.Sprintf("account %s", op.Account)
+	if op.HasABI {
+		what = fmt.Sprintf("the ABI of %s", op.Account)
+	}
+	return b.waitFor(what, timeout, func() (bool, error) {
+		if op.HasABI {
+			code, err := b.validationAPI().GetCode(op.Account)
+			if err != nil {
+				return false, err
+			}
+			return len(code.ABI.Actions) != 0, nil
+		}
+		_, err := b.validationAPI().GetAccount(op.Account)
+		return err == nil, err
+	})
+}
+
+// OpWaitForTableRow waits for a row of a contract table, optionally
+// one whose fields match `match` (compared as printed, like "1000.0000 EOS").
+type OpWaitForTableRow struct {
+	WaitTimeout
+	Code       eos.AccountName `json:"code"`
+	Scope      string          `json:"scope"`
+	Table      string          `json:"table"`
+	LowerBound string          `json:"lower_bound"`
+	// KeyField is the row field holding the primary key, needed to
+	// page through tables larger than `observer.page_size`.
+	KeyField string            `json:"key_field"`
+	Match    map[string]string `json:"match"`
+}
+
+func (op *OpWaitForTableRow) Actions(b *BIOS) ([]*eos.Action, error) {
+	return nil, nil
+}
+
+func (op *OpWaitForTableRow) Wait(b *BIOS) error {
+	timeout, err := op.duration()
+	if err != nil {
+		return err
+	}
+
+	what := fmt.Sprintf("a row in %s/%s/%s", op.Code, op.Scope, op.Table)
+	return b.waitFor(what, timeout, func() (bool, error) {
+		rows, err := b.getAllTableRows(eos.GetTableRowsRequest{
+			JSON:       true,
+			Code:       string(op.Code),
+			Scope:      op.Scope,
+			Table:      op.Table,
+			LowerBound: op.LowerBound,
+		}, op.KeyField)
+		if err != nil {
+			return false, err
+		}
+
+		for _, raw := range rows {
+			decoder := json.NewDecoder(bytes.NewReader(raw))
+			decoder.UseNumber()
+			var row map[string]interface{}
+			if err := decoder.Decode(&row); err != nil {
+				return false, fmt.Errorf("decoding rows: %s", err)
+			}
+			if op.matches(row) {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+}
+
+func (op *OpWaitForTableRow) matches(row map[string]interface{}) bool {
+	for field, expected := range op.Match {
+		value, found := row[field]
+		if !found || fmt.Sprintf("%v", value) != expected {
+			return false
+		}
+	}
+	return true
+}
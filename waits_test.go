@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForTableRowMatches(t *testing.T) {
+	op := &OpWaitForTableRow{Match: map[string]string{"id": "1234567890123456789", "balance": "1000.0000 EOS"}}
+
+	var row map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(`{"id": 1234567890123456789, "balance": "1000.0000 EOS", "memo": ""}`))
+	decoder.UseNumber()
+	require.NoError(t, decoder.Decode(&row))
+	assert.True(t, op.matches(row))
+
+	row["balance"] = "999.0000 EOS"
+	assert.False(t, op.matches(row))
+
+	delete(row, "balance")
+	assert.False(t, op.matches(row))
+}
+
+func testWaitsBIOS(t *testing.T, handler http.HandlerFunc) *BIOS {
+	node := httptest.NewServer(handler)
+	t.Cleanup(node.Close)
+
+	nodeURL, err := url.Parse(node.URL)
+	require.NoError(t, err)
+	b := &BIOS{Config: &Config{}, API: eos.New(nodeURL, make([]byte, 32))}
+	b.Config.Observer.PageSize = 2
+	return b
+}
+
+func TestWaitForTableRowPages(t *testing.T) {
+	var lowerBounds []string
+	b := testWaitsBIOS(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			LowerBound string `json:"lower_bound"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		lowerBounds = append(lowerBounds, req.LowerBound)
+
+		if req.LowerBound == "" {
+			w.Write([]byte(`{"rows": [{"id": 1, "owner": "alice"}, {"id": 1234567890123456789, "owner": "bob"}], "more": true}`))
+			return
+		}
+		w.Write([]byte(`{"rows": [{"id": 1234567890123456789, "owner": "bob"}, {"id": 1234567890123456790, "owner": "carol"}], "more": false}`))
+	})
+
+	op := &OpWaitForTableRow{Code: AN("eosio"), Scope: "eosio", Table: "rows", KeyField: "id", Match: map[string]string{"owner": "carol"}}
+	require.NoError(t, op.Wait(b))
+	assert.Equal(t, []string{"", "1234567890123456789"}, lowerBounds)
+}
+
+func TestWaitForTableRowTimeout(t *testing.T) {
+	b := testWaitsBIOS(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"rows": [{"id": 1, "owner": "alice"}], "more": false}`))
+	})
+
+	op := &OpWaitForTableRow{WaitTimeout: WaitTimeout{Timeout: "1ns"}, Code: AN("eosio"), Scope: "eosio", Table: "rows", Match: map[string]string{"owner": "carol"}}
+	err := op.Wait(b)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not reached after 1ns")
+}
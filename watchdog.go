@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Watchdog alerts when the boot makes no progress for a while: no
+// new transaction pushed, or a head block stuck on the local node.
+// A silent stall otherwise burns the launch window unnoticed.
+type Watchdog struct {
+	b          *BIOS
+	stallAfter time.Duration
+	stop       chan struct{}
+
+	lock         sync.Mutex
+	lastProgress time.Time
+	lastActivity string
+	paused       bool
+}
+
+func (b *BIOS) startWatchdog() error {
+	stallAfter, err := time.ParseDuration(b.Config.Watchdog.StallAfter)
+	if err != nil {
+		return fmt.Errorf("watchdog.stall_after: %s", err)
+	}
+
+	w := &Watchdog{
+		b:            b,
+		stallAfter:   stallAfter,
+		stop:         make(chan struct{}),
+		lastProgress: time.Now(),
+		lastActivity: "start",
+	}
+	b.Watchdog = w
+
+	go w.run()
+
+	return nil
+}
+
+// Progress records that `activity` moved forward. It is safe to call
+// on a nil Watchdog.
+func (w *Watchdog) Progress(activity string) {
+	if w == nil {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.lastProgress = time.Now()
+	w.lastActivity = activity
+	w.paused = false
+}
+
+// Pause suspends progress tracking while we wait on other
+// participants, like for kickstart data. Resume with Progress.
+func (w *Watchdog) Pause(activity string) {
+	if w == nil {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.paused = true
+	w.lastActivity = activity
+}
+
+func (w *Watchdog) Stop() {
+	if w == nil {
+		return
+	}
+	close(w.stop)
+}
+
+func (w *Watchdog) run() {
+	interval := w.stallAfter / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastHead uint32
+	lastHeadChange := time.Now()
+	progressAlerted := false
+	headAlerted := false
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+
+		w.lock.Lock()
+		stalledFor := time.Since(w.lastProgress)
+		activity := w.lastActivity
+		paused := w.paused
+		w.lock.Unlock()
+
+		if !paused && stalledFor > w.stallAfter {
+			if !progressAlerted {
+				progressAlerted = true
+				w.alert("no_progress", fmt.Sprintf("no progress for %s, last activity: %s", stalledFor.Truncate(time.Second), activity))
+			}
+		} else {
+			progressAlerted = false
+		}
+
+		chainInfo, err := w.b.API.GetInfo()
+		if err != nil {
+			// Before stage 1 starts, there might be no node to speak to yet.
+			continue
+		}
+		if chainInfo.HeadBlockNum != lastHead {
+			lastHead = chainInfo.HeadBlockNum
+			lastHeadChange = time.Now()
+			headAlerted = false
+			continue
+		}
+		if time.Since(lastHeadChange) > w.stallAfter && !headAlerted {
+			headAlerted = true
+			w.alert("head_block_stuck", fmt.Sprintf("head block stuck at %d for %s", lastHead, time.Since(lastHeadChange).Truncate(time.Second)))
+		}
+	}
+}
+
+func (w *Watchdog) alert(kind, message string) {
+	milestone.Printf("WATCHDOG ALERT: %s\n", message)
+	if err := w.b.DispatchStalled(kind, message); err != nil {
+		milestone.Println("WARNING: dispatch stalled hook:", err)
+	}
+}
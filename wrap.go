@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/system"
+)
+
+// OpSetupWrap deploys `eosio.wrap` (the "sudo" contract), marks it
+// privileged, and hands its control over to the producers, as some
+// launch agreements require.
+type OpSetupWrap struct {
+	// Account defaults to `eosio.wrap`.
+	Account         eos.AccountName
+	ContractNameRef string `json:"contract_name_ref"`
+	// Controller is either "prods" (the default) for `eosio.prods@active`,
+	// the chain's 2/3+1 of active producers, or "abps" for a multisig
+	// of the Appointed Block Producers.
+	Controller string
+	// Threshold applies to the "abps" controller. Defaults to 2/3+1.
+	Threshold uint32
+}
+
+func (op *OpSetupWrap) account() eos.AccountName {
+	if op.Account == "" {
+		return AN("eosio.wrap")
+	}
+	return op.Account
+}
+
+func (op *OpSetupWrap) controllerAuthority(b *BIOS) (eos.Authority, error) {
+	switch op.Controller {
+	case "", "prods":
+		return eos.Authority{
+			Threshold: 1,
+			Accounts: []eos.PermissionLevelWeight{
+				{Permission: eos.PermissionLevel{Actor: AN("eosio.prods"), Permission: PN("active")}, Weight: 1},
+			},
+		}, nil
+	case "abps":
+		return b.appointedProducersAuthority(op.Threshold), nil
+	}
+	return eos.Authority{}, fmt.Errorf("unknown controller %q, use either \"prods\" or \"abps\"", op.Controller)
+}
+
+func (op *OpSetupWrap) Actions(b *BIOS) (out []*eos.Action, err error) {
+	account := op.account()
+
+	auth, err := op.controllerAuthority(b)
+	if err != nil {
+		return nil, err
+	}
+
+	contractRef := op.ContractNameRef
+	if contractRef == "" {
+		contractRef = "eosio.wrap"
+	}
+	contract, found := b.Config.Contracts[contractRef]
+	if !found {
+		return nil, fmt.Errorf("contract %q not found in your config's `contracts`", contractRef)
+	}
+
+	// Created with the ephemeral key, so we can set its code before
+	// handing it over.
+	out = append(out, system.NewNewAccount(AN("eosio"), account, b.EphemeralPrivateKey.PublicKey()))
+
+	setCode, err := system.NewSetCodeTx(account, contract.CodePath, contract.ABIPath)
+	if err != nil {
+		return nil, fmt.Errorf("NewSetCodeTx %s: %s", contractRef, err)
+	}
+	out = append(out, setCode.Actions...)
+
+	verbose.Printf("- Setting up %q as privileged, controlled by %q\n", account, op.Controller)
+	out = append(out,
+		system.NewSetPriv(account),
+		system.NewUpdateAuth(account, PN("active"), PN("owner"), auth, PN("active")),
+		system.NewUpdateAuth(account, PN("owner"), PN(""), auth, PN("owner")),
+	)
+
+	return
+}
+
+func (op *OpSetupWrap) Validate(b *BIOS) error {
+	account := op.account()
+
+	acct, err := b.validationAPI().GetAccount(account)
+	if err != nil {
+		return fmt.Errorf("get account %s: %s", account, err)
+	}
+
+	if !acct.Privileged {
+		return fmt.Errorf("%s is not privileged", account)
+	}
+
+	auth, err := op.controllerAuthority(b)
+	if err != nil {
+		return err
+	}
+	if err := validateAuthority(acct, "owner", auth); err != nil {
+		return err
+	}
+	if err := validateAuthority(acct, "active", auth); err != nil {
+		return err
+	}
+
+	return nil
+}